@@ -0,0 +1,202 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/Kansuler/octobe/v3"
+	"github.com/Kansuler/octobe/v3/internal/sqlsession"
+	"github.com/go-sql-driver/mysql"
+)
+
+type mysqlDB struct {
+	db *sql.DB
+}
+
+var _ MySQLDriver = &mysqlDB{}
+
+// Open creates a driver from a go-sql-driver/mysql DSN such as
+// "user:password@tcp(localhost:3306)/app?parseTime=true" and verifies connectivity.
+// Add parseTime=true to scan DATE and DATETIME columns into time.Time.
+func Open(ctx context.Context, dsn string) MySQLOpen {
+	return func() (MySQLDriver, error) {
+		cfg, err := mysql.ParseDSN(dsn)
+		if err != nil {
+			return nil, err
+		}
+		return OpenWithConfig(ctx, cfg)()
+	}
+}
+
+// OpenWithConfig creates a driver from a parsed connection configuration and verifies connectivity.
+func OpenWithConfig(ctx context.Context, cfg *mysql.Config) MySQLOpen {
+	return func() (MySQLDriver, error) {
+		if cfg == nil {
+			return nil, errors.New("config is nil")
+		}
+
+		connector, err := mysql.NewConnector(cfg)
+		if err != nil {
+			return nil, err
+		}
+
+		db := sql.OpenDB(connector)
+		if err := db.PingContext(ctx); err != nil {
+			_ = db.Close()
+			return nil, err
+		}
+
+		return &mysqlDB{
+			db: db,
+		}, nil
+	}
+}
+
+// OpenWithDB creates a driver from an existing database handle.
+func OpenWithDB(db *sql.DB) MySQLOpen {
+	return func() (MySQLDriver, error) {
+		if db == nil {
+			return nil, errors.New("db is nil")
+		}
+
+		return &mysqlDB{
+			db: db,
+		}, nil
+	}
+}
+
+// Begin starts a new non-transactional session.
+// The session pins one connection from the pool and keeps it until Close, so
+// session state such as variables and temporary tables is shared by its statements.
+func (d *mysqlDB) Begin(ctx context.Context) (octobe.Session[Builder], error) {
+	if d.db == nil {
+		return nil, errors.New("db is nil")
+	}
+
+	session, err := sqlsession.Begin(ctx, d.db)
+	if err != nil {
+		return nil, err
+	}
+
+	return &mysqlSession{
+		Session: session,
+	}, nil
+}
+
+// BeginTx starts a new transactional session.
+func (d *mysqlDB) BeginTx(ctx context.Context, opts ...Option) (octobe.Session[Builder], error) {
+	if d.db == nil {
+		return nil, errors.New("db is nil")
+	}
+
+	var cfg Config
+	for _, opt := range transactionOptions(opts) {
+		opt(&cfg)
+	}
+
+	session, err := sqlsession.BeginTx(ctx, d.db, &sql.TxOptions{
+		Isolation: cfg.txOptions.Isolation,
+		ReadOnly:  cfg.txOptions.ReadOnly,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &mysqlSession{
+		Session: session,
+	}, nil
+}
+
+// Close closes the database handle and all of its connections.
+func (d *mysqlDB) Close(_ context.Context) error {
+	if d.db == nil {
+		return errors.New("db is nil")
+	}
+	return d.db.Close()
+}
+
+// Ping verifies that the database is reachable.
+func (d *mysqlDB) Ping(ctx context.Context) error {
+	if d.db == nil {
+		return errors.New("db is nil")
+	}
+	return d.db.PingContext(ctx)
+}
+
+// StartTransaction starts a transactional session.
+func (d *mysqlDB) StartTransaction(ctx context.Context, fn func(session octobe.BuilderSession[Builder]) error, opts ...Option) (err error) {
+	return octobe.StartTransaction[*sql.DB](ctx, d, fn, opts...)
+}
+
+// mysqlSession adds the query builder of the driver to the shared database/sql session.
+type mysqlSession struct {
+	*sqlsession.Session
+}
+
+var _ octobe.Session[Builder] = &mysqlSession{}
+
+// Builder returns a query builder function for this session.
+func (s *mysqlSession) Builder() Builder {
+	return func(query string) Segment {
+		return &mysqlSegment{
+			segment: s.Segment(query),
+		}
+	}
+}
+
+// mysqlSegment represents a single-use query with arguments and execution tracking.
+type mysqlSegment struct {
+	segment *sqlsession.Segment
+}
+
+var _ ContextSegment = &mysqlSegment{}
+
+// Arguments sets query parameters and returns the segment for method chaining.
+func (s *mysqlSegment) Arguments(args ...any) Segment {
+	s.segment.Arguments(args...)
+	return s
+}
+
+// WithContext sets a context for this statement alone, used together with the session context.
+func (s *mysqlSegment) WithContext(ctx context.Context) Segment {
+	s.segment.WithContext(ctx)
+	return s
+}
+
+// Exec executes the query and returns the number of affected rows and the generated AUTO_INCREMENT id.
+func (s *mysqlSegment) Exec() (ExecResult, error) {
+	var result ExecResult
+	err := s.segment.Exec(func(res sql.Result) error {
+		affected, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+		lastInsertID, err := res.LastInsertId()
+		if err != nil {
+			return err
+		}
+		result = ExecResult{
+			RowsAffected: affected,
+			LastInsertID: lastInsertID,
+		}
+		return nil
+	})
+	if err != nil {
+		return ExecResult{}, err
+	}
+	return result, nil
+}
+
+// QueryRow executes the query expecting exactly one row and scans into dest.
+// It returns sql.ErrNoRows when the query selects no rows.
+func (s *mysqlSegment) QueryRow(dest ...any) error {
+	return s.segment.QueryRow(dest...)
+}
+
+// Query executes the query and calls cb for each row in the result set.
+func (s *mysqlSegment) Query(cb func(Rows) error) error {
+	return s.segment.Query(func(rows *sql.Rows) error {
+		return cb(rows)
+	})
+}
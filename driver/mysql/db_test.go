@@ -0,0 +1,220 @@
+package mysql_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/Kansuler/octobe/v3"
+	"github.com/Kansuler/octobe/v3/driver/mysql"
+	"github.com/Kansuler/octobe/v3/driver/mysql/mock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type Product struct {
+	ID   int64
+	Name string
+}
+
+func AddProduct(name string) octobe.Handler[Product, mysql.Builder] {
+	return func(builder mysql.Builder) (Product, error) {
+		res, err := builder(`INSERT INTO products (name) VALUES (?)`).Arguments(name).Exec()
+		if err != nil {
+			return Product{}, err
+		}
+		return Product{ID: res.LastInsertID, Name: name}, nil
+	}
+}
+
+func ProductsByName(name string) octobe.Handler[[]Product, mysql.Builder] {
+	return func(builder mysql.Builder) ([]Product, error) {
+		var products []Product
+		query := builder(`SELECT id, name FROM products WHERE name = ?`)
+		err := query.Arguments(name).Query(func(rows mysql.Rows) error {
+			for rows.Next() {
+				var product Product
+				if err := rows.Scan(&product.ID, &product.Name); err != nil {
+					return err
+				}
+				products = append(products, product)
+			}
+			return rows.Err()
+		})
+		return products, err
+	}
+}
+
+func TestMySQLWithTxInsideStartTransaction(t *testing.T) {
+	m := mock.NewMySQLMock()
+	name := "Some name"
+	m.ExpectBeginTx().WithOptions(mysql.TxOptions{Isolation: mysql.ReadCommitted})
+	m.ExpectExec("INSERT INTO products (name) VALUES (?)").WithArgs(name).WillReturnResult(mock.NewResult(7, 1))
+	m.ExpectQuery("SELECT id, name FROM products WHERE name = ?").WithArgs(name).WillReturnRows(mock.NewRows([]string{"id", "name"}).AddRow(int64(7), name))
+	m.ExpectCommit()
+	m.ExpectClose()
+
+	ob, err := octobe.New(mysql.OpenWithDB(m.DB()))
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	err = ob.StartTransaction(ctx, func(session octobe.BuilderSession[mysql.Builder]) error {
+		product, err := octobe.Execute(session, AddProduct(name))
+		if err != nil {
+			return err
+		}
+		assert.Equal(t, Product{ID: 7, Name: name}, product)
+
+		products, err := octobe.Execute(session, ProductsByName(name))
+		if err != nil {
+			return err
+		}
+		assert.Equal(t, []Product{product}, products)
+		return nil
+	}, mysql.WithTxOptions(mysql.TxOptions{Isolation: mysql.ReadCommitted}))
+	require.NoError(t, err)
+
+	require.NoError(t, ob.Close(ctx))
+	require.NoError(t, m.AllExpectationsMet())
+}
+
+func TestMySQLStartTransactionRollbackOnError(t *testing.T) {
+	m := mock.NewMySQLMock()
+	m.ExpectBeginTx().WithOptions(mysql.TxOptions{ReadOnly: true})
+	m.ExpectQuery("SELECT id, name FROM products WHERE name = ?").WithArgs("a").WillReturnRows(mock.NewRows([]string{"id", "name"}))
+	m.ExpectRollback()
+
+	ob, err := octobe.New(mysql.OpenWithDB(m.DB()))
+	require.NoError(t, err)
+
+	expectedErr := errors.New("something went wrong")
+	err = ob.StartTransaction(context.Background(), func(session octobe.BuilderSession[mysql.Builder]) error {
+		if _, err := octobe.Execute(session, ProductsByName("a")); err != nil {
+			return err
+		}
+		return expectedErr
+	}, mysql.WithTxOptions(mysql.TxOptions{ReadOnly: true}))
+	require.Equal(t, expectedErr, err)
+	require.NoError(t, m.AllExpectationsMet())
+}
+
+func TestMySQLStartTransactionRollbackOnPanic(t *testing.T) {
+	m := mock.NewMySQLMock()
+	m.ExpectBeginTx()
+	m.ExpectRollback()
+
+	ob, err := octobe.New(mysql.OpenWithDB(m.DB()))
+	require.NoError(t, err)
+
+	require.PanicsWithValue(t, "oh no!", func() {
+		_ = ob.StartTransaction(context.Background(), func(session octobe.BuilderSession[mysql.Builder]) error {
+			panic("oh no!")
+		})
+	})
+	require.NoError(t, m.AllExpectationsMet())
+}
+
+func TestMySQLWithoutTx(t *testing.T) {
+	m := mock.NewMySQLMock()
+	m.ExpectExec("DELETE FROM products WHERE id = ?").WithArgs(7).WillReturnResult(mock.NewResult(0, 1))
+
+	ob, err := octobe.New(mysql.OpenWithDB(m.DB()))
+	require.NoError(t, err)
+
+	session, err := ob.Begin(context.Background())
+	require.NoError(t, err)
+
+	res, err := session.Builder()(`DELETE FROM products WHERE id = ?`).Arguments(7).Exec()
+	require.NoError(t, err)
+	require.Equal(t, mysql.ExecResult{RowsAffected: 1}, res)
+
+	require.EqualError(t, session.Commit(), "cannot commit without transaction")
+	require.EqualError(t, session.Rollback(), "cannot rollback without transaction")
+	require.NoError(t, session.Close())
+	require.NoError(t, session.Close())
+
+	_, err = session.Builder()(`DELETE FROM products`).Exec()
+	require.EqualError(t, err, "session is closed")
+	require.NoError(t, m.AllExpectationsMet())
+}
+
+func TestMySQLSegmentSingleUse(t *testing.T) {
+	m := mock.NewMySQLMock()
+	m.ExpectBeginTx()
+	m.ExpectExec("UPDATE products SET name = ?").WithArgs("x")
+	m.ExpectCommit()
+
+	ob, err := octobe.New(mysql.OpenWithDB(m.DB()))
+	require.NoError(t, err)
+
+	err = ob.StartTransaction(context.Background(), func(session octobe.BuilderSession[mysql.Builder]) error {
+		segment := session.Builder()(`UPDATE products SET name = ?`).Arguments("x")
+		if _, err := segment.Exec(); err != nil {
+			return err
+		}
+
+		_, err := segment.Exec()
+		assert.ErrorIs(t, err, octobe.ErrAlreadyUsed)
+		assert.ErrorIs(t, segment.QueryRow(), octobe.ErrAlreadyUsed)
+		assert.ErrorIs(t, segment.Query(func(mysql.Rows) error { return nil }), octobe.ErrAlreadyUsed)
+		return nil
+	})
+	require.NoError(t, err)
+	require.NoError(t, m.AllExpectationsMet())
+}
+
+func TestMySQLQueryRowNoRows(t *testing.T) {
+	m := mock.NewMySQLMock()
+	m.ExpectBeginTx()
+	m.ExpectQuery("SELECT name FROM products WHERE id = ?").WithArgs(1).WillReturnRows(mock.NewRows([]string{"name"}))
+	m.ExpectRollback()
+
+	ob, err := octobe.New(mysql.OpenWithDB(m.DB()))
+	require.NoError(t, err)
+
+	err = ob.StartTransaction(context.Background(), func(session octobe.BuilderSession[mysql.Builder]) error {
+		var name string
+		return session.Builder()(`SELECT name FROM products WHERE id = ?`).Arguments(1).QueryRow(&name)
+	})
+	require.ErrorIs(t, err, sql.ErrNoRows)
+	require.NoError(t, m.AllExpectationsMet())
+}
+
+func TestMySQLOpenErrors(t *testing.T) {
+	ctx := context.Background()
+
+	_, err := octobe.New(mysql.Open(ctx, "not a dsn"))
+	require.Error(t, err)
+
+	_, err = octobe.New(mysql.OpenWithConfig(ctx, nil))
+	require.EqualError(t, err, "config is nil")
+
+	_, err = octobe.New(mysql.OpenWithDB(nil))
+	require.EqualError(t, err, "db is nil")
+}
+
+func TestMySQLSegmentWithContext(t *testing.T) {
+	m := mock.NewMySQLMock()
+	m.ExpectBeginTx()
+	m.ExpectRollback()
+
+	ob, err := octobe.New(mysql.OpenWithDB(m.DB()))
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	stmtCtx, cancel := context.WithCancel(ctx)
+	cancel()
+	err = ob.StartTransaction(ctx, func(session octobe.BuilderSession[mysql.Builder]) error {
+		var n int
+		err := session.Builder()(`SELECT SLEEP(10)`).(mysql.ContextSegment).WithContext(stmtCtx).QueryRow(&n)
+		require.ErrorIs(t, err, octobe.ErrStatementCanceled)
+		require.ErrorIs(t, err, context.Canceled)
+
+		_, err = octobe.Execute(session, AddProduct("gadget"))
+		require.ErrorContains(t, err, "transaction aborted by an earlier statement")
+		return nil
+	})
+	require.ErrorIs(t, err, octobe.ErrStatementCanceled)
+	require.NoError(t, m.AllExpectationsMet())
+}
@@ -0,0 +1,95 @@
+// Package mock provides an expectation-based fake for the MySQL driver. It is
+// built on the database/sql mock in driver/sql/mock and adds MySQL specific
+// transaction options, so the expectation API, and the postgres mock features
+// it leaves out, are the same for both drivers.
+package mock
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/Kansuler/octobe/v3/driver/mysql"
+	sqlmock "github.com/Kansuler/octobe/v3/driver/sql/mock"
+)
+
+type (
+	PingExpectation     = sqlmock.PingExpectation
+	CloseExpectation    = sqlmock.CloseExpectation
+	ExecExpectation     = sqlmock.ExecExpectation
+	QueryExpectation    = sqlmock.QueryExpectation
+	CommitExpectation   = sqlmock.CommitExpectation
+	RollbackExpectation = sqlmock.RollbackExpectation
+
+	// Result is a driver.Result for mocking Exec operation results.
+	Result = sqlmock.Result
+
+	// Rows provides a mock result set for Query and QueryRow expectations.
+	Rows = sqlmock.Rows
+
+	// ArgMatcher matches a single argument of a mocked call.
+	ArgMatcher = sqlmock.ArgMatcher
+)
+
+var ErrNoExpectation = sqlmock.ErrNoExpectation
+
+// NewResult creates a result for mocking Exec operations, which the driver
+// reports as ExecResult.LastInsertID and ExecResult.RowsAffected.
+func NewResult(lastInsertID, rowsAffected int64) Result {
+	return sqlmock.NewResult(lastInsertID, rowsAffected)
+}
+
+// AnyArg matches any argument, including nil.
+func AnyArg() ArgMatcher { return sqlmock.AnyArg() }
+
+// AnyOfType matches any argument of type T. If T is an interface type, it
+// matches arguments implementing it.
+func AnyOfType[T any]() ArgMatcher { return sqlmock.AnyOfType[T]() }
+
+// TimeWithin matches a time.Time, or a non-nil *time.Time, that lies within d
+// of the time the call is matched, for timestamps set with time.Now().
+func TimeWithin(d time.Duration) ArgMatcher { return sqlmock.TimeWithin(d) }
+
+// Regexp matches string and []byte arguments against the regular expression.
+// It panics if pattern does not compile.
+func Regexp(pattern string) ArgMatcher { return sqlmock.Regexp(pattern) }
+
+// JSONEq matches arguments that hold JSON semantically equal to expected,
+// ignoring formatting and key order. It panics if expected is not valid JSON.
+func JSONEq(expected string) ArgMatcher { return sqlmock.JSONEq(expected) }
+
+// Func matches arguments for which fn returns true.
+func Func(fn func(any) bool) ArgMatcher { return sqlmock.Func(fn) }
+
+// NewRows creates an empty result set with the given columns.
+func NewRows(columns []string) *Rows {
+	return sqlmock.NewRows(columns)
+}
+
+// MySQLMock provides a fake connector for testing MySQL handlers without a
+// database. Pass DB() to mysql.OpenWithDB.
+type MySQLMock struct {
+	*sqlmock.SQLMock
+}
+
+// NewMySQLMock creates a new mock connector for testing.
+func NewMySQLMock() *MySQLMock {
+	return &MySQLMock{SQLMock: sqlmock.NewSQLMock()}
+}
+
+// ExpectBeginTx configures an expectation for starting a transaction.
+func (m *MySQLMock) ExpectBeginTx() *BeginTxExpectation {
+	return &BeginTxExpectation{BeginTxExpectation: m.SQLMock.ExpectBeginTx()}
+}
+
+type BeginTxExpectation struct {
+	*sqlmock.BeginTxExpectation
+}
+
+// WithOptions makes this expectation match only transactions started with the given options.
+func (e *BeginTxExpectation) WithOptions(opts mysql.TxOptions) *BeginTxExpectation {
+	e.BeginTxExpectation.WithOptions(sql.TxOptions{
+		Isolation: opts.Isolation,
+		ReadOnly:  opts.ReadOnly,
+	})
+	return e
+}
@@ -0,0 +1,66 @@
+package mock
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/Kansuler/octobe/v3/driver/mysql"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMySQLMock(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Exec with last insert id", func(t *testing.T) {
+		m := NewMySQLMock()
+		m.ExpectExec("INSERT INTO events (name) VALUES (?)").WithArgs("test").WillReturnResult(NewResult(42, 1))
+
+		res, err := m.DB().ExecContext(ctx, "INSERT INTO events (name) VALUES (?)", "test")
+		require.NoError(t, err)
+		id, err := res.LastInsertId()
+		require.NoError(t, err)
+		require.Equal(t, int64(42), id)
+		require.NoError(t, m.AllExpectationsMet())
+	})
+
+	t.Run("Query rows", func(t *testing.T) {
+		m := NewMySQLMock()
+		m.ExpectQuery("SELECT name FROM users WHERE id = ?").WithArgs(1).WillReturnRows(NewRows([]string{"name"}).AddRow("John"))
+
+		var name string
+		require.NoError(t, m.DB().QueryRowContext(ctx, "SELECT name FROM users WHERE id = ?", 1).Scan(&name))
+		require.Equal(t, "John", name)
+		require.NoError(t, m.AllExpectationsMet())
+	})
+
+	t.Run("Transaction options", func(t *testing.T) {
+		m := NewMySQLMock()
+		m.ExpectBeginTx().WithOptions(mysql.TxOptions{Isolation: mysql.RepeatableRead, ReadOnly: true})
+		m.ExpectCommit()
+
+		tx, err := m.DB().BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+		require.NoError(t, err)
+		require.NoError(t, tx.Commit())
+		require.NoError(t, m.AllExpectationsMet())
+	})
+
+	t.Run("Transaction options mismatch", func(t *testing.T) {
+		m := NewMySQLMock()
+		m.ExpectBeginTx().WithOptions(mysql.TxOptions{Isolation: mysql.Serializable})
+
+		_, err := m.DB().BeginTx(ctx, nil)
+		require.ErrorIs(t, err, ErrNoExpectation)
+	})
+
+	t.Run("Begin error", func(t *testing.T) {
+		m := NewMySQLMock()
+		expectedErr := errors.New("begin failed")
+		m.ExpectBeginTx().WillReturnError(expectedErr)
+
+		_, err := m.DB().BeginTx(ctx, nil)
+		require.ErrorIs(t, err, expectedErr)
+		require.NoError(t, m.AllExpectationsMet())
+	})
+}
@@ -0,0 +1,125 @@
+// Package mysql implements an Octobe driver for MySQL and MariaDB on top of
+// github.com/go-sql-driver/mysql.
+//
+// Handlers look the same as with the PostgreSQL driver, only the Builder type
+// changes and placeholders are written as `?`. MySQL has no RETURNING clause,
+// so generated keys are read from ExecResult.LastInsertID:
+//
+//	func CreateUser(name string) octobe.Handler[int64, mysql.Builder] {
+//	    return func(builder mysql.Builder) (int64, error) {
+//	        res, err := builder(`INSERT INTO users (name) VALUES (?)`).
+//	            Arguments(name).
+//	            Exec()
+//	        return res.LastInsertID, err
+//	    }
+//	}
+package mysql
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/Kansuler/octobe/v3"
+)
+
+type (
+	// MySQLDriver is the driver interface returned by Open, OpenWithConfig and OpenWithDB.
+	MySQLDriver = octobe.Driver[*sql.DB, Config, Builder]
+
+	// MySQLOpen opens a MySQL driver.
+	MySQLOpen = octobe.Open[*sql.DB, Config, Builder]
+
+	// Option configures MySQL session behavior.
+	Option = octobe.Option[Config]
+)
+
+// Builder constructs executable query segments from SQL strings.
+type Builder func(query string) Segment
+
+// IsolationLevel is the transaction isolation level used in TxOptions.
+type IsolationLevel = sql.IsolationLevel
+
+// Isolation levels supported by MySQL and MariaDB. Default uses the server's
+// transaction_isolation setting, which is REPEATABLE READ unless configured otherwise.
+const (
+	Default         IsolationLevel = sql.LevelDefault
+	ReadUncommitted IsolationLevel = sql.LevelReadUncommitted
+	ReadCommitted   IsolationLevel = sql.LevelReadCommitted
+	RepeatableRead  IsolationLevel = sql.LevelRepeatableRead
+	Serializable    IsolationLevel = sql.LevelSerializable
+)
+
+// TxOptions configures transaction isolation level and read-only mode.
+type TxOptions struct {
+	Isolation IsolationLevel
+	ReadOnly  bool
+}
+
+// Config stores MySQL driver options.
+type Config struct {
+	txOptions *TxOptions
+}
+
+// WithTxOptions configures transaction options for the session.
+func WithTxOptions(options TxOptions) Option {
+	return func(c *Config) {
+		c.txOptions = &options
+	}
+}
+
+// transactionOptions applies transaction options to the given options slice, ensuring a non-nil txOptions field.
+func transactionOptions(opts []Option) []Option {
+	txOpts := make([]Option, 0, len(opts)+1)
+	txOpts = append(txOpts, opts...)
+	txOpts = append(txOpts, func(c *Config) {
+		if c.txOptions == nil {
+			c.txOptions = &TxOptions{}
+		}
+	})
+	return txOpts
+}
+
+// Segment represents a prepared query with arguments that can be executed once.
+// Once executed, the segment becomes invalid and cannot be reused.
+//
+// The single-use nature prevents accidental query reuse and ensures predictable behavior.
+// To execute the same query multiple times, create new segments each time.
+type Segment interface {
+	Arguments(args ...any) Segment
+	Exec() (ExecResult, error)
+	QueryRow(dest ...any) error
+	Query(cb func(Rows) error) error
+}
+
+// ContextSegment is a Segment that can run under a context of its own, see octobe.ErrStatementCanceled.
+type ContextSegment interface {
+	Segment
+	WithContext(ctx context.Context) Segment
+}
+
+// ExecResult contains the outcome of an INSERT, UPDATE, or DELETE operation.
+type ExecResult struct {
+	RowsAffected int64
+
+	// LastInsertID is the AUTO_INCREMENT value generated by the statement. For
+	// multi-row inserts it is the value generated for the first row.
+	LastInsertID int64
+}
+
+// Rows provides iteration over query result sets.
+// Callers must check Err() after Next() returns false to detect premature termination.
+type Rows interface {
+	// Err returns any error encountered during iteration.
+	// Only call after rows are closed or Next() returns false.
+	Err() error
+
+	// Next advances to the next row, returning false when no more rows exist.
+	// Automatically closes rows when iteration completes.
+	Next() bool
+
+	// Scan copies column values from the current row into dest variables.
+	// Must call Next() and verify it returned true before calling Scan.
+	Scan(dest ...any) error
+}
+
+var _ Rows = (*sql.Rows)(nil)
@@ -0,0 +1,226 @@
+package fakeserver
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// Statement kinds. Transaction control is recognized by its leading keywords
+// so expectations need not spell out the options pgx adds to BEGIN.
+const (
+	kindStatement = iota
+	kindBegin
+	kindCommit
+	kindRollback
+)
+
+var kindNames = [...]string{
+	kindStatement: "statement",
+	kindBegin:     "BEGIN",
+	kindCommit:    "COMMIT",
+	kindRollback:  "ROLLBACK",
+}
+
+// Expectation is a statement the server expects, and the answer it sends.
+type Expectation struct {
+	kind  int
+	query string
+
+	args    []any
+	hasArgs bool
+
+	columns []string
+	rows    [][]any
+	tag     string
+	err     *pgconn.PgError
+
+	called bool
+}
+
+// WithArgs sets the arguments the statement must be executed with. Arguments
+// are compared in their wire encoding, so 7 matches an int32, int64 or string
+// argument of "7" sent in text format, and nil matches NULL.
+func (e *Expectation) WithArgs(args ...any) *Expectation {
+	e.args = args
+	e.hasArgs = true
+	return e
+}
+
+// WillReturnRows makes the statement return rows with the given columns. The
+// column types are inferred from the Go types of the first non-nil value in
+// each column, so 1 is sent as int8 and time.Time as timestamptz. Columns
+// without a value are sent as text.
+func (e *Expectation) WillReturnRows(columns []string, rows ...[]any) *Expectation {
+	e.columns = columns
+	e.rows = rows
+	return e
+}
+
+// WillReturnResult sets the command tag of the statement, such as "UPDATE 3".
+// By default the tag is derived from the statement and the number of rows.
+func (e *Expectation) WillReturnResult(tag string) *Expectation {
+	e.tag = tag
+	return e
+}
+
+// WillReturnError makes the statement fail with err. Inside a transaction
+// the transaction is aborted, as it would be by PostgreSQL, except for a
+// failed COMMIT, which ends it.
+func (e *Expectation) WillReturnError(err *pgconn.PgError) *Expectation {
+	e.err = err
+	return e
+}
+
+func (e *Expectation) String() string {
+	if e.kind != kindStatement {
+		return kindNames[e.kind]
+	}
+	s := fmt.Sprintf("%q", e.query)
+	if e.hasArgs {
+		s += fmt.Sprintf(" with args %v", e.args)
+	}
+	return s
+}
+
+// match reports why a statement does not match the expectation, or nil when it does.
+func (e *Expectation) match(types *pgtype.Map, kind int, query string, params [][]byte, formats []int16) error {
+	if kind != e.kind {
+		return fmt.Errorf("expected %s", e)
+	}
+	if kind != kindStatement {
+		return nil
+	}
+	if normalize(query) != normalize(e.query) {
+		return fmt.Errorf("expected %s", e)
+	}
+	if !e.hasArgs {
+		return nil
+	}
+	if len(params) != len(e.args) {
+		return fmt.Errorf("expected %d arguments, got %d", len(e.args), len(params))
+	}
+	for i, arg := range e.args {
+		want, err := types.Encode(0, paramFormat(formats, i), arg, nil)
+		if err != nil {
+			return fmt.Errorf("encode expected argument %d: %w", i+1, err)
+		}
+		if (want == nil) != (params[i] == nil) || !bytes.Equal(want, params[i]) {
+			return fmt.Errorf("argument $%d: expected %v, got %s", i+1, arg, formatParam(params[i], paramFormat(formats, i)))
+		}
+	}
+	return nil
+}
+
+// commandTag returns the configured command tag or one derived from the statement.
+func (e *Expectation) commandTag() string {
+	if e.tag != "" {
+		return e.tag
+	}
+	if e.kind != kindStatement {
+		return kindNames[e.kind]
+	}
+	verb := ""
+	if fields := strings.Fields(e.query); len(fields) > 0 {
+		verb = strings.ToUpper(fields[0])
+	}
+	switch verb {
+	case "SELECT":
+		return fmt.Sprintf("SELECT %d", len(e.rows))
+	case "INSERT":
+		return fmt.Sprintf("INSERT 0 %d", len(e.rows))
+	case "UPDATE", "DELETE", "MERGE":
+		return fmt.Sprintf("%s %d", verb, len(e.rows))
+	}
+	return verb
+}
+
+// columnOIDs infers the type of each column from its first non-nil value.
+func (e *Expectation) columnOIDs(types *pgtype.Map) []uint32 {
+	oids := make([]uint32, len(e.columns))
+	for i := range oids {
+		oids[i] = pgtype.TextOID
+		for _, row := range e.rows {
+			if i >= len(row) || row[i] == nil {
+				continue
+			}
+			if t, ok := types.TypeForValue(row[i]); ok {
+				oids[i] = t.OID
+			}
+			break
+		}
+	}
+	return oids
+}
+
+// classify returns the kind of a statement.
+func classify(query string) int {
+	fields := strings.Fields(strings.ToLower(query))
+	if len(fields) == 0 {
+		return kindStatement
+	}
+	switch fields[0] {
+	case "begin":
+		return kindBegin
+	case "start":
+		if len(fields) > 1 && fields[1] == "transaction" {
+			return kindBegin
+		}
+	case "commit", "end":
+		return kindCommit
+	case "rollback", "abort":
+		// ROLLBACK TO SAVEPOINT is an ordinary statement.
+		if rollsBackToSavepoint(query) {
+			return kindStatement
+		}
+		return kindRollback
+	}
+	return kindStatement
+}
+
+// rollsBackToSavepoint reports whether a statement is ROLLBACK TO SAVEPOINT,
+// which PostgreSQL accepts in an aborted transaction.
+func rollsBackToSavepoint(query string) bool {
+	fields := strings.Fields(strings.ToLower(query))
+	return len(fields) > 1 && (fields[0] == "rollback" || fields[0] == "abort") && fields[1] == "to"
+}
+
+// normalize collapses whitespace and drops a trailing semicolon.
+func normalize(query string) string {
+	return strings.TrimSuffix(strings.Join(strings.Fields(query), " "), ";")
+}
+
+// isEmpty reports whether a query holds only comments and whitespace, like
+// the "-- ping" pgx sends to check connections.
+func isEmpty(query string) bool {
+	for line := range strings.Lines(query) {
+		line = strings.TrimSpace(line)
+		if line != "" && line != ";" && !strings.HasPrefix(line, "--") {
+			return false
+		}
+	}
+	return true
+}
+
+func paramFormat(formats []int16, i int) int16 {
+	switch len(formats) {
+	case 0:
+		return pgtype.TextFormatCode
+	case 1:
+		return formats[0]
+	}
+	return formats[i]
+}
+
+func formatParam(param []byte, format int16) string {
+	switch {
+	case param == nil:
+		return "NULL"
+	case format == pgtype.TextFormatCode:
+		return fmt.Sprintf("%q", param)
+	}
+	return fmt.Sprintf("binary %x", param)
+}
@@ -0,0 +1,229 @@
+// Package fakeserver runs an in-process server that speaks the PostgreSQL
+// wire protocol and answers statements from scripted expectations, so the full
+// driver stack can be tested with go test alone: DSN parsing in OpenPGX and
+// OpenPGXPool, pgx connections, pooled connections and real pgx.Tx
+// transactions.
+//
+// The server is built on pgproto3. It supports the simple and the extended
+// query protocol, including the prepared statements pgx caches, transactions
+// and error responses:
+//
+//	srv := fakeserver.NewT(t)
+//	srv.ExpectBegin()
+//	srv.Expect(`INSERT INTO users (email) VALUES ($1) RETURNING id, email`).
+//	    WithArgs("alice@example.com").
+//	    WillReturnRows([]string{"id", "email"}, []any{1, "alice@example.com"})
+//	srv.ExpectCommit()
+//
+//	db, err := octobe.New(postgres.OpenPGXPool(ctx, srv.DSN()))
+//
+// Statements match expectations in the order they were added, across all
+// connections, with whitespace collapsed. A statement that does not match
+// fails with SQLSTATE XX000 and is reported by AllExpectationsMet. BEGIN,
+// COMMIT and ROLLBACK match ExpectBegin, ExpectCommit and ExpectRollback
+// whatever options they carry; the statements themselves are listed by
+// Statements. Pings are answered without expectations.
+package fakeserver
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// Server is a fake PostgreSQL server listening on a local TCP port.
+type Server struct {
+	listener net.Listener
+	wg       sync.WaitGroup
+
+	mu           sync.Mutex
+	expectations []*Expectation
+	statements   []string
+	unexpected   []error
+	conns        map[net.Conn]struct{}
+	closed       bool
+	nextPID      uint32
+}
+
+// New starts a server on a free port of the loopback interface.
+func New() (*Server, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Server{
+		listener: listener,
+		conns:    make(map[net.Conn]struct{}),
+	}
+	s.wg.Add(1)
+	go s.serve()
+	return s, nil
+}
+
+// NewT starts a server that is closed when the test ends, after which its
+// expectations are verified.
+func NewT(t testing.TB) *Server {
+	t.Helper()
+	s, err := New()
+	if err != nil {
+		t.Fatalf("start fake server: %v", err)
+	}
+	t.Cleanup(func() {
+		s.Close()
+		if err := s.AllExpectationsMet(); err != nil {
+			t.Error(err)
+		}
+	})
+	return s
+}
+
+// Addr returns the address the server listens on.
+func (s *Server) Addr() string {
+	return s.listener.Addr().String()
+}
+
+// DSN returns a connection string for the server.
+func (s *Server) DSN() string {
+	return fmt.Sprintf("postgres://octobe@%s/octobe?sslmode=disable", s.Addr())
+}
+
+// Close stops the server and closes every open connection.
+func (s *Server) Close() {
+	_ = s.listener.Close()
+	s.mu.Lock()
+	s.closed = true
+	for conn := range s.conns {
+		_ = conn.Close()
+	}
+	s.mu.Unlock()
+	s.wg.Wait()
+}
+
+// Expect adds an expectation for a statement, whether it is run with Exec,
+// Query or QueryRow.
+func (s *Server) Expect(query string) *Expectation {
+	return s.add(&Expectation{kind: kindStatement, query: query})
+}
+
+// ExpectBegin adds an expectation for a statement starting a transaction.
+func (s *Server) ExpectBegin() *Expectation {
+	return s.add(&Expectation{kind: kindBegin})
+}
+
+// ExpectCommit adds an expectation for a statement committing a transaction.
+func (s *Server) ExpectCommit() *Expectation {
+	return s.add(&Expectation{kind: kindCommit})
+}
+
+// ExpectRollback adds an expectation for a statement rolling back a transaction.
+func (s *Server) ExpectRollback() *Expectation {
+	return s.add(&Expectation{kind: kindRollback})
+}
+
+func (s *Server) add(e *Expectation) *Expectation {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.expectations = append(s.expectations, e)
+	return e
+}
+
+// Statements returns the statements the server received, in order, including
+// those that matched no expectation.
+func (s *Server) Statements() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.statements...)
+}
+
+// AllExpectationsMet returns an error for the first unexpected statement, or
+// else for the first expectation that was not met.
+func (s *Server) AllExpectationsMet() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.unexpected) > 0 {
+		return s.unexpected[0]
+	}
+	for _, e := range s.expectations {
+		if !e.called {
+			return fmt.Errorf("fakeserver: expectation not met: %s", e)
+		}
+	}
+	return nil
+}
+
+// match consumes the next expectation for a statement.
+func (s *Server) match(types *pgtype.Map, kind int, query string, params [][]byte, formats []int16) (*Expectation, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.statements = append(s.statements, query)
+
+	for _, e := range s.expectations {
+		if e.called {
+			continue
+		}
+		if err := e.match(types, kind, query, params, formats); err != nil {
+			return nil, s.unexpectedStatement(query, err)
+		}
+		e.called = true
+		return e, nil
+	}
+	return nil, s.unexpectedStatement(query, errors.New("all expectations were already met"))
+}
+
+func (s *Server) unexpectedStatement(query string, reason error) error {
+	err := fmt.Errorf("fakeserver: unexpected statement %q: %w", strings.TrimSpace(query), reason)
+	s.unexpected = append(s.unexpected, err)
+	return err
+}
+
+// describe returns the first pending expectation for query, so prepared
+// statements can be described before they are executed.
+func (s *Server) describe(query string) *Expectation {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, e := range s.expectations {
+		if !e.called && e.kind == kindStatement && normalize(e.query) == normalize(query) {
+			return e
+		}
+	}
+	return nil
+}
+
+func (s *Server) serve() {
+	defer s.wg.Done()
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+
+		s.mu.Lock()
+		if s.closed {
+			s.mu.Unlock()
+			_ = conn.Close()
+			return
+		}
+		s.conns[conn] = struct{}{}
+		s.nextPID++
+		pid := s.nextPID
+		s.mu.Unlock()
+
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			defer func() {
+				s.mu.Lock()
+				delete(s.conns, conn)
+				s.mu.Unlock()
+				_ = conn.Close()
+			}()
+			newSession(s, conn, pid).run()
+		}()
+	}
+}
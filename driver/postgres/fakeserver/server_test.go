@@ -0,0 +1,305 @@
+package fakeserver_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Kansuler/octobe/v3"
+	"github.com/Kansuler/octobe/v3/driver/postgres"
+	"github.com/Kansuler/octobe/v3/driver/postgres/fakeserver"
+	"github.com/Kansuler/octobe/v3/driver/postgres/mock"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/require"
+)
+
+type product struct {
+	ID      int
+	Name    string
+	Created time.Time
+}
+
+const (
+	insertProductSQL = `INSERT INTO products (name) VALUES ($1) RETURNING id, name, created_at`
+	listProductsSQL  = `SELECT id, name, created_at FROM products WHERE id > $1 ORDER BY id`
+	renameProductSQL = `UPDATE products SET name = $1 WHERE id = $2`
+)
+
+func createProduct(name string) octobe.Handler[product, postgres.Builder] {
+	return func(builder postgres.Builder) (product, error) {
+		var p product
+		err := builder(insertProductSQL).Arguments(name).QueryRow(&p.ID, &p.Name, &p.Created)
+		p.Created = p.Created.UTC()
+		return p, err
+	}
+}
+
+func listProducts(after int) octobe.Handler[[]product, postgres.Builder] {
+	return func(builder postgres.Builder) ([]product, error) {
+		var products []product
+		err := builder(listProductsSQL).Arguments(after).Query(func(rows postgres.Rows) error {
+			for rows.Next() {
+				var p product
+				if err := rows.Scan(&p.ID, &p.Name, &p.Created); err != nil {
+					return err
+				}
+				p.Created = p.Created.UTC()
+				products = append(products, p)
+			}
+			return rows.Err()
+		})
+		return products, err
+	}
+}
+
+func renameProduct(id int, name string) octobe.Handler[postgres.ExecResult, postgres.Builder] {
+	return func(builder postgres.Builder) (postgres.ExecResult, error) {
+		return builder(renameProductSQL).Arguments(name, id).Exec()
+	}
+}
+
+func exec(query string) octobe.Handler[postgres.ExecResult, postgres.Builder] {
+	return func(builder postgres.Builder) (postgres.ExecResult, error) {
+		return builder(query).Exec()
+	}
+}
+
+var created = time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+func TestOpenPGXPool(t *testing.T) {
+	ctx := context.Background()
+	srv := fakeserver.NewT(t)
+
+	db, err := octobe.New(postgres.OpenPGXPool(ctx, srv.DSN()))
+	require.NoError(t, err)
+	defer db.Close(ctx)
+
+	t.Run("transaction commits", func(t *testing.T) {
+		srv.ExpectBegin()
+		srv.Expect(insertProductSQL).
+			WithArgs("lamp").
+			WillReturnRows([]string{"id", "name", "created_at"}, []any{1, "lamp", created})
+		srv.Expect(renameProductSQL).WithArgs("desk lamp", 1).WillReturnResult("UPDATE 1")
+		srv.ExpectCommit()
+
+		var renamed postgres.ExecResult
+		err := db.StartTransaction(ctx, func(session octobe.BuilderSession[postgres.Builder]) error {
+			p, err := octobe.Execute(session, createProduct("lamp"))
+			if err != nil {
+				return err
+			}
+			require.Equal(t, product{ID: 1, Name: "lamp", Created: created}, p)
+
+			renamed, err = octobe.Execute(session, renameProduct(p.ID, "desk lamp"))
+			return err
+		}, postgres.WithPGXTxOptions(postgres.PGXTxOptions{IsoLevel: pgx.Serializable}))
+		require.NoError(t, err)
+		require.Equal(t, int64(1), renamed.RowsAffected)
+		require.NoError(t, srv.AllExpectationsMet())
+	})
+
+	t.Run("errors roll back", func(t *testing.T) {
+		srv.ExpectBegin()
+		srv.Expect(insertProductSQL).
+			WithArgs("lamp").
+			WillReturnError(mock.UniqueViolation("products_name_key"))
+		srv.ExpectRollback()
+
+		err := db.StartTransaction(ctx, func(session octobe.BuilderSession[postgres.Builder]) error {
+			_, err := octobe.Execute(session, createProduct("lamp"))
+			return err
+		})
+		var pgErr *pgconn.PgError
+		require.ErrorAs(t, err, &pgErr)
+		require.Equal(t, "23505", pgErr.Code)
+		require.Equal(t, "products_name_key", pgErr.ConstraintName)
+		require.NoError(t, srv.AllExpectationsMet())
+	})
+
+	t.Run("pooled session queries rows", func(t *testing.T) {
+		srv.Expect(listProductsSQL).
+			WithArgs(0).
+			WillReturnRows([]string{"id", "name", "created_at"},
+				[]any{1, "lamp", created},
+				[]any{2, "chair", created.Add(time.Hour)},
+			)
+		srv.Expect(listProductsSQL).
+			WithArgs(2).
+			WillReturnRows([]string{"id", "name", "created_at"})
+
+		session, err := db.Begin(ctx)
+		require.NoError(t, err)
+		defer session.Close()
+
+		products, err := octobe.Execute(session, listProducts(0))
+		require.NoError(t, err)
+		require.Equal(t, []product{{1, "lamp", created}, {2, "chair", created.Add(time.Hour)}}, products)
+
+		products, err = octobe.Execute(session, listProducts(2))
+		require.NoError(t, err)
+		require.Empty(t, products)
+		require.NoError(t, srv.AllExpectationsMet())
+	})
+}
+
+func TestOpenPGX(t *testing.T) {
+	ctx := context.Background()
+	srv := fakeserver.NewT(t)
+
+	db, err := octobe.New(postgres.OpenPGX(ctx, srv.DSN()))
+	require.NoError(t, err)
+	defer db.Close(ctx)
+	require.NoError(t, db.Ping(ctx))
+
+	t.Run("failed commit", func(t *testing.T) {
+		srv.ExpectBegin()
+		srv.Expect(renameProductSQL).WithArgs("desk", 1)
+		srv.ExpectCommit().WillReturnError(mock.SerializationFailure())
+
+		err := db.StartTransaction(ctx, func(session octobe.BuilderSession[postgres.Builder]) error {
+			_, err := octobe.Execute(session, renameProduct(1, "desk"))
+			return err
+		})
+		var pgErr *pgconn.PgError
+		require.ErrorAs(t, err, &pgErr)
+		require.Equal(t, "40001", pgErr.Code)
+		require.NoError(t, srv.AllExpectationsMet())
+	})
+
+	t.Run("aborted transaction", func(t *testing.T) {
+		srv.ExpectBegin()
+		srv.Expect(renameProductSQL).WithArgs("desk", 1).WillReturnError(mock.DeadlockDetected())
+		srv.ExpectCommit()
+
+		session, err := db.BeginTx(ctx)
+		require.NoError(t, err)
+		_, err = octobe.Execute(session, renameProduct(1, "desk"))
+		require.Error(t, err)
+
+		// PostgreSQL ignores statements until the transaction ends, and
+		// answers COMMIT with ROLLBACK.
+		_, err = octobe.Execute(session, renameProduct(1, "desk"))
+		require.ErrorContains(t, err, "25P02")
+		require.ErrorIs(t, session.Commit(), pgx.ErrTxCommitRollback)
+		require.NoError(t, srv.AllExpectationsMet())
+	})
+
+	t.Run("rollback to savepoint recovers an aborted transaction", func(t *testing.T) {
+		srv.ExpectBegin()
+		srv.Expect(`SAVEPOINT before_rename`)
+		srv.Expect(renameProductSQL).WithArgs("desk", 1).WillReturnError(mock.DeadlockDetected())
+		srv.Expect(`ROLLBACK TO SAVEPOINT before_rename`)
+		srv.Expect(renameProductSQL).WithArgs("desk", 2).WillReturnResult("UPDATE 1")
+		srv.ExpectCommit()
+
+		session, err := db.BeginTx(ctx)
+		require.NoError(t, err)
+		_, err = octobe.Execute(session, exec(`SAVEPOINT before_rename`))
+		require.NoError(t, err)
+		_, err = octobe.Execute(session, renameProduct(1, "desk"))
+		require.Error(t, err)
+
+		// Unlike other statements, PostgreSQL accepts ROLLBACK TO SAVEPOINT
+		// in an aborted transaction and resumes it.
+		_, err = octobe.Execute(session, exec(`ROLLBACK TO SAVEPOINT before_rename`))
+		require.NoError(t, err)
+		res, err := octobe.Execute(session, renameProduct(2, "desk"))
+		require.NoError(t, err)
+		require.Equal(t, int64(1), res.RowsAffected)
+		require.NoError(t, session.Commit())
+		require.NoError(t, srv.AllExpectationsMet())
+	})
+
+	t.Run("unexpected statement", func(t *testing.T) {
+		srv, err := fakeserver.New()
+		require.NoError(t, err)
+		defer srv.Close()
+		srv.Expect(renameProductSQL).WithArgs("desk", 1)
+
+		db, err := octobe.New(postgres.OpenPGX(ctx, srv.DSN()))
+		require.NoError(t, err)
+		defer db.Close(ctx)
+
+		session, err := db.Begin(ctx)
+		require.NoError(t, err)
+		defer session.Close()
+
+		_, err = octobe.Execute(session, renameProduct(2, "desk"))
+		require.ErrorContains(t, err, `fakeserver: unexpected statement "UPDATE products SET name = $1 WHERE id = $2": argument $2: expected 1, got "2" (SQLSTATE XX000)`)
+		require.EqualError(t, srv.AllExpectationsMet(), `fakeserver: unexpected statement "UPDATE products SET name = $1 WHERE id = $2": argument $2: expected 1, got "2"`)
+	})
+}
+
+func TestServer(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("unmet expectations", func(t *testing.T) {
+		srv, err := fakeserver.New()
+		require.NoError(t, err)
+		defer srv.Close()
+
+		srv.ExpectBegin()
+		srv.Expect(`SELECT 1`).WillReturnRows([]string{"?column?"}, []any{1})
+		conn, err := pgx.Connect(ctx, srv.DSN())
+		require.NoError(t, err)
+		defer conn.Close(ctx)
+
+		tx, err := conn.BeginTx(ctx, pgx.TxOptions{IsoLevel: pgx.RepeatableRead, AccessMode: pgx.ReadOnly})
+		require.NoError(t, err)
+		require.EqualError(t, srv.AllExpectationsMet(), `fakeserver: expectation not met: "SELECT 1"`)
+		require.Equal(t, []string{"begin isolation level repeatable read read only"}, srv.Statements())
+
+		var n int
+		require.NoError(t, tx.QueryRow(ctx, `SELECT 1`).Scan(&n))
+		require.Equal(t, 1, n)
+		require.NoError(t, srv.AllExpectationsMet())
+
+		_, err = tx.Exec(ctx, `SELECT 2`)
+		require.ErrorContains(t, err, "all expectations were already met")
+	})
+
+	t.Run("null values", func(t *testing.T) {
+		srv := fakeserver.NewT(t)
+		srv.Expect(`SELECT name, note FROM products WHERE id = $1`).
+			WithArgs(nil).
+			WillReturnRows([]string{"name", "note"}, []any{"lamp", nil})
+
+		conn, err := pgx.Connect(ctx, srv.DSN())
+		require.NoError(t, err)
+		defer conn.Close(ctx)
+
+		var name string
+		var note *string
+		require.NoError(t, conn.QueryRow(ctx, `SELECT name, note FROM products WHERE id = $1`, nil).Scan(&name, &note))
+		require.Equal(t, "lamp", name)
+		require.Nil(t, note)
+	})
+
+	t.Run("simple protocol", func(t *testing.T) {
+		srv := fakeserver.NewT(t)
+		srv.Expect(`SELECT id, name FROM products WHERE name = 'lamp'`).
+			WillReturnRows([]string{"id", "name"}, []any{3, "lamp"})
+
+		conn, err := pgx.Connect(ctx, srv.DSN())
+		require.NoError(t, err)
+		defer conn.Close(ctx)
+
+		// The simple protocol interpolates arguments on the client.
+		var p product
+		err = conn.QueryRow(ctx, `SELECT id, name FROM products WHERE name = $1`, pgx.QueryExecModeSimpleProtocol, "lamp").Scan(&p.ID, &p.Name)
+		require.NoError(t, err)
+		require.Equal(t, product{ID: 3, Name: "lamp"}, p)
+	})
+
+	t.Run("closing the server closes connections", func(t *testing.T) {
+		srv, err := fakeserver.New()
+		require.NoError(t, err)
+
+		conn, err := pgx.Connect(ctx, srv.DSN())
+		require.NoError(t, err)
+		srv.Close()
+		require.Error(t, conn.Ping(ctx))
+		require.True(t, conn.IsClosed())
+	})
+}
@@ -0,0 +1,408 @@
+package fakeserver
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"regexp"
+	"strconv"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgproto3"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// Transaction status indicators sent with ReadyForQuery.
+const (
+	txIdle   = 'I'
+	txActive = 'T'
+	txFailed = 'E'
+)
+
+// session serves one client connection.
+type session struct {
+	server  *Server
+	conn    net.Conn
+	pid     uint32
+	backend *pgproto3.Backend
+	// types encodes arguments and rows. pgtype.Map is not safe for
+	// concurrent use, so every session has its own.
+	types *pgtype.Map
+
+	txStatus   byte
+	statements map[string]*statement
+	portals    map[string]*portal
+	// failed is set after an error in the extended protocol, which discards
+	// messages until the next Sync.
+	failed bool
+}
+
+type statement struct {
+	query     string
+	paramOIDs []uint32
+}
+
+type portal struct {
+	statement     *statement
+	params        [][]byte
+	paramFormats  []int16
+	resultFormats []int16
+}
+
+// result is the answer to an executed statement.
+type result struct {
+	expectation *Expectation
+	tag         string
+}
+
+func newSession(s *Server, conn net.Conn, pid uint32) *session {
+	return &session{
+		server:     s,
+		conn:       conn,
+		pid:        pid,
+		backend:    pgproto3.NewBackend(conn, conn),
+		types:      pgtype.NewMap(),
+		txStatus:   txIdle,
+		statements: make(map[string]*statement),
+		portals:    make(map[string]*portal),
+	}
+}
+
+func (c *session) run() {
+	if err := c.startup(); err != nil {
+		return
+	}
+	for {
+		msg, err := c.backend.Receive()
+		if err != nil {
+			return
+		}
+		if _, ok := msg.(*pgproto3.Terminate); ok {
+			return
+		}
+		c.handle(msg)
+		if err := c.backend.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+// startup answers SSL and GSS encryption requests with "no" and accepts any
+// user and database without authentication.
+func (c *session) startup() error {
+	for {
+		msg, err := c.backend.ReceiveStartupMessage()
+		if err != nil {
+			return err
+		}
+		switch msg.(type) {
+		case *pgproto3.SSLRequest, *pgproto3.GSSEncRequest:
+			if _, err := c.conn.Write([]byte("N")); err != nil {
+				return err
+			}
+		case *pgproto3.StartupMessage:
+			c.backend.Send(&pgproto3.AuthenticationOk{})
+			for _, p := range [][2]string{
+				{"server_version", "17.0"},
+				{"server_encoding", "UTF8"},
+				{"client_encoding", "UTF8"},
+				{"DateStyle", "ISO, MDY"},
+				{"TimeZone", "UTC"},
+				{"integer_datetimes", "on"},
+				{"standard_conforming_strings", "on"},
+			} {
+				c.backend.Send(&pgproto3.ParameterStatus{Name: p[0], Value: p[1]})
+			}
+			c.backend.Send(&pgproto3.BackendKeyData{ProcessID: c.pid, SecretKey: binary.BigEndian.AppendUint32(nil, c.pid)})
+			c.backend.Send(&pgproto3.ReadyForQuery{TxStatus: c.txStatus})
+			return c.backend.Flush()
+		default:
+			// Cancel requests arrive on their own connection. Queries are
+			// answered immediately, so there is never anything to cancel.
+			return errors.New("fakeserver: unsupported startup message")
+		}
+	}
+}
+
+func (c *session) handle(msg pgproto3.FrontendMessage) {
+	switch msg := msg.(type) {
+	case *pgproto3.Query:
+		c.simpleQuery(msg.String)
+	case *pgproto3.Sync:
+		c.failed = false
+		c.backend.Send(&pgproto3.ReadyForQuery{TxStatus: c.txStatus})
+	case *pgproto3.Flush:
+	default:
+		if !c.failed {
+			c.extended(msg)
+		}
+	}
+}
+
+func (c *session) simpleQuery(query string) {
+	defer func() {
+		c.backend.Send(&pgproto3.ReadyForQuery{TxStatus: c.txStatus})
+	}()
+	if isEmpty(query) {
+		c.backend.Send(&pgproto3.EmptyQueryResponse{})
+		return
+	}
+
+	res, err := c.execute(query, nil, nil)
+	if err != nil {
+		c.backend.Send(errorResponse(err))
+		return
+	}
+	e := res.expectation
+	if e.columns != nil {
+		c.backend.Send(c.rowDescription(e, nil))
+	}
+	if err := c.sendRows(e, nil); err != nil {
+		c.backend.Send(errorResponse(err))
+		return
+	}
+	c.backend.Send(&pgproto3.CommandComplete{CommandTag: []byte(res.tag)})
+}
+
+func (c *session) extended(msg pgproto3.FrontendMessage) {
+	switch msg := msg.(type) {
+	case *pgproto3.Parse:
+		c.statements[msg.Name] = &statement{query: msg.Query, paramOIDs: paramOIDs(msg.Query, msg.ParameterOIDs)}
+		c.backend.Send(&pgproto3.ParseComplete{})
+
+	case *pgproto3.Bind:
+		stmt, ok := c.statements[msg.PreparedStatement]
+		if !ok {
+			c.fail(&pgconn.PgError{Code: "26000", Message: fmt.Sprintf("prepared statement %q does not exist", msg.PreparedStatement)})
+			return
+		}
+		params := make([][]byte, len(msg.Parameters))
+		for i, p := range msg.Parameters {
+			if p != nil {
+				params[i] = append([]byte{}, p...)
+			}
+		}
+		c.portals[msg.DestinationPortal] = &portal{
+			statement:     stmt,
+			params:        params,
+			paramFormats:  append([]int16(nil), msg.ParameterFormatCodes...),
+			resultFormats: append([]int16(nil), msg.ResultFormatCodes...),
+		}
+		c.backend.Send(&pgproto3.BindComplete{})
+
+	case *pgproto3.Describe:
+		c.describe(msg)
+
+	case *pgproto3.Execute:
+		p, ok := c.portals[msg.Portal]
+		if !ok {
+			c.fail(&pgconn.PgError{Code: "34000", Message: fmt.Sprintf("portal %q does not exist", msg.Portal)})
+			return
+		}
+		if isEmpty(p.statement.query) {
+			c.backend.Send(&pgproto3.EmptyQueryResponse{})
+			return
+		}
+		res, err := c.execute(p.statement.query, p.params, p.paramFormats)
+		if err != nil {
+			c.fail(err)
+			return
+		}
+		if err := c.sendRows(res.expectation, p.resultFormats); err != nil {
+			c.fail(err)
+			return
+		}
+		c.backend.Send(&pgproto3.CommandComplete{CommandTag: []byte(res.tag)})
+
+	case *pgproto3.Close:
+		if msg.ObjectType == 'S' {
+			delete(c.statements, msg.Name)
+		} else {
+			delete(c.portals, msg.Name)
+		}
+		c.backend.Send(&pgproto3.CloseComplete{})
+
+	default:
+		c.fail(fmt.Errorf("fakeserver: unsupported message %T", msg))
+	}
+}
+
+// describe describes a prepared statement or a portal from the first pending
+// expectation for its query.
+func (c *session) describe(msg *pgproto3.Describe) {
+	var stmt *statement
+	var formats []int16
+	if msg.ObjectType == 'S' {
+		s, ok := c.statements[msg.Name]
+		if !ok {
+			c.fail(&pgconn.PgError{Code: "26000", Message: fmt.Sprintf("prepared statement %q does not exist", msg.Name)})
+			return
+		}
+		stmt = s
+		c.backend.Send(&pgproto3.ParameterDescription{ParameterOIDs: stmt.paramOIDs})
+	} else {
+		p, ok := c.portals[msg.Name]
+		if !ok {
+			c.fail(&pgconn.PgError{Code: "34000", Message: fmt.Sprintf("portal %q does not exist", msg.Name)})
+			return
+		}
+		stmt, formats = p.statement, p.resultFormats
+	}
+
+	if e := c.server.describe(stmt.query); e != nil && e.columns != nil {
+		c.backend.Send(c.rowDescription(e, formats))
+		return
+	}
+	c.backend.Send(&pgproto3.NoData{})
+}
+
+// execute matches a statement against the expectations and tracks the
+// transaction status the way PostgreSQL would.
+func (c *session) execute(query string, params [][]byte, formats []int16) (*result, error) {
+	kind := classify(query)
+	rollbackTo := rollsBackToSavepoint(query)
+	if c.txStatus == txFailed && kind != kindCommit && kind != kindRollback && !rollbackTo {
+		return nil, &pgconn.PgError{Code: "25P02", Message: "current transaction is aborted, commands ignored until end of transaction block"}
+	}
+
+	e, err := c.server.match(c.types, kind, query, params, formats)
+	if err != nil {
+		c.abort()
+		return nil, err
+	}
+
+	if e.err != nil {
+		if kind == kindCommit || kind == kindRollback {
+			c.txStatus = txIdle
+		} else {
+			c.abort()
+		}
+		return nil, e.err
+	}
+
+	tag := e.commandTag()
+	switch kind {
+	case kindBegin:
+		c.txStatus = txActive
+	case kindCommit:
+		if c.txStatus == txFailed && e.tag == "" {
+			tag = "ROLLBACK"
+		}
+		c.txStatus = txIdle
+	case kindRollback:
+		c.txStatus = txIdle
+	default:
+		if rollbackTo && c.txStatus == txFailed {
+			c.txStatus = txActive
+		}
+	}
+	return &result{expectation: e, tag: tag}, nil
+}
+
+// abort marks an open transaction as failed.
+func (c *session) abort() {
+	if c.txStatus == txActive {
+		c.txStatus = txFailed
+	}
+}
+
+// fail sends an error in the extended protocol and discards messages until Sync.
+func (c *session) fail(err error) {
+	c.abort()
+	c.failed = true
+	c.backend.Send(errorResponse(err))
+}
+
+func (c *session) rowDescription(e *Expectation, formats []int16) *pgproto3.RowDescription {
+	oids := e.columnOIDs(c.types)
+	fields := make([]pgproto3.FieldDescription, len(e.columns))
+	for i, name := range e.columns {
+		fields[i] = pgproto3.FieldDescription{
+			Name:         []byte(name),
+			DataTypeOID:  oids[i],
+			DataTypeSize: -1,
+			TypeModifier: -1,
+			Format:       paramFormat(formats, i),
+		}
+	}
+	return &pgproto3.RowDescription{Fields: fields}
+}
+
+// sendRows encodes the rows of e in the requested result formats.
+func (c *session) sendRows(e *Expectation, formats []int16) error {
+	oids := e.columnOIDs(c.types)
+	for _, row := range e.rows {
+		if len(row) != len(e.columns) {
+			return fmt.Errorf("fakeserver: row %v has %d values for %d columns", row, len(row), len(e.columns))
+		}
+		values := make([][]byte, len(row))
+		for i, v := range row {
+			if v == nil {
+				continue
+			}
+			if oids[i] == pgtype.TextOID {
+				if _, ok := v.(string); !ok {
+					v = fmt.Sprint(v)
+				}
+			}
+			buf, err := c.types.Encode(oids[i], paramFormat(formats, i), v, []byte{})
+			if err != nil {
+				return fmt.Errorf("fakeserver: encode column %s: %w", e.columns[i], err)
+			}
+			values[i] = buf
+		}
+		c.backend.Send(&pgproto3.DataRow{Values: values})
+	}
+	return nil
+}
+
+var placeholder = regexp.MustCompile(`\$(\d+)`)
+
+// paramOIDs returns the parameter types of a statement: those the client
+// declared, and unspecified for the rest of its placeholders, which makes pgx
+// encode arguments by their Go type.
+func paramOIDs(query string, declared []uint32) []uint32 {
+	n := len(declared)
+	for _, m := range placeholder.FindAllStringSubmatch(query, -1) {
+		if i, err := strconv.Atoi(m[1]); err == nil {
+			n = max(n, i)
+		}
+	}
+	oids := make([]uint32, n)
+	copy(oids, declared)
+	return oids
+}
+
+// errorResponse converts err to an ErrorResponse, keeping every field of a
+// *pgconn.PgError.
+func errorResponse(err error) *pgproto3.ErrorResponse {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return &pgproto3.ErrorResponse{Severity: "ERROR", SeverityUnlocalized: "ERROR", Code: "XX000", Message: err.Error()}
+	}
+	severity := pgErr.Severity
+	if severity == "" {
+		severity = "ERROR"
+	}
+	return &pgproto3.ErrorResponse{
+		Severity:            severity,
+		SeverityUnlocalized: severity,
+		Code:                pgErr.Code,
+		Message:             pgErr.Message,
+		Detail:              pgErr.Detail,
+		Hint:                pgErr.Hint,
+		Position:            pgErr.Position,
+		InternalPosition:    pgErr.InternalPosition,
+		InternalQuery:       pgErr.InternalQuery,
+		Where:               pgErr.Where,
+		SchemaName:          pgErr.SchemaName,
+		TableName:           pgErr.TableName,
+		ColumnName:          pgErr.ColumnName,
+		DataTypeName:        pgErr.DataTypeName,
+		ConstraintName:      pgErr.ConstraintName,
+		File:                pgErr.File,
+		Line:                pgErr.Line,
+		Routine:             pgErr.Routine,
+	}
+}
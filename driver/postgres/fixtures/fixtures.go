@@ -0,0 +1,287 @@
+// Package fixtures seeds PostgreSQL databases for integration tests from YAML
+// or JSON files describing table rows, so tests need not insert their data
+// through handlers of their own.
+//
+// A fixture file maps table names to rows. Rows given as a mapping are
+// labeled, so other rows can refer to them and tests can look up the values
+// the database generated for them. Rows given as a list are not:
+//
+//	users:
+//	  alice:
+//	    email: alice@example.com
+//	    created_at: "{{ now -48h }}"
+//	posts:
+//	  hello:
+//	    author_id: "{{ ref users.alice }}"
+//	    title: Hello
+//	    published_at: "{{ today -1d }}"
+//	post_tags:
+//	  - post_id: "{{ ref posts.hello }}"
+//	    tag: go
+//
+// JSON files have the same structure. String values may contain expressions
+// in double braces:
+//
+//   - ref TABLE.LABEL is the primary key of a labeled row, and
+//     ref TABLE.LABEL COLUMN any other column of it.
+//   - now is the time the fixtures are inserted, truncated to microseconds.
+//   - today is midnight of that day.
+//
+// now and today take an optional offset such as -48h, +7d or -1d12h. A value
+// that is a single expression keeps its type, so a reference to a bigint
+// primary key is inserted as a number and now as a timestamp. Expressions
+// mixed with text are formatted into the string.
+//
+// Rows are inserted after the rows they refer to, in file order within a
+// table. Unlabeled rows share multi-row INSERTs, while each labeled row is
+// inserted on its own so the values it returns cannot go to another label.
+// Tables without references between them are inserted in the order they first
+// appear in the files. Foreign keys declared DEFERRABLE are
+// deferred to the end of the transaction, so rows may also point at each
+// other through explicit ids in any order.
+package fixtures
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"iter"
+	"os"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Fixtures holds the rows read from fixture files, in insertion order.
+type Fixtures struct {
+	tables []string
+	rows   []*row
+	labels map[string]*row
+
+	// returning holds the columns referenced in each table, with "" standing
+	// for the primary key.
+	returning map[string][]string
+}
+
+// row is a row of a fixture file.
+type row struct {
+	table   string
+	label   string
+	source  string
+	columns []string
+	values  []any
+
+	deps  []*row
+	level int
+}
+
+// key returns the name references use for the row.
+func (r *row) key() string {
+	return r.table + "." + r.label
+}
+
+// ReadFiles reads fixture files in order.
+func ReadFiles(paths ...string) (*Fixtures, error) {
+	f := newFixtures()
+	for _, name := range paths {
+		data, err := os.ReadFile(name)
+		if err != nil {
+			return nil, fmt.Errorf("fixtures: %w", err)
+		}
+		if err := f.parse(name, data); err != nil {
+			return nil, err
+		}
+	}
+	if err := f.link(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// ReadFS reads the fixture files of fsys matching patterns, as fs.Glob matches
+// them. Files matching a pattern are read in lexical order.
+func ReadFS(fsys fs.FS, patterns ...string) (*Fixtures, error) {
+	f := newFixtures()
+	for _, pattern := range patterns {
+		names, err := fs.Glob(fsys, pattern)
+		if err != nil {
+			return nil, fmt.Errorf("fixtures: %w", err)
+		}
+		if len(names) == 0 {
+			return nil, fmt.Errorf("fixtures: no files match %s", pattern)
+		}
+		for _, name := range names {
+			data, err := fs.ReadFile(fsys, name)
+			if err != nil {
+				return nil, fmt.Errorf("fixtures: %w", err)
+			}
+			if err := f.parse(name, data); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if err := f.link(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func newFixtures() *Fixtures {
+	return &Fixtures{labels: map[string]*row{}, returning: map[string][]string{}}
+}
+
+// parse adds the rows of a file. JSON is read by the YAML decoder, which
+// accepts it and, unlike encoding/json, keeps the order of object keys.
+func (f *Fixtures) parse(name string, data []byte) error {
+	var doc yaml.Node
+	if err := yaml.NewDecoder(bytes.NewReader(data)).Decode(&doc); err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		return fmt.Errorf("fixtures: %s: %w", name, err)
+	}
+
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return fmt.Errorf("fixtures: %s:%d: expected a mapping of table names to rows", name, root.Line)
+	}
+	for table, rows := range pairs(root) {
+		if !slices.Contains(f.tables, table.Value) {
+			f.tables = append(f.tables, table.Value)
+		}
+		switch rows.Kind {
+		case yaml.MappingNode:
+			for label, node := range pairs(rows) {
+				if err := f.add(name, table.Value, label.Value, node); err != nil {
+					return err
+				}
+			}
+		case yaml.SequenceNode:
+			for _, node := range rows.Content {
+				if err := f.add(name, table.Value, "", node); err != nil {
+					return err
+				}
+			}
+		default:
+			return fmt.Errorf("fixtures: %s:%d: rows of %s must be a mapping of labels to rows or a list", name, rows.Line, table.Value)
+		}
+	}
+	return nil
+}
+
+// add adds a row of table read from node.
+func (f *Fixtures) add(file, table, label string, node *yaml.Node) error {
+	r := &row{table: table, label: label, source: fmt.Sprintf("%s:%d", file, node.Line)}
+	if node.Kind != yaml.MappingNode {
+		return fmt.Errorf("fixtures: %s: expected a mapping of columns to values", r.source)
+	}
+	if label != "" {
+		if other, ok := f.labels[r.key()]; ok {
+			return fmt.Errorf("fixtures: %s: %s is already defined at %s", r.source, r.key(), other.source)
+		}
+		f.labels[r.key()] = r
+	}
+
+	for column, value := range pairs(node) {
+		if slices.Contains(r.columns, column.Value) {
+			return fmt.Errorf("fixtures: %s:%d: duplicate column %s", file, column.Line, column.Value)
+		}
+		v, err := decode(value)
+		if err != nil {
+			return fmt.Errorf("fixtures: %s:%d: column %s: %w", file, value.Line, column.Value, err)
+		}
+		r.columns = append(r.columns, column.Value)
+		r.values = append(r.values, v)
+	}
+	f.rows = append(f.rows, r)
+	return nil
+}
+
+// decode decodes a column value, parsing expressions in strings.
+func decode(node *yaml.Node) (any, error) {
+	if node.Kind == yaml.ScalarNode && node.ShortTag() == "!!str" && strings.Contains(node.Value, "{{") {
+		return parseTemplate(node.Value)
+	}
+	var v any
+	err := node.Decode(&v)
+	return v, err
+}
+
+// link resolves references and orders the rows so each follows the rows it
+// refers to.
+func (f *Fixtures) link() error {
+	for _, r := range f.rows {
+		for _, v := range r.values {
+			tpl, ok := v.(*template)
+			if !ok {
+				continue
+			}
+			for _, ref := range tpl.refs() {
+				target, ok := f.labels[ref.target]
+				if !ok {
+					return fmt.Errorf("fixtures: %s: reference to undefined row %s", r.source, ref.target)
+				}
+				ref.row = target
+				if !slices.Contains(r.deps, target) {
+					r.deps = append(r.deps, target)
+				}
+				if !slices.Contains(f.returning[target.table], ref.column) {
+					f.returning[target.table] = append(f.returning[target.table], ref.column)
+				}
+			}
+		}
+	}
+
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := make(map[*row]int, len(f.rows))
+	var visit func(r *row, path []string) error
+	visit = func(r *row, path []string) error {
+		switch state[r] {
+		case visiting:
+			return fmt.Errorf("fixtures: %s: reference cycle %s", r.source, strings.Join(append(path, r.key()), " -> "))
+		case visited:
+			return nil
+		}
+		state[r] = visiting
+		for _, dep := range r.deps {
+			if err := visit(dep, append(path, r.key())); err != nil {
+				return err
+			}
+			r.level = max(r.level, dep.level+1)
+		}
+		state[r] = visited
+		return nil
+	}
+	for _, r := range f.rows {
+		if err := visit(r, nil); err != nil {
+			return err
+		}
+	}
+
+	// The sort is stable, so rows keep their file order within a table.
+	slices.SortStableFunc(f.rows, func(a, b *row) int {
+		if a.level != b.level {
+			return a.level - b.level
+		}
+		return slices.Index(f.tables, a.table) - slices.Index(f.tables, b.table)
+	})
+	return nil
+}
+
+// pairs iterates the keys and values of a mapping node.
+func pairs(node *yaml.Node) iter.Seq2[*yaml.Node, *yaml.Node] {
+	return func(yield func(key, value *yaml.Node) bool) {
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if !yield(node.Content[i], node.Content[i+1]) {
+				return
+			}
+		}
+	}
+}
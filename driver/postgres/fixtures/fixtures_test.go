@@ -0,0 +1,253 @@
+package fixtures_test
+
+import (
+	"context"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/Kansuler/octobe/v3"
+	"github.com/Kansuler/octobe/v3/driver/postgres/fixtures"
+	"github.com/Kansuler/octobe/v3/driver/postgres/mock"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/require"
+)
+
+var now = time.Date(2024, 3, 10, 15, 4, 5, 0, time.UTC)
+
+func TestLoad(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("inserts rows after the rows they refer to", func(t *testing.T) {
+		// The tags are read first but refer to a post, which refers to a user.
+		f, err := fixtures.ReadFiles("testdata/tags.json", "testdata/blog.yaml")
+		require.NoError(t, err)
+
+		db, m := mock.OpenPGX(t)
+		m.ExpectBeginTx()
+		m.ExpectExec(`SET CONSTRAINTS ALL DEFERRED`).WillReturnResult(mock.NewResult("SET CONSTRAINTS", 0))
+		m.ExpectQuery(`INSERT INTO "users" ("email", "created_at") VALUES ($1, $2) RETURNING "id", "email"`).
+			WithArgs("alice@example.com", now.Add(-48*time.Hour)).
+			WillReturnRows(mock.NewRows([]string{"id", "email"}).AddRow(int64(1), "alice@example.com"))
+		m.ExpectQuery(`INSERT INTO "users" ("email") VALUES ($1) RETURNING "id", "email"`).
+			WithArgs("bob@example.com").
+			WillReturnRows(mock.NewRows([]string{"id", "email"}).AddRow(int64(2), "bob@example.com"))
+		m.ExpectQuery(`INSERT INTO "posts" ("author_id", "title", "published_at") VALUES ($1, $2, $3) RETURNING "id"`).
+			WithArgs(int64(1), "Hello from alice@example.com", time.Date(2024, 3, 9, 0, 0, 0, 0, time.UTC)).
+			WillReturnRows(mock.NewRows([]string{"id"}).AddRow(int64(10)))
+		m.ExpectExec(`INSERT INTO "post_tags" ("post_id", "tag") VALUES ($1, $2), ($3, $4)`).
+			WithArgs(int64(10), "go", int64(10), "sql").
+			WillReturnResult(mock.NewResult("INSERT", 2))
+		m.ExpectCommit()
+
+		refs, err := fixtures.Load(ctx, db, f, fixtures.WithNow(now))
+		require.NoError(t, err)
+		require.Equal(t, fixtures.Refs{
+			"users.alice": {"id": int64(1), "email": "alice@example.com"},
+			"users.bob":   {"id": int64(2), "email": "bob@example.com"},
+			"posts.hello": {"id": int64(10)},
+		}, refs)
+	})
+
+	t.Run("rows of a table referring to each other", func(t *testing.T) {
+		f, err := fixtures.ReadFS(fstest.MapFS{"categories.yaml": {Data: []byte(`
+public.categories:
+  child:
+    name: Child
+    parent_code: "{{ ref public.categories.root code }}"
+  root:
+    code: ROOT
+    name: Root
+`)}}, "*.yaml")
+		require.NoError(t, err)
+
+		db, m := mock.OpenPGX(t)
+		m.ExpectBeginTx()
+		m.ExpectExec(`SET CONSTRAINTS ALL DEFERRED`).WillReturnResult(mock.NewResult("SET CONSTRAINTS", 0))
+		m.ExpectQuery(`INSERT INTO "public"."categories" ("code", "name") VALUES ($1, $2) RETURNING "code"`).
+			WithArgs("ROOT", "Root").
+			WillReturnRows(mock.NewRows([]string{"code"}).AddRow("ROOT"))
+		m.ExpectQuery(`INSERT INTO "public"."categories" ("name", "parent_code") VALUES ($1, $2) RETURNING "code"`).
+			WithArgs("Child", "ROOT").
+			WillReturnRows(mock.NewRows([]string{"code"}).AddRow("CHILD"))
+		m.ExpectCommit()
+
+		refs, err := fixtures.Load(ctx, db, f, fixtures.WithPrimaryKey("public.categories", "code"))
+		require.NoError(t, err)
+		require.Equal(t, "CHILD", refs["public.categories.child"]["code"])
+	})
+
+	t.Run("labeled rows are inserted one at a time", func(t *testing.T) {
+		// PostgreSQL does not promise to return the rows of a multi-row
+		// INSERT in order, so only unlabeled rows share a statement.
+		f, err := fixtures.ReadFS(fstest.MapFS{"tags.yaml": {Data: []byte(`
+tags:
+  - name: a
+  - name: b
+`)}, "more.yaml": {Data: []byte(`
+tags:
+  go:
+    name: go
+`)}}, "tags.yaml", "more.yaml")
+		require.NoError(t, err)
+
+		db, m := mock.OpenPGX(t)
+		m.ExpectBeginTx()
+		m.ExpectExec(`SET CONSTRAINTS ALL DEFERRED`).WillReturnResult(mock.NewResult("SET CONSTRAINTS", 0))
+		m.ExpectExec(`INSERT INTO "tags" ("name") VALUES ($1), ($2)`).WithArgs("a", "b").WillReturnResult(mock.NewResult("INSERT", 2))
+		m.ExpectQuery(`INSERT INTO "tags" ("name") VALUES ($1) RETURNING "id"`).WithArgs("go").
+			WillReturnRows(mock.NewRows([]string{"id"}).AddRow(int64(3)))
+		m.ExpectCommit()
+
+		refs, err := fixtures.Load(ctx, db, f)
+		require.NoError(t, err)
+		require.Equal(t, fixtures.Refs{"tags.go": {"id": int64(3)}}, refs)
+	})
+
+	t.Run("integer refs are int64", func(t *testing.T) {
+		// pgx decodes SERIAL keys as int32.
+		f, err := fixtures.ReadFS(fstest.MapFS{"tags.yaml": {Data: []byte(`
+tags:
+  go:
+    name: go
+`)}}, "tags.yaml")
+		require.NoError(t, err)
+
+		db, m := mock.OpenPGX(t)
+		m.ExpectBeginTx()
+		m.ExpectExec(`SET CONSTRAINTS ALL DEFERRED`).WillReturnResult(mock.NewResult("SET CONSTRAINTS", 0))
+		m.ExpectQuery(`INSERT INTO "tags" ("name") VALUES ($1) RETURNING "id"`).WithArgs("go").
+			WillReturnRows(mock.NewRows([]string{"id"}).AddRow(int32(3)))
+		m.ExpectCommit()
+
+		refs, err := fixtures.Load(ctx, db, f)
+		require.NoError(t, err)
+		require.Equal(t, int64(3), refs["tags.go"]["id"].(int64))
+	})
+
+	t.Run("failed inserts roll back", func(t *testing.T) {
+		f, err := fixtures.ReadFiles("testdata/blog.yaml")
+		require.NoError(t, err)
+
+		db, m := mock.OpenPGX(t)
+		m.ExpectBeginTx()
+		m.ExpectExec(`SET CONSTRAINTS ALL DEFERRED`).WillReturnResult(mock.NewResult("SET CONSTRAINTS", 0))
+		m.ExpectQuery(`INSERT INTO "users"`).Contains().WillReturnError(mock.UniqueViolation("users_email_key"))
+		m.ExpectRollback()
+
+		_, err = fixtures.Load(ctx, db, f)
+		require.ErrorContains(t, err, "fixtures: insert into users")
+		var pgErr *pgconn.PgError
+		require.ErrorAs(t, err, &pgErr)
+		require.Equal(t, "23505", pgErr.Code)
+	})
+
+	t.Run("handler in a session of the caller", func(t *testing.T) {
+		f, err := fixtures.ReadFS(fstest.MapFS{"audit.json": {Data: []byte(`{"audit_log": [{"at": "{{ now +1d2h }}"}, {}]}`)}}, "*.json")
+		require.NoError(t, err)
+
+		db, m := mock.OpenPGX(t)
+		m.ExpectExec(`SET CONSTRAINTS ALL DEFERRED`).WillReturnResult(mock.NewResult("SET CONSTRAINTS", 0))
+		m.ExpectExec(`INSERT INTO "audit_log" ("at") VALUES ($1), (DEFAULT)`).WithArgs(now.Add(26 * time.Hour)).WillReturnResult(mock.NewResult("INSERT", 2))
+
+		session, err := db.Begin(ctx)
+		require.NoError(t, err)
+		refs, err := octobe.Execute(session, f.Insert(fixtures.WithNow(now)))
+		require.NoError(t, err)
+		require.Empty(t, refs)
+		require.NoError(t, session.Close())
+	})
+}
+
+func TestRead(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string]string
+		err   string
+	}{
+		{
+			name:  "undefined reference",
+			files: map[string]string{"a.yaml": "posts:\n  - author_id: '{{ ref users.carol }}'\n"},
+			err:   "a.yaml:2: reference to undefined row users.carol",
+		},
+		{
+			name: "reference cycle",
+			files: map[string]string{"a.yaml": `
+users:
+  alice:
+    best_post_id: "{{ ref posts.hello }}"
+posts:
+  hello:
+    author_id: "{{ ref users.alice }}"
+`},
+			err: "reference cycle users.alice -> posts.hello -> users.alice",
+		},
+		{
+			name: "label defined twice",
+			files: map[string]string{
+				"a.yaml": "users:\n  alice:\n    email: a@example.com\n",
+				"b.json": `{"users": {"alice": {"email": "b@example.com"}}}`,
+			},
+			err: "b.json:1: users.alice is already defined at a.yaml:3",
+		},
+		{
+			name:  "unknown function",
+			files: map[string]string{"a.yaml": "users:\n  - created_at: '{{ later }}'\n"},
+			err:   "a.yaml:2: column created_at: {{ later }}: unknown function later",
+		},
+		{
+			name:  "invalid offset",
+			files: map[string]string{"a.yaml": "users:\n  - created_at: '{{ now -2w }}'\n"},
+			err:   "invalid offset -2w",
+		},
+		{
+			name:  "unclosed expression",
+			files: map[string]string{"a.yaml": "users:\n  - name: '{{ now'\n"},
+			err:   "expression is missing }}",
+		},
+		{
+			name:  "rows that are not a mapping",
+			files: map[string]string{"a.yaml": "users: alice\n"},
+			err:   "a.yaml:1: rows of users must be a mapping of labels to rows or a list",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fsys := fstest.MapFS{}
+			for name, data := range tt.files {
+				fsys[name] = &fstest.MapFile{Data: []byte(data)}
+			}
+			_, err := fixtures.ReadFS(fsys, "*")
+			require.ErrorContains(t, err, tt.err)
+		})
+	}
+
+	t.Run("missing files", func(t *testing.T) {
+		_, err := fixtures.ReadFiles("testdata/missing.yaml")
+		require.Error(t, err)
+
+		_, err = fixtures.ReadFS(fstest.MapFS{}, "*.yaml")
+		require.ErrorContains(t, err, "no files match *.yaml")
+	})
+}
+
+func TestInsertReferenceErrors(t *testing.T) {
+	f, err := fixtures.ReadFS(fstest.MapFS{"a.yaml": {Data: []byte(`
+users:
+  alice:
+    email: alice@example.com
+posts:
+  - author_id: "{{ ref users.alice }}"
+`)}}, "a.yaml")
+	require.NoError(t, err)
+
+	db, m := mock.OpenPGX(t)
+	m.ExpectExec(`SET CONSTRAINTS ALL DEFERRED`).WillReturnResult(mock.NewResult("SET CONSTRAINTS", 0))
+	m.ExpectExec(`INSERT INTO "users" ("email") VALUES ($1)`).WithArgs("alice@example.com").WillReturnResult(mock.NewResult("INSERT", 1))
+
+	session, err := db.Begin(context.Background())
+	require.NoError(t, err)
+	defer session.Close()
+	_, err = octobe.Execute(session, f.Insert(fixtures.WithPrimaryKey("users", "")))
+	require.ErrorContains(t, err, "ref users.alice: table users has no primary key")
+}
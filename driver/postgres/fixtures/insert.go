@@ -0,0 +1,311 @@
+package fixtures
+
+import (
+	"context"
+	"fmt"
+	"iter"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Kansuler/octobe/v3"
+	"github.com/Kansuler/octobe/v3/driver/postgres"
+	"github.com/jackc/pgx/v5"
+)
+
+// maxParameters is the number of parameters PostgreSQL accepts in a statement.
+const maxParameters = 65535
+
+// Refs holds the values the database returned for labeled rows, by row name
+// such as "users.alice" and column. Rows hold their primary key and every
+// column other rows refer to. Integers are stored as int64 whatever their
+// column type, so SERIAL and BIGSERIAL keys are read the same way:
+//
+//	aliceID := refs["users.alice"]["id"].(int64)
+type Refs map[string]map[string]any
+
+// Option configures how fixtures are inserted.
+type Option func(*config)
+
+type config struct {
+	now         time.Time
+	primaryKeys map[string]string
+}
+
+// WithPrimaryKey sets the primary key column of table, which is id by
+// default. Labeled rows of table return it, and references without a column
+// refer to it. An empty column makes labeled rows of table return only the
+// columns other rows refer to, for tables without a single-column key.
+func WithPrimaryKey(table, column string) Option {
+	return func(c *config) {
+		c.primaryKeys[table] = column
+	}
+}
+
+// WithNow sets the time now and today refer to, for fixtures that must be
+// the same on every run, such as those of golden tests.
+func WithNow(now time.Time) Option {
+	return func(c *config) {
+		c.now = now
+	}
+}
+
+func newConfig(opts []Option) *config {
+	cfg := &config{now: time.Now().Truncate(time.Microsecond), primaryKeys: map[string]string{}}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	return cfg
+}
+
+func (c *config) primaryKey(table string) string {
+	if column, ok := c.primaryKeys[table]; ok {
+		return column
+	}
+	return "id"
+}
+
+// Driver starts transactions. It is implemented by postgres.PGXDriver and
+// postgres.PGXPoolDriver.
+type Driver interface {
+	StartTransaction(ctx context.Context, fn func(session octobe.BuilderSession[postgres.Builder]) error, opts ...postgres.Option) error
+}
+
+// Load inserts the fixtures in a transaction of db and returns the values
+// generated for labeled rows.
+//
+// Example:
+//
+//	f, err := fixtures.ReadFiles("testdata/users.yaml", "testdata/posts.yaml")
+//	require.NoError(t, err)
+//	refs, err := fixtures.Load(ctx, db, f)
+//	require.NoError(t, err)
+func Load(ctx context.Context, db Driver, f *Fixtures, opts ...Option) (Refs, error) {
+	var refs Refs
+	err := db.StartTransaction(ctx, func(session octobe.BuilderSession[postgres.Builder]) (err error) {
+		refs, err = octobe.Execute(session, f.Insert(opts...))
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return refs, nil
+}
+
+// Insert returns a handler inserting the fixtures, for sessions of the
+// caller's own. Deferrable constraints are only deferred when the session is
+// transactional.
+func (f *Fixtures) Insert(opts ...Option) octobe.Handler[Refs, postgres.Builder] {
+	return func(builder postgres.Builder) (Refs, error) {
+		cfg := newConfig(opts)
+		if _, err := builder(`SET CONSTRAINTS ALL DEFERRED`).Exec(); err != nil {
+			return nil, fmt.Errorf("fixtures: defer constraints: %w", err)
+		}
+
+		refs := Refs{}
+		for batch := range f.batches() {
+			for rows := range statements(batch) {
+				columns, returning := f.batchColumns(cfg, rows)
+				// Chunk large tables to stay within the parameter limit.
+				// INSERT ... DEFAULT VALUES inserts a single row.
+				size := 1
+				if len(columns) > 0 {
+					size = max(maxParameters/len(columns), 1)
+				}
+				for chunk := range slices.Chunk(rows, size) {
+					if err := insert(builder, cfg, refs, chunk, columns, returning); err != nil {
+						return nil, err
+					}
+				}
+			}
+		}
+		return refs, nil
+	}
+}
+
+// statements splits a batch into the rows inserted by one statement each, in
+// order. Every labeled row gets a statement of its own, since PostgreSQL does
+// not promise to return the rows of a multi-row INSERT in the order of its
+// VALUES list, and the generated values must go to the right label.
+func statements(batch []*row) iter.Seq[[]*row] {
+	return func(yield func([]*row) bool) {
+		for start := 0; start < len(batch); {
+			end := start + 1
+			if batch[start].label == "" {
+				for end < len(batch) && batch[end].label == "" {
+					end++
+				}
+			}
+			if !yield(batch[start:end]) {
+				return
+			}
+			start = end
+		}
+	}
+}
+
+// batches iterates runs of rows of the same table and level, which can be
+// inserted with one statement.
+func (f *Fixtures) batches() iter.Seq[[]*row] {
+	return func(yield func([]*row) bool) {
+		for start := 0; start < len(f.rows); {
+			end := start + 1
+			for end < len(f.rows) && f.rows[end].table == f.rows[start].table && f.rows[end].level == f.rows[start].level {
+				end++
+			}
+			if !yield(f.rows[start:end]) {
+				return
+			}
+			start = end
+		}
+	}
+}
+
+// batchColumns returns the columns set by the rows of a batch in order of
+// appearance, and the columns to return for its labeled rows.
+func (f *Fixtures) batchColumns(cfg *config, batch []*row) (columns, returning []string) {
+	labeled := false
+	for _, r := range batch {
+		for _, column := range r.columns {
+			if !slices.Contains(columns, column) {
+				columns = append(columns, column)
+			}
+		}
+		labeled = labeled || r.label != ""
+	}
+	if !labeled {
+		return columns, nil
+	}
+
+	table := batch[0].table
+	pk := cfg.primaryKey(table)
+	if pk != "" {
+		returning = append(returning, pk)
+	}
+	for _, column := range f.returning[table] {
+		if column == "" {
+			column = pk
+		}
+		if column != "" && !slices.Contains(returning, column) {
+			returning = append(returning, column)
+		}
+	}
+	return columns, returning
+}
+
+// insert inserts rows with a multi-row INSERT. Columns a row does not set get
+// their default value.
+func insert(builder postgres.Builder, cfg *config, refs Refs, rows []*row, columns, returning []string) error {
+	table := rows[0].table
+	var query strings.Builder
+	query.WriteString("INSERT INTO ")
+	query.WriteString(identifier(table))
+
+	var args []any
+	if len(columns) == 0 {
+		query.WriteString(" DEFAULT VALUES")
+	} else {
+		quoted := make([]string, len(columns))
+		for i, column := range columns {
+			quoted[i] = identifier(column)
+		}
+		query.WriteString(" (" + strings.Join(quoted, ", ") + ") VALUES ")
+		for i, r := range rows {
+			if i > 0 {
+				query.WriteString(", ")
+			}
+			values := make([]string, len(columns))
+			for j, column := range columns {
+				k := slices.Index(r.columns, column)
+				if k < 0 {
+					values[j] = "DEFAULT"
+					continue
+				}
+				v, err := value(cfg, refs, r.values[k])
+				if err != nil {
+					return fmt.Errorf("fixtures: %s: column %s: %w", r.source, column, err)
+				}
+				args = append(args, v)
+				values[j] = "$" + strconv.Itoa(len(args))
+			}
+			query.WriteString("(" + strings.Join(values, ", ") + ")")
+		}
+	}
+
+	if len(returning) == 0 {
+		if _, err := builder(query.String()).Arguments(args...).Exec(); err != nil {
+			return fmt.Errorf("fixtures: insert into %s: %w", table, err)
+		}
+		for _, r := range rows {
+			if r.label != "" {
+				refs[r.key()] = map[string]any{}
+			}
+		}
+		return nil
+	}
+
+	quoted := make([]string, len(returning))
+	for i, column := range returning {
+		quoted[i] = identifier(column)
+	}
+	query.WriteString(" RETURNING " + strings.Join(quoted, ", "))
+
+	// Rows with a label are inserted one at a time, see statements.
+	n := 0
+	err := builder(query.String()).Arguments(args...).Query(func(result postgres.Rows) error {
+		for result.Next() {
+			if n == len(rows) {
+				return fmt.Errorf("returned more than %d rows", len(rows))
+			}
+			values := make([]any, len(returning))
+			dest := make([]any, len(returning))
+			for i := range values {
+				dest[i] = &values[i]
+			}
+			if err := result.Scan(dest...); err != nil {
+				return err
+			}
+			if r := rows[n]; r.label != "" {
+				refs[r.key()] = make(map[string]any, len(returning))
+				for i, column := range returning {
+					refs[r.key()][column] = refValue(values[i])
+				}
+			}
+			n++
+		}
+		return result.Err()
+	})
+	if err == nil && n != len(rows) {
+		err = fmt.Errorf("returned %d rows, want %d", n, len(rows))
+	}
+	if err != nil {
+		return fmt.Errorf("fixtures: insert into %s: %w", table, err)
+	}
+	return nil
+}
+
+// refValue widens the integers pgx decodes from smallint and integer columns
+// to int64.
+func refValue(v any) any {
+	switch v := v.(type) {
+	case int16:
+		return int64(v)
+	case int32:
+		return int64(v)
+	}
+	return v
+}
+
+// value evaluates the expressions of a column value.
+func value(cfg *config, refs Refs, v any) (any, error) {
+	if tpl, ok := v.(*template); ok {
+		return tpl.eval(cfg, refs)
+	}
+	return v, nil
+}
+
+// identifier quotes a possibly schema-qualified name.
+func identifier(name string) string {
+	return pgx.Identifier(strings.Split(name, ".")).Sanitize()
+}
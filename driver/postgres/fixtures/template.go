@@ -0,0 +1,192 @@
+package fixtures
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Expression kinds.
+const (
+	exprRef = iota
+	exprNow
+	exprToday
+)
+
+// template is a string value holding expressions.
+type template struct {
+	parts []part
+}
+
+// part is either text or an expression.
+type part struct {
+	text string
+	expr *expr
+}
+
+type expr struct {
+	kind int
+
+	// target names the row a reference refers to, resolved to row when
+	// the fixtures are linked. An empty column stands for the primary key.
+	target string
+	column string
+	row    *row
+
+	offset time.Duration
+}
+
+func parseTemplate(s string) (*template, error) {
+	var tpl template
+	for s != "" {
+		before, after, found := strings.Cut(s, "{{")
+		if before != "" {
+			tpl.parts = append(tpl.parts, part{text: before})
+		}
+		if !found {
+			break
+		}
+		source, rest, closed := strings.Cut(after, "}}")
+		if !closed {
+			return nil, errors.New("expression is missing }}")
+		}
+		e, err := parseExpr(source)
+		if err != nil {
+			return nil, fmt.Errorf("{{%s}}: %w", source, err)
+		}
+		tpl.parts = append(tpl.parts, part{expr: e})
+		s = rest
+	}
+	return &tpl, nil
+}
+
+func parseExpr(source string) (*expr, error) {
+	fields := strings.Fields(source)
+	if len(fields) == 0 {
+		return nil, errors.New("empty expression")
+	}
+	switch name, args := fields[0], fields[1:]; name {
+	case "ref":
+		if len(args) == 0 || len(args) > 2 {
+			return nil, errors.New("ref takes a row such as users.alice and an optional column")
+		}
+		target := strings.Trim(args[0], `"`)
+		if i := strings.LastIndex(target, "."); i <= 0 || i == len(target)-1 {
+			return nil, fmt.Errorf("ref %s does not name a row as TABLE.LABEL", target)
+		}
+		e := &expr{kind: exprRef, target: target}
+		if len(args) == 2 {
+			e.column = strings.Trim(args[1], `"`)
+		}
+		return e, nil
+	case "now", "today":
+		e := &expr{kind: exprNow}
+		if name == "today" {
+			e.kind = exprToday
+		}
+		if len(args) > 1 {
+			return nil, fmt.Errorf("%s takes an optional offset such as -48h", name)
+		}
+		if len(args) == 1 {
+			offset, err := parseOffset(strings.Trim(args[0], `"`))
+			if err != nil {
+				return nil, err
+			}
+			e.offset = offset
+		}
+		return e, nil
+	}
+	return nil, fmt.Errorf("unknown function %s", fields[0])
+}
+
+// parseOffset parses a duration as time.ParseDuration does, additionally
+// accepting a leading number of days, as in 7d or -1d12h.
+func parseOffset(s string) (time.Duration, error) {
+	sign := time.Duration(1)
+	rest := s
+	switch {
+	case strings.HasPrefix(rest, "-"):
+		sign, rest = -1, rest[1:]
+	case strings.HasPrefix(rest, "+"):
+		rest = rest[1:]
+	}
+
+	var offset time.Duration
+	if days, after, ok := strings.Cut(rest, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("invalid offset %s", s)
+		}
+		offset, rest = time.Duration(n)*24*time.Hour, after
+	}
+	if rest != "" {
+		d, err := time.ParseDuration(rest)
+		if err != nil || d < 0 {
+			return 0, fmt.Errorf("invalid offset %s", s)
+		}
+		offset += d
+	}
+	return sign * offset, nil
+}
+
+// refs returns the references of the template.
+func (t *template) refs() []*expr {
+	var refs []*expr
+	for _, p := range t.parts {
+		if p.expr != nil && p.expr.kind == exprRef {
+			refs = append(refs, p.expr)
+		}
+	}
+	return refs
+}
+
+// eval returns the value of a template. A template that is a single
+// expression evaluates to the value of the expression; anything else is
+// formatted into a string.
+func (t *template) eval(cfg *config, refs Refs) (any, error) {
+	if len(t.parts) == 1 && t.parts[0].expr != nil {
+		return t.parts[0].expr.eval(cfg, refs)
+	}
+	var b strings.Builder
+	for _, p := range t.parts {
+		if p.expr == nil {
+			b.WriteString(p.text)
+			continue
+		}
+		v, err := p.expr.eval(cfg, refs)
+		if err != nil {
+			return nil, err
+		}
+		if tm, ok := v.(time.Time); ok {
+			b.WriteString(tm.Format(time.RFC3339Nano))
+		} else {
+			fmt.Fprint(&b, v)
+		}
+	}
+	return b.String(), nil
+}
+
+func (e *expr) eval(cfg *config, refs Refs) (any, error) {
+	switch e.kind {
+	case exprNow:
+		return cfg.now.Add(e.offset), nil
+	case exprToday:
+		y, m, d := cfg.now.Date()
+		return time.Date(y, m, d, 0, 0, 0, 0, cfg.now.Location()).Add(e.offset), nil
+	}
+
+	column := e.column
+	if column == "" {
+		column = cfg.primaryKey(e.row.table)
+		if column == "" {
+			return nil, fmt.Errorf("ref %s: table %s has no primary key", e.target, e.row.table)
+		}
+	}
+	v, ok := refs[e.target][column]
+	if !ok {
+		return nil, fmt.Errorf("ref %s: no value for column %s", e.target, column)
+	}
+	return v, nil
+}
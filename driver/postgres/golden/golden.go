@@ -0,0 +1,249 @@
+// Package golden snapshots the SQL PostgreSQL handlers run, so query changes
+// show up as golden file diffs in code review without hand-written mock
+// expectations.
+//
+// Run executes a handler with a recording postgres.Builder and compares the
+// statements it built, their arguments and the way they were executed with a
+// golden file under testdata. A mismatch fails the test with a unified diff.
+// Running the test binary with -golden.update rewrites the golden files:
+//
+//	func TestCreatePostWithTags(t *testing.T) {
+//	    golden.Run(t, CreatePostWithTags(1, "Hello", []string{"go", "sql"}))
+//	}
+//
+//	go test ./... -run TestCreatePostWithTags -golden.update
+//
+// No database is involved: Exec reports no affected rows, QueryRow leaves its
+// destinations untouched and Query yields no rows. Handlers that branch on
+// query results take the path for empty results.
+package golden
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Kansuler/octobe/v3"
+	"github.com/Kansuler/octobe/v3/driver/postgres"
+	"github.com/Kansuler/octobe/v3/internal/textdiff"
+)
+
+var update = flag.Bool("golden.update", false, "rewrite golden SQL snapshots")
+
+// Updating reports whether golden files are being rewritten with -golden.update.
+func Updating() bool {
+	return *update
+}
+
+// Run runs handler with a recording builder and compares the statements it
+// executed with the golden file testdata/<test name>.sql, or rewrites the file
+// with -golden.update. It returns the handler's result and error.
+func Run[RESULT any](t testing.TB, handler octobe.Handler[RESULT, postgres.Builder]) (RESULT, error) {
+	t.Helper()
+	return RunFile(t, Path(t), handler)
+}
+
+// RunFile is Run with an explicit golden file path.
+func RunFile[RESULT any](t testing.TB, path string, handler octobe.Handler[RESULT, postgres.Builder]) (RESULT, error) {
+	t.Helper()
+	r := NewRecorder()
+	result, err := handler(r.Builder())
+	r.Assert(t, path)
+	return result, err
+}
+
+// Path returns the default golden file of a test: testdata/<test name>.sql,
+// with subtests in subdirectories.
+func Path(t testing.TB) string {
+	return filepath.Join("testdata", filepath.FromSlash(t.Name())+".sql")
+}
+
+// Statement is a statement executed through a recording builder.
+type Statement struct {
+	// Method is Exec, QueryRow or Query.
+	Method string
+	Query  string
+	Args   []any
+}
+
+// Recorder records the statements executed through its builders. Use it
+// directly to snapshot several handlers, or code that takes a builder, in one
+// golden file.
+type Recorder struct {
+	mu         sync.Mutex
+	statements []Statement
+}
+
+// NewRecorder returns a Recorder without statements.
+func NewRecorder() *Recorder {
+	return &Recorder{}
+}
+
+// Builder returns a postgres.Builder whose segments record their execution.
+func (r *Recorder) Builder() postgres.Builder {
+	return func(query string) postgres.Segment {
+		return &segment{recorder: r, query: query}
+	}
+}
+
+// Statements returns the statements executed so far, in order.
+func (r *Recorder) Statements() []Statement {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Statement(nil), r.statements...)
+}
+
+func (r *Recorder) record(method, query string, args []any) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.statements = append(r.statements, Statement{Method: method, Query: query, Args: args})
+}
+
+// String formats the statements as they are written to golden files. Each
+// statement is headed by its position and method and followed by one comment
+// line per argument.
+func (r *Recorder) String() string {
+	var b strings.Builder
+	for i, s := range r.Statements() {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		fmt.Fprintf(&b, "-- %d: %s\n%s\n", i+1, s.Method, s.Query)
+		for j, arg := range s.Args {
+			fmt.Fprintf(&b, "-- $%d: %s\n", j+1, formatArg(arg))
+		}
+	}
+	return b.String()
+}
+
+// Assert compares the recorded statements with the golden file at path, or
+// writes them to it with -golden.update.
+func (r *Recorder) Assert(t testing.TB, path string) {
+	t.Helper()
+	got := r.String()
+
+	if Updating() {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("write golden file: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(got), 0o644); err != nil {
+			t.Fatalf("write golden file: %v", err)
+		}
+		return
+	}
+
+	want, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("golden file %s does not exist; run with -golden.update to create it", path)
+	}
+	if err != nil {
+		t.Fatalf("read golden file: %v", err)
+	}
+	want = bytes.ReplaceAll(want, []byte("\r\n"), []byte("\n"))
+	if string(want) != got {
+		t.Errorf("SQL differs from golden file %s; run with -golden.update to accept it:\n%s", path, textdiff.Unified(lines(string(want)), lines(got)))
+	}
+}
+
+func lines(s string) []string {
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
+
+// formatArg formats an argument deterministically: pointers are followed,
+// strings quoted and times written in RFC 3339. The Go type is appended so a
+// changed argument type shows up in the diff as well.
+func formatArg(arg any) string {
+	v := reflect.ValueOf(arg)
+	for v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return fmt.Sprintf("NULL (%T)", arg)
+		}
+		v = v.Elem()
+	}
+	if !v.IsValid() {
+		return "NULL"
+	}
+
+	var value string
+	switch x := v.Interface().(type) {
+	case string:
+		value = strconv.Quote(x)
+	case []byte:
+		value = fmt.Sprintf(`'\x%x'`, x)
+	case time.Time:
+		value = x.Format(time.RFC3339Nano)
+	case fmt.Stringer:
+		value = x.String()
+	default:
+		value = fmt.Sprintf("%v", x)
+	}
+	return fmt.Sprintf("%s (%T)", value, arg)
+}
+
+// segment records its execution instead of running it. Like the driver's
+// segments it can only be executed once.
+type segment struct {
+	recorder *Recorder
+	query    string
+	args     []any
+	used     bool
+}
+
+func (s *segment) Arguments(args ...any) postgres.Segment {
+	s.args = args
+	return s
+}
+
+// WithContext implements postgres.ContextSegment for handlers that set a
+// context, and leaves the snapshot unchanged since no statement runs.
+func (s *segment) WithContext(context.Context) postgres.Segment {
+	return s
+}
+
+func (s *segment) use(method string) error {
+	if s.used {
+		return octobe.ErrAlreadyUsed
+	}
+	s.used = true
+	s.recorder.record(method, s.query, s.args)
+	return nil
+}
+
+func (s *segment) Exec() (postgres.ExecResult, error) {
+	return postgres.ExecResult{}, s.use("Exec")
+}
+
+func (s *segment) QueryRow(dest ...any) error {
+	return s.use("QueryRow")
+}
+
+func (s *segment) Query(cb func(postgres.Rows) error) error {
+	if err := s.use("Query"); err != nil {
+		return err
+	}
+	return cb(emptyRows{})
+}
+
+type emptyRows struct{}
+
+func (emptyRows) Err() error {
+	return nil
+}
+
+func (emptyRows) Next() bool {
+	return false
+}
+
+func (emptyRows) Scan(dest ...any) error {
+	return errors.New("golden: no rows to scan")
+}
@@ -0,0 +1,176 @@
+package golden
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/Kansuler/octobe/v3"
+	"github.com/Kansuler/octobe/v3/driver/postgres"
+	"github.com/stretchr/testify/require"
+)
+
+type post struct {
+	ID    int
+	Title string
+}
+
+func createPostWithTags(authorID int, title string, tags []string, published *time.Time) octobe.Handler[post, postgres.Builder] {
+	return func(builder postgres.Builder) (post, error) {
+		p := post{Title: title}
+		err := builder(`
+			INSERT INTO posts (author_id, title, published_at)
+			VALUES ($1, $2, $3)
+			RETURNING id`).
+			Arguments(authorID, title, published).
+			QueryRow(&p.ID)
+		if err != nil {
+			return p, err
+		}
+
+		for _, tag := range tags {
+			_, err := builder(`INSERT INTO post_tags (post_id, tag) VALUES ($1, $2)`).
+				Arguments(p.ID, tag).
+				Exec()
+			if err != nil {
+				return p, err
+			}
+		}
+
+		return p, builder(`SELECT id FROM posts WHERE author_id = $1`).Arguments(authorID).Query(func(rows postgres.Rows) error {
+			for rows.Next() {
+				return errors.New("unexpected row")
+			}
+			return rows.Err()
+		})
+	}
+}
+
+// recordingT captures failures instead of failing the test. Fatalf stops the
+// goroutine like testing.T does, so assertions run in their own goroutine.
+type recordingT struct {
+	testing.TB
+	errors []string
+}
+
+func (t *recordingT) Helper() {}
+
+func (t *recordingT) Name() string {
+	return "TestRecordingT"
+}
+
+func (t *recordingT) Errorf(format string, args ...any) {
+	t.errors = append(t.errors, fmt.Sprintf(format, args...))
+}
+
+func (t *recordingT) Fatalf(format string, args ...any) {
+	t.Errorf(format, args...)
+	runtime.Goexit()
+}
+
+func (t *recordingT) run(fn func()) {
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		fn()
+	}()
+	<-done
+}
+
+func TestRun(t *testing.T) {
+	published := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	p, err := Run(t, createPostWithTags(7, "Hello", []string{"go", "sql"}, &published))
+	require.NoError(t, err)
+	require.Equal(t, post{Title: "Hello"}, p)
+}
+
+func TestRecorder(t *testing.T) {
+	t.Run("formats statements", func(t *testing.T) {
+		r := NewRecorder()
+		_, err := createPostWithTags(1, "a\"b", []string{"x"}, nil)(r.Builder())
+		require.NoError(t, err)
+
+		require.Equal(t, `-- 1: QueryRow
+
+			INSERT INTO posts (author_id, title, published_at)
+			VALUES ($1, $2, $3)
+			RETURNING id
+-- $1: 1 (int)
+-- $2: "a\"b" (string)
+-- $3: NULL (*time.Time)
+
+-- 2: Exec
+INSERT INTO post_tags (post_id, tag) VALUES ($1, $2)
+-- $1: 0 (int)
+-- $2: "x" (string)
+
+-- 3: Query
+SELECT id FROM posts WHERE author_id = $1
+-- $1: 1 (int)
+`, r.String())
+		require.Len(t, r.Statements(), 3)
+	})
+
+	t.Run("segments run once", func(t *testing.T) {
+		r := NewRecorder()
+		segment := r.Builder()(`DELETE FROM posts`)
+		_, err := segment.Exec()
+		require.NoError(t, err)
+		_, err = segment.Exec()
+		require.ErrorIs(t, err, octobe.ErrAlreadyUsed)
+		require.Len(t, r.Statements(), 1)
+	})
+
+	t.Run("mismatch reports a diff", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "changed.sql")
+		require.NoError(t, os.WriteFile(path, []byte("-- 1: Exec\nDELETE FROM posts\n-- $1: 1 (int)\n"), 0o644))
+
+		r := NewRecorder()
+		_, err := r.Builder()(`DELETE FROM posts`).Arguments("1").Exec()
+		require.NoError(t, err)
+
+		rt := &recordingT{}
+		rt.run(func() { r.Assert(rt, path) })
+		require.Equal(t, []string{fmt.Sprintf(`SQL differs from golden file %s; run with -golden.update to accept it:
+--- expected
++++ actual
+@@ -1,3 +1,3 @@
+ -- 1: Exec
+ DELETE FROM posts
+--- $1: 1 (int)
++-- $1: "1" (string)
+`, path)}, rt.errors)
+	})
+
+	t.Run("missing golden file", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "missing.sql")
+		rt := &recordingT{}
+		rt.run(func() { NewRecorder().Assert(rt, path) })
+		require.Equal(t, []string{"golden file " + path + " does not exist; run with -golden.update to create it"}, rt.errors)
+	})
+
+	t.Run("update writes the golden file", func(t *testing.T) {
+		*update = true
+		defer func() { *update = false }()
+
+		path := filepath.Join(t.TempDir(), "nested", "new.sql")
+		r := NewRecorder()
+		require.NoError(t, r.Builder()(`SELECT 1`).QueryRow())
+		r.Assert(t, path)
+
+		data, err := os.ReadFile(path)
+		require.NoError(t, err)
+		require.Equal(t, "-- 1: QueryRow\nSELECT 1\n", string(data))
+	})
+}
+
+func TestPath(t *testing.T) {
+	require.Equal(t, filepath.Join("testdata", "TestPath.sql"), Path(t))
+	t.Run("sub test", func(t *testing.T) {
+		require.Equal(t, filepath.Join("testdata", "TestPath", "sub_test.sql"), Path(t))
+	})
+}
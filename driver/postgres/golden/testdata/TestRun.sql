@@ -0,0 +1,22 @@
+-- 1: QueryRow
+
+			INSERT INTO posts (author_id, title, published_at)
+			VALUES ($1, $2, $3)
+			RETURNING id
+-- $1: 7 (int)
+-- $2: "Hello" (string)
+-- $3: 2026-01-02T03:04:05Z (*time.Time)
+
+-- 2: Exec
+INSERT INTO post_tags (post_id, tag) VALUES ($1, $2)
+-- $1: 0 (int)
+-- $2: "go" (string)
+
+-- 3: Exec
+INSERT INTO post_tags (post_id, tag) VALUES ($1, $2)
+-- $1: 0 (int)
+-- $2: "sql" (string)
+
+-- 4: Query
+SELECT id FROM posts WHERE author_id = $1
+-- $1: 7 (int)
@@ -0,0 +1,161 @@
+package mock
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync/atomic"
+
+	"github.com/Kansuler/octobe/v3/internal/mockmatch"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// BatchExpectation expects a SendBatch call with the queued statements added
+// through its ExpectExec, ExpectQuery and ExpectQueryRow methods, in order.
+// Each statement is matched like a standalone expectation, exactly or with
+// Contains or Regex, and its WillReturn methods configure the result read for
+// it from the returned pgx.BatchResults. Call counts and delays configured on
+// statements are ignored; set them on the batch instead.
+//
+// AllExpectationsMet reports batch results that were never closed.
+type BatchExpectation struct {
+	basicExpectation
+	statements []expectation
+	open       atomic.Int32
+	err        error
+}
+
+// ExpectExec adds a statement whose result is read with BatchResults.Exec.
+func (e *BatchExpectation) ExpectExec(query string) *ExecExpectation {
+	s := &ExecExpectation{basicExpectation: basicExpectation{statement: mockmatch.Statement{Method: "Exec", Query: query, Mode: mockmatch.Exact}}}
+	e.statements = append(e.statements, s)
+	return s
+}
+
+// ExpectQuery adds a statement whose result is read with BatchResults.Query.
+func (e *BatchExpectation) ExpectQuery(query string) *QueryExpectation {
+	s := &QueryExpectation{basicExpectation: basicExpectation{statement: mockmatch.Statement{Method: "Query", Query: query, Mode: mockmatch.Exact}}}
+	e.statements = append(e.statements, s)
+	return s
+}
+
+// ExpectQueryRow adds a statement whose result is read with BatchResults.QueryRow.
+func (e *BatchExpectation) ExpectQueryRow(query string) *QueryRowExpectation {
+	s := &QueryRowExpectation{basicExpectation: basicExpectation{statement: mockmatch.Statement{Method: "QueryRow", Query: query, Mode: mockmatch.Exact}}}
+	e.statements = append(e.statements, s)
+	return s
+}
+
+// WillReturnError makes every result of the batch, and closing it, fail with
+// err, as when the batch cannot be sent.
+func (e *BatchExpectation) WillReturnError(err error) *BatchExpectation {
+	e.err = err
+	return e
+}
+
+func (e *BatchExpectation) match(method string, args ...any) error {
+	if e.statement.Method != method {
+		return fmt.Errorf("method mismatch: expected %s, got %s", e.statement.Method, method)
+	}
+	batch, _ := args[0].(*pgx.Batch)
+	if batch == nil {
+		return errors.New("batch is nil")
+	}
+	if len(batch.QueuedQueries) != len(e.statements) {
+		return fmt.Errorf("batch mismatch: expected %d statements, got %d", len(e.statements), len(batch.QueuedQueries))
+	}
+	for i, q := range batch.QueuedQueries {
+		s := e.statements[i].base()
+		if err := s.match(s.statement.Method, append([]any{q.SQL}, q.Arguments...)...); err != nil {
+			return fmt.Errorf("batch statement %d: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// call records the call and builds fresh results from the statement responses.
+func (e *BatchExpectation) call() []any {
+	e.basicExpectation.call()
+	results := &BatchResults{expectation: e, err: e.err}
+	for _, s := range e.statements {
+		results.methods = append(results.methods, s.base().statement.Method)
+		results.responses = append(results.responses, s.call())
+	}
+	e.open.Add(1)
+	return []any{results}
+}
+
+func (e *BatchExpectation) verify() error {
+	if n := e.open.Load(); n > 0 {
+		return fmt.Errorf("%d batch results were not closed", n)
+	}
+	return nil
+}
+
+func (e *BatchExpectation) String() string {
+	statements := make([]string, len(e.statements))
+	for i, s := range e.statements {
+		statements[i] = s.String()
+	}
+	return fmt.Sprintf("method SendBatch with statements [%s]", strings.Join(statements, "; "))
+}
+
+// BatchResults provides a mock implementation of pgx.BatchResults that serves
+// the results configured on a BatchExpectation, one statement at a time.
+type BatchResults struct {
+	expectation *BatchExpectation
+	methods     []string
+	responses   [][]any
+	pos         int
+	closed      bool
+	err         error
+}
+
+var _ pgx.BatchResults = (*BatchResults)(nil)
+
+// next returns the response of the next statement, which must be read with method.
+func (r *BatchResults) next(method string) ([]any, error) {
+	if r.closed {
+		return nil, errors.New("batch already closed")
+	}
+	if r.err != nil {
+		return nil, r.err
+	}
+	if r.pos >= len(r.responses) {
+		return nil, errors.New("no more results in batch")
+	}
+	i := r.pos
+	r.pos++
+	if r.methods[i] != method {
+		return nil, fmt.Errorf("batch statement %d was expected to be read with %s, got %s", i, r.methods[i], method)
+	}
+	if len(r.responses[i]) == 0 {
+		return nil, fmt.Errorf("batch statement %d: %w", i, noResponse(method))
+	}
+	return r.responses[i], nil
+}
+
+func (r *BatchResults) Exec() (pgconn.CommandTag, error) {
+	return execResult(r.next("Exec"))
+}
+
+func (r *BatchResults) Query() (pgx.Rows, error) {
+	return queryResult(r.next("Query"))
+}
+
+func (r *BatchResults) QueryRow() pgx.Row {
+	return queryRowResult(r.next("QueryRow"))
+}
+
+// Close marks the results as closed. It returns the batch error, if any.
+func (r *BatchResults) Close() error {
+	if r.closed {
+		return nil
+	}
+	r.closed = true
+	if r.expectation != nil {
+		r.expectation.open.Add(-1)
+	}
+	return r.err
+}
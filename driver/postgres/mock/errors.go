@@ -0,0 +1,147 @@
+package mock
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// PgErrorOption sets a field of an error built by PgError or one of the
+// factories for common SQLSTATE codes.
+type PgErrorOption func(*pgconn.PgError)
+
+// WithMessage sets the primary error message.
+func WithMessage(msg string) PgErrorOption {
+	return func(e *pgconn.PgError) { e.Message = msg }
+}
+
+// WithDetail sets the detail message.
+func WithDetail(detail string) PgErrorOption {
+	return func(e *pgconn.PgError) { e.Detail = detail }
+}
+
+// WithHint sets the hint message.
+func WithHint(hint string) PgErrorOption {
+	return func(e *pgconn.PgError) { e.Hint = hint }
+}
+
+// WithTable sets the schema and table the error refers to.
+func WithTable(schema, table string) PgErrorOption {
+	return func(e *pgconn.PgError) {
+		e.SchemaName = schema
+		e.TableName = table
+	}
+}
+
+// WithColumn sets the column the error refers to.
+func WithColumn(column string) PgErrorOption {
+	return func(e *pgconn.PgError) { e.ColumnName = column }
+}
+
+// WithConstraint sets the constraint the error refers to.
+func WithConstraint(constraint string) PgErrorOption {
+	return func(e *pgconn.PgError) { e.ConstraintName = constraint }
+}
+
+// PgError builds the error PostgreSQL reports with SQLSTATE code, with
+// severity ERROR and a generic message unless opts set one.
+func PgError(code string, opts ...PgErrorOption) *pgconn.PgError {
+	e := &pgconn.PgError{
+		Severity:            "ERROR",
+		SeverityUnlocalized: "ERROR",
+		Code:                code,
+		Message:             fmt.Sprintf("mock error with SQLSTATE %s", code),
+	}
+	for _, opt := range opts {
+		opt(e)
+	}
+	return e
+}
+
+// UniqueViolation builds the error for an insert or update that violates the
+// unique constraint (SQLSTATE 23505).
+func UniqueViolation(constraint string, opts ...PgErrorOption) *pgconn.PgError {
+	return PgError("23505", append([]PgErrorOption{
+		WithMessage(fmt.Sprintf("duplicate key value violates unique constraint %q", constraint)),
+		WithDetail("Key already exists."),
+		WithConstraint(constraint),
+	}, opts...)...)
+}
+
+// ForeignKeyViolation builds the error for an insert or update on table that
+// violates the foreign key constraint (SQLSTATE 23503).
+func ForeignKeyViolation(table, constraint string, opts ...PgErrorOption) *pgconn.PgError {
+	return PgError("23503", append([]PgErrorOption{
+		WithMessage(fmt.Sprintf("insert or update on table %q violates foreign key constraint %q", table, constraint)),
+		WithDetail("Key is not present in the referenced table."),
+		WithTable("public", table),
+		WithConstraint(constraint),
+	}, opts...)...)
+}
+
+// SerializationFailure builds the error a serializable or repeatable read
+// transaction fails with when it conflicts with a concurrent one (SQLSTATE
+// 40001). The transaction should be retried.
+func SerializationFailure(opts ...PgErrorOption) *pgconn.PgError {
+	return PgError("40001", append([]PgErrorOption{
+		WithMessage("could not serialize access due to read/write dependencies among transactions"),
+		WithDetail("Reason code: Canceled on identification as a pivot, during commit attempt."),
+		WithHint("The transaction might succeed if retried."),
+	}, opts...)...)
+}
+
+// DeadlockDetected builds the error the transaction chosen to break a
+// deadlock fails with (SQLSTATE 40P01). The transaction should be retried.
+func DeadlockDetected(opts ...PgErrorOption) *pgconn.PgError {
+	return PgError("40P01", append([]PgErrorOption{
+		WithMessage("deadlock detected"),
+		WithDetail("Process 4242 waits for ShareLock on transaction 1001; blocked by process 4243.\n" +
+			"Process 4243 waits for ShareLock on transaction 1002; blocked by process 4242."),
+		WithHint("See server log for query details."),
+	}, opts...)...)
+}
+
+// QueryCanceled builds the error for a statement canceled on request or by
+// statement_timeout (SQLSTATE 57014).
+func QueryCanceled(opts ...PgErrorOption) *pgconn.PgError {
+	return PgError("57014", append([]PgErrorOption{
+		WithMessage("canceling statement due to user request"),
+	}, opts...)...)
+}
+
+// ConnectionLost returns the error pgconn reports when the connection breaks
+// while a call is in flight, such as when the server restarts in the middle
+// of a transaction. It is not safe to retry, since the statement may have run.
+func ConnectionLost() error {
+	return &connError{msg: "failed to receive message", err: io.ErrUnexpectedEOF}
+}
+
+// CommitFailed returns the error pgx reports when a commit ends in a rollback
+// instead, as when the transaction failed earlier. Nothing was committed.
+func CommitFailed() error {
+	return pgx.ErrTxCommitRollback
+}
+
+// CommitOutcomeUnknown returns the error for a connection that breaks after
+// COMMIT was sent and before its result arrived, so the transaction may or
+// may not have been committed. It is not safe to retry.
+func CommitOutcomeUnknown() error {
+	return &connError{msg: "commit outcome unknown: failed to receive message", err: io.ErrUnexpectedEOF}
+}
+
+// connError mirrors the errors pgconn returns for a broken connection.
+type connError struct {
+	msg string
+	err error
+}
+
+func (e *connError) Error() string {
+	return fmt.Sprintf("%s: %s", e.msg, e.err)
+}
+
+func (e *connError) Unwrap() error { return e.err }
+
+// SafeToRetry reports false, since the server may have run the call.
+func (e *connError) SafeToRetry() bool { return false }
@@ -0,0 +1,132 @@
+package mock
+
+import (
+	"context"
+	"io"
+	"testing"
+
+	"github.com/Kansuler/octobe/v3"
+	"github.com/Kansuler/octobe/v3/driver/postgres"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPgErrors(t *testing.T) {
+	t.Run("SQLSTATE factories", func(t *testing.T) {
+		for _, tt := range []struct {
+			err     *pgconn.PgError
+			code    string
+			message string
+		}{
+			{UniqueViolation("users_email_key"), "23505", `duplicate key value violates unique constraint "users_email_key"`},
+			{ForeignKeyViolation("orders", "orders_user_id_fkey"), "23503", `insert or update on table "orders" violates foreign key constraint "orders_user_id_fkey"`},
+			{SerializationFailure(), "40001", "could not serialize access due to read/write dependencies among transactions"},
+			{DeadlockDetected(), "40P01", "deadlock detected"},
+			{QueryCanceled(), "57014", "canceling statement due to user request"},
+		} {
+			require.Equal(t, tt.code, tt.err.Code)
+			require.Equal(t, tt.message, tt.err.Message)
+			require.Equal(t, "ERROR", tt.err.Severity)
+			require.Equal(t, "ERROR: "+tt.message+" (SQLSTATE "+tt.code+")", tt.err.Error())
+		}
+
+		fk := ForeignKeyViolation("orders", "orders_user_id_fkey")
+		require.Equal(t, "public", fk.SchemaName)
+		require.Equal(t, "orders", fk.TableName)
+		require.Equal(t, "orders_user_id_fkey", fk.ConstraintName)
+	})
+
+	t.Run("Options", func(t *testing.T) {
+		err := PgError("23514",
+			WithMessage(`new row for relation "orders" violates check constraint "orders_total_check"`),
+			WithTable("shop", "orders"),
+			WithColumn("total"),
+			WithConstraint("orders_total_check"),
+			WithHint("Totals must be positive."),
+		)
+		require.Equal(t, "23514", err.Code)
+		require.Equal(t, "shop", err.SchemaName)
+		require.Equal(t, "total", err.ColumnName)
+		require.Equal(t, "Totals must be positive.", err.Hint)
+
+		unique := UniqueViolation("users_email_key", WithDetail("Key (email)=(a@example.com) already exists."))
+		require.Equal(t, "Key (email)=(a@example.com) already exists.", unique.Detail)
+
+		require.Equal(t, "mock error with SQLSTATE 0A000", PgError("0A000").Message)
+	})
+
+	t.Run("Connection failures", func(t *testing.T) {
+		lost := ConnectionLost()
+		require.ErrorIs(t, lost, io.ErrUnexpectedEOF)
+		require.False(t, pgconn.SafeToRetry(lost))
+		require.EqualError(t, lost, "failed to receive message: unexpected EOF")
+
+		unknown := CommitOutcomeUnknown()
+		require.ErrorIs(t, unknown, io.ErrUnexpectedEOF)
+		require.False(t, pgconn.SafeToRetry(unknown))
+
+		require.ErrorIs(t, CommitFailed(), pgx.ErrTxCommitRollback)
+	})
+
+	t.Run("Failures through StartTransaction", func(t *testing.T) {
+		ctx := context.Background()
+		conflict := SerializationFailure()
+		for _, tt := range []struct {
+			name   string
+			expect func(m *PGXMock)
+			want   error
+		}{
+			{
+				name: "serialization failure rolls back",
+				expect: func(m *PGXMock) {
+					m.ExpectExec("UPDATE accounts SET balance = balance - 10").InTransaction().WillReturnError(conflict)
+					m.ExpectRollback()
+				},
+				want: conflict,
+			},
+			{
+				name: "connection lost in the middle of the transaction",
+				expect: func(m *PGXMock) {
+					m.ExpectExec("UPDATE accounts SET balance = balance - 10").InTransaction().WillReturnError(ConnectionLost())
+					m.ExpectRollback().WillReturnError(ConnectionLost())
+				},
+				want: io.ErrUnexpectedEOF,
+			},
+			{
+				name: "commit with unknown outcome",
+				expect: func(m *PGXMock) {
+					m.ExpectExec("UPDATE accounts SET balance = balance - 10").InTransaction().WillReturnResult(NewResult("UPDATE", 1))
+					m.ExpectCommit().WillReturnError(CommitOutcomeUnknown())
+					m.ExpectRollback()
+				},
+				want: io.ErrUnexpectedEOF,
+			},
+			{
+				name: "commit that rolled back",
+				expect: func(m *PGXMock) {
+					m.ExpectExec("UPDATE accounts SET balance = balance - 10").InTransaction().WillReturnResult(NewResult("UPDATE", 1))
+					m.ExpectCommit().WillReturnError(CommitFailed())
+					m.ExpectRollback()
+				},
+				want: pgx.ErrTxCommitRollback,
+			},
+		} {
+			t.Run(tt.name, func(t *testing.T) {
+				m := NewPGXMock()
+				db, err := octobe.New(postgres.OpenPGXWithConn(m))
+				require.NoError(t, err)
+
+				m.ExpectBeginTx()
+				tt.expect(m)
+
+				err = db.StartTransaction(ctx, func(session octobe.BuilderSession[postgres.Builder]) error {
+					_, err := session.Builder()("UPDATE accounts SET balance = balance - 10").Exec()
+					return err
+				})
+				require.ErrorIs(t, err, tt.want)
+				require.NoError(t, m.AllExpectationsMet())
+			})
+		}
+	})
+}
@@ -0,0 +1,557 @@
+package mock
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// node is an expectation or a group of them.
+type node interface {
+	// satisfied reports whether the node received all the calls it requires.
+	satisfied() bool
+	// exhausted reports whether the node can take no further calls.
+	exhausted() bool
+	// seal stops the node from taking further calls.
+	seal()
+}
+
+// group is a set of expectations and nested groups that are matched either in
+// the order they were added or in any order.
+type group struct {
+	ordered  bool
+	children []node
+}
+
+func (g *group) satisfied() bool {
+	for _, child := range g.children {
+		if !child.satisfied() {
+			return false
+		}
+	}
+	return true
+}
+
+func (g *group) exhausted() bool {
+	for _, child := range g.children {
+		if !child.exhausted() {
+			return false
+		}
+	}
+	return true
+}
+
+func (g *group) seal() {
+	for _, child := range g.children {
+		child.seal()
+	}
+}
+
+// candidates returns the expectations that may match the next call. An ordered
+// group offers its children up to and including the first one that still
+// requires calls, an unordered group offers all of them.
+func (g *group) candidates() []expectation {
+	var out []expectation
+	for _, child := range g.children {
+		if child.exhausted() {
+			continue
+		}
+		switch c := child.(type) {
+		case expectation:
+			out = append(out, c)
+		case *group:
+			out = append(out, c.candidates()...)
+		}
+		if g.ordered && !child.satisfied() {
+			break
+		}
+	}
+	return out
+}
+
+// advance reports whether e belongs to the group. In ordered groups it seals
+// the children before the one holding e, since the call moved past them.
+func (g *group) advance(e expectation) bool {
+	for i, child := range g.children {
+		found := child == node(e)
+		if c, ok := child.(*group); ok && !found {
+			found = c.advance(e)
+		}
+		if !found {
+			continue
+		}
+		if g.ordered {
+			for _, prev := range g.children[:i] {
+				prev.seal()
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// firstUnsatisfied returns the first expectation in declaration order that did
+// not receive all the calls it requires.
+func (g *group) firstUnsatisfied() *basicExpectation {
+	for _, child := range g.children {
+		switch c := child.(type) {
+		case expectation:
+			if !c.satisfied() {
+				return c.base()
+			}
+		case *group:
+			if e := c.firstUnsatisfied(); e != nil {
+				return e
+			}
+		}
+	}
+	return nil
+}
+
+// expectations returns the expectations of the group and its nested groups in
+// declaration order.
+func (g *group) expectations() []expectation {
+	var out []expectation
+	for _, child := range g.children {
+		switch c := child.(type) {
+		case expectation:
+			out = append(out, c)
+		case *group:
+			out = append(out, c.expectations()...)
+		}
+	}
+	return out
+}
+
+// verifier is implemented by expectations with checks beyond call counts.
+type verifier interface {
+	verify() error
+}
+
+// verify runs the extra checks of all expectations in declaration order.
+func (g *group) verify() error {
+	for _, child := range g.children {
+		switch c := child.(type) {
+		case *group:
+			if err := c.verify(); err != nil {
+				return err
+			}
+		case verifier:
+			if err := c.verify(); err != nil {
+				return fmt.Errorf("unfulfilled expectation: %s: %w", child, err)
+			}
+		}
+	}
+	return nil
+}
+
+// expectationSet holds the expectations of a mock and serialises matching, so a
+// mock can be shared by concurrent goroutines.
+type expectationSet struct {
+	mu         sync.Mutex
+	root       *group
+	current    *group
+	log        []logEntry
+	txs        int
+	savepoints map[int]int
+	// onUnexpected, when set, is called with the error of every call that
+	// matched no expectation.
+	onUnexpected func(error)
+}
+
+func (s *expectationSet) init() {
+	if s.root == nil {
+		s.root = &group{ordered: true}
+		s.current = s.root
+	}
+}
+
+func (s *expectationSet) add(e expectation) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.init()
+	s.current.children = append(s.current.children, e)
+}
+
+func (s *expectationSet) setInOrder(ordered bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.init()
+	s.root.ordered = ordered
+}
+
+// nest adds a group to the current one and registers the expectations added by fn into it.
+func (s *expectationSet) nest(ordered bool, fn func()) {
+	s.mu.Lock()
+	s.init()
+	g := &group{ordered: ordered}
+	parent := s.current
+	parent.children = append(parent.children, g)
+	s.current = g
+	s.mu.Unlock()
+
+	defer func() {
+		s.mu.Lock()
+		s.current = parent
+		s.mu.Unlock()
+	}()
+	fn()
+}
+
+// find matches a call made in scope sc against the current candidates, records
+// it on the first match and returns the response configured for that call. The
+// error lists every candidate and why it did not match.
+//
+// Like pgx, a call whose context is already done fails without reaching the
+// expectations, and a context that ends during a configured delay fails the
+// call after it was matched.
+func (s *expectationSet) find(ctx context.Context, sc scope, method string, args ...any) ([]any, error) {
+	ret, _, _, err := s.call(ctx, sc, false, method, args...)
+	return ret, err
+}
+
+// beginTx matches a Begin or BeginTx call made in scope sc. On a match it
+// opens a transaction with opts, or a savepoint when sc is already in one. The
+// transaction calls into this set, or into one of its scoped mocks when the
+// matched expectation has them.
+func (s *expectationSet) beginTx(ctx context.Context, sc scope, opts pgx.TxOptions, method string, args ...any) (pgx.Tx, error) {
+	sc.options = opts
+	ret, sc, e, err := s.call(ctx, sc, true, method, args...)
+	if err := beginResult(ret, err); err != nil {
+		return nil, err
+	}
+	if e.base().scoped != nil {
+		return &Tx{scope: sc, claim: &claim{parent: s, method: method, args: args}}, nil
+	}
+	return &Tx{set: s, scope: sc}, nil
+}
+
+func (s *expectationSet) call(ctx context.Context, sc scope, begin bool, method string, args ...any) ([]any, scope, expectation, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, sc, nil, &contextAlreadyDoneError{err: err}
+	}
+	ret, sc, e, err := s.match(sc, begin, method, args...)
+	if err != nil {
+		s.notify(err)
+		return nil, sc, nil, err
+	}
+	if err := wait(ctx, e.base().delay); err != nil {
+		return nil, sc, e, err
+	}
+	return ret, sc, e, nil
+}
+
+// notify passes the error of an unexpected call to onUnexpected, if set.
+func (s *expectationSet) notify(err error) {
+	if s.onUnexpected != nil {
+		s.onUnexpected(err)
+	}
+}
+
+func (s *expectationSet) match(sc scope, begin bool, method string, args ...any) ([]any, scope, expectation, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.init()
+
+	e, err := s.try(sc, method, args)
+	if err != nil {
+		return nil, sc, nil, s.unexpected(sc, method, args, err)
+	}
+	s.root.advance(e)
+	if begin {
+		sc = s.open(sc)
+	}
+	s.log = append(s.log, logEntry{seq: callSeq.Add(1), call: newCall(e.base().statement.HasQuery(), sc, method, args), expectation: e})
+	return e.call(), sc, e, nil
+}
+
+// try returns the first candidate that matches a call made in scope sc, or an
+// error listing every candidate and why it did not match.
+func (s *expectationSet) try(sc scope, method string, args []any) (expectation, error) {
+	candidates := s.root.candidates()
+	if len(candidates) == 0 {
+		return nil, fmt.Errorf("%w for %s with args %v", ErrNoExpectation, method, args)
+	}
+
+	mismatches := make([]error, len(candidates))
+	for i, e := range candidates {
+		if err := e.match(method, args...); err != nil {
+			mismatches[i] = err
+			continue
+		}
+		if err := e.base().matchScope(sc); err != nil {
+			mismatches[i] = err
+			continue
+		}
+		return e, nil
+	}
+
+	if len(candidates) == 1 {
+		return nil, fmt.Errorf("%w: next expectation %s does not match %s with args %v: %w", ErrNoExpectation, candidates[0], method, args, mismatches[0])
+	}
+	var b strings.Builder
+	for i, e := range candidates {
+		fmt.Fprintf(&b, "\n  - %s: %s", e, indent(mismatches[i].Error(), "    "))
+	}
+	return nil, fmt.Errorf("%w: none of %d candidate expectations match %s with args %v:%s", ErrNoExpectation, len(candidates), method, args, b.String())
+}
+
+// accepts reports why a call made in scope sc would not match, without
+// recording it.
+func (s *expectationSet) accepts(sc scope, method string, args []any) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.init()
+	_, err := s.try(sc, method, args)
+	return err
+}
+
+// claim picks the scoped mock for a session that was opened by a call of
+// openMethod with openArgs and now makes its first call. It takes the first
+// unclaimed scoped mock, in declaration order, whose owner matches the opening
+// call and whose next expectation matches the first call, so sessions follow
+// their own scripts whatever order they were opened in.
+func (s *expectationSet) claim(openMethod string, openArgs []any, sc scope, method string, args []any) (*expectationSet, error) {
+	s.mu.Lock()
+	s.init()
+	var b strings.Builder
+	for _, e := range s.root.expectations() {
+		sm := e.base().scoped
+		if sm == nil || sm.claimed || e.base().match(openMethod, openArgs...) != nil {
+			continue
+		}
+		if err := sm.expectations.accepts(sc, method, args); err != nil {
+			fmt.Fprintf(&b, "\n  - scoped mock of %s: %s", e, indent(err.Error(), "    "))
+			continue
+		}
+		sm.claimed = true
+		sm.expectations.onUnexpected = s.onUnexpected
+		s.mu.Unlock()
+		return &sm.expectations, nil
+	}
+	err := s.unexpected(sc, method, args, fmt.Errorf("%w: no unclaimed scoped mock of %s expects %s with args %v%s", ErrNoExpectation, openMethod, method, args, b.String()))
+	s.mu.Unlock()
+	s.notify(err)
+	return nil, err
+}
+
+// unexpected logs a call that matched no expectation and returns err.
+func (s *expectationSet) unexpected(sc scope, method string, args []any, err error) error {
+	_, hasQuery := queryMethods[method]
+	s.log = append(s.log, logEntry{seq: callSeq.Add(1), call: newCall(hasQuery && len(args) > 0, sc, method, args), err: err})
+	return err
+}
+
+// open returns the scope of a transaction begun from sc: a new transaction
+// outside of one, and a savepoint of the current transaction inside one.
+func (s *expectationSet) open(sc scope) scope {
+	if sc.tx == 0 {
+		s.txs++
+		return scope{tx: s.txs, options: sc.options}
+	}
+	if s.savepoints == nil {
+		s.savepoints = map[int]int{}
+	}
+	s.savepoints[sc.tx]++
+	sc.savepoint = s.savepoints[sc.tx]
+	return sc
+}
+
+func (s *expectationSet) recordedCalls() []Call {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var calls []Call
+	for _, entry := range s.entries() {
+		if entry.expectation != nil {
+			calls = append(calls, entry.call)
+		}
+	}
+	return calls
+}
+
+// unfulfilled returns the unexpected calls a mock could not report to its
+// caller, or else the first expectation that was not met. The error ends
+// with a report of all calls and expectations.
+func (s *expectationSet) unfulfilled(unexpected ...error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.init()
+	err := errors.Join(unexpected...)
+	if err == nil {
+		err = s.check()
+	}
+	if err == nil {
+		return nil
+	}
+	return fmt.Errorf("%w\n\n%s", err, s.report())
+}
+
+// check returns the first expectation that was not met, looking into scoped
+// mocks after the expectations of the set itself. The caller holds s.mu.
+func (s *expectationSet) check() error {
+	if e := s.root.firstUnsatisfied(); e != nil {
+		return fmt.Errorf("unfulfilled expectation: %s: called %d times, expected %s", e, e.calls, e.expectedCalls())
+	}
+	if err := s.root.verify(); err != nil {
+		return err
+	}
+	var err error
+	s.eachScoped(func(owner expectation, set *expectationSet) {
+		if err == nil {
+			if err = set.check(); err != nil {
+				err = fmt.Errorf("scoped mock of %s: %w", owner, err)
+			}
+		}
+	})
+	return err
+}
+
+// eachScoped calls fn with the expectation set of every scoped mock, locked.
+// The caller holds s.mu.
+func (s *expectationSet) eachScoped(fn func(owner expectation, set *expectationSet)) {
+	for _, e := range s.root.expectations() {
+		if sm := e.base().scoped; sm != nil {
+			sm.expectations.mu.Lock()
+			sm.expectations.init()
+			fn(e, &sm.expectations)
+			sm.expectations.mu.Unlock()
+		}
+	}
+}
+
+// entries returns the calls logged by the set and its scoped mocks in the
+// order they were made. The caller holds s.mu.
+func (s *expectationSet) entries() []logEntry {
+	entries := slices.Clone(s.log)
+	s.eachScoped(func(_ expectation, set *expectationSet) {
+		entries = append(entries, set.entries()...)
+	})
+	slices.SortFunc(entries, func(a, b logEntry) int { return cmp.Compare(a.seq, b.seq) })
+	return entries
+}
+
+// report lists the calls in the order they were made, each marked expected or
+// unexpected, followed by the expectations in the order they were added, each
+// marked fulfilled or unfulfilled. The caller holds s.mu.
+func (s *expectationSet) report() string {
+	var b strings.Builder
+	b.WriteString("calls:")
+	entries := s.entries()
+	if len(entries) == 0 {
+		b.WriteString(" none")
+	}
+	for _, entry := range entries {
+		status := "expected"
+		if entry.expectation == nil {
+			status = "unexpected"
+		}
+		fmt.Fprintf(&b, "\n  %-10s %s", status, entry.call)
+		if entry.call.Tx != 0 {
+			fmt.Fprintf(&b, " in %s", scope{tx: entry.call.Tx, savepoint: entry.call.Savepoint})
+		}
+		if entry.err != nil {
+			fmt.Fprintf(&b, "\n      %s", indent(entry.err.Error(), "      "))
+		}
+	}
+	b.WriteString("\nexpectations:")
+	s.writeExpectations(&b, "  ")
+	return b.String()
+}
+
+// writeExpectations lists the expectations of the set, followed by those of
+// each scoped mock indented below its owner. The caller holds s.mu.
+func (s *expectationSet) writeExpectations(b *strings.Builder, prefix string) {
+	for _, e := range s.root.expectations() {
+		status := "fulfilled"
+		if !e.satisfied() {
+			status = "unfulfilled"
+		}
+		fmt.Fprintf(b, "\n%s%-11s %s: called %d times, expected %s", prefix, status, e, e.base().calls, e.base().expectedCalls())
+		if sm := e.base().scoped; sm != nil {
+			sm.expectations.mu.Lock()
+			sm.expectations.init()
+			sm.expectations.writeExpectations(b, prefix+"  ")
+			sm.expectations.mu.Unlock()
+		}
+	}
+}
+
+// logEntry is a call as it reached the expectations, matched or not.
+type logEntry struct {
+	seq         uint64
+	call        Call
+	expectation expectation // nil for an unexpected call
+	err         error
+}
+
+// callSeq orders the calls of all expectation sets, so the calls of scoped
+// mocks can be merged with those of their parent.
+var callSeq atomic.Uint64
+
+// queryMethods take the query as their first argument.
+var queryMethods = map[string]struct{}{"Exec": {}, "Query": {}, "QueryRow": {}}
+
+func newCall(hasQuery bool, sc scope, method string, args []any) Call {
+	c := Call{Method: method, Tx: sc.tx, Savepoint: sc.savepoint, TxOptions: sc.options}
+	if hasQuery {
+		c.Query, _ = args[0].(string)
+		args = args[1:]
+	}
+	c.Args = args
+	return c
+}
+
+// wait blocks for the configured delay of a call, or until ctx is done.
+func wait(ctx context.Context, delay time.Duration) error {
+	if delay <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return &timeoutError{err: ctx.Err()}
+	}
+}
+
+// contextAlreadyDoneError mirrors the error pgconn returns for a call started
+// with a context that is already done. The call never reaches the server.
+type contextAlreadyDoneError struct {
+	err error
+}
+
+func (e *contextAlreadyDoneError) Error() string {
+	return fmt.Sprintf("context already done: %s", e.err)
+}
+
+func (e *contextAlreadyDoneError) Unwrap() error { return e.err }
+
+// SafeToRetry reports true, as pgconn does, since nothing was sent.
+func (e *contextAlreadyDoneError) SafeToRetry() bool { return true }
+
+// timeoutError mirrors the error pgconn returns when the context ends while a
+// call is in flight.
+type timeoutError struct {
+	err error
+}
+
+func (e *timeoutError) Error() string {
+	return fmt.Sprintf("timeout: %s", e.err)
+}
+
+func (e *timeoutError) Unwrap() error { return e.err }
+
+// indent prefixes every line of s after the first with prefix.
+func indent(s, prefix string) string {
+	return strings.ReplaceAll(s, "\n", "\n"+prefix)
+}
@@ -0,0 +1,313 @@
+package mock
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// csvNull marks a NULL value in CSV fixtures, as in the PostgreSQL COPY text format.
+const csvNull = `\N`
+
+// RowsFromStructs builds rows from a slice of structs or struct pointers. Each
+// exported field becomes a column named by its db tag, or by its lowercased
+// field name without one, the way pgx.RowToStructByName maps columns back to
+// fields. Fields tagged db:"-" are skipped and embedded structs are flattened.
+// It panics if T is not a struct or a pointer to one.
+func RowsFromStructs[T any](items []T) *Rows {
+	columns, paths := structColumns(reflect.TypeFor[T]())
+	rows := NewRows(columns)
+	for _, item := range items {
+		rows.AddRow(structValues(reflect.ValueOf(item), paths)...)
+	}
+	return rows
+}
+
+// RowFromStruct builds a row for QueryRow from a struct or struct pointer,
+// with values in the column order RowsFromStructs uses.
+func RowFromStruct[T any](item T) *Row {
+	_, paths := structColumns(reflect.TypeFor[T]())
+	return NewRow(structValues(reflect.ValueOf(item), paths)...)
+}
+
+// RowsFromCSV builds rows from CSV with a header line of column names. Every
+// record must have one value per column. Values are text unless types holds a
+// data type OID for their column, such as pgtype.Int8OID, in which case they
+// are decoded from the PostgreSQL text format; 0 leaves a column as text. A
+// value of \N is NULL.
+func RowsFromCSV(r io.Reader, types ...uint32) (*Rows, error) {
+	records, err := csv.NewReader(r).ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("read csv fixture: %w", err)
+	}
+	if len(records) == 0 {
+		return nil, errors.New("csv fixture has no header")
+	}
+
+	columns := records[0]
+	ft, err := newFixtureTypes(columns, types)
+	if err != nil {
+		return nil, err
+	}
+	rows := NewRows(columns)
+	for n, record := range records[1:] {
+		values := make([]any, len(record))
+		for i, field := range record {
+			if field == csvNull {
+				continue
+			}
+			if values[i], err = ft.decode(i, field); err != nil {
+				return nil, fmt.Errorf("csv fixture row %d: %w", n+1, err)
+			}
+		}
+		rows.AddRow(values...)
+	}
+	return rows.typed(types), nil
+}
+
+// RowFromCSV builds a row for QueryRow from CSV holding a header and exactly
+// one record, decoded like RowsFromCSV.
+func RowFromCSV(r io.Reader, types ...uint32) (*Row, error) {
+	rows, err := RowsFromCSV(r, types...)
+	if err != nil {
+		return nil, err
+	}
+	return rows.single()
+}
+
+// RowsFromJSON builds rows from a JSON array of objects, or a single object.
+// Columns follow the key order of the first object and every object must have
+// the same keys. Strings, numbers and booleans are decoded from the PostgreSQL
+// text format when types holds a data type OID for their column; otherwise
+// whole numbers become int64 and other numbers float64. Nested objects and
+// arrays are kept as decoded by encoding/json.
+func RowsFromJSON(r io.Reader, types ...uint32) (*Rows, error) {
+	objects, err := readJSONObjects(r)
+	if err != nil {
+		return nil, fmt.Errorf("read json fixture: %w", err)
+	}
+	if len(objects) == 0 {
+		return nil, errors.New("json fixture has no rows to take columns from")
+	}
+
+	columns := objects[0].keys
+	ft, err := newFixtureTypes(columns, types)
+	if err != nil {
+		return nil, err
+	}
+	rows := NewRows(columns)
+	for n, obj := range objects {
+		if !slices.Equal(sorted(obj.keys), sorted(columns)) {
+			return nil, fmt.Errorf("json fixture row %d has columns %v, want %v", n, obj.keys, columns)
+		}
+		values := make([]any, len(columns))
+		for i, col := range columns {
+			if values[i], err = ft.jsonValue(i, obj.values[col]); err != nil {
+				return nil, fmt.Errorf("json fixture row %d: %w", n, err)
+			}
+		}
+		rows.AddRow(values...)
+	}
+	return rows.typed(types), nil
+}
+
+// RowFromJSON builds a row for QueryRow from a JSON object, or an array
+// holding exactly one, decoded like RowsFromJSON.
+func RowFromJSON(r io.Reader, types ...uint32) (*Row, error) {
+	rows, err := RowsFromJSON(r, types...)
+	if err != nil {
+		return nil, err
+	}
+	return rows.single()
+}
+
+// typed sets the column types when they were given for a fixture.
+func (r *Rows) typed(types []uint32) *Rows {
+	if len(types) == 0 {
+		return r
+	}
+	return r.WithColumnTypes(types...)
+}
+
+func (r *Rows) single() (*Row, error) {
+	if len(r.rows) != 1 {
+		return nil, fmt.Errorf("fixture has %d rows, want exactly one", len(r.rows))
+	}
+	return NewRow(r.rows[0]...), nil
+}
+
+// fixtureTypes decodes fixture text using the type hints of the columns.
+type fixtureTypes struct {
+	columns []string
+	types   []uint32
+	m       *pgtype.Map
+}
+
+func newFixtureTypes(columns []string, types []uint32) (*fixtureTypes, error) {
+	if len(types) != 0 && len(types) != len(columns) {
+		return nil, fmt.Errorf("fixture has %d columns but %d types", len(columns), len(types))
+	}
+	return &fixtureTypes{columns: columns, types: types, m: pgtype.NewMap()}, nil
+}
+
+func (f *fixtureTypes) hinted(i int) bool {
+	return len(f.types) != 0 && f.types[i] != 0
+}
+
+// decode returns text as is for columns without a type hint, and decoded from
+// the PostgreSQL text format of the hinted type otherwise.
+func (f *fixtureTypes) decode(i int, text string) (any, error) {
+	if !f.hinted(i) {
+		return text, nil
+	}
+	t, ok := f.m.TypeForOID(f.types[i])
+	if !ok {
+		return nil, fmt.Errorf("column %s has unknown type OID %d", f.columns[i], f.types[i])
+	}
+	v, err := t.Codec.DecodeValue(f.m, f.types[i], pgtype.TextFormatCode, []byte(text))
+	if err != nil {
+		return nil, fmt.Errorf("column %s: %w", f.columns[i], err)
+	}
+	return v, nil
+}
+
+// jsonValue converts a decoded JSON value of column i.
+func (f *fixtureTypes) jsonValue(i int, v any) (any, error) {
+	switch v := v.(type) {
+	case json.Number:
+		if f.hinted(i) {
+			return f.decode(i, v.String())
+		}
+		if n, err := v.Int64(); err == nil {
+			return n, nil
+		}
+		return v.Float64()
+	case string, bool:
+		if f.hinted(i) {
+			return f.decode(i, fmt.Sprint(v))
+		}
+	}
+	return v, nil
+}
+
+type jsonObject struct {
+	keys   []string
+	values map[string]any
+}
+
+// readJSONObjects decodes objects keeping their key order, which determines the column order.
+func readJSONObjects(r io.Reader) ([]jsonObject, error) {
+	dec := json.NewDecoder(r)
+	dec.UseNumber()
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	switch tok {
+	case json.Delim('{'):
+		obj, err := readJSONObject(dec)
+		if err != nil {
+			return nil, err
+		}
+		return []jsonObject{obj}, nil
+	case json.Delim('['):
+		var objects []jsonObject
+		for dec.More() {
+			if tok, err := dec.Token(); err != nil {
+				return nil, err
+			} else if tok != json.Delim('{') {
+				return nil, fmt.Errorf("expected an object, got %v", tok)
+			}
+			obj, err := readJSONObject(dec)
+			if err != nil {
+				return nil, err
+			}
+			objects = append(objects, obj)
+		}
+		return objects, nil
+	}
+	return nil, fmt.Errorf("expected an object or an array of objects, got %v", tok)
+}
+
+// readJSONObject reads the members of an object whose opening brace was consumed.
+func readJSONObject(dec *json.Decoder) (jsonObject, error) {
+	obj := jsonObject{values: map[string]any{}}
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return obj, err
+		}
+		key := tok.(string)
+		var v any
+		if err := dec.Decode(&v); err != nil {
+			return obj, err
+		}
+		if _, dup := obj.values[key]; !dup {
+			obj.keys = append(obj.keys, key)
+		}
+		obj.values[key] = v
+	}
+	_, err := dec.Token()
+	return obj, err
+}
+
+func sorted(s []string) []string {
+	return slices.Sorted(slices.Values(s))
+}
+
+// structColumns returns the column names of a struct type and the field index path of each.
+func structColumns(t reflect.Type) ([]string, [][]int) {
+	if !isStruct(t) {
+		panic(fmt.Sprintf("mock: %s is not a struct", t))
+	}
+
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	var columns []string
+	var paths [][]int
+	for _, f := range reflect.VisibleFields(t) {
+		if !f.IsExported() || (f.Anonymous && isStruct(f.Type) && f.Tag.Get("db") == "") {
+			continue
+		}
+		name, _, _ := strings.Cut(f.Tag.Get("db"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = strings.ToLower(f.Name)
+		}
+		columns = append(columns, name)
+		paths = append(paths, f.Index)
+	}
+	return columns, paths
+}
+
+func structValues(v reflect.Value, paths [][]int) []any {
+	if v.Kind() == reflect.Pointer {
+		v = v.Elem()
+	}
+	values := make([]any, len(paths))
+	for i, path := range paths {
+		// A field promoted through a nil embedded pointer is NULL.
+		if f, err := v.FieldByIndexErr(path); err == nil {
+			values[i] = f.Interface()
+		}
+	}
+	return values
+}
+
+func isStruct(t reflect.Type) bool {
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return t.Kind() == reflect.Struct
+}
@@ -0,0 +1,161 @@
+package mock
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Kansuler/octobe/v3"
+	"github.com/Kansuler/octobe/v3/driver/postgres"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/require"
+)
+
+type fixtureUser struct {
+	ID        int64     `db:"id"`
+	Email     string    `db:"email"`
+	Active    bool      `db:"active"`
+	CreatedAt time.Time `db:"created_at"`
+	Nickname  *string   `db:"nickname"`
+}
+
+var userTypes = []uint32{pgtype.Int8OID, 0, pgtype.BoolOID, pgtype.TimestamptzOID, 0}
+
+func fixtureUsers() []fixtureUser {
+	ali := "ali"
+	return []fixtureUser{
+		{ID: 1, Email: "alice@example.com", Active: true, CreatedAt: time.Date(2024, 3, 1, 12, 30, 0, 0, time.UTC), Nickname: &ali},
+		{ID: 2, Email: "bob@example.com", CreatedAt: time.Date(2024, 3, 2, 8, 0, 0, 0, time.UTC)},
+	}
+}
+
+func listUsers() octobe.Handler[[]fixtureUser, postgres.Builder] {
+	return func(builder postgres.Builder) ([]fixtureUser, error) {
+		var users []fixtureUser
+		err := builder(`SELECT id, email, active, created_at, nickname FROM users`).Query(func(rows postgres.Rows) error {
+			for rows.Next() {
+				var u fixtureUser
+				if err := rows.Scan(&u.ID, &u.Email, &u.Active, &u.CreatedAt, &u.Nickname); err != nil {
+					return err
+				}
+				users = append(users, u)
+			}
+			return rows.Err()
+		})
+		return users, err
+	}
+}
+
+func runListUsers(t *testing.T, rows *Rows) ([]fixtureUser, error) {
+	t.Helper()
+	m := NewPGXMock()
+	m.ExpectQuery("SELECT id, email, active, created_at, nickname FROM users").WillReturnRows(rows)
+	db, err := octobe.New(postgres.OpenPGXWithConn(m))
+	require.NoError(t, err)
+	session, err := db.Begin(context.Background())
+	require.NoError(t, err)
+	return octobe.Execute(session, listUsers())
+}
+
+func normalizeUsers(users []fixtureUser) []fixtureUser {
+	for i := range users {
+		users[i].CreatedAt = users[i].CreatedAt.UTC()
+	}
+	return users
+}
+
+func TestRowsFromStructs(t *testing.T) {
+	rows := RowsFromStructs(fixtureUsers())
+	fields := rows.FieldDescriptions()
+	require.Len(t, fields, 5)
+	require.Equal(t, "created_at", fields[3].Name)
+	require.Equal(t, uint32(pgtype.TimestamptzOID), fields[3].DataTypeOID)
+
+	users, err := runListUsers(t, rows)
+	require.NoError(t, err)
+	require.Equal(t, fixtureUsers(), users)
+
+	type base struct {
+		ID int `db:"id"`
+	}
+	type post struct {
+		base
+		Title   string
+		private int
+		Skipped string `db:"-"`
+	}
+	rows = RowsFromStructs([]*post{{base: base{ID: 7}, Title: "hello"}})
+	require.Equal(t, "id", rows.FieldDescriptions()[0].Name)
+	require.Equal(t, "title", rows.FieldDescriptions()[1].Name)
+	require.Len(t, rows.FieldDescriptions(), 2)
+
+	var id int
+	var title string
+	require.NoError(t, RowFromStruct(post{base: base{ID: 7}, Title: "hello"}).Scan(&id, &title))
+	require.Equal(t, 7, id)
+	require.Equal(t, "hello", title)
+
+	require.Panics(t, func() { RowsFromStructs([]int{1}) })
+}
+
+func TestRowsFromCSV(t *testing.T) {
+	f, err := os.Open("testdata/users.csv")
+	require.NoError(t, err)
+	defer f.Close()
+
+	rows, err := RowsFromCSV(f, userTypes...)
+	require.NoError(t, err)
+	users, err := runListUsers(t, rows)
+	require.NoError(t, err)
+	require.Equal(t, fixtureUsers(), normalizeUsers(users))
+
+	row, err := RowFromCSV(strings.NewReader("id,email\n3,carol@example.com\n"), pgtype.Int4OID, 0)
+	require.NoError(t, err)
+	var id int
+	var email string
+	require.NoError(t, row.Scan(&id, &email))
+	require.Equal(t, 3, id)
+
+	_, err = RowsFromCSV(strings.NewReader("id,email\n3\n"))
+	require.ErrorContains(t, err, "wrong number of fields")
+	_, err = RowsFromCSV(strings.NewReader("id,email\n3,x\n"), pgtype.Int4OID)
+	require.EqualError(t, err, "fixture has 2 columns but 1 types")
+	_, err = RowsFromCSV(strings.NewReader("id\nx\n"), pgtype.Int4OID)
+	require.ErrorContains(t, err, "csv fixture row 1: column id:")
+	_, err = RowFromCSV(strings.NewReader("id\n1\n2\n"))
+	require.EqualError(t, err, "fixture has 2 rows, want exactly one")
+}
+
+func TestRowsFromJSON(t *testing.T) {
+	f, err := os.Open("testdata/users.json")
+	require.NoError(t, err)
+	defer f.Close()
+
+	rows, err := RowsFromJSON(f, 0, 0, 0, pgtype.TimestamptzOID, 0)
+	require.NoError(t, err)
+	require.Equal(t, "email", rows.FieldDescriptions()[1].Name)
+	users, err := runListUsers(t, rows)
+	require.NoError(t, err)
+	require.Equal(t, fixtureUsers(), normalizeUsers(users))
+
+	row, err := RowFromJSON(strings.NewReader(`{"price": 9.5, "tags": ["a"]}`))
+	require.NoError(t, err)
+	var price float64
+	var tags []any
+	require.NoError(t, row.Scan(&price, &tags))
+	require.Equal(t, 9.5, price)
+	require.Equal(t, []any{"a"}, tags)
+
+	_, err = RowsFromJSON(strings.NewReader(`[{"id": 1, "email": "a"}, {"id": 2}]`))
+	require.EqualError(t, err, "json fixture row 1 has columns [id], want [id email]")
+	_, err = RowsFromJSON(strings.NewReader(`[1]`))
+	require.ErrorContains(t, err, "expected an object, got 1")
+
+	// A fixture with fewer columns than the handler scans fails the scan.
+	rows, err = RowsFromJSON(strings.NewReader(`[{"id": 1, "email": "a"}]`))
+	require.NoError(t, err)
+	_, err = runListUsers(t, rows)
+	require.EqualError(t, err, "scan expected 2 destinations, got 5")
+}
@@ -3,125 +3,207 @@ package mock
 import (
 	"errors"
 	"fmt"
-	"io"
 	"reflect"
-	"regexp"
-	"strings"
+	"time"
 
+	"github.com/Kansuler/octobe/v3/internal/mockmatch"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgconn"
 )
 
 // expectation defines the interface for mock database operation expectations.
 type expectation interface {
-	fulfilled() bool
+	node
+	base() *basicExpectation
+	call() []any
 	match(method string, args ...any) error
-	getReturns() []any
 	String() string
 }
 
-type queryMatchMode int
-
-const (
-	queryMatchNone queryMatchMode = iota
-	queryMatchExact
-	queryMatchContains
-	queryMatchRegex
-)
+// unlimited marks an expectation without an upper bound on its calls.
+const unlimited = -1
 
 type basicExpectation struct {
-	method      string
-	isFulfilled bool
-	returns     []any
-	query       string
-	queryRE     *regexp.Regexp
-	queryMatch  queryMatchMode
-	args        []any
+	method    string
+	calls     int
+	times     *callRange
+	sealed    bool
+	delay     time.Duration
+	txScope   txScope
+	returns   []any
+	responses [][]any
+	// then makes the next response follow the configured ones instead of
+	// replacing the last of them.
+	then      bool
+	statement mockmatch.Statement
+	// scoped holds the expectations of the session opened by an Acquire,
+	// Begin or BeginTx expectation made with Scoped.
+	scoped *ScopedMock
+}
+
+// callRange bounds how often an expectation may be called. Expectations
+// without one are called exactly once.
+type callRange struct {
+	min, max int
+}
+
+func (e *basicExpectation) callRange() callRange {
+	if e.times == nil {
+		return callRange{min: 1, max: 1}
+	}
+	return *e.times
 }
 
-func (e *basicExpectation) fulfilled() bool {
-	return e.isFulfilled
+func (e *basicExpectation) satisfied() bool {
+	return e.calls >= e.callRange().min
 }
 
-func (e *basicExpectation) getReturns() []any {
-	e.isFulfilled = true
-	return e.returns
+func (e *basicExpectation) exhausted() bool {
+	r := e.callRange()
+	return e.sealed || (r.max != unlimited && e.calls >= r.max)
 }
 
-func (e *basicExpectation) WithArgs(args ...any) {
-	e.args = args
+func (e *basicExpectation) base() *basicExpectation {
+	return e
 }
 
-func (e *basicExpectation) setContains() {
-	e.queryMatch = queryMatchContains
-	e.queryRE = nil
+func (e *basicExpectation) seal() {
+	e.sealed = true
 }
 
-func (e *basicExpectation) setRegex() {
-	e.queryMatch = queryMatchRegex
-	e.queryRE = regexp.MustCompile(e.query)
+// call records a call and returns the response configured for it. The last
+// response repeats once the sequence runs out.
+func (e *basicExpectation) call() []any {
+	e.calls++
+	if len(e.responses) == 0 {
+		return e.returns
+	}
+	if e.calls <= len(e.responses) {
+		return e.responses[e.calls-1]
+	}
+	return repeatResponse(e.responses[len(e.responses)-1])
 }
 
-// match validates that the method call matches the expected signature and arguments.
-func (e *basicExpectation) match(method string, args ...any) error {
-	if e.method != method {
-		return fmt.Errorf("method mismatch: expected %s, got %s", e.method, method)
+// respond sets the response of the current step of the sequence, replacing an
+// earlier one, or starts the next step after Then.
+func (e *basicExpectation) respond(ret ...any) {
+	if e.then || len(e.responses) == 0 {
+		e.responses = append(e.responses, ret)
+	} else {
+		e.responses[len(e.responses)-1] = ret
 	}
+	e.then = false
+}
 
-	if e.queryMatch != queryMatchNone {
-		if len(args) == 0 {
-			return fmt.Errorf("missing query argument")
-		}
-		query, ok := args[0].(string)
-		if !ok {
-			return fmt.Errorf("first argument was not a string query")
-		}
-		if err := e.matchQuery(query); err != nil {
-			return err
-		}
-		args = args[1:]
+// Then makes the next WillReturn call configure the response of the following
+// matching call instead of replacing the response configured so far.
+func (e *basicExpectation) Then() {
+	if len(e.responses) > 0 {
+		e.then = true
 	}
+}
 
-	if e.args != nil {
-		if !reflect.DeepEqual(e.args, args) {
-			return fmt.Errorf("args mismatch: expected %v, got %v", e.args, args)
-		}
+func (e *basicExpectation) setCalls(minCalls, maxCalls int) {
+	if minCalls < 0 || (maxCalls != unlimited && maxCalls < minCalls) {
+		panic(fmt.Sprintf("invalid call count range %d..%d", minCalls, maxCalls))
+	}
+	e.times = &callRange{min: minCalls, max: maxCalls}
+}
+
+// expectedCalls describes the configured call count for error messages.
+func (e *basicExpectation) expectedCalls() string {
+	r := e.callRange()
+	switch {
+	case r.max == unlimited && r.min == 0:
+		return "any number of times"
+	case r.max == unlimited:
+		return fmt.Sprintf("at least %d", r.min)
+	case r.min == r.max:
+		return fmt.Sprintf("%d", r.min)
+	case r.min == 0:
+		return fmt.Sprintf("at most %d", r.max)
+	default:
+		return fmt.Sprintf("between %d and %d", r.min, r.max)
 	}
-
-	return nil
 }
 
-func (e *basicExpectation) matchQuery(query string) error {
-	switch e.queryMatch {
-	case queryMatchExact:
-		if query != e.query {
-			return fmt.Errorf("query mismatch: expected %q, got %q", e.query, query)
-		}
-	case queryMatchContains:
-		if !strings.Contains(query, e.query) {
-			return fmt.Errorf("query does not contain %q", e.query)
-		}
-	case queryMatchRegex:
-		if !e.queryRE.MatchString(query) {
-			return fmt.Errorf("query does not match regexp %s", e.queryRE)
+// repeatResponse copies a response that is served again, so mock rows restart
+// from the first row instead of being already consumed.
+func repeatResponse(ret []any) []any {
+	out := make([]any, len(ret))
+	for i, v := range ret {
+		if rows, ok := v.(*Rows); ok {
+			v = rows.clone()
 		}
+		out[i] = v
 	}
-	return nil
+	return out
+}
+
+// WithArgs sets the expected arguments. Each one is a literal value compared
+// with reflect.DeepEqual or an ArgMatcher.
+func (e *basicExpectation) WithArgs(args ...any) {
+	e.statement.Args = args
+}
+
+func (e *basicExpectation) setContains() {
+	e.statement.SetContains()
+}
+
+func (e *basicExpectation) setRegex() {
+	e.statement.SetRegex()
+}
+
+// match validates that the method call matches the expected signature and arguments.
+func (e *basicExpectation) match(method string, args ...any) error {
+	return e.statement.Match(method, args...)
 }
 
 func (e *basicExpectation) String() string {
-	queryStr := "<nil>"
-	switch e.queryMatch {
-	case queryMatchExact:
-		queryStr = fmt.Sprintf("exact %q", e.query)
-	case queryMatchContains:
-		queryStr = fmt.Sprintf("contains %q", e.query)
-	case queryMatchRegex:
-		queryStr = fmt.Sprintf("regexp %s", e.queryRE)
+	return e.statement.String()
+}
+
+// NormalizeWhitespace compares queries with leading and trailing whitespace
+// trimmed and every other run of whitespace collapsed into a single space, so
+// indentation and line breaks in long SQL do not matter. It applies to exact,
+// Contains and Regex matching.
+func (e *basicExpectation) NormalizeWhitespace() { e.statement.Normalize = true }
+
+// Times expects exactly n calls.
+func (e *basicExpectation) Times(n int) { e.setCalls(n, n) }
+
+// AtLeast expects n or more calls.
+func (e *basicExpectation) AtLeast(n int) { e.setCalls(n, unlimited) }
+
+// AnyTimes allows any number of calls, including none.
+func (e *basicExpectation) AnyTimes() { e.setCalls(0, unlimited) }
+
+// Maybe allows the call to happen once or not at all.
+func (e *basicExpectation) Maybe() { e.setCalls(0, 1) }
+
+// InTransaction makes the expectation match only calls made on a transaction
+// or one of its savepoints.
+func (e *basicExpectation) InTransaction() { e.txScope = txScopeInside }
+
+// OutsideTransaction makes the expectation match only calls made outside a
+// transaction, such as on the pool or a pinned pool connection.
+func (e *basicExpectation) OutsideTransaction() { e.txScope = txScopeOutside }
+
+func (e *basicExpectation) matchScope(sc scope) error {
+	switch {
+	case e.txScope == txScopeInside && sc.tx == 0:
+		return errors.New("scope mismatch: expected a call inside a transaction, got one outside")
+	case e.txScope == txScopeOutside && sc.tx != 0:
+		return fmt.Errorf("scope mismatch: expected a call outside a transaction, got one in %s", sc)
 	}
-	return fmt.Sprintf("method %s with query %s and args %v", e.method, queryStr, e.args)
+	return nil
 }
 
+// WillDelayFor makes each matching call block for d before it returns. If the
+// call's context ends first, the call fails with the context error the way pgx
+// reports a timeout.
+func (e *basicExpectation) WillDelayFor(d time.Duration) { e.delay = d }
+
 type PingExpectation struct {
 	basicExpectation
 }
@@ -143,6 +225,15 @@ func NewResult(command string, rowsAffected int64) pgconn.CommandTag {
 	return pgconn.NewCommandTag(fmt.Sprintf("%s 0 %d", command, rowsAffected))
 }
 
+// ExecExpectation expects an Exec call. WillReturnResult and WillReturnError
+// set its response, the last call replacing earlier ones. An expectation
+// repeated with Times can answer each call differently by separating the
+// responses with Then; the last response is repeated once the sequence runs
+// out:
+//
+//	mock.ExpectExec(poll).Times(2).
+//	    WillReturnError(busy).
+//	    Then().WillReturnResult(NewResult("UPDATE", 4))
 type ExecExpectation struct {
 	basicExpectation
 }
@@ -164,14 +255,64 @@ func (e *ExecExpectation) Regex() *ExecExpectation {
 	return e
 }
 
-func (e *ExecExpectation) WillReturnResult(res pgconn.CommandTag) {
-	e.returns = []any{res, nil}
+func (e *ExecExpectation) NormalizeWhitespace() *ExecExpectation {
+	e.basicExpectation.NormalizeWhitespace()
+	return e
+}
+
+func (e *ExecExpectation) Times(n int) *ExecExpectation {
+	e.basicExpectation.Times(n)
+	return e
+}
+
+func (e *ExecExpectation) AtLeast(n int) *ExecExpectation {
+	e.basicExpectation.AtLeast(n)
+	return e
+}
+
+func (e *ExecExpectation) AnyTimes() *ExecExpectation {
+	e.basicExpectation.AnyTimes()
+	return e
+}
+
+func (e *ExecExpectation) Maybe() *ExecExpectation {
+	e.basicExpectation.Maybe()
+	return e
+}
+
+func (e *ExecExpectation) Then() *ExecExpectation {
+	e.basicExpectation.Then()
+	return e
+}
+
+func (e *ExecExpectation) WillDelayFor(d time.Duration) *ExecExpectation {
+	e.basicExpectation.WillDelayFor(d)
+	return e
+}
+
+func (e *ExecExpectation) InTransaction() *ExecExpectation {
+	e.basicExpectation.InTransaction()
+	return e
 }
 
-func (e *ExecExpectation) WillReturnError(err error) {
-	e.returns = []any{pgconn.CommandTag{}, err}
+func (e *ExecExpectation) OutsideTransaction() *ExecExpectation {
+	e.basicExpectation.OutsideTransaction()
+	return e
 }
 
+func (e *ExecExpectation) WillReturnResult(res pgconn.CommandTag) *ExecExpectation {
+	e.respond(res, nil)
+	return e
+}
+
+func (e *ExecExpectation) WillReturnError(err error) *ExecExpectation {
+	e.respond(pgconn.CommandTag{}, err)
+	return e
+}
+
+// QueryExpectation expects a Query call. Like ExecExpectation, responses
+// separated with Then answer successive matching calls. Mock rows served again
+// by a repeated response start over from the first row.
 type QueryExpectation struct {
 	basicExpectation
 }
@@ -193,14 +334,63 @@ func (e *QueryExpectation) Regex() *QueryExpectation {
 	return e
 }
 
-func (e *QueryExpectation) WillReturnRows(rows pgx.Rows) {
-	e.returns = []any{rows, nil}
+func (e *QueryExpectation) NormalizeWhitespace() *QueryExpectation {
+	e.basicExpectation.NormalizeWhitespace()
+	return e
+}
+
+func (e *QueryExpectation) Times(n int) *QueryExpectation {
+	e.basicExpectation.Times(n)
+	return e
+}
+
+func (e *QueryExpectation) AtLeast(n int) *QueryExpectation {
+	e.basicExpectation.AtLeast(n)
+	return e
+}
+
+func (e *QueryExpectation) AnyTimes() *QueryExpectation {
+	e.basicExpectation.AnyTimes()
+	return e
 }
 
-func (e *QueryExpectation) WillReturnError(err error) {
-	e.returns = []any{nil, err}
+func (e *QueryExpectation) Maybe() *QueryExpectation {
+	e.basicExpectation.Maybe()
+	return e
 }
 
+func (e *QueryExpectation) Then() *QueryExpectation {
+	e.basicExpectation.Then()
+	return e
+}
+
+func (e *QueryExpectation) WillDelayFor(d time.Duration) *QueryExpectation {
+	e.basicExpectation.WillDelayFor(d)
+	return e
+}
+
+func (e *QueryExpectation) InTransaction() *QueryExpectation {
+	e.basicExpectation.InTransaction()
+	return e
+}
+
+func (e *QueryExpectation) OutsideTransaction() *QueryExpectation {
+	e.basicExpectation.OutsideTransaction()
+	return e
+}
+
+func (e *QueryExpectation) WillReturnRows(rows pgx.Rows) *QueryExpectation {
+	e.respond(rows, nil)
+	return e
+}
+
+func (e *QueryExpectation) WillReturnError(err error) *QueryExpectation {
+	e.respond(nil, err)
+	return e
+}
+
+// QueryRowExpectation expects a QueryRow call. Rows separated with Then
+// answer successive matching calls.
 type QueryRowExpectation struct {
 	basicExpectation
 }
@@ -222,30 +412,220 @@ func (e *QueryRowExpectation) Regex() *QueryRowExpectation {
 	return e
 }
 
-func (e *QueryRowExpectation) WillReturnRow(row pgx.Row) {
-	e.returns = []any{row}
+func (e *QueryRowExpectation) NormalizeWhitespace() *QueryRowExpectation {
+	e.basicExpectation.NormalizeWhitespace()
+	return e
+}
+
+func (e *QueryRowExpectation) Times(n int) *QueryRowExpectation {
+	e.basicExpectation.Times(n)
+	return e
+}
+
+func (e *QueryRowExpectation) AtLeast(n int) *QueryRowExpectation {
+	e.basicExpectation.AtLeast(n)
+	return e
+}
+
+func (e *QueryRowExpectation) AnyTimes() *QueryRowExpectation {
+	e.basicExpectation.AnyTimes()
+	return e
+}
+
+func (e *QueryRowExpectation) Maybe() *QueryRowExpectation {
+	e.basicExpectation.Maybe()
+	return e
+}
+
+func (e *QueryRowExpectation) Then() *QueryRowExpectation {
+	e.basicExpectation.Then()
+	return e
+}
+
+func (e *QueryRowExpectation) WillDelayFor(d time.Duration) *QueryRowExpectation {
+	e.basicExpectation.WillDelayFor(d)
+	return e
+}
+
+func (e *QueryRowExpectation) InTransaction() *QueryRowExpectation {
+	e.basicExpectation.InTransaction()
+	return e
+}
+
+func (e *QueryRowExpectation) OutsideTransaction() *QueryRowExpectation {
+	e.basicExpectation.OutsideTransaction()
+	return e
+}
+
+func (e *QueryRowExpectation) WillReturnRow(row pgx.Row) *QueryRowExpectation {
+	e.respond(row)
+	return e
 }
 
 type BeginExpectation struct{ basicExpectation }
 
-func (e *BeginExpectation) WillReturnError(err error) { e.returns = []any{nil, err} }
+func (e *BeginExpectation) Times(n int) *BeginExpectation {
+	e.basicExpectation.Times(n)
+	return e
+}
+
+func (e *BeginExpectation) AtLeast(n int) *BeginExpectation {
+	e.basicExpectation.AtLeast(n)
+	return e
+}
+
+func (e *BeginExpectation) AnyTimes() *BeginExpectation {
+	e.basicExpectation.AnyTimes()
+	return e
+}
+
+func (e *BeginExpectation) Maybe() *BeginExpectation {
+	e.basicExpectation.Maybe()
+	return e
+}
+
+func (e *BeginExpectation) Then() *BeginExpectation {
+	e.basicExpectation.Then()
+	return e
+}
+
+func (e *BeginExpectation) WillDelayFor(d time.Duration) *BeginExpectation {
+	e.basicExpectation.WillDelayFor(d)
+	return e
+}
+
+func (e *BeginExpectation) WillReturnError(err error) *BeginExpectation {
+	e.respond(nil, err)
+	return e
+}
+
+// Scoped gives the transaction opened by this expectation its own ordered
+// expectations, configured on the returned mock. See ScopedMock.
+func (e *BeginExpectation) Scoped() *ScopedMock {
+	return e.newScoped()
+}
 
 type BeginTxExpectation struct{ basicExpectation }
 
 func (e *BeginTxExpectation) WithOptions(opts pgx.TxOptions) *BeginTxExpectation {
-	e.args = []any{opts}
+	e.statement.Args = []any{opts}
 	return e
 }
 
-func (e *BeginTxExpectation) WillReturnError(err error) { e.returns = []any{nil, err} }
+func (e *BeginTxExpectation) Times(n int) *BeginTxExpectation {
+	e.basicExpectation.Times(n)
+	return e
+}
+
+func (e *BeginTxExpectation) AtLeast(n int) *BeginTxExpectation {
+	e.basicExpectation.AtLeast(n)
+	return e
+}
+
+func (e *BeginTxExpectation) AnyTimes() *BeginTxExpectation {
+	e.basicExpectation.AnyTimes()
+	return e
+}
+
+func (e *BeginTxExpectation) Maybe() *BeginTxExpectation {
+	e.basicExpectation.Maybe()
+	return e
+}
+
+func (e *BeginTxExpectation) Then() *BeginTxExpectation {
+	e.basicExpectation.Then()
+	return e
+}
+
+func (e *BeginTxExpectation) WillDelayFor(d time.Duration) *BeginTxExpectation {
+	e.basicExpectation.WillDelayFor(d)
+	return e
+}
+
+func (e *BeginTxExpectation) WillReturnError(err error) *BeginTxExpectation {
+	e.respond(nil, err)
+	return e
+}
+
+// Scoped gives the transaction opened by this expectation its own ordered
+// expectations, configured on the returned mock. See ScopedMock.
+func (e *BeginTxExpectation) Scoped() *ScopedMock {
+	return e.newScoped()
+}
 
 type CommitExpectation struct{ basicExpectation }
 
-func (e *CommitExpectation) WillReturnError(err error) { e.returns = []any{err} }
+func (e *CommitExpectation) Times(n int) *CommitExpectation {
+	e.basicExpectation.Times(n)
+	return e
+}
+
+func (e *CommitExpectation) AtLeast(n int) *CommitExpectation {
+	e.basicExpectation.AtLeast(n)
+	return e
+}
+
+func (e *CommitExpectation) AnyTimes() *CommitExpectation {
+	e.basicExpectation.AnyTimes()
+	return e
+}
+
+func (e *CommitExpectation) Maybe() *CommitExpectation {
+	e.basicExpectation.Maybe()
+	return e
+}
+
+func (e *CommitExpectation) Then() *CommitExpectation {
+	e.basicExpectation.Then()
+	return e
+}
+
+func (e *CommitExpectation) WillDelayFor(d time.Duration) *CommitExpectation {
+	e.basicExpectation.WillDelayFor(d)
+	return e
+}
+
+func (e *CommitExpectation) WillReturnError(err error) *CommitExpectation {
+	e.respond(err)
+	return e
+}
 
 type RollbackExpectation struct{ basicExpectation }
 
-func (e *RollbackExpectation) WillReturnError(err error) { e.returns = []any{err} }
+func (e *RollbackExpectation) Times(n int) *RollbackExpectation {
+	e.basicExpectation.Times(n)
+	return e
+}
+
+func (e *RollbackExpectation) AtLeast(n int) *RollbackExpectation {
+	e.basicExpectation.AtLeast(n)
+	return e
+}
+
+func (e *RollbackExpectation) AnyTimes() *RollbackExpectation {
+	e.basicExpectation.AnyTimes()
+	return e
+}
+
+func (e *RollbackExpectation) Maybe() *RollbackExpectation {
+	e.basicExpectation.Maybe()
+	return e
+}
+
+func (e *RollbackExpectation) Then() *RollbackExpectation {
+	e.basicExpectation.Then()
+	return e
+}
+
+func (e *RollbackExpectation) WillDelayFor(d time.Duration) *RollbackExpectation {
+	e.basicExpectation.WillDelayFor(d)
+	return e
+}
+
+func (e *RollbackExpectation) WillReturnError(err error) *RollbackExpectation {
+	e.respond(err)
+	return e
+}
 
 // Row provides a mock implementation of pgx.Row for testing QueryRow operations.
 type Row struct {
@@ -282,7 +662,16 @@ func scanValues(values []any, dest []any) error {
 		}
 
 		elem := target.Elem()
-		if val == nil {
+		source := reflect.ValueOf(val)
+		// Dereference pointer values scanned into non-pointer destinations.
+		for source.IsValid() && source.Kind() == reflect.Pointer && !source.Type().AssignableTo(elem.Type()) {
+			if source.IsNil() {
+				source = reflect.Value{}
+				break
+			}
+			source = source.Elem()
+		}
+		if !source.IsValid() {
 			if !canSetNil(elem.Kind()) {
 				return fmt.Errorf("cannot scan nil into destination %d of type %s", i, elem.Type())
 			}
@@ -290,114 +679,39 @@ func scanValues(values []any, dest []any) error {
 			continue
 		}
 
-		source := reflect.ValueOf(val)
-		if source.Type().AssignableTo(elem.Type()) {
-			elem.Set(source)
-			continue
-		}
-		if source.Type().ConvertibleTo(elem.Type()) {
-			elem.Set(source.Convert(elem.Type()))
-			continue
+		if !assign(elem, source) {
+			return fmt.Errorf("cannot scan %T into destination %d of type %s", val, i, elem.Type())
 		}
-		return fmt.Errorf("cannot scan %T into destination %d of type %s", val, i, elem.Type())
 	}
 
 	return nil
 }
 
-func canSetNil(kind reflect.Kind) bool {
-	switch kind {
-	case reflect.Chan, reflect.Func, reflect.Interface, reflect.Map, reflect.Pointer, reflect.Slice:
-		return true
+// assign sets dst to src, converting it if needed. A pointer destination is
+// allocated for a non-pointer source.
+func assign(dst, src reflect.Value) bool {
+	switch {
+	case src.Type().AssignableTo(dst.Type()):
+		dst.Set(src)
+	case src.Type().ConvertibleTo(dst.Type()):
+		dst.Set(src.Convert(dst.Type()))
+	case dst.Kind() == reflect.Pointer:
+		p := reflect.New(dst.Type().Elem())
+		if !assign(p.Elem(), src) {
+			return false
+		}
+		dst.Set(p)
 	default:
 		return false
 	}
+	return true
 }
 
-// Rows provides a mock implementation of pgx.Rows for testing Query operations.
-// Supports adding rows and controlling iteration behavior.
-type Rows struct {
-	fields []pgconn.FieldDescription
-	rows   [][]any
-	pos    int
-	err    error
-	closed bool
-}
-
-func NewRows(columns []string) *Rows {
-	fields := make([]pgconn.FieldDescription, len(columns))
-	for i, col := range columns {
-		fields[i] = pgconn.FieldDescription{Name: col}
-	}
-	return &Rows{fields: fields, pos: -1}
-}
-
-// AddRow appends a data row with values matching the column count.
-func (r *Rows) AddRow(values ...any) *Rows {
-	if len(values) != len(r.fields) {
-		panic("number of values does not match number of columns")
-	}
-	r.rows = append(r.rows, values)
-	return r
-}
-
-func (r *Rows) Close() { r.closed = true }
-
-func (r *Rows) Err() error { return r.err }
-
-func (r *Rows) CommandTag() pgconn.CommandTag { return pgconn.CommandTag{} }
-
-func (r *Rows) FieldDescriptions() []pgconn.FieldDescription { return r.fields }
-
-func (r *Rows) Next() bool {
-	if r.closed {
+func canSetNil(kind reflect.Kind) bool {
+	switch kind {
+	case reflect.Chan, reflect.Func, reflect.Interface, reflect.Map, reflect.Pointer, reflect.Slice:
+		return true
+	default:
 		return false
 	}
-	r.pos++
-	return r.pos < len(r.rows)
-}
-
-// Scan copies current row values into destination pointers using reflection.
-func (r *Rows) Scan(dest ...any) error {
-	if r.err != nil {
-		return r.err
-	}
-	if r.closed {
-		return errors.New("rows is closed")
-	}
-	if r.pos < 0 || r.pos >= len(r.rows) {
-		return io.EOF
-	}
-	return scanValues(r.rows[r.pos], dest)
-}
-
-func (r *Rows) Values() ([]any, error) {
-	if r.pos < 0 || r.pos >= len(r.rows) {
-		return nil, io.EOF
-	}
-	return r.rows[r.pos], nil
-}
-
-// RawValues returns current row values as byte slices for compatibility.
-func (r *Rows) RawValues() [][]byte {
-	if r.pos < 0 || r.pos >= len(r.rows) {
-		return nil
-	}
-
-	rawValues := make([][]byte, len(r.rows[r.pos]))
-	for i, val := range r.rows[r.pos] {
-		if val == nil {
-			rawValues[i] = nil
-		} else {
-			rawValues[i] = []byte(fmt.Sprintf("%v", val))
-		}
-	}
-	return rawValues
-}
-
-func (r *Rows) Conn() *pgx.Conn { return nil }
-
-// GetRowsForTesting exposes internal row data for test verification.
-func (r *Rows) GetRowsForTesting() [][]any {
-	return r.rows
 }
@@ -3,10 +3,9 @@ package mock
 import (
 	"context"
 	"errors"
-	"fmt"
-	"sync"
 
 	"github.com/Kansuler/octobe/v3/driver/postgres"
+	"github.com/Kansuler/octobe/v3/internal/mockmatch"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgconn"
 )
@@ -16,8 +15,7 @@ var ErrNoExpectation = errors.New("no expectation found")
 // PGXMock provides a mock implementation of postgres.PGXConn and pgx.Tx interfaces
 // for testing database interactions without requiring an actual database connection.
 type PGXMock struct {
-	mu           sync.Mutex
-	expectations []expectation
+	expectations expectationSet
 }
 
 var (
@@ -30,217 +28,150 @@ func NewPGXMock() *PGXMock {
 	return &PGXMock{}
 }
 
-// findExpectation locates the first unfulfilled expectation matching the method and arguments.
-func (m *PGXMock) findExpectation(method string, args ...any) (expectation, error) {
-	m.mu.Lock()
-	defer m.mu.Unlock()
-
-	for _, e := range m.expectations {
-		if e.fulfilled() {
-			continue
-		}
-		if err := e.match(method, args...); err != nil {
-			return nil, fmt.Errorf("%w: next expectation %s does not match %s with args %v: %w", ErrNoExpectation, e, method, args, err)
-		}
-		return e, nil
-	}
-
-	return nil, fmt.Errorf("%w for %s with args %v", ErrNoExpectation, method, args)
+// findExpectation matches a call against the expectations and returns the configured response.
+func (m *PGXMock) findExpectation(ctx context.Context, method string, args ...any) ([]any, error) {
+	return m.expectations.find(ctx, scope{}, method, args...)
 }
 
 // AllExpectationsMet verifies that all configured expectations have been fulfilled.
 func (m *PGXMock) AllExpectationsMet() error {
-	m.mu.Lock()
-	defer m.mu.Unlock()
-	for _, e := range m.expectations {
-		if !e.fulfilled() {
-			return fmt.Errorf("unfulfilled expectation: %s", e)
-		}
-	}
-	return nil
+	return m.expectations.unfulfilled()
+}
+
+// MatchExpectationsInOrder controls whether top-level expectations must be met in
+// the order they were added, which is the default. With false, each call matches
+// any unfulfilled expectation, for handlers whose query order is not deterministic.
+func (m *PGXMock) MatchExpectationsInOrder(inOrder bool) {
+	m.expectations.setInOrder(inOrder)
+}
+
+// Calls returns the calls matched so far, in order, with the transaction and
+// savepoint each ran in.
+func (m *PGXMock) Calls() []Call {
+	return m.expectations.recordedCalls()
+}
+
+// TransactionReport describes which statements ran in which transaction and
+// with which transaction options, for logging when a test fails.
+func (m *PGXMock) TransactionReport() string {
+	return transactionReport(m.Calls())
+}
+
+// Ordered groups the expectations added by fn so they must be met in order,
+// even when the surrounding group is unordered.
+func (m *PGXMock) Ordered(fn func()) {
+	m.expectations.nest(true, fn)
+}
+
+// Unordered groups the expectations added by fn so they may be met in any
+// order. The group as a whole keeps its position in the surrounding group.
+func (m *PGXMock) Unordered(fn func()) {
+	m.expectations.nest(false, fn)
 }
 
 func (m *PGXMock) ExpectPing() *PingExpectation {
-	e := &PingExpectation{basicExpectation: basicExpectation{method: "Ping"}}
-	m.expectations = append(m.expectations, e)
+	e := &PingExpectation{basicExpectation: basicExpectation{statement: mockmatch.Statement{Method: "Ping"}}}
+	m.expectations.add(e)
 	return e
 }
 
 func (m *PGXMock) Ping(ctx context.Context) error {
-	e, err := m.findExpectation("Ping")
-	if err != nil {
-		return err
-	}
-	ret := e.getReturns()
-	if len(ret) > 0 && ret[0] != nil {
-		return ret[0].(error)
-	}
-	return nil
+	return errorResult(m.findExpectation(ctx, "Ping"))
 }
 
 func (m *PGXMock) ExpectClose() *CloseExpectation {
-	e := &CloseExpectation{basicExpectation: basicExpectation{method: "Close"}}
-	m.expectations = append(m.expectations, e)
+	e := &CloseExpectation{basicExpectation: basicExpectation{statement: mockmatch.Statement{Method: "Close"}}}
+	m.expectations.add(e)
 	return e
 }
 
 func (m *PGXMock) Close(ctx context.Context) error {
-	e, err := m.findExpectation("Close")
-	if err != nil {
-		return err
-	}
-	ret := e.getReturns()
-	if len(ret) > 0 && ret[0] != nil {
-		return ret[0].(error)
-	}
-	return nil
+	return errorResult(m.findExpectation(ctx, "Close"))
 }
 
 // ExpectExec configures an expectation for an Exec operation with the specified query.
 func (m *PGXMock) ExpectExec(query string) *ExecExpectation {
 	e := &ExecExpectation{
 		basicExpectation: basicExpectation{
-			method:     "Exec",
-			query:      query,
-			queryMatch: queryMatchExact,
+			statement: mockmatch.Statement{Method: "Exec", Query: query, Mode: mockmatch.Exact},
 		},
 	}
-	m.expectations = append(m.expectations, e)
+	m.expectations.add(e)
 	return e
 }
 
 func (m *PGXMock) Exec(ctx context.Context, query string, args ...any) (pgconn.CommandTag, error) {
-	e, err := m.findExpectation("Exec", append([]any{query}, args...)...)
-	if err != nil {
-		return pgconn.CommandTag{}, err
-	}
-	ret := e.getReturns()
-	if ret[1] != nil {
-		return pgconn.CommandTag{}, ret[1].(error)
-	}
-	return ret[0].(pgconn.CommandTag), nil
+	return execResult(m.findExpectation(ctx, "Exec", append([]any{query}, args...)...))
 }
 
 // ExpectQuery configures an expectation for a Query operation with the specified query.
 func (m *PGXMock) ExpectQuery(query string) *QueryExpectation {
 	e := &QueryExpectation{
 		basicExpectation: basicExpectation{
-			method:     "Query",
-			query:      query,
-			queryMatch: queryMatchExact,
+			statement: mockmatch.Statement{Method: "Query", Query: query, Mode: mockmatch.Exact},
 		},
 	}
-	m.expectations = append(m.expectations, e)
+	m.expectations.add(e)
 	return e
 }
 
 func (m *PGXMock) Query(ctx context.Context, query string, args ...any) (pgx.Rows, error) {
-	e, err := m.findExpectation("Query", append([]any{query}, args...)...)
-	if err != nil {
-		return nil, err
-	}
-	ret := e.getReturns()
-	if ret[1] != nil {
-		return nil, ret[1].(error)
-	}
-	if ret[0] == nil {
-		return nil, nil
-	}
-	return ret[0].(pgx.Rows), nil
+	return queryResult(m.findExpectation(ctx, "Query", append([]any{query}, args...)...))
 }
 
 // ExpectQueryRow configures an expectation for a QueryRow operation with the specified query.
 func (m *PGXMock) ExpectQueryRow(query string) *QueryRowExpectation {
 	e := &QueryRowExpectation{
 		basicExpectation: basicExpectation{
-			method:     "QueryRow",
-			query:      query,
-			queryMatch: queryMatchExact,
+			statement: mockmatch.Statement{Method: "QueryRow", Query: query, Mode: mockmatch.Exact},
 		},
 	}
-	m.expectations = append(m.expectations, e)
+	m.expectations.add(e)
 	return e
 }
 
 func (m *PGXMock) QueryRow(ctx context.Context, query string, args ...any) pgx.Row {
-	e, err := m.findExpectation("QueryRow", append([]any{query}, args...)...)
-	if err != nil {
-		return &Row{err: err}
-	}
-	ret := e.getReturns()
-	return ret[0].(pgx.Row)
+	return queryRowResult(m.findExpectation(ctx, "QueryRow", append([]any{query}, args...)...))
 }
 
 func (m *PGXMock) ExpectBegin() *BeginExpectation {
-	e := &BeginExpectation{basicExpectation: basicExpectation{method: "Begin"}}
-	m.expectations = append(m.expectations, e)
+	e := &BeginExpectation{basicExpectation: basicExpectation{statement: mockmatch.Statement{Method: "Begin"}}}
+	m.expectations.add(e)
 	return e
 }
 
 func (m *PGXMock) Begin(ctx context.Context) (pgx.Tx, error) {
-	e, err := m.findExpectation("Begin")
-	if err != nil {
-		return nil, err
-	}
-	ret := e.getReturns()
-	if len(ret) > 1 && ret[1] != nil {
-		return nil, ret[1].(error)
-	}
-	return m, nil
+	return m.expectations.beginTx(ctx, scope{}, pgx.TxOptions{}, "Begin")
 }
 
 func (m *PGXMock) ExpectBeginTx() *BeginTxExpectation {
-	e := &BeginTxExpectation{basicExpectation: basicExpectation{method: "BeginTx"}}
-	m.expectations = append(m.expectations, e)
+	e := &BeginTxExpectation{basicExpectation: basicExpectation{statement: mockmatch.Statement{Method: "BeginTx"}}}
+	m.expectations.add(e)
 	return e
 }
 
 func (m *PGXMock) BeginTx(ctx context.Context, txOptions pgx.TxOptions) (pgx.Tx, error) {
-	e, err := m.findExpectation("BeginTx", txOptions)
-	if err != nil {
-		return nil, err
-	}
-	ret := e.getReturns()
-	if len(ret) > 1 && ret[1] != nil {
-		return nil, ret[1].(error)
-	}
-	return m, nil
+	return m.expectations.beginTx(ctx, scope{}, txOptions, "BeginTx", txOptions)
 }
 
 func (m *PGXMock) ExpectCommit() *CommitExpectation {
-	e := &CommitExpectation{basicExpectation: basicExpectation{method: "Commit"}}
-	m.expectations = append(m.expectations, e)
+	e := &CommitExpectation{basicExpectation: basicExpectation{statement: mockmatch.Statement{Method: "Commit"}}}
+	m.expectations.add(e)
 	return e
 }
 
 func (m *PGXMock) Commit(ctx context.Context) error {
-	e, err := m.findExpectation("Commit")
-	if err != nil {
-		return err
-	}
-	ret := e.getReturns()
-	if len(ret) > 0 && ret[0] != nil {
-		return ret[0].(error)
-	}
-	return nil
+	return errorResult(m.findExpectation(ctx, "Commit"))
 }
 
 func (m *PGXMock) ExpectRollback() *RollbackExpectation {
-	e := &RollbackExpectation{basicExpectation: basicExpectation{method: "Rollback"}}
-	m.expectations = append(m.expectations, e)
+	e := &RollbackExpectation{basicExpectation: basicExpectation{statement: mockmatch.Statement{Method: "Rollback"}}}
+	m.expectations.add(e)
 	return e
 }
 
 func (m *PGXMock) Rollback(ctx context.Context) error {
-	e, err := m.findExpectation("Rollback")
-	if err != nil {
-		return err
-	}
-	ret := e.getReturns()
-	if len(ret) > 0 && ret[0] != nil {
-		return ret[0].(error)
-	}
-	return nil
+	return errorResult(m.findExpectation(ctx, "Rollback"))
 }
 
 type PrepareExpectation struct {
@@ -248,7 +179,7 @@ type PrepareExpectation struct {
 }
 
 func (e *PrepareExpectation) WithName(name string) *PrepareExpectation {
-	e.args = []any{name}
+	e.statement.Args = []any{name}
 	return e
 }
 
@@ -264,30 +195,15 @@ func (e *PrepareExpectation) WillReturnError(err error) {
 func (m *PGXMock) ExpectPrepare(name, sql string) *PrepareExpectation {
 	e := &PrepareExpectation{
 		basicExpectation: basicExpectation{
-			method: "Prepare",
-			args:   []any{name, sql},
+			statement: mockmatch.Statement{Method: "Prepare", Args: []any{name, sql}},
 		},
 	}
-	m.expectations = append(m.expectations, e)
+	m.expectations.add(e)
 	return e
 }
 
 func (m *PGXMock) Prepare(ctx context.Context, name, sql string) (*pgconn.StatementDescription, error) {
-	e, err := m.findExpectation("Prepare", name, sql)
-	if err != nil {
-		return nil, err
-	}
-	ret := e.getReturns()
-	if len(ret) > 1 && ret[1] != nil {
-		return nil, ret[1].(error)
-	}
-	if len(ret) > 0 && ret[0] == nil {
-		return &pgconn.StatementDescription{Name: name, SQL: sql}, nil
-	}
-	if len(ret) > 0 {
-		return ret[0].(*pgconn.StatementDescription), nil
-	}
-	return &pgconn.StatementDescription{Name: name, SQL: sql}, nil
+	return prepareResult(name, sql)(m.findExpectation(ctx, "Prepare", name, sql))
 }
 
 type DeallocateExpectation struct {
@@ -301,24 +217,15 @@ func (e *DeallocateExpectation) WillReturnError(err error) {
 func (m *PGXMock) ExpectDeallocate(name string) *DeallocateExpectation {
 	e := &DeallocateExpectation{
 		basicExpectation: basicExpectation{
-			method: "Deallocate",
-			args:   []any{name},
+			statement: mockmatch.Statement{Method: "Deallocate", Args: []any{name}},
 		},
 	}
-	m.expectations = append(m.expectations, e)
+	m.expectations.add(e)
 	return e
 }
 
 func (m *PGXMock) Deallocate(ctx context.Context, name string) error {
-	e, err := m.findExpectation("Deallocate", name)
-	if err != nil {
-		return err
-	}
-	ret := e.getReturns()
-	if len(ret) > 0 && ret[0] != nil {
-		return ret[0].(error)
-	}
-	return nil
+	return errorResult(m.findExpectation(ctx, "Deallocate", name))
 }
 
 type DeallocateAllExpectation struct {
@@ -331,22 +238,14 @@ func (e *DeallocateAllExpectation) WillReturnError(err error) {
 
 func (m *PGXMock) ExpectDeallocateAll() *DeallocateAllExpectation {
 	e := &DeallocateAllExpectation{
-		basicExpectation: basicExpectation{method: "DeallocateAll"},
+		basicExpectation: basicExpectation{statement: mockmatch.Statement{Method: "DeallocateAll"}},
 	}
-	m.expectations = append(m.expectations, e)
+	m.expectations.add(e)
 	return e
 }
 
 func (m *PGXMock) DeallocateAll(ctx context.Context) error {
-	e, err := m.findExpectation("DeallocateAll")
-	if err != nil {
-		return err
-	}
-	ret := e.getReturns()
-	if len(ret) > 0 && ret[0] != nil {
-		return ret[0].(error)
-	}
-	return nil
+	return errorResult(m.findExpectation(ctx, "DeallocateAll"))
 }
 
 type CopyFromExpectation struct {
@@ -354,7 +253,7 @@ type CopyFromExpectation struct {
 }
 
 func (e *CopyFromExpectation) WithColumns(columns []string) *CopyFromExpectation {
-	e.args = append(e.args, columns)
+	e.statement.Args = append(e.statement.Args, columns)
 	return e
 }
 
@@ -370,27 +269,30 @@ func (e *CopyFromExpectation) WillReturnError(err error) {
 func (m *PGXMock) ExpectCopyFrom(tableName pgx.Identifier) *CopyFromExpectation {
 	e := &CopyFromExpectation{
 		basicExpectation: basicExpectation{
-			method: "CopyFrom",
-			args:   []any{tableName},
+			statement: mockmatch.Statement{Method: "CopyFrom", Args: []any{tableName}},
 		},
 	}
-	m.expectations = append(m.expectations, e)
+	m.expectations.add(e)
 	return e
 }
 
 func (m *PGXMock) CopyFrom(ctx context.Context, tableName pgx.Identifier, columnNames []string, rowSrc pgx.CopyFromSource) (int64, error) {
-	e, err := m.findExpectation("CopyFrom", tableName, columnNames)
-	if err != nil {
-		return 0, err
-	}
-	ret := e.getReturns()
-	if len(ret) > 1 && ret[1] != nil {
-		return 0, ret[1].(error)
-	}
-	if len(ret) > 0 {
-		return ret[0].(int64), nil
-	}
-	return 0, nil
+	return copyFromResult(m.findExpectation(ctx, "CopyFrom", tableName, columnNames))
+}
+
+// ExpectBatch configures an expectation for a SendBatch call. Add the queued
+// statements with the ExpectExec, ExpectQuery and ExpectQueryRow methods of
+// the returned expectation.
+func (m *PGXMock) ExpectBatch() *BatchExpectation {
+	e := &BatchExpectation{basicExpectation: basicExpectation{statement: mockmatch.Statement{Method: "SendBatch"}}}
+	m.expectations.add(e)
+	return e
+}
+
+// SendBatch returns the results configured on the matching batch expectation.
+// Like pgx, errors surface when the results are read.
+func (m *PGXMock) SendBatch(ctx context.Context, batch *pgx.Batch) pgx.BatchResults {
+	return batchResult(m.findExpectation(ctx, "SendBatch", batch))
 }
 
 // Methods that return nil/defaults for interface compliance
@@ -400,7 +302,3 @@ func (m *PGXMock) LargeObjects() pgx.LargeObjects {
 	panic("not implemented")
 }
 func (m *PGXMock) Conn() *pgx.Conn { return nil }
-
-func (m *PGXMock) SendBatch(ctx context.Context, batch *pgx.Batch) pgx.BatchResults {
-	return nil
-}
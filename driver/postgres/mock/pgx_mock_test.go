@@ -3,12 +3,15 @@ package mock
 import (
 	"context"
 	"errors"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/Kansuler/octobe/v3"
 	"github.com/Kansuler/octobe/v3/driver/postgres"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/stretchr/testify/require"
 )
 
@@ -453,3 +456,551 @@ func TestMock(t *testing.T) {
 		require.NoError(t, mock.AllExpectationsMet())
 	})
 }
+
+func TestMatchExpectations(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Unordered top level", func(t *testing.T) {
+		mock := NewPGXMock()
+		mock.MatchExpectationsInOrder(false)
+		mock.ExpectExec("DELETE FROM tags WHERE post_id = $1").WithArgs(1).WillReturnResult(NewResult("DELETE", 2))
+		mock.ExpectExec("DELETE FROM comments WHERE post_id = $1").WithArgs(1).WillReturnResult(NewResult("DELETE", 3))
+
+		tag, err := mock.Exec(ctx, "DELETE FROM comments WHERE post_id = $1", 1)
+		require.NoError(t, err)
+		require.Equal(t, int64(3), tag.RowsAffected())
+		tag, err = mock.Exec(ctx, "DELETE FROM tags WHERE post_id = $1", 1)
+		require.NoError(t, err)
+		require.Equal(t, int64(2), tag.RowsAffected())
+		require.NoError(t, mock.AllExpectationsMet())
+	})
+
+	t.Run("Unordered group keeps its position", func(t *testing.T) {
+		mock := NewPGXMock()
+		mock.ExpectBegin()
+		mock.Unordered(func() {
+			mock.ExpectExec("UPDATE a").WillReturnResult(NewResult("UPDATE", 1))
+			mock.ExpectExec("UPDATE b").WillReturnResult(NewResult("UPDATE", 1))
+		})
+		mock.ExpectCommit()
+
+		_, err := mock.Exec(ctx, "UPDATE a")
+		require.ErrorIs(t, err, ErrNoExpectation)
+
+		_, err = mock.Begin(ctx)
+		require.NoError(t, err)
+		require.ErrorIs(t, mock.Commit(ctx), ErrNoExpectation)
+		_, err = mock.Exec(ctx, "UPDATE b")
+		require.NoError(t, err)
+		_, err = mock.Exec(ctx, "UPDATE a")
+		require.NoError(t, err)
+		require.NoError(t, mock.Commit(ctx))
+		require.NoError(t, mock.AllExpectationsMet())
+	})
+
+	t.Run("Ordered group inside unordered mock", func(t *testing.T) {
+		mock := NewPGXMock()
+		mock.MatchExpectationsInOrder(false)
+		mock.Ordered(func() {
+			mock.ExpectBegin()
+			mock.ExpectCommit()
+		})
+		mock.ExpectPing()
+
+		require.ErrorIs(t, mock.Commit(ctx), ErrNoExpectation)
+		_, err := mock.Begin(ctx)
+		require.NoError(t, err)
+		require.NoError(t, mock.Ping(ctx))
+		require.NoError(t, mock.Commit(ctx))
+		require.NoError(t, mock.AllExpectationsMet())
+	})
+
+	t.Run("Unfulfilled expectation inside a group", func(t *testing.T) {
+		mock := NewPGXMock()
+		mock.Unordered(func() {
+			mock.ExpectPing()
+			mock.ExpectClose()
+		})
+
+		require.NoError(t, mock.Close(ctx))
+		require.EqualError(t, mock.AllExpectationsMet(), `unfulfilled expectation: method Ping with query <nil> and args []: called 0 times, expected 1
+
+calls:
+  expected   Close
+expectations:
+  unfulfilled method Ping with query <nil> and args []: called 0 times, expected 1
+  fulfilled   method Close with query <nil> and args []: called 1 times, expected 1`)
+	})
+
+	t.Run("Error lists every candidate", func(t *testing.T) {
+		mock := NewPGXMock()
+		mock.MatchExpectationsInOrder(false)
+		mock.ExpectExec("UPDATE users").Contains().WithArgs(1)
+		mock.ExpectQuery("SELECT id FROM users")
+
+		_, err := mock.Exec(ctx, "UPDATE users SET name = $1", "bob")
+		require.ErrorIs(t, err, ErrNoExpectation)
+		require.EqualError(t, err, `no expectation found: none of 2 candidate expectations match Exec with args [UPDATE users SET name = $1 bob]:
+  - method Exec with query contains "UPDATE users" and args [1]: args mismatch: expected [1], got [bob]
+      - argument 0: 1
+      + argument 0: "bob"
+  - method Query with query exact "SELECT id FROM users" and args []: method mismatch: expected Query, got Exec`)
+	})
+}
+
+func TestRepeatableExpectations(t *testing.T) {
+	ctx := context.Background()
+	const poll = "UPDATE jobs SET state = 'running' WHERE state = 'queued'"
+
+	t.Run("Times", func(t *testing.T) {
+		mock := NewPGXMock()
+		mock.ExpectExec(poll).Times(3).WillReturnResult(NewResult("UPDATE", 1))
+
+		for range 3 {
+			_, err := mock.Exec(ctx, poll)
+			require.NoError(t, err)
+		}
+		_, err := mock.Exec(ctx, poll)
+		require.ErrorIs(t, err, ErrNoExpectation)
+		require.NoError(t, mock.AllExpectationsMet())
+	})
+
+	t.Run("Call counts in unfulfilled error", func(t *testing.T) {
+		mock := NewPGXMock()
+		mock.ExpectExec(poll).Times(3).WillReturnResult(NewResult("UPDATE", 1))
+		_, err := mock.Exec(ctx, poll)
+		require.NoError(t, err)
+		require.ErrorContains(t, mock.AllExpectationsMet(), "called 1 times, expected 3")
+
+		mock = NewPGXMock()
+		mock.ExpectBegin().AtLeast(2)
+		_, err = mock.Begin(ctx)
+		require.NoError(t, err)
+		require.ErrorContains(t, mock.AllExpectationsMet(), "called 1 times, expected at least 2")
+	})
+
+	t.Run("AtLeast blocks later expectations until met", func(t *testing.T) {
+		mock := NewPGXMock()
+		mock.ExpectExec(poll).AtLeast(2).WillReturnResult(NewResult("UPDATE", 0))
+		mock.ExpectPing()
+
+		_, err := mock.Exec(ctx, poll)
+		require.NoError(t, err)
+		require.ErrorIs(t, mock.Ping(ctx), ErrNoExpectation)
+		for range 3 {
+			_, err = mock.Exec(ctx, poll)
+			require.NoError(t, err)
+		}
+		require.NoError(t, mock.Ping(ctx))
+
+		// The call moved past the repeated expectation, so it no longer matches.
+		_, err = mock.Exec(ctx, poll)
+		require.ErrorIs(t, err, ErrNoExpectation)
+		require.NoError(t, mock.AllExpectationsMet())
+	})
+
+	t.Run("AnyTimes and Maybe may be skipped", func(t *testing.T) {
+		mock := NewPGXMock()
+		mock.ExpectBegin()
+		mock.ExpectExec(poll).AnyTimes().WillReturnResult(NewResult("UPDATE", 1))
+		mock.ExpectRollback().Maybe()
+		mock.ExpectCommit()
+
+		_, err := mock.Begin(ctx)
+		require.NoError(t, err)
+		require.NoError(t, mock.Commit(ctx))
+		require.NoError(t, mock.AllExpectationsMet())
+	})
+
+	t.Run("Return sequence", func(t *testing.T) {
+		mock := NewPGXMock()
+		mock.ExpectQuery("SELECT id FROM jobs").Times(3).
+			WillReturnRows(NewRows([]string{"id"}).AddRow(1)).
+			Then().WillReturnRows(NewRows([]string{"id"}).AddRow(2).AddRow(3))
+
+		ids := func() []int {
+			rows, err := mock.Query(ctx, "SELECT id FROM jobs")
+			require.NoError(t, err)
+			var ids []int
+			for rows.Next() {
+				var id int
+				require.NoError(t, rows.Scan(&id))
+				ids = append(ids, id)
+			}
+			return ids
+		}
+		require.Equal(t, []int{1}, ids())
+		require.Equal(t, []int{2, 3}, ids())
+		// The last response repeats with its rows starting over.
+		require.Equal(t, []int{2, 3}, ids())
+		require.NoError(t, mock.AllExpectationsMet())
+	})
+
+	t.Run("Error then result", func(t *testing.T) {
+		mock := NewPGXMock()
+		busy := errors.New("could not obtain lock")
+		mock.ExpectExec(poll).Times(2).WillReturnError(busy).Then().WillReturnResult(NewResult("UPDATE", 4))
+
+		_, err := mock.Exec(ctx, poll)
+		require.Equal(t, busy, err)
+		tag, err := mock.Exec(ctx, poll)
+		require.NoError(t, err)
+		require.Equal(t, int64(4), tag.RowsAffected())
+		require.NoError(t, mock.AllExpectationsMet())
+	})
+
+	t.Run("Last response wins without Then", func(t *testing.T) {
+		mock := NewPGXMock()
+		e := mock.ExpectExec(poll).Times(2).WillReturnResult(NewResult("UPDATE", 1))
+		// A test overriding the response of a shared expectation.
+		e.WillReturnResult(NewResult("UPDATE", 2))
+
+		for range 2 {
+			tag, err := mock.Exec(ctx, poll)
+			require.NoError(t, err)
+			require.Equal(t, int64(2), tag.RowsAffected())
+		}
+
+		commit := mock.ExpectCommit().Then().WillReturnError(errors.New("first"))
+		commit.WillReturnError(nil)
+		require.NoError(t, mock.Commit(ctx), "Then before any response has nothing to follow")
+		require.NoError(t, mock.AllExpectationsMet())
+	})
+
+	t.Run("Repeated transactions on a pool", func(t *testing.T) {
+		pool := NewPGXPoolMock()
+		pool.MatchExpectationsInOrder(false)
+		pool.ExpectBeginTx().Times(2)
+		pool.ExpectCommit().Times(2)
+
+		for range 2 {
+			tx, err := pool.BeginTx(ctx, pgx.TxOptions{})
+			require.NoError(t, err)
+			require.NoError(t, tx.Commit(ctx))
+		}
+		require.ErrorIs(t, pool.Commit(ctx), ErrNoExpectation)
+		require.NoError(t, pool.AllExpectationsMet())
+	})
+}
+
+func TestArgMatchers(t *testing.T) {
+	ctx := context.Background()
+	const insert = "INSERT INTO users (id, email, password, settings, created_at) VALUES ($1, $2, $3, $4, $5)"
+	now := time.Now()
+
+	expect := func(args ...any) *PGXMock {
+		mock := NewPGXMock()
+		mock.ExpectExec(insert).WithArgs(args...).WillReturnResult(NewResult("INSERT", 1))
+		return mock
+	}
+
+	t.Run("Matchers alongside literals", func(t *testing.T) {
+		mock := expect(
+			AnyOfType[string](),
+			"alice@example.com",
+			Regexp(`^\$2[aby]\$`),
+			JSONEq(`{"theme": "dark", "beta": true}`),
+			TimeWithin(time.Minute),
+		)
+		_, err := mock.Exec(ctx, insert, "0b6d1c9e", "alice@example.com", []byte("$2a$10$abcdefgh"), map[string]any{"beta": true, "theme": "dark"}, now)
+		require.NoError(t, err)
+		require.NoError(t, mock.AllExpectationsMet())
+	})
+
+	t.Run("AnyArg and Func", func(t *testing.T) {
+		mock := expect(AnyArg(), AnyArg(), AnyArg(), AnyArg(), Func(func(arg any) bool {
+			t, ok := arg.(*time.Time)
+			return ok && t == nil
+		}))
+		_, err := mock.Exec(ctx, insert, nil, 1, "x", `{}`, (*time.Time)(nil))
+		require.NoError(t, err)
+	})
+
+	mismatches := []struct {
+		name    string
+		matcher ArgMatcher
+		arg     any
+		message string
+	}{
+		{"AnyOfType", AnyOfType[int64](), 1, "argument 4 does not match AnyOfType[int64](), got 1"},
+		{"TimeWithin", TimeWithin(time.Second), now.Add(-time.Hour), "argument 4 does not match TimeWithin(1s)"},
+		{"TimeWithin other type", TimeWithin(time.Second), "now", `argument 4 does not match TimeWithin(1s), got "now"`},
+		{"Regexp", Regexp(`^\d+$`), "12a", `argument 4 does not match Regexp("^\\d+$"), got "12a"`},
+		{"JSONEq", JSONEq(`{"a": 1}`), `{"a": 2}`, `argument 4 does not match JSONEq({"a": 1})`},
+		{"JSONEq invalid", JSONEq(`[1]`), "[1", `argument 4 does not match JSONEq([1])`},
+		{"Func", Func(func(any) bool { return false }), 1, "argument 4 does not match Func(github.com/Kansuler/octobe/v3/driver/postgres/mock.TestArgMatchers."},
+	}
+	for _, tc := range mismatches {
+		t.Run(tc.name+" mismatch", func(t *testing.T) {
+			mock := expect(AnyArg(), AnyArg(), AnyArg(), AnyArg(), tc.matcher)
+			_, err := mock.Exec(ctx, insert, 1, 2, 3, 4, tc.arg)
+			require.ErrorIs(t, err, ErrNoExpectation)
+			require.ErrorContains(t, err, "args mismatch: "+tc.message)
+		})
+	}
+
+	t.Run("Argument count mismatch", func(t *testing.T) {
+		mock := expect(AnyArg())
+		_, err := mock.Exec(ctx, insert, 1, 2)
+		require.ErrorContains(t, err, "args mismatch: expected [AnyArg()], got [1 2]")
+	})
+
+	t.Run("Invalid JSONEq panics", func(t *testing.T) {
+		require.Panics(t, func() { JSONEq("{") })
+	})
+}
+
+func TestDelayAndContext(t *testing.T) {
+	const slow = "SELECT pg_sleep(1)"
+
+	t.Run("Delay", func(t *testing.T) {
+		mock := NewPGXMock()
+		mock.ExpectExec(slow).WillDelayFor(20 * time.Millisecond).WillReturnResult(NewResult("SELECT", 1))
+
+		start := time.Now()
+		_, err := mock.Exec(context.Background(), slow)
+		require.NoError(t, err)
+		require.GreaterOrEqual(t, time.Since(start), 20*time.Millisecond)
+	})
+
+	t.Run("Deadline during delay", func(t *testing.T) {
+		mock := NewPGXMock()
+		mock.ExpectQuery(slow).WillDelayFor(time.Minute).WillReturnRows(NewRows([]string{"pg_sleep"}))
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		_, err := mock.Query(ctx, slow)
+		require.ErrorIs(t, err, context.DeadlineExceeded)
+		require.EqualError(t, err, "timeout: context deadline exceeded")
+		require.NoError(t, mock.AllExpectationsMet())
+	})
+
+	t.Run("Context already done", func(t *testing.T) {
+		mock := NewPGXMock()
+		mock.ExpectQueryRow(slow).WillReturnRow(NewRow(1))
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		var n int
+		err := mock.QueryRow(ctx, slow).Scan(&n)
+		require.ErrorIs(t, err, context.Canceled)
+		require.EqualError(t, err, "context already done: context canceled")
+		require.ErrorContains(t, mock.AllExpectationsMet(), "called 0 times, expected 1")
+	})
+
+	t.Run("Timeout through StartTransaction", func(t *testing.T) {
+		pool := NewPGXPoolMock()
+		db, err := octobe.New(postgres.OpenPGXWithPool(pool))
+		require.NoError(t, err)
+
+		pool.ExpectBeginTx()
+		pool.ExpectExec(slow).WillDelayFor(time.Minute).WillReturnResult(NewResult("SELECT", 1))
+		// The rollback runs with the expired context and never reaches the pool.
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		err = db.StartTransaction(ctx, func(session octobe.BuilderSession[postgres.Builder]) error {
+			_, err := session.Builder()(slow).Exec()
+			return err
+		})
+		require.ErrorIs(t, err, context.DeadlineExceeded)
+		require.NoError(t, pool.AllExpectationsMet())
+	})
+}
+
+func TestRows(t *testing.T) {
+	ctx := context.Background()
+
+	names := func(rows postgres.Rows, out *[]string) error {
+		for rows.Next() {
+			var id int
+			var name string
+			if err := rows.Scan(&id, &name); err != nil {
+				return err
+			}
+			*out = append(*out, name)
+		}
+		return rows.Err()
+	}
+
+	t.Run("Row error part way through", func(t *testing.T) {
+		mock := NewPGXMock()
+		o, err := octobe.New(postgres.OpenPGXWithConn(mock))
+		require.NoError(t, err)
+		session, err := o.Begin(ctx)
+		require.NoError(t, err)
+
+		broken := errors.New("unexpected EOF")
+		mock.ExpectQuery("SELECT id, name FROM users").WillReturnRows(
+			NewRows([]string{"id", "name"}).AddRow(1, "alice").AddRow(2, "bob").AddRow(3, "carol").WithRowError(2, broken),
+		)
+
+		var got []string
+		err = session.Builder()("SELECT id, name FROM users").Query(func(rows postgres.Rows) error {
+			return names(rows, &got)
+		})
+		require.Equal(t, broken, err)
+		require.Equal(t, []string{"alice", "bob"}, got)
+	})
+
+	t.Run("Close error after iteration", func(t *testing.T) {
+		mock := NewPGXMock()
+		o, err := octobe.New(postgres.OpenPGXWithConn(mock))
+		require.NoError(t, err)
+		session, err := o.Begin(ctx)
+		require.NoError(t, err)
+
+		closeErr := errors.New("conn busy")
+		mock.ExpectQuery("SELECT id, name FROM users").WillReturnRows(
+			NewRows([]string{"id", "name"}).AddRow(1, "alice").WithCloseError(closeErr),
+		)
+
+		var got []string
+		err = session.Builder()("SELECT id, name FROM users").Query(func(rows postgres.Rows) error {
+			return names(rows, &got)
+		})
+		require.Equal(t, closeErr, err)
+		require.Equal(t, []string{"alice"}, got)
+	})
+
+	t.Run("Command tag", func(t *testing.T) {
+		rows := NewRows([]string{"id"}).AddRow(1).WithCommandTag(NewResult("SELECT", 1))
+		require.Equal(t, "SELECT 0 1", rows.CommandTag().String())
+	})
+
+	t.Run("Typed field descriptions and values", func(t *testing.T) {
+		created := time.Date(2024, 3, 1, 12, 30, 0, 0, time.UTC)
+		rows := NewRows([]string{"id", "name", "active", "created_at", "score"}).
+			AddRow(1, "alice", true, created, nil).
+			WithColumnTypes(pgtype.Int4OID, 0, 0, pgtype.TimestamptzOID, pgtype.Float8OID)
+
+		fields := rows.FieldDescriptions()
+		require.Equal(t, uint32(pgtype.Int4OID), fields[0].DataTypeOID)
+		require.Equal(t, uint32(pgtype.TextOID), fields[1].DataTypeOID)
+		require.Equal(t, uint32(pgtype.BoolOID), fields[2].DataTypeOID)
+		require.Equal(t, uint32(pgtype.TimestamptzOID), fields[3].DataTypeOID)
+		require.Equal(t, uint32(pgtype.Float8OID), fields[4].DataTypeOID)
+
+		require.True(t, rows.Next())
+		raw := rows.RawValues()
+		require.Equal(t, "1", string(raw[0]))
+		require.Equal(t, "t", string(raw[2]))
+		require.Equal(t, "2024-03-01 12:30:00Z", string(raw[3]))
+		require.Nil(t, raw[4])
+
+		values, err := rows.Values()
+		require.NoError(t, err)
+		require.Equal(t, int32(1), values[0])
+		require.Equal(t, "alice", values[1])
+		require.Equal(t, true, values[2])
+		require.True(t, created.Equal(values[3].(time.Time)))
+		require.Nil(t, values[4])
+	})
+
+	t.Run("Repeated responses keep row configuration", func(t *testing.T) {
+		mock := NewPGXMock()
+		broken := errors.New("unexpected EOF")
+		mock.ExpectQuery("SELECT id, name FROM users").Times(2).WillReturnRows(
+			NewRows([]string{"id", "name"}).AddRow(1, "alice").AddRow(2, "bob").WithRowError(1, broken),
+		)
+
+		for range 2 {
+			rows, err := mock.Query(ctx, "SELECT id, name FROM users")
+			require.NoError(t, err)
+			var got []string
+			require.Equal(t, broken, names(rows, &got))
+			require.Equal(t, []string{"alice"}, got)
+		}
+	})
+}
+
+func TestMismatchReporting(t *testing.T) {
+	ctx := context.Background()
+	const report = `SELECT u.id, u.name, count(o.id)
+FROM users u
+LEFT JOIN orders o ON o.user_id = u.id
+WHERE u.active
+GROUP BY u.id, u.name
+ORDER BY u.name`
+
+	t.Run("Query diff", func(t *testing.T) {
+		mock := NewPGXMock()
+		mock.ExpectQuery(report)
+
+		_, err := mock.Query(ctx, strings.Replace(report, "WHERE u.active", "WHERE u.active AND u.verified", 1))
+		require.ErrorIs(t, err, ErrNoExpectation)
+		require.ErrorContains(t, err, `query mismatch:
+--- expected
++++ actual
+@@ -1,6 +1,6 @@
+ SELECT u.id, u.name, count(o.id)
+ FROM users u
+ LEFT JOIN orders o ON o.user_id = u.id
+-WHERE u.active
++WHERE u.active AND u.verified
+ GROUP BY u.id, u.name
+ ORDER BY u.name`)
+	})
+
+	t.Run("Whitespace normalized queries", func(t *testing.T) {
+		mock := NewPGXMock()
+		mock.ExpectQuery(report).NormalizeWhitespace().WillReturnRows(NewRows([]string{"id", "name", "count"}))
+		mock.ExpectQuery(report).NormalizeWhitespace()
+
+		indented := "\n\t" + strings.ReplaceAll(report, "\n", "\n\t  ") + "\n"
+		rows, err := mock.Query(ctx, indented)
+		require.NoError(t, err)
+		rows.Close()
+
+		_, err = mock.Query(ctx, strings.Replace(indented, "ORDER BY u.name", "ORDER  BY u.id", 1))
+		require.ErrorContains(t, err, `exact "SELECT u.id, u.name, count(o.id)\nFROM users u`)
+		require.ErrorContains(t, err, "ignoring whitespace")
+		require.ErrorContains(t, err, `@@ -3,4 +3,4 @@
+ LEFT JOIN orders o ON o.user_id = u.id
+ WHERE u.active
+ GROUP BY u.id, u.name
+-ORDER BY u.name
++ORDER BY u.id`)
+	})
+
+	t.Run("Argument diff", func(t *testing.T) {
+		mock := NewPGXMock()
+		mock.ExpectExec("UPDATE users SET name = $1 WHERE id = $2").WithArgs("alice", AnyOfType[int]())
+
+		_, err := mock.Exec(ctx, "UPDATE users SET name = $1 WHERE id = $2", "alice", int64(7), true)
+		require.ErrorIs(t, err, ErrNoExpectation)
+		require.ErrorContains(t, err, `args mismatch: expected [alice AnyOfType[int]()], got [alice 7 true]
+    argument 0: "alice"
+  - argument 1: AnyOfType[int]()
+  + argument 1: 7
+  + argument 2: true`)
+	})
+
+	t.Run("Report of all calls", func(t *testing.T) {
+		mock := NewPGXMock()
+		mock.ExpectBegin()
+		mock.ExpectExec("DELETE FROM sessions WHERE user_id = $1").WithArgs(1).WillReturnResult(NewResult("DELETE", 1))
+		mock.ExpectCommit()
+
+		tx, err := mock.Begin(ctx)
+		require.NoError(t, err)
+		_, err = tx.Exec(ctx, "DELETE FROM sessions WHERE user_id = $1", 2)
+		require.Error(t, err)
+		_, err = tx.Exec(ctx, "DELETE FROM sessions WHERE user_id = $1", 1)
+		require.NoError(t, err)
+
+		require.EqualError(t, mock.AllExpectationsMet(), `unfulfilled expectation: method Commit with query <nil> and args []: called 0 times, expected 1
+
+calls:
+  expected   Begin in transaction 1
+  unexpected Exec "DELETE FROM sessions WHERE user_id = $1" [2] in transaction 1
+      no expectation found: next expectation method Exec with query exact "DELETE FROM sessions WHERE user_id = $1" and args [1] does not match Exec with args [DELETE FROM sessions WHERE user_id = $1 2]: args mismatch: expected [1], got [2]
+        - argument 0: 1
+        + argument 0: 2
+  expected   Exec "DELETE FROM sessions WHERE user_id = $1" [1] in transaction 1
+expectations:
+  fulfilled   method Begin with query <nil> and args []: called 1 times, expected 1
+  fulfilled   method Exec with query exact "DELETE FROM sessions WHERE user_id = $1" and args [1]: called 1 times, expected 1
+  unfulfilled method Commit with query <nil> and args []: called 0 times, expected 1`)
+	})
+}
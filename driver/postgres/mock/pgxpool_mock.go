@@ -2,11 +2,11 @@ package mock
 
 import (
 	"context"
-	"errors"
 	"fmt"
 	"sync"
 
 	"github.com/Kansuler/octobe/v3/driver/postgres"
+	"github.com/Kansuler/octobe/v3/internal/mockmatch"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
@@ -16,7 +16,7 @@ import (
 // for testing database pool interactions without requiring an actual database connection.
 type PGXPoolMock struct {
 	mu              sync.Mutex
-	expectations    []expectation
+	expectations    expectationSet
 	unexpectedCalls []error
 }
 
@@ -32,22 +32,9 @@ func NewPGXPoolMock() *PGXPoolMock {
 	return &PGXPoolMock{}
 }
 
-// findExpectation locates the first unfulfilled expectation matching the method and arguments.
-func (m *PGXPoolMock) findExpectation(method string, args ...any) (expectation, error) {
-	m.mu.Lock()
-	defer m.mu.Unlock()
-
-	for _, e := range m.expectations {
-		if e.fulfilled() {
-			continue
-		}
-		if err := e.match(method, args...); err != nil {
-			return nil, fmt.Errorf("%w: next expectation %s does not match %s with args %v: %w", ErrNoExpectation, e, method, args, err)
-		}
-		return e, nil
-	}
-
-	return nil, fmt.Errorf("%w for %s with args %v", ErrNoExpectation, method, args)
+// findExpectation matches a call against the expectations and returns the configured response.
+func (m *PGXPoolMock) findExpectation(ctx context.Context, method string, args ...any) ([]any, error) {
+	return m.expectations.find(ctx, scope{}, method, args...)
 }
 
 func (m *PGXPoolMock) recordUnexpectedCall(err error) {
@@ -60,48 +47,62 @@ func (m *PGXPoolMock) recordUnexpectedCall(err error) {
 func (m *PGXPoolMock) AllExpectationsMet() error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
-	if len(m.unexpectedCalls) > 0 {
-		return errors.Join(m.unexpectedCalls...)
-	}
-	for _, e := range m.expectations {
-		if !e.fulfilled() {
-			return fmt.Errorf("unfulfilled expectation: %s", e)
-		}
-	}
-	return nil
+	return m.expectations.unfulfilled(m.unexpectedCalls...)
+}
+
+// MatchExpectationsInOrder controls whether top-level expectations must be met in
+// the order they were added, which is the default. With false, each call matches
+// any unfulfilled expectation, for handlers whose query order is not deterministic.
+func (m *PGXPoolMock) MatchExpectationsInOrder(inOrder bool) {
+	m.expectations.setInOrder(inOrder)
+}
+
+// Calls returns the calls matched so far, in order, with the transaction and
+// savepoint each ran in.
+func (m *PGXPoolMock) Calls() []Call {
+	return m.expectations.recordedCalls()
+}
+
+// TransactionReport describes which statements ran in which transaction and
+// with which transaction options, for logging when a test fails.
+func (m *PGXPoolMock) TransactionReport() string {
+	return transactionReport(m.Calls())
+}
+
+// Ordered groups the expectations added by fn so they must be met in order,
+// even when the surrounding group is unordered.
+func (m *PGXPoolMock) Ordered(fn func()) {
+	m.expectations.nest(true, fn)
+}
+
+// Unordered groups the expectations added by fn so they may be met in any
+// order. The group as a whole keeps its position in the surrounding group.
+func (m *PGXPoolMock) Unordered(fn func()) {
+	m.expectations.nest(false, fn)
 }
 
 func (m *PGXPoolMock) ExpectPing() *PingExpectation {
-	e := &PingExpectation{basicExpectation: basicExpectation{method: "Ping"}}
-	m.expectations = append(m.expectations, e)
+	e := &PingExpectation{basicExpectation: basicExpectation{statement: mockmatch.Statement{Method: "Ping"}}}
+	m.expectations.add(e)
 	return e
 }
 
 func (m *PGXPoolMock) Ping(ctx context.Context) error {
-	e, err := m.findExpectation("Ping")
-	if err != nil {
-		return err
-	}
-	ret := e.getReturns()
-	if len(ret) > 0 && ret[0] != nil {
-		return ret[0].(error)
-	}
-	return nil
+	return errorResult(m.findExpectation(ctx, "Ping"))
 }
 
 func (m *PGXPoolMock) ExpectClose() *CloseExpectation {
-	e := &CloseExpectation{basicExpectation: basicExpectation{method: "Close"}}
-	m.expectations = append(m.expectations, e)
+	e := &CloseExpectation{basicExpectation: basicExpectation{statement: mockmatch.Statement{Method: "Close"}}}
+	m.expectations.add(e)
 	return e
 }
 
 func (m *PGXPoolMock) Close() {
-	e, err := m.findExpectation("Close")
+	ret, err := m.findExpectation(context.Background(), "Close")
 	if err != nil {
 		m.recordUnexpectedCall(fmt.Errorf("unexpected Close: %w", err))
 		return
 	}
-	ret := e.getReturns()
 	if len(ret) > 0 && ret[0] != nil {
 		return
 	}
@@ -110,165 +111,100 @@ func (m *PGXPoolMock) Close() {
 type ReleaseExpectation struct{ basicExpectation }
 
 func (m *PGXPoolMock) ExpectRelease() *ReleaseExpectation {
-	e := &ReleaseExpectation{basicExpectation: basicExpectation{method: "Release"}}
-	m.expectations = append(m.expectations, e)
+	e := &ReleaseExpectation{basicExpectation: basicExpectation{statement: mockmatch.Statement{Method: "Release"}}}
+	m.expectations.add(e)
 	return e
 }
 
 func (m *PGXPoolMock) Release() {
-	e, err := m.findExpectation("Release")
-	if err != nil {
+	if _, err := m.findExpectation(context.Background(), "Release"); err != nil {
 		m.recordUnexpectedCall(fmt.Errorf("unexpected Release: %w", err))
-		return
 	}
-	e.getReturns()
 }
 
 // ExpectExec configures an expectation for an Exec operation with the specified query.
 func (m *PGXPoolMock) ExpectExec(query string) *ExecExpectation {
 	e := &ExecExpectation{
 		basicExpectation: basicExpectation{
-			method:     "Exec",
-			query:      query,
-			queryMatch: queryMatchExact,
+			statement: mockmatch.Statement{Method: "Exec", Query: query, Mode: mockmatch.Exact},
 		},
 	}
-	m.expectations = append(m.expectations, e)
+	m.expectations.add(e)
 	return e
 }
 
 func (m *PGXPoolMock) Exec(ctx context.Context, query string, args ...any) (pgconn.CommandTag, error) {
-	e, err := m.findExpectation("Exec", append([]any{query}, args...)...)
-	if err != nil {
-		return pgconn.CommandTag{}, err
-	}
-	ret := e.getReturns()
-	if ret[1] != nil {
-		return pgconn.CommandTag{}, ret[1].(error)
-	}
-	return ret[0].(pgconn.CommandTag), nil
+	return execResult(m.findExpectation(ctx, "Exec", append([]any{query}, args...)...))
 }
 
 // ExpectQuery configures an expectation for a Query operation with the specified query.
 func (m *PGXPoolMock) ExpectQuery(query string) *QueryExpectation {
 	e := &QueryExpectation{
 		basicExpectation: basicExpectation{
-			method:     "Query",
-			query:      query,
-			queryMatch: queryMatchExact,
+			statement: mockmatch.Statement{Method: "Query", Query: query, Mode: mockmatch.Exact},
 		},
 	}
-	m.expectations = append(m.expectations, e)
+	m.expectations.add(e)
 	return e
 }
 
 func (m *PGXPoolMock) Query(ctx context.Context, query string, args ...any) (pgx.Rows, error) {
-	e, err := m.findExpectation("Query", append([]any{query}, args...)...)
-	if err != nil {
-		return nil, err
-	}
-	ret := e.getReturns()
-	if ret[1] != nil {
-		return nil, ret[1].(error)
-	}
-	if ret[0] == nil {
-		return nil, nil
-	}
-	return ret[0].(pgx.Rows), nil
+	return queryResult(m.findExpectation(ctx, "Query", append([]any{query}, args...)...))
 }
 
 // ExpectQueryRow configures an expectation for a QueryRow operation with the specified query.
 func (m *PGXPoolMock) ExpectQueryRow(query string) *QueryRowExpectation {
 	e := &QueryRowExpectation{
 		basicExpectation: basicExpectation{
-			method:     "QueryRow",
-			query:      query,
-			queryMatch: queryMatchExact,
+			statement: mockmatch.Statement{Method: "QueryRow", Query: query, Mode: mockmatch.Exact},
 		},
 	}
-	m.expectations = append(m.expectations, e)
+	m.expectations.add(e)
 	return e
 }
 
 func (m *PGXPoolMock) QueryRow(ctx context.Context, query string, args ...any) pgx.Row {
-	e, err := m.findExpectation("QueryRow", append([]any{query}, args...)...)
-	if err != nil {
-		return &Row{err: err}
-	}
-	ret := e.getReturns()
-	return ret[0].(pgx.Row)
+	return queryRowResult(m.findExpectation(ctx, "QueryRow", append([]any{query}, args...)...))
 }
 
 func (m *PGXPoolMock) ExpectBegin() *BeginExpectation {
-	e := &BeginExpectation{basicExpectation: basicExpectation{method: "Begin"}}
-	m.expectations = append(m.expectations, e)
+	e := &BeginExpectation{basicExpectation: basicExpectation{statement: mockmatch.Statement{Method: "Begin"}}}
+	m.expectations.add(e)
 	return e
 }
 
 func (m *PGXPoolMock) Begin(ctx context.Context) (pgx.Tx, error) {
-	e, err := m.findExpectation("Begin")
-	if err != nil {
-		return nil, err
-	}
-	ret := e.getReturns()
-	if len(ret) > 1 && ret[1] != nil {
-		return nil, ret[1].(error)
-	}
-	return m, nil
+	return m.expectations.beginTx(ctx, scope{}, pgx.TxOptions{}, "Begin")
 }
 
 func (m *PGXPoolMock) ExpectBeginTx() *BeginTxExpectation {
-	e := &BeginTxExpectation{basicExpectation: basicExpectation{method: "BeginTx"}}
-	m.expectations = append(m.expectations, e)
+	e := &BeginTxExpectation{basicExpectation: basicExpectation{statement: mockmatch.Statement{Method: "BeginTx"}}}
+	m.expectations.add(e)
 	return e
 }
 
 func (m *PGXPoolMock) BeginTx(ctx context.Context, txOptions pgx.TxOptions) (pgx.Tx, error) {
-	e, err := m.findExpectation("BeginTx", txOptions)
-	if err != nil {
-		return nil, err
-	}
-	ret := e.getReturns()
-	if len(ret) > 1 && ret[1] != nil {
-		return nil, ret[1].(error)
-	}
-	return m, nil
+	return m.expectations.beginTx(ctx, scope{}, txOptions, "BeginTx", txOptions)
 }
 
 func (m *PGXPoolMock) ExpectCommit() *CommitExpectation {
-	e := &CommitExpectation{basicExpectation: basicExpectation{method: "Commit"}}
-	m.expectations = append(m.expectations, e)
+	e := &CommitExpectation{basicExpectation: basicExpectation{statement: mockmatch.Statement{Method: "Commit"}}}
+	m.expectations.add(e)
 	return e
 }
 
 func (m *PGXPoolMock) Commit(ctx context.Context) error {
-	e, err := m.findExpectation("Commit")
-	if err != nil {
-		return err
-	}
-	ret := e.getReturns()
-	if len(ret) > 0 && ret[0] != nil {
-		return ret[0].(error)
-	}
-	return nil
+	return errorResult(m.findExpectation(ctx, "Commit"))
 }
 
 func (m *PGXPoolMock) ExpectRollback() *RollbackExpectation {
-	e := &RollbackExpectation{basicExpectation: basicExpectation{method: "Rollback"}}
-	m.expectations = append(m.expectations, e)
+	e := &RollbackExpectation{basicExpectation: basicExpectation{statement: mockmatch.Statement{Method: "Rollback"}}}
+	m.expectations.add(e)
 	return e
 }
 
 func (m *PGXPoolMock) Rollback(ctx context.Context) error {
-	e, err := m.findExpectation("Rollback")
-	if err != nil {
-		return err
-	}
-	ret := e.getReturns()
-	if len(ret) > 0 && ret[0] != nil {
-		return ret[0].(error)
-	}
-	return nil
+	return errorResult(m.findExpectation(ctx, "Rollback"))
 }
 
 type AcquireExpectation struct {
@@ -283,19 +219,24 @@ func (e *AcquireExpectation) WillReturnError(err error) {
 	e.returns = []any{nil, err}
 }
 
+// Scoped gives the connection AcquireSession returns for this expectation its
+// own ordered expectations, configured on the returned mock. See ScopedMock.
+func (e *AcquireExpectation) Scoped() *ScopedMock {
+	return e.newScoped()
+}
+
 // ExpectAcquire configures an expectation for acquiring a connection from the pool.
 func (m *PGXPoolMock) ExpectAcquire() *AcquireExpectation {
-	e := &AcquireExpectation{basicExpectation: basicExpectation{method: "Acquire", returns: []any{nil, nil}}}
-	m.expectations = append(m.expectations, e)
+	e := &AcquireExpectation{basicExpectation: basicExpectation{statement: mockmatch.Statement{Method: "Acquire"}, returns: []any{nil, nil}}}
+	m.expectations.add(e)
 	return e
 }
 
 func (m *PGXPoolMock) Acquire(ctx context.Context) (*pgxpool.Conn, error) {
-	e, err := m.findExpectation("Acquire")
+	ret, err := m.findExpectation(ctx, "Acquire")
 	if err != nil {
 		return nil, err
 	}
-	ret := e.getReturns()
 	if ret[1] != nil {
 		return nil, ret[1].(error)
 	}
@@ -305,15 +246,19 @@ func (m *PGXPoolMock) Acquire(ctx context.Context) (*pgxpool.Conn, error) {
 	return ret[0].(*pgxpool.Conn), nil
 }
 
+// AcquireSession matches an Acquire expectation. For a scoped expectation it
+// returns a connection that calls into the expectations of a ScopedMock.
 func (m *PGXPoolMock) AcquireSession(ctx context.Context) (postgres.PGXPoolSessionConn, error) {
-	e, err := m.findExpectation("Acquire")
+	ret, _, e, err := m.expectations.call(ctx, scope{}, false, "Acquire")
 	if err != nil {
 		return nil, err
 	}
-	ret := e.getReturns()
 	if ret[1] != nil {
 		return nil, ret[1].(error)
 	}
+	if e.base().scoped != nil {
+		return &Conn{pool: m, claim: &claim{parent: &m.expectations, method: "Acquire"}}, nil
+	}
 	if ret[0] == nil {
 		return m, nil
 	}
@@ -334,18 +279,17 @@ func (e *AcquireFuncExpectation) WillReturnError(err error) {
 
 // ExpectAcquireFunc configures an expectation for AcquireFunc operations.
 func (m *PGXPoolMock) ExpectAcquireFunc() *AcquireFuncExpectation {
-	e := &AcquireFuncExpectation{basicExpectation: basicExpectation{method: "AcquireFunc"}}
-	m.expectations = append(m.expectations, e)
+	e := &AcquireFuncExpectation{basicExpectation: basicExpectation{statement: mockmatch.Statement{Method: "AcquireFunc"}}}
+	m.expectations.add(e)
 	return e
 }
 
 // AcquireFunc executes fn with a nil connection for mock purposes.
 func (m *PGXPoolMock) AcquireFunc(ctx context.Context, fn func(*pgxpool.Conn) error) error {
-	e, err := m.findExpectation("AcquireFunc")
+	ret, err := m.findExpectation(ctx, "AcquireFunc")
 	if err != nil {
 		return err
 	}
-	ret := e.getReturns()
 	if len(ret) > 0 && ret[0] != nil {
 		return ret[0].(error)
 	}
@@ -362,17 +306,16 @@ func (e *AcquireAllIdleExpectation) WillReturnConns(conns []*pgxpool.Conn) {
 
 // ExpectAcquireAllIdle configures an expectation for acquiring all idle connections.
 func (m *PGXPoolMock) ExpectAcquireAllIdle() *AcquireAllIdleExpectation {
-	e := &AcquireAllIdleExpectation{basicExpectation: basicExpectation{method: "AcquireAllIdle"}}
-	m.expectations = append(m.expectations, e)
+	e := &AcquireAllIdleExpectation{basicExpectation: basicExpectation{statement: mockmatch.Statement{Method: "AcquireAllIdle"}}}
+	m.expectations.add(e)
 	return e
 }
 
 func (m *PGXPoolMock) AcquireAllIdle(ctx context.Context) []*pgxpool.Conn {
-	e, err := m.findExpectation("AcquireAllIdle")
+	ret, err := m.findExpectation(ctx, "AcquireAllIdle")
 	if err != nil {
 		return nil
 	}
-	ret := e.getReturns()
 	if len(ret) > 0 && ret[0] != nil {
 		return ret[0].([]*pgxpool.Conn)
 	}
@@ -384,7 +327,7 @@ type PoolPrepareExpectation struct {
 }
 
 func (e *PoolPrepareExpectation) WithName(name string) *PoolPrepareExpectation {
-	e.args = []any{name}
+	e.statement.Args = []any{name}
 	return e
 }
 
@@ -400,30 +343,15 @@ func (e *PoolPrepareExpectation) WillReturnError(err error) {
 func (m *PGXPoolMock) ExpectPrepare(name, sql string) *PoolPrepareExpectation {
 	e := &PoolPrepareExpectation{
 		basicExpectation: basicExpectation{
-			method: "Prepare",
-			args:   []any{name, sql},
+			statement: mockmatch.Statement{Method: "Prepare", Args: []any{name, sql}},
 		},
 	}
-	m.expectations = append(m.expectations, e)
+	m.expectations.add(e)
 	return e
 }
 
 func (m *PGXPoolMock) Prepare(ctx context.Context, name, sql string) (*pgconn.StatementDescription, error) {
-	e, err := m.findExpectation("Prepare", name, sql)
-	if err != nil {
-		return nil, err
-	}
-	ret := e.getReturns()
-	if len(ret) > 1 && ret[1] != nil {
-		return nil, ret[1].(error)
-	}
-	if len(ret) > 0 && ret[0] == nil {
-		return &pgconn.StatementDescription{Name: name, SQL: sql}, nil
-	}
-	if len(ret) > 0 {
-		return ret[0].(*pgconn.StatementDescription), nil
-	}
-	return &pgconn.StatementDescription{Name: name, SQL: sql}, nil
+	return prepareResult(name, sql)(m.findExpectation(ctx, "Prepare", name, sql))
 }
 
 type PoolCopyFromExpectation struct {
@@ -431,7 +359,7 @@ type PoolCopyFromExpectation struct {
 }
 
 func (e *PoolCopyFromExpectation) WithColumns(columns []string) *PoolCopyFromExpectation {
-	e.args = append(e.args, columns)
+	e.statement.Args = append(e.statement.Args, columns)
 	return e
 }
 
@@ -447,27 +375,30 @@ func (e *PoolCopyFromExpectation) WillReturnError(err error) {
 func (m *PGXPoolMock) ExpectCopyFrom(tableName pgx.Identifier) *PoolCopyFromExpectation {
 	e := &PoolCopyFromExpectation{
 		basicExpectation: basicExpectation{
-			method: "CopyFrom",
-			args:   []any{tableName},
+			statement: mockmatch.Statement{Method: "CopyFrom", Args: []any{tableName}},
 		},
 	}
-	m.expectations = append(m.expectations, e)
+	m.expectations.add(e)
 	return e
 }
 
 func (m *PGXPoolMock) CopyFrom(ctx context.Context, tableName pgx.Identifier, columnNames []string, rowSrc pgx.CopyFromSource) (int64, error) {
-	e, err := m.findExpectation("CopyFrom", tableName, columnNames)
-	if err != nil {
-		return 0, err
-	}
-	ret := e.getReturns()
-	if len(ret) > 1 && ret[1] != nil {
-		return 0, ret[1].(error)
-	}
-	if len(ret) > 0 {
-		return ret[0].(int64), nil
-	}
-	return 0, nil
+	return copyFromResult(m.findExpectation(ctx, "CopyFrom", tableName, columnNames))
+}
+
+// ExpectBatch configures an expectation for a SendBatch call. Add the queued
+// statements with the ExpectExec, ExpectQuery and ExpectQueryRow methods of
+// the returned expectation.
+func (m *PGXPoolMock) ExpectBatch() *BatchExpectation {
+	e := &BatchExpectation{basicExpectation: basicExpectation{statement: mockmatch.Statement{Method: "SendBatch"}}}
+	m.expectations.add(e)
+	return e
+}
+
+// SendBatch returns the results configured on the matching batch expectation.
+// Like pgx, errors surface when the results are read.
+func (m *PGXPoolMock) SendBatch(ctx context.Context, batch *pgx.Batch) pgx.BatchResults {
+	return batchResult(m.findExpectation(ctx, "SendBatch", batch))
 }
 
 // Methods that return nil/defaults for interface compliance
@@ -478,7 +409,3 @@ func (m *PGXPoolMock) LargeObjects() pgx.LargeObjects {
 	panic("not implemented")
 }
 func (m *PGXPoolMock) Conn() *pgx.Conn { return nil }
-
-func (m *PGXPoolMock) SendBatch(ctx context.Context, batch *pgx.Batch) pgx.BatchResults {
-	return nil
-}
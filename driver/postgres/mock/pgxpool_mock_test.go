@@ -3,6 +3,8 @@ package mock
 import (
 	"context"
 	"errors"
+	"io"
+	"sync"
 	"testing"
 
 	"github.com/Kansuler/octobe/v3"
@@ -432,3 +434,394 @@ func TestPoolMock(t *testing.T) {
 		require.Contains(t, err.Error(), "unexpected Release")
 	})
 }
+
+func TestPoolMockConcurrentUnorderedCalls(t *testing.T) {
+	ctx := context.Background()
+	mock := NewPGXPoolMock()
+	mock.MatchExpectationsInOrder(false)
+
+	const calls = 50
+	for i := range calls {
+		mock.ExpectExec("UPDATE counters SET n = n + 1 WHERE id = $1").WithArgs(i).WillReturnResult(NewResult("UPDATE", 1))
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, calls)
+	for i := range calls {
+		wg.Go(func() {
+			_, err := mock.Exec(ctx, "UPDATE counters SET n = n + 1 WHERE id = $1", i)
+			errs <- err
+		})
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		require.NoError(t, err)
+	}
+	require.NoError(t, mock.AllExpectationsMet())
+
+	_, err := mock.Exec(ctx, "UPDATE counters SET n = n + 1 WHERE id = $1", 0)
+	require.ErrorIs(t, err, ErrNoExpectation)
+}
+
+func TestPoolMockBatch(t *testing.T) {
+	ctx := context.Background()
+
+	newBatch := func() *pgx.Batch {
+		batch := &pgx.Batch{}
+		batch.Queue("INSERT INTO tags (name) VALUES ($1)", "go")
+		batch.Queue("SELECT id, name FROM tags WHERE name = ANY($1)", []string{"go", "sql"})
+		batch.Queue("SELECT count(*) FROM tags")
+		return batch
+	}
+	expectBatch := func(m *PGXPoolMock) *BatchExpectation {
+		b := m.ExpectBatch()
+		b.ExpectExec("INSERT INTO tags").Contains().WithArgs("go").WillReturnResult(NewResult("INSERT", 1))
+		b.ExpectQuery(`SELECT id, name FROM tags WHERE name = ANY\(\$1\)`).Regex().WithArgs([]string{"go", "sql"}).
+			WillReturnRows(NewRows([]string{"id", "name"}).AddRow(1, "go").AddRow(2, "sql"))
+		b.ExpectQueryRow("SELECT count(*) FROM tags").WillReturnRow(NewRow(2))
+		return b
+	}
+
+	t.Run("Results per statement", func(t *testing.T) {
+		m := NewPGXPoolMock()
+		expectBatch(m)
+
+		results := m.SendBatch(ctx, newBatch())
+		tag, err := results.Exec()
+		require.NoError(t, err)
+		require.Equal(t, int64(1), tag.RowsAffected())
+
+		rows, err := results.Query()
+		require.NoError(t, err)
+		var names []string
+		for rows.Next() {
+			var id int
+			var name string
+			require.NoError(t, rows.Scan(&id, &name))
+			names = append(names, name)
+		}
+		require.Equal(t, []string{"go", "sql"}, names)
+
+		var count int
+		require.NoError(t, results.QueryRow().Scan(&count))
+		require.Equal(t, 2, count)
+
+		_, err = results.Exec()
+		require.EqualError(t, err, "no more results in batch")
+		require.NoError(t, results.Close())
+		require.NoError(t, m.AllExpectationsMet())
+	})
+
+	t.Run("Results must be closed", func(t *testing.T) {
+		m := NewPGXPoolMock()
+		expectBatch(m)
+
+		results := m.SendBatch(ctx, newBatch())
+		_, err := results.Exec()
+		require.NoError(t, err)
+		require.ErrorContains(t, m.AllExpectationsMet(), "1 batch results were not closed")
+
+		require.NoError(t, results.Close())
+		_, err = results.Exec()
+		require.EqualError(t, err, "batch already closed")
+		require.NoError(t, m.AllExpectationsMet())
+	})
+
+	t.Run("Statement mismatch", func(t *testing.T) {
+		m := NewPGXPoolMock()
+		expectBatch(m)
+
+		batch := newBatch()
+		batch.QueuedQueries[0].Arguments = []any{"rust"}
+		results := m.SendBatch(ctx, batch)
+		_, err := results.Exec()
+		require.ErrorIs(t, err, ErrNoExpectation)
+		require.ErrorContains(t, err, "batch statement 0: args mismatch: expected [go], got [rust]")
+		require.ErrorIs(t, results.Close(), ErrNoExpectation)
+
+		batch.Queue("SELECT 1")
+		_, err = m.SendBatch(ctx, batch).Exec()
+		require.ErrorContains(t, err, "batch mismatch: expected 3 statements, got 4")
+	})
+
+	t.Run("Result read with the wrong method", func(t *testing.T) {
+		m := NewPGXPoolMock()
+		expectBatch(m)
+
+		results := m.SendBatch(ctx, newBatch())
+		_, err := results.Query()
+		require.EqualError(t, err, "batch statement 0 was expected to be read with Exec, got Query")
+		require.NoError(t, results.Close())
+	})
+
+	t.Run("Batch and statement errors", func(t *testing.T) {
+		m := NewPGXPoolMock()
+		unique := &pgconn.PgError{Code: "23505"}
+		b := m.ExpectBatch()
+		b.ExpectExec("INSERT INTO tags (name) VALUES ($1)").WillReturnError(unique)
+		m.ExpectBatch().WillReturnError(errors.New("conn closed"))
+
+		batch := &pgx.Batch{}
+		batch.Queue("INSERT INTO tags (name) VALUES ($1)", "go")
+		results := m.SendBatch(ctx, batch)
+		_, err := results.Exec()
+		require.Equal(t, unique, err)
+		require.NoError(t, results.Close())
+
+		results = m.SendBatch(ctx, &pgx.Batch{})
+		require.EqualError(t, results.QueryRow().Scan(), "conn closed")
+		require.EqualError(t, results.Close(), "conn closed")
+		require.NoError(t, m.AllExpectationsMet())
+	})
+
+	t.Run("Statements without a response", func(t *testing.T) {
+		m := NewPGXPoolMock()
+		b := m.ExpectBatch()
+		b.ExpectExec("DELETE FROM tags")
+		b.ExpectQuery("SELECT name FROM tags")
+		b.ExpectQueryRow("SELECT count(*) FROM tags")
+		m.ExpectExec("DELETE FROM posts")
+
+		batch := &pgx.Batch{}
+		batch.Queue("DELETE FROM tags")
+		batch.Queue("SELECT name FROM tags")
+		batch.Queue("SELECT count(*) FROM tags")
+		results := m.SendBatch(ctx, batch)
+		_, err := results.Exec()
+		require.EqualError(t, err, "batch statement 0: no response configured for Exec, set one with WillReturnResult or WillReturnError")
+		_, err = results.Query()
+		require.EqualError(t, err, "batch statement 1: no response configured for Query, set one with WillReturnRows or WillReturnError")
+		var count int
+		require.EqualError(t, results.QueryRow().Scan(&count), "batch statement 2: no response configured for QueryRow, set one with WillReturnRow")
+		require.NoError(t, results.Close())
+
+		_, err = m.Exec(ctx, "DELETE FROM posts")
+		require.EqualError(t, err, "no response configured for Exec, set one with WillReturnResult or WillReturnError")
+		require.NoError(t, m.AllExpectationsMet())
+	})
+}
+
+func TestPoolMockTransactionScopes(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Statements inside and outside transactions", func(t *testing.T) {
+		m := NewPGXPoolMock()
+		db, err := octobe.New(postgres.OpenPGXWithPool(m))
+		require.NoError(t, err)
+
+		m.ExpectAcquire()
+		m.ExpectExec("UPDATE counters SET n = n + 1").OutsideTransaction().WillReturnResult(NewResult("UPDATE", 1))
+		m.ExpectRelease()
+		m.ExpectBeginTx().WithOptions(pgx.TxOptions{IsoLevel: pgx.Serializable, AccessMode: pgx.ReadWrite})
+		m.ExpectExec("INSERT INTO orders (id) VALUES ($1)").WithArgs(1).InTransaction().WillReturnResult(NewResult("INSERT", 1))
+		m.ExpectCommit()
+
+		session, err := db.Begin(ctx)
+		require.NoError(t, err)
+		_, err = session.Builder()("UPDATE counters SET n = n + 1").Exec()
+		require.NoError(t, err)
+		require.NoError(t, session.Close())
+
+		err = db.StartTransaction(ctx, func(session octobe.BuilderSession[postgres.Builder]) error {
+			_, err := session.Builder()("INSERT INTO orders (id) VALUES ($1)").Arguments(1).Exec()
+			return err
+		}, postgres.WithPGXTxOptions(postgres.PGXTxOptions{IsoLevel: pgx.Serializable, AccessMode: pgx.ReadWrite}))
+		require.NoError(t, err)
+		require.NoError(t, m.AllExpectationsMet())
+
+		calls := m.Calls()
+		require.Len(t, calls, 6)
+		require.Equal(t, Call{Method: "Exec", Query: "UPDATE counters SET n = n + 1", Args: []any{}}, calls[1])
+		require.Equal(t, 1, calls[4].Tx)
+		require.Equal(t, pgx.Serializable, calls[4].TxOptions.IsoLevel)
+		require.Equal(t, `outside transactions:
+  Acquire
+  Exec "UPDATE counters SET n = n + 1"
+  Release
+transaction 1 (isolation serializable, read write):
+  BeginTx [{serializable read write   }]
+  Exec "INSERT INTO orders (id) VALUES ($1)" [1]
+  Commit
+`, m.TransactionReport())
+	})
+
+	t.Run("Savepoints", func(t *testing.T) {
+		m := NewPGXPoolMock()
+		m.ExpectBegin()
+		m.ExpectBegin()
+		m.ExpectExec("DELETE FROM carts").InTransaction().WillReturnResult(NewResult("DELETE", 1))
+		m.ExpectRollback()
+		m.ExpectCommit()
+
+		tx, err := m.Begin(ctx)
+		require.NoError(t, err)
+		sp, err := tx.Begin(ctx)
+		require.NoError(t, err)
+		_, err = sp.Exec(ctx, "DELETE FROM carts")
+		require.NoError(t, err)
+		require.NoError(t, sp.Rollback(ctx))
+		require.NoError(t, tx.Commit(ctx))
+
+		require.Equal(t, `transaction 1 (default options):
+  Begin
+  savepoint 1: Begin
+  savepoint 1: Exec "DELETE FROM carts"
+  savepoint 1: Rollback
+  Commit
+`, m.TransactionReport())
+	})
+
+	t.Run("Scope mismatches", func(t *testing.T) {
+		m := NewPGXPoolMock()
+		m.ExpectExec("UPDATE accounts").InTransaction()
+		_, err := m.Exec(ctx, "UPDATE accounts")
+		require.ErrorIs(t, err, ErrNoExpectation)
+		require.ErrorContains(t, err, "scope mismatch: expected a call inside a transaction, got one outside")
+
+		m = NewPGXPoolMock()
+		m.ExpectBeginTx()
+		m.ExpectQuery("SELECT 1").OutsideTransaction()
+		tx, err := m.BeginTx(ctx, pgx.TxOptions{})
+		require.NoError(t, err)
+		_, err = tx.Query(ctx, "SELECT 1")
+		require.ErrorContains(t, err, "scope mismatch: expected a call outside a transaction, got one in transaction 1")
+	})
+}
+
+func TestPoolMockScopedSessions(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Concurrent transactions follow their own scripts", func(t *testing.T) {
+		m := NewPGXPoolMock()
+		db, err := octobe.New(postgres.OpenPGXWithPool(m))
+		require.NoError(t, err)
+
+		const workers = 8
+		m.MatchExpectationsInOrder(false)
+		for i := range workers {
+			tx := m.ExpectBeginTx().Scoped()
+			tx.ExpectExec("UPDATE accounts SET balance = balance - $1 WHERE id = $2").WithArgs(10, i).WillReturnResult(NewResult("UPDATE", 1))
+			if i%2 == 0 {
+				tx.ExpectExec("INSERT INTO ledger (account_id) VALUES ($1)").WithArgs(i).WillReturnResult(NewResult("INSERT", 1))
+				tx.ExpectCommit()
+			} else {
+				tx.ExpectExec("INSERT INTO ledger (account_id) VALUES ($1)").WithArgs(i).WillReturnError(UniqueViolation("ledger_account_id_key"))
+				tx.ExpectRollback()
+			}
+		}
+
+		var wg sync.WaitGroup
+		errs := make([]error, workers)
+		for i := range workers {
+			wg.Go(func() {
+				errs[i] = db.StartTransaction(ctx, func(session octobe.BuilderSession[postgres.Builder]) error {
+					if _, err := session.Builder()("UPDATE accounts SET balance = balance - $1 WHERE id = $2").Arguments(10, i).Exec(); err != nil {
+						return err
+					}
+					_, err := session.Builder()("INSERT INTO ledger (account_id) VALUES ($1)").Arguments(i).Exec()
+					return err
+				})
+			})
+		}
+		wg.Wait()
+
+		for i, err := range errs {
+			if i%2 == 0 {
+				require.NoError(t, err)
+			} else {
+				var pgErr *pgconn.PgError
+				require.ErrorAs(t, err, &pgErr)
+				require.Equal(t, "23505", pgErr.Code)
+			}
+		}
+		require.NoError(t, m.AllExpectationsMet())
+		require.Len(t, m.Calls(), workers*4)
+	})
+
+	t.Run("Connections are released after errors", func(t *testing.T) {
+		m := NewPGXPoolMock()
+		db, err := octobe.New(postgres.OpenPGXWithPool(m))
+		require.NoError(t, err)
+
+		m.MatchExpectationsInOrder(false)
+		reader := m.ExpectAcquire().Scoped()
+		reader.ExpectQueryRow("SELECT count(*) FROM jobs").WillReturnRow(NewRow(3))
+		reader.ExpectRelease()
+		writer := m.ExpectAcquire().Scoped()
+		writer.ExpectExec("DELETE FROM jobs WHERE done").WillReturnError(ConnectionLost())
+		writer.ExpectRelease()
+
+		var wg sync.WaitGroup
+		var count int
+		var countErr, deleteErr error
+		wg.Go(func() {
+			session, err := db.Begin(ctx)
+			if err != nil {
+				countErr = err
+				return
+			}
+			defer session.Close()
+			countErr = session.Builder()("SELECT count(*) FROM jobs").QueryRow(&count)
+		})
+		wg.Go(func() {
+			session, err := db.Begin(ctx)
+			if err != nil {
+				deleteErr = err
+				return
+			}
+			defer session.Close()
+			_, deleteErr = session.Builder()("DELETE FROM jobs WHERE done").Exec()
+		})
+		wg.Wait()
+
+		require.NoError(t, countErr)
+		require.Equal(t, 3, count)
+		require.ErrorIs(t, deleteErr, io.ErrUnexpectedEOF)
+		require.NoError(t, m.AllExpectationsMet())
+	})
+
+	t.Run("Savepoints in a scoped transaction", func(t *testing.T) {
+		m := NewPGXPoolMock()
+		tx := m.ExpectBegin().Scoped()
+		tx.ExpectBegin()
+		tx.ExpectExec("DELETE FROM carts").WillReturnResult(NewResult("DELETE", 1))
+		tx.ExpectRollback()
+		tx.ExpectCommit()
+
+		begun, err := m.Begin(ctx)
+		require.NoError(t, err)
+		sp, err := begun.Begin(ctx)
+		require.NoError(t, err)
+		_, err = sp.Exec(ctx, "DELETE FROM carts")
+		require.NoError(t, err)
+		require.NoError(t, sp.Rollback(ctx))
+		require.NoError(t, begun.Commit(ctx))
+		require.NoError(t, m.AllExpectationsMet())
+		require.Equal(t, 1, m.Calls()[2].Savepoint)
+	})
+
+	t.Run("Unmatched first call", func(t *testing.T) {
+		m := NewPGXPoolMock()
+		tx := m.ExpectBeginTx().Scoped()
+		tx.ExpectExec("UPDATE accounts SET balance = 0").WillReturnResult(NewResult("UPDATE", 2))
+		tx.ExpectCommit()
+
+		begun, err := m.BeginTx(ctx, pgx.TxOptions{})
+		require.NoError(t, err)
+		_, err = begun.Exec(ctx, "DELETE FROM accounts")
+		require.ErrorIs(t, err, ErrNoExpectation)
+		require.ErrorContains(t, err, "no unclaimed scoped mock of BeginTx expects Exec with args [DELETE FROM accounts]")
+		require.ErrorContains(t, err, `scoped mock of method BeginTx with query <nil> and args []: no expectation found: next expectation method Exec with query exact "UPDATE accounts SET balance = 0"`)
+
+		_, err = begun.Exec(ctx, "UPDATE accounts SET balance = 0")
+		require.NoError(t, err)
+		err = m.AllExpectationsMet()
+		require.ErrorContains(t, err, "scoped mock of method BeginTx with query <nil> and args []: unfulfilled expectation: method Commit")
+		require.ErrorContains(t, err, `expectations:
+  fulfilled   method BeginTx with query <nil> and args []: called 1 times, expected 1
+    fulfilled   method Exec with query exact "UPDATE accounts SET balance = 0" and args []: called 1 times, expected 1
+    unfulfilled method Commit with query <nil> and args []: called 0 times, expected 1`)
+	})
+}
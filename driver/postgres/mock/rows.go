@@ -0,0 +1,243 @@
+package mock
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// Rows provides a mock implementation of pgx.Rows for testing Query operations.
+// Supports adding rows and controlling iteration behavior, including errors
+// part way through the result set and when the rows are closed.
+//
+// Values and RawValues encode row values with the PostgreSQL text format of
+// each column's data type. Columns without a type set with WithColumnTypes
+// take the type pgx would use for the Go value in the first non-nil row.
+type Rows struct {
+	fields   []pgconn.FieldDescription
+	rows     [][]any
+	pos      int
+	err      error
+	closed   bool
+	errAt    int
+	rowErr   error
+	closeErr error
+	tag      pgconn.CommandTag
+	typeMap  *pgtype.Map
+}
+
+func NewRows(columns []string) *Rows {
+	fields := make([]pgconn.FieldDescription, len(columns))
+	for i, col := range columns {
+		fields[i] = pgconn.FieldDescription{Name: col}
+	}
+	return &Rows{fields: fields, pos: -1}
+}
+
+// AddRow appends a data row with values matching the column count.
+func (r *Rows) AddRow(values ...any) *Rows {
+	if len(values) != len(r.fields) {
+		panic("number of values does not match number of columns")
+	}
+	r.rows = append(r.rows, values)
+	return r
+}
+
+// WithRowError makes iteration fail when advancing to row n, counted from zero:
+// the rows before n are returned, then Next reports false and Err returns err.
+func (r *Rows) WithRowError(n int, err error) *Rows {
+	r.errAt, r.rowErr = n, err
+	return r
+}
+
+// WithCloseError makes Err return err once the rows are closed, either
+// explicitly or by iterating past the last row, unless iteration failed first.
+func (r *Rows) WithCloseError(err error) *Rows {
+	r.closeErr = err
+	return r
+}
+
+// WithCommandTag sets the command tag reported after the rows are read.
+func (r *Rows) WithCommandTag(tag pgconn.CommandTag) *Rows {
+	r.tag = tag
+	return r
+}
+
+// WithColumnTypes sets the data type OID of each column, such as
+// pgtype.Int4OID or pgtype.TimestamptzOID, in column order.
+func (r *Rows) WithColumnTypes(oids ...uint32) *Rows {
+	if len(oids) != len(r.fields) {
+		panic("number of column types does not match number of columns")
+	}
+	fields := make([]pgconn.FieldDescription, len(r.fields))
+	copy(fields, r.fields)
+	for i, oid := range oids {
+		fields[i].DataTypeOID = oid
+	}
+	r.fields = fields
+	return r
+}
+
+// clone returns a copy of the rows positioned before the first row.
+func (r *Rows) clone() *Rows {
+	return &Rows{
+		fields:   r.fields,
+		rows:     r.rows,
+		pos:      -1,
+		errAt:    r.errAt,
+		rowErr:   r.rowErr,
+		closeErr: r.closeErr,
+		tag:      r.tag,
+	}
+}
+
+// Close closes the rows. Like pgx, a configured close error is reported by Err.
+func (r *Rows) Close() {
+	if r.closed {
+		return
+	}
+	r.closed = true
+	if r.err == nil {
+		r.err = r.closeErr
+	}
+}
+
+func (r *Rows) Err() error { return r.err }
+
+func (r *Rows) CommandTag() pgconn.CommandTag { return r.tag }
+
+// FieldDescriptions returns the columns with their configured or inferred data type OIDs.
+func (r *Rows) FieldDescriptions() []pgconn.FieldDescription {
+	fields := make([]pgconn.FieldDescription, len(r.fields))
+	for i, f := range r.fields {
+		f.DataTypeOID = r.columnType(i)
+		fields[i] = f
+	}
+	return fields
+}
+
+// Next advances to the next row. It closes the rows after the last row or when
+// a configured row error is reached.
+func (r *Rows) Next() bool {
+	if r.closed {
+		return false
+	}
+	r.pos++
+	if r.rowErr != nil && r.pos == r.errAt {
+		r.err = r.rowErr
+		r.Close()
+		return false
+	}
+	if r.pos >= len(r.rows) {
+		r.Close()
+		return false
+	}
+	return true
+}
+
+// Scan copies current row values into destination pointers using reflection.
+func (r *Rows) Scan(dest ...any) error {
+	if r.err != nil {
+		return r.err
+	}
+	if r.pos < 0 || r.pos >= len(r.rows) {
+		return io.EOF
+	}
+	if r.closed {
+		return errors.New("rows is closed")
+	}
+	return scanValues(r.rows[r.pos], dest)
+}
+
+// Values returns the current row decoded the way pgx decodes each column's
+// data type, for example int64 for an int8 column.
+func (r *Rows) Values() ([]any, error) {
+	if r.pos < 0 || r.pos >= len(r.rows) {
+		return nil, io.EOF
+	}
+
+	m := r.types()
+	raw := r.RawValues()
+	values := make([]any, len(raw))
+	for i, buf := range raw {
+		if buf == nil {
+			continue
+		}
+		oid := r.columnType(i)
+		t, ok := m.TypeForOID(oid)
+		if !ok {
+			values[i] = string(buf)
+			continue
+		}
+		v, err := t.Codec.DecodeValue(m, oid, pgtype.TextFormatCode, buf)
+		if err != nil {
+			return nil, fmt.Errorf("decode column %s: %w", r.fields[i].Name, err)
+		}
+		values[i] = v
+	}
+	return values, nil
+}
+
+// RawValues returns the current row encoded in the text format of each column's data type.
+func (r *Rows) RawValues() [][]byte {
+	if r.pos < 0 || r.pos >= len(r.rows) {
+		return nil
+	}
+
+	rawValues := make([][]byte, len(r.rows[r.pos]))
+	for i, val := range r.rows[r.pos] {
+		rawValues[i] = r.encode(i, val)
+	}
+	return rawValues
+}
+
+// encode returns val in the text format of column i, falling back to its Go
+// formatting when the value cannot be encoded as that type.
+func (r *Rows) encode(i int, val any) []byte {
+	if val == nil {
+		return nil
+	}
+	if oid := r.columnType(i); oid != 0 {
+		buf, err := r.types().Encode(oid, pgtype.TextFormatCode, val, nil)
+		if err == nil && buf != nil {
+			return buf
+		}
+	}
+	return fmt.Appendf(nil, "%v", val)
+}
+
+// columnType returns the configured OID of column i, or the OID pgx maps the
+// first non-nil value in the column to.
+func (r *Rows) columnType(i int) uint32 {
+	if oid := r.fields[i].DataTypeOID; oid != 0 {
+		return oid
+	}
+	for _, row := range r.rows {
+		if row[i] == nil {
+			continue
+		}
+		if t, ok := r.types().TypeForValue(row[i]); ok {
+			return t.OID
+		}
+		return 0
+	}
+	return 0
+}
+
+func (r *Rows) types() *pgtype.Map {
+	if r.typeMap == nil {
+		r.typeMap = pgtype.NewMap()
+	}
+	return r.typeMap
+}
+
+func (r *Rows) Conn() *pgx.Conn { return nil }
+
+// GetRowsForTesting exposes internal row data for test verification.
+func (r *Rows) GetRowsForTesting() [][]any {
+	return r.rows
+}
@@ -0,0 +1,174 @@
+package mock
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/Kansuler/octobe/v3/driver/postgres"
+	"github.com/Kansuler/octobe/v3/internal/mockmatch"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// ScopedMock holds the expectations of a single session: a connection acquired
+// from a pool mock, or a transaction begun on a mock. It is created with the
+// Scoped method of an Acquire, Begin or BeginTx expectation, and the calls on
+// the session it opens match only the expectations of the scoped mock, in the
+// order they were added, including the final Release, Commit or Rollback.
+//
+// Sessions claim a scoped mock on their first call, taking the first unclaimed
+// one whose next expectation matches it. Concurrent sessions therefore follow
+// their own scripts whatever order they are opened in, as long as each script
+// starts with a distinct call. A scoped expectation opens one session; add an
+// expectation for each session the code under test opens.
+//
+// AllExpectationsMet on the mock verifies the scoped mocks too.
+type ScopedMock struct {
+	expectations expectationSet
+	// claimed is guarded by the mutex of the expectation set the owner was added to.
+	claimed bool
+}
+
+func (e *basicExpectation) newScoped() *ScopedMock {
+	if e.scoped == nil {
+		e.scoped = &ScopedMock{}
+	}
+	return e.scoped
+}
+
+// ExpectExec configures an expectation for an Exec operation with the specified query.
+func (m *ScopedMock) ExpectExec(query string) *ExecExpectation {
+	e := &ExecExpectation{
+		basicExpectation: basicExpectation{
+			statement: mockmatch.Statement{Method: "Exec", Query: query, Mode: mockmatch.Exact},
+		},
+	}
+	m.expectations.add(e)
+	return e
+}
+
+// ExpectQuery configures an expectation for a Query operation with the specified query.
+func (m *ScopedMock) ExpectQuery(query string) *QueryExpectation {
+	e := &QueryExpectation{
+		basicExpectation: basicExpectation{
+			statement: mockmatch.Statement{Method: "Query", Query: query, Mode: mockmatch.Exact},
+		},
+	}
+	m.expectations.add(e)
+	return e
+}
+
+// ExpectQueryRow configures an expectation for a QueryRow operation with the specified query.
+func (m *ScopedMock) ExpectQueryRow(query string) *QueryRowExpectation {
+	e := &QueryRowExpectation{
+		basicExpectation: basicExpectation{
+			statement: mockmatch.Statement{Method: "QueryRow", Query: query, Mode: mockmatch.Exact},
+		},
+	}
+	m.expectations.add(e)
+	return e
+}
+
+// ExpectBatch configures an expectation for a SendBatch call on a transaction.
+func (m *ScopedMock) ExpectBatch() *BatchExpectation {
+	e := &BatchExpectation{basicExpectation: basicExpectation{statement: mockmatch.Statement{Method: "SendBatch"}}}
+	m.expectations.add(e)
+	return e
+}
+
+// ExpectBegin configures an expectation for a savepoint begun on a transaction.
+func (m *ScopedMock) ExpectBegin() *BeginExpectation {
+	e := &BeginExpectation{basicExpectation: basicExpectation{statement: mockmatch.Statement{Method: "Begin"}}}
+	m.expectations.add(e)
+	return e
+}
+
+// ExpectCommit configures an expectation for committing a transaction.
+func (m *ScopedMock) ExpectCommit() *CommitExpectation {
+	e := &CommitExpectation{basicExpectation: basicExpectation{statement: mockmatch.Statement{Method: "Commit"}}}
+	m.expectations.add(e)
+	return e
+}
+
+// ExpectRollback configures an expectation for rolling back a transaction.
+func (m *ScopedMock) ExpectRollback() *RollbackExpectation {
+	e := &RollbackExpectation{basicExpectation: basicExpectation{statement: mockmatch.Statement{Method: "Rollback"}}}
+	m.expectations.add(e)
+	return e
+}
+
+// ExpectRelease configures an expectation for releasing an acquired connection.
+func (m *ScopedMock) ExpectRelease() *ReleaseExpectation {
+	e := &ReleaseExpectation{basicExpectation: basicExpectation{statement: mockmatch.Statement{Method: "Release"}}}
+	m.expectations.add(e)
+	return e
+}
+
+// claim binds a session opened by a scoped expectation to one of the scoped
+// mocks of the expectation set it was opened on. The session is bound on its
+// first call.
+type claim struct {
+	parent *expectationSet
+	// method and args are those of the call that opened the session.
+	method string
+	args   []any
+
+	mu  sync.Mutex
+	set *expectationSet
+}
+
+// target returns the expectation set of the claimed scoped mock, claiming
+// one for a first call made in scope sc.
+func (c *claim) target(sc scope, method string, args []any) (*expectationSet, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.set == nil {
+		set, err := c.parent.claim(c.method, c.args, sc, method, args)
+		if err != nil {
+			return nil, err
+		}
+		c.set = set
+	}
+	return c.set, nil
+}
+
+// Conn is the connection AcquireSession returns for a scoped Acquire
+// expectation. Its calls match the expectations of a ScopedMock.
+type Conn struct {
+	pool  *PGXPoolMock
+	claim *claim
+}
+
+var _ postgres.PGXPoolSessionConn = (*Conn)(nil)
+
+func (c *Conn) find(ctx context.Context, method string, args ...any) ([]any, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, &contextAlreadyDoneError{err: err}
+	}
+	set, err := c.claim.target(scope{}, method, args)
+	if err != nil {
+		return nil, err
+	}
+	return set.find(ctx, scope{}, method, args...)
+}
+
+// Release matches a Release expectation. Like the pool's Release, an
+// unexpected call is reported by AllExpectationsMet.
+func (c *Conn) Release() {
+	if _, err := c.find(context.Background(), "Release"); err != nil {
+		c.pool.recordUnexpectedCall(fmt.Errorf("unexpected Release: %w", err))
+	}
+}
+
+func (c *Conn) Exec(ctx context.Context, query string, args ...any) (pgconn.CommandTag, error) {
+	return execResult(c.find(ctx, "Exec", append([]any{query}, args...)...))
+}
+
+func (c *Conn) Query(ctx context.Context, query string, args ...any) (pgx.Rows, error) {
+	return queryResult(c.find(ctx, "Query", append([]any{query}, args...)...))
+}
+
+func (c *Conn) QueryRow(ctx context.Context, query string, args ...any) pgx.Row {
+	return queryRowResult(c.find(ctx, "QueryRow", append([]any{query}, args...)...))
+}
@@ -0,0 +1,107 @@
+package mock
+
+import (
+	"fmt"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/Kansuler/octobe/v3"
+	"github.com/Kansuler/octobe/v3/driver/postgres"
+)
+
+// NewPGXMockT creates a mock connection bound to t. Calls that match no
+// expectation fail the test right away with the line they were made from, and
+// the expectations are verified with AllExpectationsMet when the test ends.
+func NewPGXMockT(t testing.TB) *PGXMock {
+	t.Helper()
+	m := NewPGXMock()
+	watch(t, &m.expectations, m.AllExpectationsMet)
+	return m
+}
+
+// NewPGXPoolMockT creates a mock pool bound to t, like NewPGXMockT.
+func NewPGXPoolMockT(t testing.TB) *PGXPoolMock {
+	t.Helper()
+	m := NewPGXPoolMock()
+	watch(t, &m.expectations, m.AllExpectationsMet)
+	return m
+}
+
+// OpenPGX returns a driver on a mock connection bound to t, and the mock to
+// configure expectations on:
+//
+//	db, m := mock.OpenPGX(t)
+//	m.ExpectExec("DELETE FROM sessions").WillReturnResult(mock.NewResult("DELETE", 1))
+func OpenPGX(t testing.TB) (postgres.PGXDriver, *PGXMock) {
+	t.Helper()
+	m := NewPGXMockT(t)
+	return open(t, postgres.OpenPGXWithConn(m)), m
+}
+
+// OpenPGXPool returns a driver on a mock pool bound to t, and the mock to
+// configure expectations on.
+func OpenPGXPool(t testing.TB) (postgres.PGXPoolDriver, *PGXPoolMock) {
+	t.Helper()
+	m := NewPGXPoolMockT(t)
+	return open(t, postgres.OpenPGXWithPool(m)), m
+}
+
+func open[DRIVER any](t testing.TB, init octobe.Open[DRIVER, postgres.Config, postgres.Builder]) octobe.Driver[DRIVER, postgres.Config, postgres.Builder] {
+	t.Helper()
+	db, err := octobe.New(init)
+	if err != nil {
+		t.Fatalf("open driver: %v", err)
+	}
+	return db
+}
+
+func watch(t testing.TB, s *expectationSet, verify func() error) {
+	s.onUnexpected = func(err error) {
+		t.Errorf("unexpected call at %s: %v", callerLine(), err)
+	}
+	t.Cleanup(func() {
+		if err := verify(); err != nil {
+			t.Error(err)
+		}
+	})
+}
+
+// internalPackages are skipped when looking for the line that made a call,
+// since calls reach the mock through octobe and its postgres driver.
+var internalPackages = []string{
+	"github.com/Kansuler/octobe/v3.",
+	"github.com/Kansuler/octobe/v3/driver/postgres.",
+	"github.com/Kansuler/octobe/v3/driver/postgres/mock.",
+	"github.com/jackc/pgx/",
+}
+
+// callerLine returns the file and line of the first caller outside octobe,
+// its postgres driver, this package and pgx.
+func callerLine() string {
+	pcs := make([]uintptr, 64)
+	frames := runtime.CallersFrames(pcs[:runtime.Callers(2, pcs)])
+	for {
+		f, more := frames.Next()
+		if !internal(f) {
+			return fmt.Sprintf("%s:%d", filepath.Base(f.File), f.Line)
+		}
+		if !more {
+			return "unknown line"
+		}
+	}
+}
+
+func internal(f runtime.Frame) bool {
+	// Tests of the listed packages are callers too.
+	if strings.HasSuffix(f.File, "_test.go") {
+		return false
+	}
+	for _, prefix := range internalPackages {
+		if strings.HasPrefix(f.Function, prefix) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,102 @@
+package mock
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"testing"
+
+	"github.com/Kansuler/octobe/v3"
+	"github.com/Kansuler/octobe/v3/driver/postgres"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingT captures the failures and cleanups of a test bound to a mock.
+type recordingT struct {
+	testing.TB
+	errors   []string
+	cleanups []func()
+}
+
+func (t *recordingT) Helper() {}
+
+func (t *recordingT) Errorf(format string, args ...any) {
+	t.errors = append(t.errors, fmt.Sprintf(format, args...))
+}
+
+func (t *recordingT) Error(args ...any) {
+	t.errors = append(t.errors, fmt.Sprint(args...))
+}
+
+func (t *recordingT) Cleanup(fn func()) {
+	t.cleanups = append(t.cleanups, fn)
+}
+
+func (t *recordingT) finish() {
+	for i := len(t.cleanups) - 1; i >= 0; i-- {
+		t.cleanups[i]()
+	}
+}
+
+func TestMocksBoundToT(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Unexpected calls fail with the caller's line", func(t *testing.T) {
+		rt := &recordingT{}
+		db, m := OpenPGX(rt)
+		m.ExpectBeginTx()
+		m.ExpectExec("DELETE FROM carts").Maybe()
+		m.ExpectRollback()
+
+		err := db.StartTransaction(ctx, func(session octobe.BuilderSession[postgres.Builder]) error {
+			_, err := session.Builder()("DELETE FROM orders").Exec()
+			return err
+		})
+		_, _, line, _ := runtime.Caller(0)
+		require.ErrorIs(t, err, ErrNoExpectation)
+		require.Len(t, rt.errors, 1)
+		require.Contains(t, rt.errors[0], fmt.Sprintf("unexpected call at testing_test.go:%d: no expectation found", line-3))
+
+		rt.finish()
+		require.Len(t, rt.errors, 1)
+	})
+
+	t.Run("Expectations are verified on cleanup", func(t *testing.T) {
+		rt := &recordingT{}
+		m := NewPGXMockT(rt)
+		m.ExpectPing()
+		m.ExpectClose()
+		require.NoError(t, m.Ping(ctx))
+		require.Empty(t, rt.errors)
+
+		rt.finish()
+		require.Len(t, rt.errors, 1)
+		require.Contains(t, rt.errors[0], "unfulfilled expectation: method Close")
+	})
+
+	t.Run("Pool driver", func(t *testing.T) {
+		rt := &recordingT{}
+		db, m := OpenPGXPool(rt)
+		m.ExpectAcquire()
+		m.ExpectExec("UPDATE counters SET n = n + 1").WillReturnResult(NewResult("UPDATE", 1))
+		m.ExpectRelease()
+
+		session, err := db.Begin(ctx)
+		require.NoError(t, err)
+		_, err = session.Builder()("UPDATE counters SET n = n + 1").Exec()
+		require.NoError(t, err)
+		require.NoError(t, session.Close())
+
+		rt.finish()
+		require.Empty(t, rt.errors)
+	})
+
+	t.Run("Pool Release without expectation", func(t *testing.T) {
+		rt := &recordingT{}
+		m := NewPGXPoolMockT(rt)
+		m.Release()
+		_, _, line, _ := runtime.Caller(0)
+		require.Len(t, rt.errors, 1)
+		require.Contains(t, rt.errors[0], fmt.Sprintf("unexpected call at testing_test.go:%d", line-1))
+	})
+}
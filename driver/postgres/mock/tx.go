@@ -0,0 +1,297 @@
+package mock
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+type txScope int
+
+const (
+	txScopeAny txScope = iota
+	txScopeInside
+	txScopeOutside
+)
+
+// scope is the session context a call runs in.
+type scope struct {
+	tx        int // 0 outside a transaction
+	savepoint int // 0 outside a savepoint
+	options   pgx.TxOptions
+}
+
+func (sc scope) String() string {
+	if sc.tx == 0 {
+		return "no transaction"
+	}
+	if sc.savepoint != 0 {
+		return fmt.Sprintf("transaction %d savepoint %d", sc.tx, sc.savepoint)
+	}
+	return fmt.Sprintf("transaction %d", sc.tx)
+}
+
+// Call is a call matched by a mock, with the transaction it ran in.
+type Call struct {
+	Method string
+	// Query and Args are the statement and its arguments, for calls that take a query.
+	Query string
+	Args  []any
+	// Tx numbers the transactions in the order they began, from 1. It is 0 for
+	// calls made outside a transaction.
+	Tx int
+	// Savepoint numbers the savepoints of a transaction, from 1. It is 0 for
+	// calls made on the transaction itself.
+	Savepoint int
+	// TxOptions are the options the transaction began with.
+	TxOptions pgx.TxOptions
+}
+
+func (c Call) String() string {
+	var b strings.Builder
+	b.WriteString(c.Method)
+	if c.Query != "" {
+		fmt.Fprintf(&b, " %q", c.Query)
+	}
+	if len(c.Args) > 0 {
+		fmt.Fprintf(&b, " %v", c.Args)
+	}
+	return b.String()
+}
+
+// transactionReport lists the calls grouped by the transaction they ran in.
+func transactionReport(calls []Call) string {
+	var order []int
+	byTx := map[int][]Call{}
+	for _, c := range calls {
+		if _, ok := byTx[c.Tx]; !ok {
+			order = append(order, c.Tx)
+		}
+		byTx[c.Tx] = append(byTx[c.Tx], c)
+	}
+
+	var b strings.Builder
+	for _, tx := range order {
+		group := byTx[tx]
+		if tx == 0 {
+			b.WriteString("outside transactions:\n")
+		} else {
+			fmt.Fprintf(&b, "transaction %d (%s):\n", tx, formatTxOptions(group[0].TxOptions))
+		}
+		for _, c := range group {
+			if c.Savepoint != 0 {
+				fmt.Fprintf(&b, "  savepoint %d: %s\n", c.Savepoint, c)
+			} else {
+				fmt.Fprintf(&b, "  %s\n", c)
+			}
+		}
+	}
+	return b.String()
+}
+
+func formatTxOptions(opts pgx.TxOptions) string {
+	var parts []string
+	if opts.IsoLevel != "" {
+		parts = append(parts, "isolation "+string(opts.IsoLevel))
+	}
+	if opts.AccessMode != "" {
+		parts = append(parts, string(opts.AccessMode))
+	}
+	if opts.DeferrableMode != "" {
+		parts = append(parts, string(opts.DeferrableMode))
+	}
+	if len(parts) == 0 {
+		return "default options"
+	}
+	return strings.Join(parts, ", ")
+}
+
+// Tx is the pgx.Tx returned by the mocks' Begin and BeginTx. Calls on it are
+// matched against the expectations of the mock that began it, in the scope of
+// its transaction, so expectations can require InTransaction. Begin on a Tx
+// opens a savepoint, as in pgx.
+//
+// A Tx begun by a scoped expectation calls into a ScopedMock instead, which it
+// claims on its first call.
+type Tx struct {
+	set   *expectationSet
+	scope scope
+	claim *claim
+}
+
+var _ pgx.Tx = (*Tx)(nil)
+
+// find matches a call against the expectations of the transaction.
+func (t *Tx) find(ctx context.Context, method string, args ...any) ([]any, error) {
+	set, err := t.target(ctx, method, args)
+	if err != nil {
+		return nil, err
+	}
+	return set.find(ctx, t.scope, method, args...)
+}
+
+func (t *Tx) target(ctx context.Context, method string, args []any) (*expectationSet, error) {
+	if t.claim == nil {
+		return t.set, nil
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, &contextAlreadyDoneError{err: err}
+	}
+	return t.claim.target(t.scope, method, args)
+}
+
+func (t *Tx) Begin(ctx context.Context) (pgx.Tx, error) {
+	set, err := t.target(ctx, "Begin", nil)
+	if err != nil {
+		return nil, err
+	}
+	return set.beginTx(ctx, t.scope, t.scope.options, "Begin")
+}
+
+func (t *Tx) Commit(ctx context.Context) error {
+	return errorResult(t.find(ctx, "Commit"))
+}
+
+func (t *Tx) Rollback(ctx context.Context) error {
+	return errorResult(t.find(ctx, "Rollback"))
+}
+
+func (t *Tx) Exec(ctx context.Context, query string, args ...any) (pgconn.CommandTag, error) {
+	return execResult(t.find(ctx, "Exec", append([]any{query}, args...)...))
+}
+
+func (t *Tx) Query(ctx context.Context, query string, args ...any) (pgx.Rows, error) {
+	return queryResult(t.find(ctx, "Query", append([]any{query}, args...)...))
+}
+
+func (t *Tx) QueryRow(ctx context.Context, query string, args ...any) pgx.Row {
+	return queryRowResult(t.find(ctx, "QueryRow", append([]any{query}, args...)...))
+}
+
+func (t *Tx) Prepare(ctx context.Context, name, sql string) (*pgconn.StatementDescription, error) {
+	return prepareResult(name, sql)(t.find(ctx, "Prepare", name, sql))
+}
+
+func (t *Tx) CopyFrom(ctx context.Context, tableName pgx.Identifier, columnNames []string, rowSrc pgx.CopyFromSource) (int64, error) {
+	return copyFromResult(t.find(ctx, "CopyFrom", tableName, columnNames))
+}
+
+func (t *Tx) SendBatch(ctx context.Context, batch *pgx.Batch) pgx.BatchResults {
+	return batchResult(t.find(ctx, "SendBatch", batch))
+}
+
+func (t *Tx) LargeObjects() pgx.LargeObjects {
+	panic("not implemented")
+}
+
+func (t *Tx) Conn() *pgx.Conn { return nil }
+
+// The helpers below turn the response of a matched expectation into the
+// results of the pgx method it stands for.
+
+func errorResult(ret []any, err error) error {
+	if err != nil {
+		return err
+	}
+	if len(ret) > 0 && ret[0] != nil {
+		return ret[0].(error)
+	}
+	return nil
+}
+
+func beginResult(ret []any, err error) error {
+	if err != nil {
+		return err
+	}
+	if len(ret) > 1 && ret[1] != nil {
+		return ret[1].(error)
+	}
+	return nil
+}
+
+// noResponse is the error of a call whose expectation was not given a response.
+func noResponse(method string) error {
+	setters := map[string]string{
+		"Exec":     "WillReturnResult or WillReturnError",
+		"Query":    "WillReturnRows or WillReturnError",
+		"QueryRow": "WillReturnRow",
+	}[method]
+	return fmt.Errorf("no response configured for %s, set one with %s", method, setters)
+}
+
+func execResult(ret []any, err error) (pgconn.CommandTag, error) {
+	if err != nil {
+		return pgconn.CommandTag{}, err
+	}
+	if len(ret) < 2 {
+		return pgconn.CommandTag{}, noResponse("Exec")
+	}
+	if ret[1] != nil {
+		return pgconn.CommandTag{}, ret[1].(error)
+	}
+	return ret[0].(pgconn.CommandTag), nil
+}
+
+func queryResult(ret []any, err error) (pgx.Rows, error) {
+	if err != nil {
+		return nil, err
+	}
+	if len(ret) < 2 {
+		return nil, noResponse("Query")
+	}
+	if ret[1] != nil {
+		return nil, ret[1].(error)
+	}
+	if ret[0] == nil {
+		return nil, nil
+	}
+	return ret[0].(pgx.Rows), nil
+}
+
+func queryRowResult(ret []any, err error) pgx.Row {
+	if err != nil {
+		return &Row{err: err}
+	}
+	if len(ret) == 0 || ret[0] == nil {
+		return &Row{err: noResponse("QueryRow")}
+	}
+	return ret[0].(pgx.Row)
+}
+
+func prepareResult(name, sql string) func([]any, error) (*pgconn.StatementDescription, error) {
+	return func(ret []any, err error) (*pgconn.StatementDescription, error) {
+		if err != nil {
+			return nil, err
+		}
+		if len(ret) > 1 && ret[1] != nil {
+			return nil, ret[1].(error)
+		}
+		if len(ret) > 0 && ret[0] != nil {
+			return ret[0].(*pgconn.StatementDescription), nil
+		}
+		return &pgconn.StatementDescription{Name: name, SQL: sql}, nil
+	}
+}
+
+func copyFromResult(ret []any, err error) (int64, error) {
+	if err != nil {
+		return 0, err
+	}
+	if len(ret) > 1 && ret[1] != nil {
+		return 0, ret[1].(error)
+	}
+	if len(ret) > 0 {
+		return ret[0].(int64), nil
+	}
+	return 0, nil
+}
+
+func batchResult(ret []any, err error) pgx.BatchResults {
+	if err != nil {
+		return &BatchResults{err: err}
+	}
+	return ret[0].(*BatchResults)
+}
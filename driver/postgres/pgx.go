@@ -5,6 +5,7 @@ import (
 	"errors"
 
 	"github.com/Kansuler/octobe/v3"
+	"github.com/Kansuler/octobe/v3/internal/stmtctx"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgconn"
 )
@@ -38,7 +39,12 @@ var _ PGXDriver = &pgxConn{}
 // OpenPGX creates a pgx connection driver from a DSN string.
 func OpenPGX(ctx context.Context, dsn string) PGXOpen {
 	return func() (PGXDriver, error) {
-		conn, err := pgx.Connect(ctx, dsn)
+		cfg, err := pgx.ParseConfig(dsn)
+		if err != nil {
+			return nil, err
+		}
+		cancelWithRequest(&cfg.Config)
+		conn, err := pgx.ConnectConfig(ctx, cfg)
 		if err != nil {
 			return nil, err
 		}
@@ -57,7 +63,12 @@ type ParseConfigOptions struct {
 // OpenPGXWithOptions creates a pgx connection driver with custom parse options.
 func OpenPGXWithOptions(ctx context.Context, dsn string, options ParseConfigOptions) PGXOpen {
 	return func() (PGXDriver, error) {
-		conn, err := pgx.ConnectWithOptions(ctx, dsn, pgx.ParseConfigOptions{ParseConfigOptions: options.ParseConfigOptions})
+		cfg, err := pgx.ParseConfigWithOptions(dsn, pgx.ParseConfigOptions{ParseConfigOptions: options.ParseConfigOptions})
+		if err != nil {
+			return nil, err
+		}
+		cancelWithRequest(&cfg.Config)
+		conn, err := pgx.ConnectConfig(ctx, cfg)
 		if err != nil {
 			return nil, err
 		}
@@ -69,6 +80,10 @@ func OpenPGXWithOptions(ctx context.Context, dsn string, options ParseConfigOpti
 }
 
 // OpenPGXWithConn creates a driver from an existing pgx connection.
+//
+// Unlike OpenPGX, it keeps the way the connection handles a statement whose
+// context ends. By default pgx closes the connection, which leaves the driver
+// without one; see ContextSegment for how to keep it open.
 func OpenPGXWithConn(c PGXConn) PGXOpen {
 	return func() (PGXDriver, error) {
 		if c == nil {
@@ -151,6 +166,10 @@ type pgxSession struct {
 	d         *pgxConn
 	committed bool
 	closed    bool
+
+	// aborted holds the error of a statement canceled by its own context,
+	// which aborted the transaction.
+	aborted error
 }
 
 var _ octobe.Session[Builder] = &pgxSession{}
@@ -167,6 +186,9 @@ func (s *pgxSession) Commit() error {
 	if s.closed {
 		return errors.New("cannot commit a session that has already been closed")
 	}
+	if s.aborted != nil {
+		return stmtctx.Aborted(s.aborted)
+	}
 	err := s.tx.Commit(s.ctx)
 	s.committed = true
 	if err == nil {
@@ -218,10 +240,11 @@ type pgxSegment struct {
 	query   string
 	args    []any
 	used    bool
+	ctx     context.Context
 	session *pgxSession
 }
 
-var _ Segment = &pgxSegment{}
+var _ ContextSegment = &pgxSegment{}
 
 func (s *pgxSegment) use() {
 	s.used = true
@@ -232,6 +255,9 @@ func (s *pgxSegment) activeSession() (*pgxSession, error) {
 	if s.session == nil || s.session.closed {
 		return nil, errors.New("session is closed")
 	}
+	if s.session.aborted != nil {
+		return nil, stmtctx.Aborted(s.session.aborted)
+	}
 	return s.session, nil
 }
 
@@ -241,8 +267,24 @@ func (s *pgxSegment) Arguments(args ...any) Segment {
 	return s
 }
 
+// WithContext sets a context for this statement alone, used together with the session context.
+func (s *pgxSegment) WithContext(ctx context.Context) Segment {
+	s.ctx = ctx
+	return s
+}
+
+// finish wraps the error of a statement canceled by the segment context, which
+// aborts the transaction of the session.
+func (s *pgxSegment) finish(session *pgxSession, err error) error {
+	err = stmtctx.Err(session.ctx, s.ctx, err, queryCanceled)
+	if session.tx != nil && errors.Is(err, octobe.ErrStatementCanceled) {
+		session.aborted = err
+	}
+	return err
+}
+
 // Exec executes the query and returns the number of affected rows.
-func (s *pgxSegment) Exec() (ExecResult, error) {
+func (s *pgxSegment) Exec() (_ ExecResult, err error) {
 	if s.used {
 		return ExecResult{}, octobe.ErrAlreadyUsed
 	}
@@ -251,8 +293,13 @@ func (s *pgxSegment) Exec() (ExecResult, error) {
 	if err != nil {
 		return ExecResult{}, err
 	}
+	ctx, cancel := stmtctx.Merge(session.ctx, s.ctx)
+	defer cancel()
+	defer func() {
+		err = s.finish(session, err)
+	}()
 	if session.tx == nil {
-		res, err := session.d.conn.Exec(session.ctx, s.query, s.args...)
+		res, err := session.d.conn.Exec(ctx, s.query, s.args...)
 		if err != nil {
 			return ExecResult{}, err
 		}
@@ -262,7 +309,7 @@ func (s *pgxSegment) Exec() (ExecResult, error) {
 		}, nil
 	}
 
-	res, err := session.tx.Exec(session.ctx, s.query, s.args...)
+	res, err := session.tx.Exec(ctx, s.query, s.args...)
 	if err != nil {
 		return ExecResult{}, err
 	}
@@ -272,7 +319,7 @@ func (s *pgxSegment) Exec() (ExecResult, error) {
 }
 
 // QueryRow executes the query expecting exactly one row and scans into dest.
-func (s *pgxSegment) QueryRow(dest ...any) error {
+func (s *pgxSegment) QueryRow(dest ...any) (err error) {
 	if s.used {
 		return octobe.ErrAlreadyUsed
 	}
@@ -281,14 +328,19 @@ func (s *pgxSegment) QueryRow(dest ...any) error {
 	if err != nil {
 		return err
 	}
+	ctx, cancel := stmtctx.Merge(session.ctx, s.ctx)
+	defer cancel()
+	defer func() {
+		err = s.finish(session, err)
+	}()
 	if session.tx == nil {
-		return session.d.conn.QueryRow(session.ctx, s.query, s.args...).Scan(dest...)
+		return session.d.conn.QueryRow(ctx, s.query, s.args...).Scan(dest...)
 	}
-	return session.tx.QueryRow(session.ctx, s.query, s.args...).Scan(dest...)
+	return session.tx.QueryRow(ctx, s.query, s.args...).Scan(dest...)
 }
 
 // Query executes the query and calls cb for each row in the result set.
-func (s *pgxSegment) Query(cb func(Rows) error) error {
+func (s *pgxSegment) Query(cb func(Rows) error) (err error) {
 	if s.used {
 		return octobe.ErrAlreadyUsed
 	}
@@ -298,15 +350,20 @@ func (s *pgxSegment) Query(cb func(Rows) error) error {
 	if err != nil {
 		return err
 	}
+	ctx, cancel := stmtctx.Merge(session.ctx, s.ctx)
+	defer cancel()
+	defer func() {
+		err = s.finish(session, err)
+	}()
 
 	var rows pgx.Rows
 	if session.tx == nil {
-		rows, err = session.d.conn.Query(session.ctx, s.query, s.args...)
+		rows, err = session.d.conn.Query(ctx, s.query, s.args...)
 		if err != nil {
 			return err
 		}
 	} else {
-		rows, err = session.tx.Query(session.ctx, s.query, s.args...)
+		rows, err = session.tx.Query(ctx, s.query, s.args...)
 		if err != nil {
 			return err
 		}
@@ -4,11 +4,14 @@ import (
 	"context"
 	"errors"
 	"testing"
+	"time"
 
 	"github.com/Kansuler/octobe/v3"
 	"github.com/Kansuler/octobe/v3/driver/postgres"
 	"github.com/Kansuler/octobe/v3/driver/postgres/mock"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestPGXWithTxInsideStartTransaction(t *testing.T) {
@@ -792,3 +795,101 @@ func TestSegmentQueryError(t *testing.T) {
 		assert.NoError(t, m.AllExpectationsMet())
 	})
 }
+
+func TestSegmentWithContext(t *testing.T) {
+	const slow = "SELECT pg_sleep(1)"
+
+	t.Run("statement deadline aborts the transaction", func(t *testing.T) {
+		m := mock.NewPGXMock()
+		m.ExpectBeginTx()
+		m.ExpectExec(slow).WillDelayFor(time.Minute).WillReturnResult(mock.NewResult("SELECT", 1))
+		m.ExpectRollback()
+
+		ob, err := octobe.New(postgres.OpenPGXWithConn(m))
+		require.NoError(t, err)
+		ctx := context.Background()
+		session, err := ob.BeginTx(ctx)
+		require.NoError(t, err)
+
+		stmtCtx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+		defer cancel()
+		_, err = session.Builder()(slow).(postgres.ContextSegment).WithContext(stmtCtx).Exec()
+		require.ErrorIs(t, err, octobe.ErrStatementCanceled)
+		require.ErrorIs(t, err, context.DeadlineExceeded)
+
+		var n int
+		err = session.Builder()("SELECT 1").QueryRow(&n)
+		require.ErrorIs(t, err, octobe.ErrStatementCanceled)
+		require.ErrorContains(t, err, "transaction aborted by an earlier statement")
+		require.ErrorIs(t, session.Commit(), octobe.ErrStatementCanceled)
+
+		require.NoError(t, session.Rollback())
+		require.NoError(t, m.AllExpectationsMet())
+	})
+
+	t.Run("statement canceled by the server", func(t *testing.T) {
+		// With a cancel request, PostgreSQL ends the statement with
+		// query_canceled instead of pgx returning the context error.
+		m := mock.NewPGXMock()
+		m.ExpectQuery(slow).WillReturnRows(mock.NewRows([]string{"pg_sleep"}).WithRowError(0, mock.QueryCanceled()))
+
+		ob, err := octobe.New(postgres.OpenPGXWithConn(m))
+		require.NoError(t, err)
+		ctx := context.Background()
+		session, err := ob.Begin(ctx)
+		require.NoError(t, err)
+
+		stmtCtx, cancel := context.WithCancel(ctx)
+		err = session.Builder()(slow).(postgres.ContextSegment).WithContext(stmtCtx).Query(func(rows postgres.Rows) error {
+			cancel()
+			for rows.Next() {
+			}
+			return rows.Err()
+		})
+		require.ErrorIs(t, err, octobe.ErrStatementCanceled)
+		require.ErrorIs(t, err, context.Canceled)
+		var pgErr *pgconn.PgError
+		require.ErrorAs(t, err, &pgErr)
+		require.Equal(t, "57014", pgErr.Code)
+		require.NoError(t, session.Close())
+		require.NoError(t, m.AllExpectationsMet())
+	})
+
+	t.Run("statement timeout of the server", func(t *testing.T) {
+		// query_canceled also reports statement_timeout, which is not the
+		// statement context ending.
+		m := mock.NewPGXMock()
+		m.ExpectExec(slow).WillReturnError(mock.QueryCanceled())
+
+		ob, err := octobe.New(postgres.OpenPGXWithConn(m))
+		require.NoError(t, err)
+		ctx := context.Background()
+		session, err := ob.Begin(ctx)
+		require.NoError(t, err)
+
+		stmtCtx, cancel := context.WithTimeout(ctx, time.Minute)
+		defer cancel()
+		_, err = session.Builder()(slow).(postgres.ContextSegment).WithContext(stmtCtx).Exec()
+		require.Error(t, err)
+		require.NotErrorIs(t, err, octobe.ErrStatementCanceled)
+		require.NoError(t, session.Close())
+		require.NoError(t, m.AllExpectationsMet())
+	})
+
+	t.Run("session context still applies", func(t *testing.T) {
+		m := mock.NewPGXMock()
+		m.ExpectBeginTx()
+		m.ExpectExec(slow).WillDelayFor(time.Minute).WillReturnResult(mock.NewResult("SELECT", 1))
+
+		ob, err := octobe.New(postgres.OpenPGXWithConn(m))
+		require.NoError(t, err)
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		err = ob.StartTransaction(ctx, func(session octobe.BuilderSession[postgres.Builder]) error {
+			_, err := session.Builder()(slow).(postgres.ContextSegment).WithContext(context.Background()).Exec()
+			return err
+		})
+		require.ErrorIs(t, err, context.DeadlineExceeded)
+		require.NotErrorIs(t, err, octobe.ErrStatementCanceled)
+	})
+}
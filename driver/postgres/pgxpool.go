@@ -5,6 +5,7 @@ import (
 	"errors"
 
 	"github.com/Kansuler/octobe/v3"
+	"github.com/Kansuler/octobe/v3/internal/stmtctx"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
@@ -65,7 +66,12 @@ var (
 // OpenPGXPool creates a connection pool driver from a DSN and verifies connectivity.
 func OpenPGXPool(ctx context.Context, dsn string) PGXPoolOpen {
 	return func() (PGXPoolDriver, error) {
-		pool, err := pgxpool.New(ctx, dsn)
+		cfg, err := pgxpool.ParseConfig(dsn)
+		if err != nil {
+			return nil, err
+		}
+		cancelWithRequest(&cfg.ConnConfig.Config)
+		pool, err := pgxpool.NewWithConfig(ctx, cfg)
 		if err != nil {
 			return nil, err
 		}
@@ -81,6 +87,10 @@ func OpenPGXPool(ctx context.Context, dsn string) PGXPoolOpen {
 }
 
 // OpenPGXWithPool creates a driver from an existing pool.
+//
+// Unlike OpenPGXPool, it keeps the way the connections of the pool handle a
+// statement whose context ends. By default pgx closes the connection; see
+// ContextSegment for how to keep it open.
 func OpenPGXWithPool(pool PGXPool) PGXPoolOpen {
 	return func() (PGXPoolDriver, error) {
 		if pool == nil {
@@ -194,6 +204,10 @@ type pgxpoolSession struct {
 	conn      PGXPoolSessionConn
 	committed bool
 	closed    bool
+
+	// aborted holds the error of a statement canceled by its own context,
+	// which aborted the transaction.
+	aborted error
 }
 
 var _ octobe.Session[Builder] = &pgxpoolSession{}
@@ -209,6 +223,9 @@ func (s *pgxpoolSession) Commit() error {
 	if s.closed {
 		return errors.New("cannot commit a session that has already been closed")
 	}
+	if s.aborted != nil {
+		return stmtctx.Aborted(s.aborted)
+	}
 	err := s.tx.Commit(s.ctx)
 	s.committed = true
 	if err == nil {
@@ -264,10 +281,11 @@ type pgxpoolSegment struct {
 	query   string
 	args    []any
 	used    bool
+	ctx     context.Context
 	session *pgxpoolSession
 }
 
-var _ Segment = &pgxpoolSegment{}
+var _ ContextSegment = &pgxpoolSegment{}
 
 func (s *pgxpoolSegment) use() {
 	s.used = true
@@ -278,6 +296,9 @@ func (s *pgxpoolSegment) activeSession() (*pgxpoolSession, error) {
 	if s.session == nil || s.session.closed {
 		return nil, errors.New("session is closed")
 	}
+	if s.session.aborted != nil {
+		return nil, stmtctx.Aborted(s.session.aborted)
+	}
 	return s.session, nil
 }
 
@@ -287,8 +308,24 @@ func (s *pgxpoolSegment) Arguments(args ...any) Segment {
 	return s
 }
 
+// WithContext sets a context for this statement alone, used together with the session context.
+func (s *pgxpoolSegment) WithContext(ctx context.Context) Segment {
+	s.ctx = ctx
+	return s
+}
+
+// finish wraps the error of a statement canceled by the segment context, which
+// aborts the transaction of the session.
+func (s *pgxpoolSegment) finish(session *pgxpoolSession, err error) error {
+	err = stmtctx.Err(session.ctx, s.ctx, err, queryCanceled)
+	if session.tx != nil && errors.Is(err, octobe.ErrStatementCanceled) {
+		session.aborted = err
+	}
+	return err
+}
+
 // Exec executes the query and returns affected rows.
-func (s *pgxpoolSegment) Exec() (ExecResult, error) {
+func (s *pgxpoolSegment) Exec() (_ ExecResult, err error) {
 	if s.used {
 		return ExecResult{}, octobe.ErrAlreadyUsed
 	}
@@ -297,11 +334,16 @@ func (s *pgxpoolSegment) Exec() (ExecResult, error) {
 	if err != nil {
 		return ExecResult{}, err
 	}
+	ctx, cancel := stmtctx.Merge(session.ctx, s.ctx)
+	defer cancel()
+	defer func() {
+		err = s.finish(session, err)
+	}()
 	if session.tx == nil {
 		if session.conn == nil {
 			return ExecResult{}, errors.New("pool session connection is nil")
 		}
-		res, err := session.conn.Exec(session.ctx, s.query, s.args...)
+		res, err := session.conn.Exec(ctx, s.query, s.args...)
 		if err != nil {
 			return ExecResult{}, err
 		}
@@ -311,7 +353,7 @@ func (s *pgxpoolSegment) Exec() (ExecResult, error) {
 		}, nil
 	}
 
-	res, err := session.tx.Exec(session.ctx, s.query, s.args...)
+	res, err := session.tx.Exec(ctx, s.query, s.args...)
 	if err != nil {
 		return ExecResult{}, err
 	}
@@ -321,7 +363,7 @@ func (s *pgxpoolSegment) Exec() (ExecResult, error) {
 }
 
 // QueryRow executes the query expecting one row and scans into dest.
-func (s *pgxpoolSegment) QueryRow(dest ...any) error {
+func (s *pgxpoolSegment) QueryRow(dest ...any) (err error) {
 	if s.used {
 		return octobe.ErrAlreadyUsed
 	}
@@ -330,17 +372,22 @@ func (s *pgxpoolSegment) QueryRow(dest ...any) error {
 	if err != nil {
 		return err
 	}
+	ctx, cancel := stmtctx.Merge(session.ctx, s.ctx)
+	defer cancel()
+	defer func() {
+		err = s.finish(session, err)
+	}()
 	if session.tx == nil {
 		if session.conn == nil {
 			return errors.New("pool session connection is nil")
 		}
-		return session.conn.QueryRow(session.ctx, s.query, s.args...).Scan(dest...)
+		return session.conn.QueryRow(ctx, s.query, s.args...).Scan(dest...)
 	}
-	return session.tx.QueryRow(session.ctx, s.query, s.args...).Scan(dest...)
+	return session.tx.QueryRow(ctx, s.query, s.args...).Scan(dest...)
 }
 
 // Query executes the query and calls cb for each row.
-func (s *pgxpoolSegment) Query(cb func(Rows) error) error {
+func (s *pgxpoolSegment) Query(cb func(Rows) error) (err error) {
 	if s.used {
 		return octobe.ErrAlreadyUsed
 	}
@@ -350,18 +397,23 @@ func (s *pgxpoolSegment) Query(cb func(Rows) error) error {
 	if err != nil {
 		return err
 	}
+	ctx, cancel := stmtctx.Merge(session.ctx, s.ctx)
+	defer cancel()
+	defer func() {
+		err = s.finish(session, err)
+	}()
 
 	var rows pgx.Rows
 	if session.tx == nil {
 		if session.conn == nil {
 			return errors.New("pool session connection is nil")
 		}
-		rows, err = session.conn.Query(session.ctx, s.query, s.args...)
+		rows, err = session.conn.Query(ctx, s.query, s.args...)
 		if err != nil {
 			return err
 		}
 	} else {
-		rows, err = session.tx.Query(session.ctx, s.query, s.args...)
+		rows, err = session.tx.Query(ctx, s.query, s.args...)
 		if err != nil {
 			return err
 		}
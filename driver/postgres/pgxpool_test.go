@@ -4,11 +4,13 @@ import (
 	"context"
 	"errors"
 	"testing"
+	"time"
 
 	"github.com/Kansuler/octobe/v3"
 	"github.com/Kansuler/octobe/v3/driver/postgres"
 	"github.com/Kansuler/octobe/v3/driver/postgres/mock"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestPGXPoolWithTxInsideStartTransaction(t *testing.T) {
@@ -693,3 +695,30 @@ func TestPGXPoolSegmentQueryError(t *testing.T) {
 
 	assert.NoError(t, m.AllExpectationsMet())
 }
+
+func TestPGXPoolSegmentWithContext(t *testing.T) {
+	const slow = "SELECT pg_sleep(1)"
+
+	m := mock.NewPGXPoolMock()
+	m.ExpectBeginTx()
+	m.ExpectQueryRow(slow).WillDelayFor(time.Minute).WillReturnRow(mock.NewRow(1))
+	m.ExpectRollback()
+
+	ob, err := octobe.New(postgres.OpenPGXWithPool(m))
+	require.NoError(t, err)
+	ctx := context.Background()
+	err = ob.StartTransaction(ctx, func(session octobe.BuilderSession[postgres.Builder]) error {
+		stmtCtx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+		defer cancel()
+		var n int
+		err := session.Builder()(slow).(postgres.ContextSegment).WithContext(stmtCtx).QueryRow(&n)
+		require.ErrorIs(t, err, octobe.ErrStatementCanceled)
+
+		// Carrying on after the canceled statement does not commit a broken transaction.
+		_, err = session.Builder()("DELETE FROM sessions").Exec()
+		require.ErrorContains(t, err, "transaction aborted by an earlier statement")
+		return nil
+	})
+	require.ErrorIs(t, err, octobe.ErrStatementCanceled)
+	require.NoError(t, m.AllExpectationsMet())
+}
@@ -1,8 +1,14 @@
 package postgres
 
 import (
+	"context"
+	"errors"
+	"time"
+
 	"github.com/Kansuler/octobe/v3"
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgconn/ctxwatch"
 )
 
 type (
@@ -82,6 +88,31 @@ type Segment interface {
 	Query(cb func(Rows) error) error
 }
 
+// ContextSegment is a Segment that can run under a context of its own, see
+// octobe.ErrStatementCanceled. The segments of the pgx and pgxpool drivers
+// implement it.
+//
+// When the context of a statement ends, pgx by default closes its connection,
+// and on a driver with a single pgx connection no statement can run after
+// that. OpenPGX, OpenPGXWithOptions and OpenPGXPool set up their connections
+// to ask the server to cancel the statement instead, which ends with
+// query_canceled (SQLSTATE 57014) and keeps the connection open. Set the same
+// up for a connection or pool given to OpenPGXWithConn or OpenPGXWithPool:
+//
+//	cfg.BuildContextWatcherHandler = func(conn *pgconn.PgConn) ctxwatch.Handler {
+//	    return &pgconn.CancelRequestContextWatcherHandler{Conn: conn, DeadlineDelay: 5 * time.Second}
+//	}
+//
+// Example:
+//
+//	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+//	defer cancel()
+//	err := builder(`SELECT count(*) FROM events`).(postgres.ContextSegment).WithContext(ctx).QueryRow(&count)
+type ContextSegment interface {
+	Segment
+	WithContext(ctx context.Context) Segment
+}
+
 // ExecResult contains the outcome of an INSERT, UPDATE, or DELETE operation.
 type ExecResult struct {
 	RowsAffected int64
@@ -104,3 +135,22 @@ type Rows interface {
 }
 
 var _ Rows = (pgx.Rows)(nil)
+
+// cancelRequestDeadline is how long a connection waits for the server to
+// answer a cancel request before it is closed after all.
+const cancelRequestDeadline = 5 * time.Second
+
+// cancelWithRequest makes the connections of cfg send a cancel request to the
+// server when the context of a statement ends, instead of closing.
+func cancelWithRequest(cfg *pgconn.Config) {
+	cfg.BuildContextWatcherHandler = func(conn *pgconn.PgConn) ctxwatch.Handler {
+		return &pgconn.CancelRequestContextWatcherHandler{Conn: conn, DeadlineDelay: cancelRequestDeadline}
+	}
+}
+
+// queryCanceled reports whether the server canceled a statement on a cancel
+// request, which it reports as query_canceled (SQLSTATE 57014).
+func queryCanceled(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "57014"
+}
@@ -0,0 +1,212 @@
+package replay
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// Version is the cassette format version written by recorders. Load rejects
+// cassettes with a different version so stale recordings fail loudly.
+const Version = 1
+
+// Session 0 is the pool or connection itself. Pinned pool connections and
+// transactions get their own session ids in the order they are opened.
+const rootSession = 0
+
+// Methods recorded in a cassette.
+const (
+	MethodPing     = "Ping"
+	MethodClose    = "Close"
+	MethodAcquire  = "Acquire"
+	MethodRelease  = "Release"
+	MethodBegin    = "Begin"
+	MethodBeginTx  = "BeginTx"
+	MethodCommit   = "Commit"
+	MethodRollback = "Rollback"
+	MethodExec     = "Exec"
+	MethodQuery    = "Query"
+	MethodQueryRow = "QueryRow"
+)
+
+// Cassette is a recorded sequence of database calls.
+type Cassette struct {
+	Version int      `json:"version"`
+	Events  []*Event `json:"events"`
+}
+
+// Event is a single recorded call and its outcome.
+type Event struct {
+	// Session is the session the call ran on. For Acquire it is the id of the
+	// acquired connection.
+	Session int    `json:"session"`
+	Method  string `json:"method"`
+
+	// Tx is the session id of the transaction opened by Begin and BeginTx.
+	Tx        int            `json:"tx,omitempty"`
+	TxOptions *pgx.TxOptions `json:"txOptions,omitempty"`
+
+	Query string          `json:"query,omitempty"`
+	Args  json.RawMessage `json:"args,omitempty"`
+
+	// CommandTag is the command tag of Exec, Query and QueryRow.
+	CommandTag string `json:"commandTag,omitempty"`
+
+	// Fields and Rows hold the result set consumed by the caller, in the wire
+	// format PostgreSQL sent it. NULL values are recorded as null.
+	Fields []pgconn.FieldDescription `json:"fields,omitempty"`
+	Rows   [][][]byte                `json:"rows,omitempty"`
+
+	// Error is the error returned by the call itself, RowsError the error
+	// reported by the result set after iteration.
+	Error     *Error `json:"error,omitempty"`
+	RowsError *Error `json:"rowsError,omitempty"`
+}
+
+// Error kinds that are replayed as the matching sentinel error.
+const (
+	ErrorKindNoRows           = "no_rows"
+	ErrorKindTxClosed         = "tx_closed"
+	ErrorKindTxCommitRollback = "tx_commit_rollback"
+	ErrorKindCanceled         = "canceled"
+	ErrorKindDeadlineExceeded = "deadline_exceeded"
+)
+
+// Error is a recorded error. PostgreSQL errors keep all of their fields so
+// handlers can inspect SQLSTATE codes and constraint names on replay.
+type Error struct {
+	Kind    string          `json:"kind,omitempty"`
+	Message string          `json:"message"`
+	PgError *pgconn.PgError `json:"pg,omitempty"`
+}
+
+// Load reads a cassette from path.
+func Load(path string) (*Cassette, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var c Cassette
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, fmt.Errorf("decode cassette %s: %w", path, err)
+	}
+	if c.Version != Version {
+		return nil, fmt.Errorf("cassette %s has version %d, want %d; re-record it", path, c.Version, Version)
+	}
+	return &c, nil
+}
+
+// Save writes the cassette to path, creating parent directories as needed.
+func (c *Cassette) Save(path string) error {
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
+
+// encodeArgs records query arguments as JSON. Arguments that cannot be
+// marshaled are recorded by their Go representation instead.
+func encodeArgs(args []any) json.RawMessage {
+	if len(args) == 0 {
+		return nil
+	}
+	data, err := json.Marshal(args)
+	if err != nil {
+		data, _ = json.Marshal(fmt.Sprintf("%#v", args))
+	}
+	return data
+}
+
+// equalArgs reports whether two encoded argument lists are equal, ignoring formatting.
+func equalArgs(a, b json.RawMessage) bool {
+	return bytes.Equal(compact(a), compact(b))
+}
+
+func compact(data json.RawMessage) []byte {
+	if len(data) == 0 {
+		return nil
+	}
+	var buf bytes.Buffer
+	if err := json.Compact(&buf, data); err != nil {
+		return data
+	}
+	return buf.Bytes()
+}
+
+func encodeError(err error) *Error {
+	if err == nil {
+		return nil
+	}
+
+	e := &Error{Message: err.Error()}
+	var pgErr *pgconn.PgError
+	switch {
+	case errors.As(err, &pgErr):
+		e.PgError = pgErr
+	case errors.Is(err, pgx.ErrNoRows):
+		e.Kind = ErrorKindNoRows
+	case errors.Is(err, pgx.ErrTxClosed):
+		e.Kind = ErrorKindTxClosed
+	case errors.Is(err, pgx.ErrTxCommitRollback):
+		e.Kind = ErrorKindTxCommitRollback
+	case errors.Is(err, context.Canceled):
+		e.Kind = ErrorKindCanceled
+	case errors.Is(err, context.DeadlineExceeded):
+		e.Kind = ErrorKindDeadlineExceeded
+	}
+	return e
+}
+
+// err rebuilds the recorded error. Errors without a known kind are replayed
+// with their message only.
+func (e *Error) err() error {
+	if e == nil {
+		return nil
+	}
+	if e.PgError != nil {
+		pgErr := *e.PgError
+		return &pgErr
+	}
+
+	var sentinel error
+	switch e.Kind {
+	case ErrorKindNoRows:
+		sentinel = pgx.ErrNoRows
+	case ErrorKindTxClosed:
+		sentinel = pgx.ErrTxClosed
+	case ErrorKindTxCommitRollback:
+		sentinel = pgx.ErrTxCommitRollback
+	case ErrorKindCanceled:
+		sentinel = context.Canceled
+	case ErrorKindDeadlineExceeded:
+		sentinel = context.DeadlineExceeded
+	default:
+		return errors.New(e.Message)
+	}
+	if sentinel.Error() == e.Message {
+		return sentinel
+	}
+	return &recordedError{message: e.Message, sentinel: sentinel}
+}
+
+// recordedError keeps the recorded message of an error that wrapped a sentinel.
+type recordedError struct {
+	message  string
+	sentinel error
+}
+
+func (e *recordedError) Error() string { return e.message }
+
+func (e *recordedError) Unwrap() error { return e.sentinel }
@@ -0,0 +1,431 @@
+package replay
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/Kansuler/octobe/v3/driver/postgres"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// ErrDivergence is returned when a call does not match the next recorded event.
+var ErrDivergence = errors.New("replay diverged from recording")
+
+// errUnsupported is returned by pgx methods the driver does not use and that are not recorded.
+var errUnsupported = errors.New("replay: method is not recorded")
+
+// player serves recorded events in strict order. After the first divergence
+// every call fails with the same error.
+type player struct {
+	mu      sync.Mutex
+	events  []*Event
+	pos     int
+	err     error
+	typeMap *pgtype.Map
+}
+
+func newPlayer(c *Cassette) player {
+	return player{events: c.Events, typeMap: pgtype.NewMap()}
+}
+
+// next consumes the next event if it matches the call.
+func (p *player) next(session int, method, query string, args []any) (*Event, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.err != nil {
+		return nil, p.err
+	}
+
+	call := describeCall(session, method, query)
+	if p.pos >= len(p.events) {
+		p.err = fmt.Errorf("%w: unexpected %s after the last recorded event", ErrDivergence, call)
+		return nil, p.err
+	}
+
+	ev := p.events[p.pos]
+	var mismatch string
+	encoded := encodeArgs(args)
+	switch {
+	case ev.Method != method:
+		mismatch = "method differs"
+	case method != MethodAcquire && ev.Session != session:
+		mismatch = "session differs"
+	case ev.Query != query:
+		mismatch = "query differs"
+	case !equalArgs(ev.Args, encoded):
+		mismatch = fmt.Sprintf("args differ: recorded %s, got %s", compact(ev.Args), compact(encoded))
+	}
+	if mismatch != "" {
+		p.err = fmt.Errorf("%w at event %d: recorded %s, got %s: %s",
+			ErrDivergence, p.pos, describeCall(ev.Session, ev.Method, ev.Query), call, mismatch)
+		return nil, p.err
+	}
+
+	p.pos++
+	return ev, nil
+}
+
+func describeCall(session int, method, query string) string {
+	if query == "" {
+		return fmt.Sprintf("%s on session %d", method, session)
+	}
+	return fmt.Sprintf("%s %q on session %d", method, query, session)
+}
+
+// AllEventsReplayed returns the first divergence, or an error if recorded events were never replayed.
+func (p *player) AllEventsReplayed() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.err != nil {
+		return p.err
+	}
+	if p.pos < len(p.events) {
+		ev := p.events[p.pos]
+		return fmt.Errorf("%w: %d recorded events were not replayed, next is %s",
+			ErrDivergence, len(p.events)-p.pos, describeCall(ev.Session, ev.Method, ev.Query))
+	}
+	return nil
+}
+
+func (p *player) call(session int, method string) error {
+	ev, err := p.next(session, method, "", nil)
+	if err != nil {
+		return err
+	}
+	return ev.Error.err()
+}
+
+func (p *player) exec(session int, query string, args []any) (pgconn.CommandTag, error) {
+	ev, err := p.next(session, MethodExec, query, args)
+	if err != nil {
+		return pgconn.CommandTag{}, err
+	}
+	return pgconn.NewCommandTag(ev.CommandTag), ev.Error.err()
+}
+
+func (p *player) query(session int, method, query string, args []any) (pgx.Rows, error) {
+	ev, err := p.next(session, method, query, args)
+	if err != nil {
+		return nil, err
+	}
+	if ev.Error != nil {
+		return nil, ev.Error.err()
+	}
+	return &playerRows{ev: ev, typeMap: p.typeMap, pos: -1}, nil
+}
+
+func (p *player) queryRow(session int, query string, args []any) pgx.Row {
+	rows, err := p.query(session, MethodQueryRow, query, args)
+	return &queryRow{rows: rows, err: err}
+}
+
+func (p *player) begin(session int, method string, opts *pgx.TxOptions) (pgx.Tx, error) {
+	ev, err := p.next(session, method, "", nil)
+	if err != nil {
+		return nil, err
+	}
+	if ev.Error != nil {
+		return nil, ev.Error.err()
+	}
+	if !equalTxOptions(ev.TxOptions, opts) {
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		p.err = fmt.Errorf("%w: %s: transaction options differ: recorded %+v, got %+v",
+			ErrDivergence, describeCall(session, method, ""), ev.TxOptions, opts)
+		return nil, p.err
+	}
+	return &playerTx{p: p, session: ev.Tx}, nil
+}
+
+func equalTxOptions(a, b *pgx.TxOptions) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+// PoolPlayer replays a cassette recorded with a PoolRecorder. Pass it to
+// postgres.OpenPGXWithPool.
+type PoolPlayer struct {
+	player
+}
+
+var (
+	_ postgres.PGXPool                = &PoolPlayer{}
+	_ postgres.PGXPoolSessionAcquirer = &PoolPlayer{}
+)
+
+// NewPoolPlayer creates a player for a pool cassette.
+func NewPoolPlayer(c *Cassette) *PoolPlayer {
+	return &PoolPlayer{player: newPlayer(c)}
+}
+
+func (p *PoolPlayer) Close() {
+	_ = p.call(rootSession, MethodClose)
+}
+
+// Acquire is not supported, as a *pgxpool.Conn cannot be replayed. The driver
+// acquires session connections through AcquireSession.
+func (p *PoolPlayer) Acquire(context.Context) (*pgxpool.Conn, error) {
+	return nil, errUnsupported
+}
+
+func (p *PoolPlayer) AcquireSession(context.Context) (postgres.PGXPoolSessionConn, error) {
+	ev, err := p.next(rootSession, MethodAcquire, "", nil)
+	if err != nil {
+		return nil, err
+	}
+	if ev.Error != nil {
+		return nil, ev.Error.err()
+	}
+	return &playerSession{p: &p.player, session: ev.Session}, nil
+}
+
+func (p *PoolPlayer) BeginTx(_ context.Context, opts pgx.TxOptions) (pgx.Tx, error) {
+	return p.begin(rootSession, MethodBeginTx, &opts)
+}
+
+func (p *PoolPlayer) Ping(context.Context) error {
+	return p.call(rootSession, MethodPing)
+}
+
+// playerSession replays the calls made on a pinned pool connection.
+type playerSession struct {
+	p       *player
+	session int
+}
+
+var _ postgres.PGXPoolSessionConn = &playerSession{}
+
+// Release has no error to return; a divergence is reported by AllEventsReplayed.
+func (s *playerSession) Release() {
+	_ = s.p.call(s.session, MethodRelease)
+}
+
+func (s *playerSession) Exec(_ context.Context, query string, args ...any) (pgconn.CommandTag, error) {
+	return s.p.exec(s.session, query, args)
+}
+
+func (s *playerSession) Query(_ context.Context, query string, args ...any) (pgx.Rows, error) {
+	return s.p.query(s.session, MethodQuery, query, args)
+}
+
+func (s *playerSession) QueryRow(_ context.Context, query string, args ...any) pgx.Row {
+	return s.p.queryRow(s.session, query, args)
+}
+
+// ConnPlayer replays a cassette recorded with a ConnRecorder. Pass it to
+// postgres.OpenPGXWithConn. Methods that are not recorded return an error.
+type ConnPlayer struct {
+	player
+}
+
+var _ postgres.PGXConn = &ConnPlayer{}
+
+// NewConnPlayer creates a player for a connection cassette.
+func NewConnPlayer(c *Cassette) *ConnPlayer {
+	return &ConnPlayer{player: newPlayer(c)}
+}
+
+func (c *ConnPlayer) Close(context.Context) error {
+	return c.call(rootSession, MethodClose)
+}
+
+func (c *ConnPlayer) Prepare(context.Context, string, string) (*pgconn.StatementDescription, error) {
+	return nil, errUnsupported
+}
+
+func (c *ConnPlayer) Deallocate(context.Context, string) error { return errUnsupported }
+
+func (c *ConnPlayer) DeallocateAll(context.Context) error { return errUnsupported }
+
+func (c *ConnPlayer) Ping(context.Context) error {
+	return c.call(rootSession, MethodPing)
+}
+
+func (c *ConnPlayer) PgConn() *pgconn.PgConn { return nil }
+
+func (c *ConnPlayer) Config() *pgx.ConnConfig { return nil }
+
+func (c *ConnPlayer) Exec(_ context.Context, query string, args ...any) (pgconn.CommandTag, error) {
+	return c.exec(rootSession, query, args)
+}
+
+func (c *ConnPlayer) Query(_ context.Context, query string, args ...any) (pgx.Rows, error) {
+	return c.query(rootSession, MethodQuery, query, args)
+}
+
+func (c *ConnPlayer) QueryRow(_ context.Context, query string, args ...any) pgx.Row {
+	return c.queryRow(rootSession, query, args)
+}
+
+func (c *ConnPlayer) SendBatch(context.Context, *pgx.Batch) pgx.BatchResults {
+	return unsupportedBatch{}
+}
+
+func (c *ConnPlayer) Begin(context.Context) (pgx.Tx, error) {
+	return c.begin(rootSession, MethodBegin, nil)
+}
+
+func (c *ConnPlayer) BeginTx(_ context.Context, opts pgx.TxOptions) (pgx.Tx, error) {
+	return c.begin(rootSession, MethodBeginTx, &opts)
+}
+
+func (c *ConnPlayer) CopyFrom(context.Context, pgx.Identifier, []string, pgx.CopyFromSource) (int64, error) {
+	return 0, errUnsupported
+}
+
+// playerTx replays the calls made in a recorded transaction.
+type playerTx struct {
+	p       *player
+	session int
+}
+
+var _ pgx.Tx = &playerTx{}
+
+func (t *playerTx) Begin(context.Context) (pgx.Tx, error) {
+	return t.p.begin(t.session, MethodBegin, nil)
+}
+
+func (t *playerTx) Commit(context.Context) error {
+	return t.p.call(t.session, MethodCommit)
+}
+
+func (t *playerTx) Rollback(context.Context) error {
+	return t.p.call(t.session, MethodRollback)
+}
+
+func (t *playerTx) CopyFrom(context.Context, pgx.Identifier, []string, pgx.CopyFromSource) (int64, error) {
+	return 0, errUnsupported
+}
+
+func (t *playerTx) SendBatch(context.Context, *pgx.Batch) pgx.BatchResults {
+	return unsupportedBatch{}
+}
+
+func (t *playerTx) LargeObjects() pgx.LargeObjects { return pgx.LargeObjects{} }
+
+func (t *playerTx) Prepare(context.Context, string, string) (*pgconn.StatementDescription, error) {
+	return nil, errUnsupported
+}
+
+func (t *playerTx) Exec(_ context.Context, query string, args ...any) (pgconn.CommandTag, error) {
+	return t.p.exec(t.session, query, args)
+}
+
+func (t *playerTx) Query(_ context.Context, query string, args ...any) (pgx.Rows, error) {
+	return t.p.query(t.session, MethodQuery, query, args)
+}
+
+func (t *playerTx) QueryRow(_ context.Context, query string, args ...any) pgx.Row {
+	return t.p.queryRow(t.session, query, args)
+}
+
+func (t *playerTx) Conn() *pgx.Conn { return nil }
+
+// playerRows serves recorded rows and decodes them with pgx's default type map,
+// so scanning behaves as it did against the database.
+type playerRows struct {
+	ev      *Event
+	typeMap *pgtype.Map
+	pos     int
+	err     error
+	closed  bool
+}
+
+var _ pgx.Rows = &playerRows{}
+
+func (r *playerRows) Close() {
+	if r.closed {
+		return
+	}
+	r.closed = true
+	if r.err == nil {
+		r.err = r.ev.RowsError.err()
+	}
+}
+
+func (r *playerRows) Err() error { return r.err }
+
+func (r *playerRows) CommandTag() pgconn.CommandTag { return pgconn.NewCommandTag(r.ev.CommandTag) }
+
+func (r *playerRows) FieldDescriptions() []pgconn.FieldDescription { return r.ev.Fields }
+
+func (r *playerRows) Next() bool {
+	if r.closed {
+		return false
+	}
+	r.pos++
+	if r.pos >= len(r.ev.Rows) {
+		r.Close()
+		return false
+	}
+	return true
+}
+
+func (r *playerRows) Scan(dest ...any) error {
+	if r.closed || r.pos < 0 || r.pos >= len(r.ev.Rows) {
+		return errors.New("replay: scan called without a current row")
+	}
+	if err := pgx.ScanRow(r.typeMap, r.ev.Fields, r.ev.Rows[r.pos], dest...); err != nil {
+		r.err = err
+		r.Close()
+		return err
+	}
+	return nil
+}
+
+func (r *playerRows) Values() ([]any, error) {
+	if r.pos < 0 || r.pos >= len(r.ev.Rows) {
+		return nil, errors.New("replay: values called without a current row")
+	}
+	row := r.ev.Rows[r.pos]
+	values := make([]any, len(row))
+	for i, raw := range row {
+		if raw == nil {
+			continue
+		}
+		field := r.ev.Fields[i]
+		if t, ok := r.typeMap.TypeForOID(field.DataTypeOID); ok {
+			value, err := t.Codec.DecodeValue(r.typeMap, field.DataTypeOID, field.Format, raw)
+			if err != nil {
+				return nil, err
+			}
+			values[i] = value
+			continue
+		}
+		if field.Format == pgtype.TextFormatCode {
+			values[i] = string(raw)
+		} else {
+			values[i] = append([]byte{}, raw...)
+		}
+	}
+	return values, nil
+}
+
+func (r *playerRows) RawValues() [][]byte {
+	if r.pos < 0 || r.pos >= len(r.ev.Rows) {
+		return nil
+	}
+	return r.ev.Rows[r.pos]
+}
+
+func (r *playerRows) Conn() *pgx.Conn { return nil }
+
+// unsupportedBatch is returned by SendBatch, which is not recorded.
+type unsupportedBatch struct{}
+
+func (unsupportedBatch) Exec() (pgconn.CommandTag, error) { return pgconn.CommandTag{}, errUnsupported }
+
+func (unsupportedBatch) Query() (pgx.Rows, error) { return nil, errUnsupported }
+
+func (unsupportedBatch) QueryRow() pgx.Row { return &queryRow{err: errUnsupported} }
+
+func (unsupportedBatch) Close() error { return errUnsupported }
@@ -0,0 +1,168 @@
+package replay
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Kansuler/octobe/v3/driver/postgres"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/require"
+)
+
+func deleteCassette() *Cassette {
+	return &Cassette{Version: Version, Events: []*Event{
+		{Method: MethodAcquire, Session: 1},
+		{Session: 1, Method: MethodExec, Query: "DELETE FROM products WHERE id = $1", Args: json.RawMessage(`[1]`), CommandTag: "DELETE 1"},
+		{Session: 1, Method: MethodRelease},
+	}}
+}
+
+func TestPlayerDivergence(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("query", func(t *testing.T) {
+		p := NewPoolPlayer(deleteCassette())
+		conn, err := p.AcquireSession(ctx)
+		require.NoError(t, err)
+
+		_, err = conn.Exec(ctx, "DELETE FROM orders WHERE id = $1", 1)
+		require.ErrorIs(t, err, ErrDivergence)
+		require.ErrorContains(t, err, `at event 1: recorded Exec "DELETE FROM products WHERE id = $1" on session 1, got Exec "DELETE FROM orders WHERE id = $1" on session 1: query differs`)
+
+		// Every later call reports the first divergence.
+		conn.Release()
+		require.Equal(t, err, p.AllEventsReplayed())
+	})
+
+	t.Run("args", func(t *testing.T) {
+		p := NewPoolPlayer(deleteCassette())
+		conn, err := p.AcquireSession(ctx)
+		require.NoError(t, err)
+
+		_, err = conn.Exec(ctx, "DELETE FROM products WHERE id = $1", 2)
+		require.ErrorIs(t, err, ErrDivergence)
+		require.ErrorContains(t, err, "args differ: recorded [1], got [2]")
+	})
+
+	t.Run("method", func(t *testing.T) {
+		p := NewPoolPlayer(deleteCassette())
+		_, err := p.BeginTx(ctx, pgx.TxOptions{})
+		require.ErrorIs(t, err, ErrDivergence)
+		require.ErrorContains(t, err, "method differs")
+	})
+
+	t.Run("session", func(t *testing.T) {
+		c := deleteCassette()
+		c.Events = append(c.Events[:1], &Event{Method: MethodAcquire, Session: 2}, c.Events[1])
+		p := NewPoolPlayer(c)
+
+		first, err := p.AcquireSession(ctx)
+		require.NoError(t, err)
+		_, err = p.AcquireSession(ctx)
+		require.NoError(t, err)
+
+		_, err = first.Exec(ctx, "DELETE FROM products WHERE id = $1", 1)
+		require.NoError(t, err)
+
+		c.Events[2].Session = 2
+		p = NewPoolPlayer(c)
+		first, err = p.AcquireSession(ctx)
+		require.NoError(t, err)
+		_, err = p.AcquireSession(ctx)
+		require.NoError(t, err)
+		_, err = first.Exec(ctx, "DELETE FROM products WHERE id = $1", 1)
+		require.ErrorContains(t, err, "session differs")
+	})
+
+	t.Run("transaction options", func(t *testing.T) {
+		p := NewPoolPlayer(&Cassette{Version: Version, Events: []*Event{
+			{Method: MethodBeginTx, Tx: 1, TxOptions: &pgx.TxOptions{IsoLevel: pgx.Serializable}},
+		}})
+		_, err := p.BeginTx(ctx, pgx.TxOptions{})
+		require.ErrorIs(t, err, ErrDivergence)
+		require.ErrorContains(t, err, "transaction options differ")
+	})
+
+	t.Run("extra call", func(t *testing.T) {
+		p := NewPoolPlayer(&Cassette{Version: Version})
+		require.ErrorContains(t, p.Ping(ctx), "unexpected Ping on session 0 after the last recorded event")
+	})
+
+	t.Run("unreplayed events", func(t *testing.T) {
+		p := NewPoolPlayer(deleteCassette())
+		_, err := p.AcquireSession(ctx)
+		require.NoError(t, err)
+		require.EqualError(t, p.AllEventsReplayed(), `replay diverged from recording: 2 recorded events were not replayed, next is Exec "DELETE FROM products WHERE id = $1" on session 1`)
+	})
+}
+
+func TestPlayerRowsAndErrors(t *testing.T) {
+	ctx := context.Background()
+	p := NewConnPlayer(&Cassette{Version: Version, Events: []*Event{
+		{Method: MethodQuery, Query: "SELECT name, note FROM products", CommandTag: "SELECT 2",
+			Fields:    []pgconn.FieldDescription{{Name: "name"}, {Name: "note"}},
+			Rows:      [][][]byte{{[]byte("lamp"), nil}, {[]byte("desk"), []byte("new")}},
+			RowsError: &Error{Kind: ErrorKindCanceled, Message: "timeout: context canceled"},
+		},
+		{Method: MethodBegin, Tx: 1},
+		{Session: 1, Method: MethodCommit, Error: &Error{Kind: ErrorKindTxCommitRollback, Message: "commit unexpectedly resulted in rollback"}},
+	}})
+
+	rows, err := p.Query(ctx, "SELECT name, note FROM products")
+	require.NoError(t, err)
+	var names []string
+	var notes []*string
+	for rows.Next() {
+		var name string
+		var note *string
+		require.NoError(t, rows.Scan(&name, &note))
+		values, err := rows.Values()
+		require.NoError(t, err)
+		require.Len(t, values, 2)
+		names = append(names, name)
+		notes = append(notes, note)
+	}
+	require.Equal(t, []string{"lamp", "desk"}, names)
+	require.Nil(t, notes[0])
+	require.Equal(t, "new", *notes[1])
+	require.ErrorIs(t, rows.Err(), context.Canceled)
+	require.EqualError(t, rows.Err(), "timeout: context canceled")
+	require.Equal(t, "SELECT 2", rows.CommandTag().String())
+
+	tx, err := p.Begin(ctx)
+	require.NoError(t, err)
+	require.Equal(t, pgx.ErrTxCommitRollback, tx.Commit(ctx))
+	require.NoError(t, p.AllEventsReplayed())
+
+	_, err = p.Prepare(ctx, "stmt", "SELECT 1")
+	require.Error(t, err)
+}
+
+func TestLoadRejectsOtherVersions(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "old.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"version": 0, "events": []}`), 0o644))
+
+	_, err := Load(path)
+	require.ErrorContains(t, err, "has version 0, want 1; re-record it")
+}
+
+func TestOpenPGXPoolReplaysTestdata(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "delete.json")
+	require.NoError(t, deleteCassette().Save(path))
+
+	db := OpenPGXPool(t, path, func(context.Context) (postgres.PGXPool, error) {
+		t.Fatal("connect must not be called when replaying")
+		return nil, nil
+	})
+
+	session, err := db.Begin(context.Background())
+	require.NoError(t, err)
+	res, err := session.Builder()(`DELETE FROM products WHERE id = $1`).Arguments(1).Exec()
+	require.NoError(t, err)
+	require.Equal(t, postgres.ExecResult{RowsAffected: 1}, res)
+	require.NoError(t, session.Close())
+}
@@ -0,0 +1,405 @@
+package replay
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/Kansuler/octobe/v3/driver/postgres"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// recorder appends events to a cassette. It is shared by every session opened
+// through a PoolRecorder or ConnRecorder.
+type recorder struct {
+	mu          sync.Mutex
+	events      []*Event
+	lastSession int
+}
+
+// record appends ev and returns it, so results that arrive later can be filled in.
+func (r *recorder) record(ev *Event) *Event {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, ev)
+	return ev
+}
+
+func (r *recorder) nextSession() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.lastSession++
+	return r.lastSession
+}
+
+// update changes a recorded event under the lock, as the cassette may be read concurrently.
+func (r *recorder) update(fn func()) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	fn()
+}
+
+// Cassette returns a copy of everything recorded so far.
+func (r *recorder) Cassette() *Cassette {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	events := make([]*Event, len(r.events))
+	copy(events, r.events)
+	return &Cassette{Version: Version, Events: events}
+}
+
+// Save writes everything recorded so far to path.
+func (r *recorder) Save(path string) error {
+	return r.Cassette().Save(path)
+}
+
+// querier is implemented by pgx connections, pool connections and transactions.
+type querier interface {
+	Exec(context.Context, string, ...any) (pgconn.CommandTag, error)
+	Query(context.Context, string, ...any) (pgx.Rows, error)
+}
+
+func (r *recorder) exec(ctx context.Context, q querier, session int, query string, args []any) (pgconn.CommandTag, error) {
+	ev := r.record(&Event{Session: session, Method: MethodExec, Query: query, Args: encodeArgs(args)})
+	tag, err := q.Exec(ctx, query, args...)
+	r.update(func() {
+		ev.CommandTag = tag.String()
+		ev.Error = encodeError(err)
+	})
+	return tag, err
+}
+
+func (r *recorder) query(ctx context.Context, q querier, session int, method, query string, args []any) (pgx.Rows, error) {
+	ev := r.record(&Event{Session: session, Method: method, Query: query, Args: encodeArgs(args)})
+	rows, err := q.Query(ctx, query, args...)
+	if err != nil {
+		r.update(func() {
+			ev.Error = encodeError(err)
+		})
+		return nil, err
+	}
+	return &recordRows{Rows: rows, r: r, ev: ev}, nil
+}
+
+// queryRow records QueryRow through Query so the raw row values can be captured.
+func (r *recorder) queryRow(ctx context.Context, q querier, session int, query string, args []any) pgx.Row {
+	rows, err := r.query(ctx, q, session, MethodQueryRow, query, args)
+	return &queryRow{rows: rows, err: err}
+}
+
+func (r *recorder) begin(session int, method string, opts *pgx.TxOptions, begin func() (pgx.Tx, error)) (pgx.Tx, error) {
+	ev := r.record(&Event{Session: session, Method: method, TxOptions: opts})
+	tx, err := begin()
+	if err != nil {
+		r.update(func() {
+			ev.Error = encodeError(err)
+		})
+		return nil, err
+	}
+	id := r.nextSession()
+	r.update(func() {
+		ev.Tx = id
+	})
+	return &recordTx{Tx: tx, r: r, session: id}, nil
+}
+
+func (r *recorder) call(session int, method string, fn func() error) error {
+	ev := r.record(&Event{Session: session, Method: method})
+	err := fn()
+	r.update(func() {
+		ev.Error = encodeError(err)
+	})
+	return err
+}
+
+// PoolRecorder wraps a pool and records every call made through the driver.
+// Pass it to postgres.OpenPGXWithPool and save the cassette when done.
+type PoolRecorder struct {
+	recorder
+	pool postgres.PGXPool
+}
+
+var (
+	_ postgres.PGXPool                = &PoolRecorder{}
+	_ postgres.PGXPoolSessionAcquirer = &PoolRecorder{}
+)
+
+// NewPoolRecorder creates a recorder around pool.
+func NewPoolRecorder(pool postgres.PGXPool) *PoolRecorder {
+	return &PoolRecorder{pool: pool}
+}
+
+func (p *PoolRecorder) Close() {
+	_ = p.call(rootSession, MethodClose, func() error {
+		p.pool.Close()
+		return nil
+	})
+}
+
+// Acquire passes through to the pool unrecorded. Sessions acquire their
+// connections through AcquireSession, which is recorded.
+func (p *PoolRecorder) Acquire(ctx context.Context) (*pgxpool.Conn, error) {
+	return p.pool.Acquire(ctx)
+}
+
+// AcquireSession acquires and records a pinned connection for a non-transactional session.
+func (p *PoolRecorder) AcquireSession(ctx context.Context) (postgres.PGXPoolSessionConn, error) {
+	ev := p.record(&Event{Method: MethodAcquire})
+	conn, err := p.acquire(ctx)
+	if err != nil {
+		p.update(func() {
+			ev.Error = encodeError(err)
+		})
+		return nil, err
+	}
+	id := p.nextSession()
+	p.update(func() {
+		ev.Session = id
+	})
+	return &recordSession{conn: conn, r: &p.recorder, session: id}, nil
+}
+
+func (p *PoolRecorder) acquire(ctx context.Context) (postgres.PGXPoolSessionConn, error) {
+	if acquirer, ok := p.pool.(postgres.PGXPoolSessionAcquirer); ok {
+		return acquirer.AcquireSession(ctx)
+	}
+	conn, err := p.pool.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if conn == nil {
+		return nil, errors.New("pool acquired nil connection")
+	}
+	return &acquiredConn{conn: conn}, nil
+}
+
+func (p *PoolRecorder) BeginTx(ctx context.Context, opts pgx.TxOptions) (pgx.Tx, error) {
+	return p.begin(rootSession, MethodBeginTx, &opts, func() (pgx.Tx, error) {
+		return p.pool.BeginTx(ctx, opts)
+	})
+}
+
+func (p *PoolRecorder) Ping(ctx context.Context) error {
+	return p.call(rootSession, MethodPing, func() error {
+		return p.pool.Ping(ctx)
+	})
+}
+
+// acquiredConn adapts a *pgxpool.Conn to postgres.PGXPoolSessionConn.
+type acquiredConn struct {
+	conn *pgxpool.Conn
+}
+
+func (c *acquiredConn) Release() { c.conn.Release() }
+
+func (c *acquiredConn) Exec(ctx context.Context, query string, args ...any) (pgconn.CommandTag, error) {
+	return c.conn.Exec(ctx, query, args...)
+}
+
+func (c *acquiredConn) Query(ctx context.Context, query string, args ...any) (pgx.Rows, error) {
+	return c.conn.Query(ctx, query, args...)
+}
+
+func (c *acquiredConn) QueryRow(ctx context.Context, query string, args ...any) pgx.Row {
+	return c.conn.QueryRow(ctx, query, args...)
+}
+
+// recordSession records the calls made on a pinned pool connection.
+type recordSession struct {
+	conn    postgres.PGXPoolSessionConn
+	r       *recorder
+	session int
+}
+
+var _ postgres.PGXPoolSessionConn = &recordSession{}
+
+func (s *recordSession) Release() {
+	_ = s.r.call(s.session, MethodRelease, func() error {
+		s.conn.Release()
+		return nil
+	})
+}
+
+func (s *recordSession) Exec(ctx context.Context, query string, args ...any) (pgconn.CommandTag, error) {
+	return s.r.exec(ctx, s.conn, s.session, query, args)
+}
+
+func (s *recordSession) Query(ctx context.Context, query string, args ...any) (pgx.Rows, error) {
+	return s.r.query(ctx, s.conn, s.session, MethodQuery, query, args)
+}
+
+func (s *recordSession) QueryRow(ctx context.Context, query string, args ...any) pgx.Row {
+	return s.r.queryRow(ctx, s.conn, s.session, query, args)
+}
+
+// ConnRecorder wraps a single pgx connection and records every call made
+// through the driver. Pass it to postgres.OpenPGXWithConn. Methods the driver
+// does not use pass through to the connection unrecorded.
+type ConnRecorder struct {
+	postgres.PGXConn
+	recorder
+}
+
+var _ postgres.PGXConn = &ConnRecorder{}
+
+// NewConnRecorder creates a recorder around conn.
+func NewConnRecorder(conn postgres.PGXConn) *ConnRecorder {
+	return &ConnRecorder{PGXConn: conn}
+}
+
+func (c *ConnRecorder) Close(ctx context.Context) error {
+	return c.call(rootSession, MethodClose, func() error {
+		return c.PGXConn.Close(ctx)
+	})
+}
+
+func (c *ConnRecorder) Ping(ctx context.Context) error {
+	return c.call(rootSession, MethodPing, func() error {
+		return c.PGXConn.Ping(ctx)
+	})
+}
+
+func (c *ConnRecorder) Exec(ctx context.Context, query string, args ...any) (pgconn.CommandTag, error) {
+	return c.exec(ctx, c.PGXConn, rootSession, query, args)
+}
+
+func (c *ConnRecorder) Query(ctx context.Context, query string, args ...any) (pgx.Rows, error) {
+	return c.query(ctx, c.PGXConn, rootSession, MethodQuery, query, args)
+}
+
+func (c *ConnRecorder) QueryRow(ctx context.Context, query string, args ...any) pgx.Row {
+	return c.queryRow(ctx, c.PGXConn, rootSession, query, args)
+}
+
+func (c *ConnRecorder) Begin(ctx context.Context) (pgx.Tx, error) {
+	return c.begin(rootSession, MethodBegin, nil, func() (pgx.Tx, error) {
+		return c.PGXConn.Begin(ctx)
+	})
+}
+
+func (c *ConnRecorder) BeginTx(ctx context.Context, opts pgx.TxOptions) (pgx.Tx, error) {
+	return c.begin(rootSession, MethodBeginTx, &opts, func() (pgx.Tx, error) {
+		return c.PGXConn.BeginTx(ctx, opts)
+	})
+}
+
+// recordTx records the calls made in a transaction. Nested transactions
+// (savepoints) are recorded as sessions of their own.
+type recordTx struct {
+	pgx.Tx
+	r       *recorder
+	session int
+}
+
+func (t *recordTx) Begin(ctx context.Context) (pgx.Tx, error) {
+	return t.r.begin(t.session, MethodBegin, nil, func() (pgx.Tx, error) {
+		return t.Tx.Begin(ctx)
+	})
+}
+
+func (t *recordTx) Commit(ctx context.Context) error {
+	return t.r.call(t.session, MethodCommit, func() error {
+		return t.Tx.Commit(ctx)
+	})
+}
+
+func (t *recordTx) Rollback(ctx context.Context) error {
+	return t.r.call(t.session, MethodRollback, func() error {
+		return t.Tx.Rollback(ctx)
+	})
+}
+
+func (t *recordTx) Exec(ctx context.Context, query string, args ...any) (pgconn.CommandTag, error) {
+	return t.r.exec(ctx, t.Tx, t.session, query, args)
+}
+
+func (t *recordTx) Query(ctx context.Context, query string, args ...any) (pgx.Rows, error) {
+	return t.r.query(ctx, t.Tx, t.session, MethodQuery, query, args)
+}
+
+func (t *recordTx) QueryRow(ctx context.Context, query string, args ...any) pgx.Row {
+	return t.r.queryRow(ctx, t.Tx, t.session, query, args)
+}
+
+// recordRows records the rows the caller reads. Rows the caller never reaches
+// are not recorded, which is what a replay of the same handler will consume.
+type recordRows struct {
+	pgx.Rows
+	r        *recorder
+	ev       *Event
+	finished bool
+}
+
+func (r *recordRows) Next() bool {
+	if !r.Rows.Next() {
+		r.finish()
+		return false
+	}
+
+	raw := r.Rows.RawValues()
+	row := make([][]byte, len(raw))
+	for i, value := range raw {
+		if value != nil {
+			row[i] = append([]byte{}, value...)
+		}
+	}
+	r.r.update(func() {
+		if r.ev.Fields == nil {
+			r.ev.Fields = append([]pgconn.FieldDescription{}, r.Rows.FieldDescriptions()...)
+		}
+		r.ev.Rows = append(r.ev.Rows, row)
+	})
+	return true
+}
+
+func (r *recordRows) Close() {
+	r.Rows.Close()
+	r.finish()
+}
+
+// finish records the command tag and iteration error once the rows are closed.
+func (r *recordRows) finish() {
+	if r.finished {
+		return
+	}
+	r.finished = true
+	r.r.update(func() {
+		if r.ev.Fields == nil && len(r.Rows.FieldDescriptions()) > 0 {
+			r.ev.Fields = append([]pgconn.FieldDescription{}, r.Rows.FieldDescriptions()...)
+		}
+		r.ev.CommandTag = r.Rows.CommandTag().String()
+		r.ev.RowsError = encodeError(r.Rows.Err())
+	})
+}
+
+// queryRow implements pgx.Row on top of Rows the same way pgx does.
+type queryRow struct {
+	rows pgx.Rows
+	err  error
+}
+
+func (r *queryRow) Scan(dest ...any) error {
+	if r.err != nil {
+		return r.err
+	}
+	defer r.rows.Close()
+
+	if !r.rows.Next() {
+		if err := r.rows.Err(); err != nil {
+			return err
+		}
+		return pgx.ErrNoRows
+	}
+
+	if err := r.rows.Scan(dest...); err != nil {
+		r.rows.Close()
+		if rowsErr := r.rows.Err(); rowsErr != nil {
+			return rowsErr
+		}
+		return err
+	}
+	r.rows.Close()
+	return r.rows.Err()
+}
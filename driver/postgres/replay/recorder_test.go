@@ -0,0 +1,183 @@
+package replay
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/Kansuler/octobe/v3"
+	"github.com/Kansuler/octobe/v3/driver/postgres"
+	"github.com/Kansuler/octobe/v3/driver/postgres/mock"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/require"
+)
+
+type product struct {
+	ID   int
+	Name string
+}
+
+func addProduct(name string) octobe.Handler[product, postgres.Builder] {
+	return func(builder postgres.Builder) (product, error) {
+		var p product
+		err := builder(`INSERT INTO products (name) VALUES ($1) RETURNING id, name`).
+			Arguments(name).
+			QueryRow(&p.ID, &p.Name)
+		return p, err
+	}
+}
+
+func renameProducts(from, to string) octobe.Handler[int64, postgres.Builder] {
+	return func(builder postgres.Builder) (int64, error) {
+		res, err := builder(`UPDATE products SET name = $1 WHERE name = $2`).Arguments(to, from).Exec()
+		return res.RowsAffected, err
+	}
+}
+
+func listProducts() octobe.Handler[[]product, postgres.Builder] {
+	return func(builder postgres.Builder) ([]product, error) {
+		var products []product
+		err := builder(`SELECT id, name FROM products ORDER BY id`).Query(func(rows postgres.Rows) error {
+			for rows.Next() {
+				var p product
+				if err := rows.Scan(&p.ID, &p.Name); err != nil {
+					return err
+				}
+				products = append(products, p)
+			}
+			return rows.Err()
+		})
+		return products, err
+	}
+}
+
+// runScenario runs the same handlers against a recorder and a player.
+func runScenario(t *testing.T, db postgres.PGXPoolDriver) {
+	t.Helper()
+	ctx := context.Background()
+
+	err := db.StartTransaction(ctx, func(session octobe.BuilderSession[postgres.Builder]) error {
+		p, err := octobe.Execute(session, addProduct("lamp"))
+		if err != nil {
+			return err
+		}
+		require.Equal(t, product{ID: 1, Name: "lamp"}, p)
+
+		affected, err := octobe.Execute(session, renameProducts("lamp", "desk lamp"))
+		if err != nil {
+			return err
+		}
+		require.Equal(t, int64(1), affected)
+		return nil
+	}, postgres.WithPGXTxOptions(postgres.PGXTxOptions{IsoLevel: pgx.Serializable}))
+	require.NoError(t, err)
+
+	err = db.StartTransaction(ctx, func(session octobe.BuilderSession[postgres.Builder]) error {
+		_, err := octobe.Execute(session, addProduct("lamp"))
+		return err
+	})
+	var pgErr *pgconn.PgError
+	require.ErrorAs(t, err, &pgErr)
+	require.Equal(t, "23505", pgErr.Code)
+	require.Equal(t, "products_name_key", pgErr.ConstraintName)
+
+	session, err := db.Begin(ctx)
+	require.NoError(t, err)
+	products, err := octobe.Execute(session, listProducts())
+	require.NoError(t, err)
+	require.Equal(t, []product{{ID: 1, Name: "desk lamp"}, {ID: 2, Name: "chair"}}, products)
+
+	var missing product
+	err = session.Builder()(`SELECT id, name FROM products WHERE id = $1`).Arguments(3).QueryRow(&missing.ID, &missing.Name)
+	require.ErrorIs(t, err, pgx.ErrNoRows)
+	require.NoError(t, session.Close())
+
+	require.NoError(t, db.Ping(ctx))
+	require.NoError(t, db.Close(ctx))
+}
+
+func TestPoolRecordAndReplay(t *testing.T) {
+	uniqueViolation := &pgconn.PgError{Severity: "ERROR", Code: "23505", Message: "duplicate key value violates unique constraint", ConstraintName: "products_name_key"}
+
+	pool := mock.NewPGXPoolMock()
+	pool.ExpectBeginTx().WithOptions(pgx.TxOptions{IsoLevel: pgx.Serializable})
+	pool.ExpectQuery("INSERT INTO products").Contains().WithArgs("lamp").WillReturnRows(mock.NewRows([]string{"id", "name"}).AddRow(1, "lamp"))
+	pool.ExpectExec("UPDATE products").Contains().WithArgs("desk lamp", "lamp").WillReturnResult(mock.NewResult("UPDATE", 1))
+	pool.ExpectCommit()
+	pool.ExpectBeginTx()
+	pool.ExpectQuery("INSERT INTO products").Contains().WithArgs("lamp").WillReturnError(uniqueViolation)
+	pool.ExpectRollback()
+	pool.ExpectAcquire()
+	pool.ExpectQuery("SELECT id, name FROM products ORDER BY id").WillReturnRows(mock.NewRows([]string{"id", "name"}).AddRow(1, "desk lamp").AddRow(2, "chair"))
+	pool.ExpectQuery("SELECT id, name FROM products WHERE id = $1").WithArgs(3).WillReturnRows(mock.NewRows([]string{"id", "name"}))
+	pool.ExpectRelease()
+	pool.ExpectPing()
+	pool.ExpectClose()
+
+	recorder := NewPoolRecorder(pool)
+	db, err := octobe.New(postgres.OpenPGXWithPool(recorder))
+	require.NoError(t, err)
+	runScenario(t, db)
+	require.NoError(t, pool.AllExpectationsMet())
+
+	path := filepath.Join(t.TempDir(), "testdata", "products.json")
+	require.NoError(t, recorder.Save(path))
+
+	cassette, err := Load(path)
+	require.NoError(t, err)
+	methods := make([]string, len(cassette.Events))
+	for i, ev := range cassette.Events {
+		methods[i] = ev.Method
+	}
+	require.Equal(t, []string{
+		MethodBeginTx, MethodQueryRow, MethodExec, MethodCommit,
+		MethodBeginTx, MethodQueryRow, MethodRollback,
+		MethodAcquire, MethodQuery, MethodQueryRow, MethodRelease,
+		MethodPing, MethodClose,
+	}, methods)
+	require.Equal(t, 1, cassette.Events[0].Tx)
+	require.Equal(t, 1, cassette.Events[1].Session)
+	require.Equal(t, 3, cassette.Events[7].Session)
+	require.Equal(t, mock.NewResult("UPDATE", 1).String(), cassette.Events[2].CommandTag)
+
+	player := NewPoolPlayer(cassette)
+	db, err = octobe.New(postgres.OpenPGXWithPool(player))
+	require.NoError(t, err)
+	runScenario(t, db)
+	require.NoError(t, player.AllEventsReplayed())
+}
+
+func TestConnRecordAndReplay(t *testing.T) {
+	ctx := context.Background()
+	expectedErr := errors.New("connection reset")
+
+	conn := mock.NewPGXMock()
+	conn.ExpectExec("DELETE FROM products").WillReturnResult(mock.NewResult("DELETE", 2))
+	conn.ExpectQuery("SELECT id, name FROM products ORDER BY id").WillReturnError(expectedErr)
+
+	run := func(db postgres.PGXDriver) {
+		session, err := db.Begin(ctx)
+		require.NoError(t, err)
+
+		res, err := session.Builder()(`DELETE FROM products`).Exec()
+		require.NoError(t, err)
+		require.Equal(t, int64(2), res.RowsAffected)
+
+		_, err = octobe.Execute(session, listProducts())
+		require.EqualError(t, err, "connection reset")
+	}
+
+	recorder := NewConnRecorder(conn)
+	db, err := octobe.New(postgres.OpenPGXWithConn(recorder))
+	require.NoError(t, err)
+	run(db)
+	require.NoError(t, conn.AllExpectationsMet())
+
+	player := NewConnPlayer(recorder.Cassette())
+	db, err = octobe.New(postgres.OpenPGXWithConn(player))
+	require.NoError(t, err)
+	run(db)
+	require.NoError(t, player.AllEventsReplayed())
+}
@@ -0,0 +1,128 @@
+// Package replay records the database calls of PostgreSQL handlers into a
+// versioned cassette file and replays them offline, so large handlers can be
+// tested deterministically without writing expectation chains by hand.
+//
+// A recorder wraps a real pgx pool or connection and captures every statement,
+// its arguments, the raw result rows, command tags and errors, together with
+// the session the call ran on: the pool itself, a pinned pool connection or a
+// transaction. A player serves the cassette in strict order and fails on the
+// first call that diverges from it.
+//
+// Most tests use OpenPGXPool or OpenPGX. They replay testdata by default and
+// record against a live database when the test binary runs with -replay.update:
+//
+//	func TestCreatePostWithTags(t *testing.T) {
+//	    db := replay.OpenPGXPool(t, "testdata/create_post_with_tags.json", func(ctx context.Context) (postgres.PGXPool, error) {
+//	        return pgxpool.New(ctx, os.Getenv("DSN"))
+//	    })
+//	    // run handlers against db
+//	}
+//
+//	go test ./... -run TestCreatePostWithTags -replay.update
+//
+// Replay requires handlers to be deterministic: arguments are compared with
+// their recorded JSON encoding, so values such as time.Now() or random ids must
+// be injected by the test. Calls from concurrent goroutines are replayed in the
+// recorded order only.
+package replay
+
+import (
+	"context"
+	"flag"
+	"testing"
+
+	"github.com/Kansuler/octobe/v3"
+	"github.com/Kansuler/octobe/v3/driver/postgres"
+)
+
+var update = flag.Bool("replay.update", false, "record replay cassettes against a live database")
+
+// Updating reports whether cassettes are being re-recorded with -replay.update.
+func Updating() bool {
+	return *update
+}
+
+// OpenPGXPool returns a pool driver that replays the cassette at path. With
+// -replay.update it instead connects with connect and records a new cassette,
+// which is written when the test passes.
+func OpenPGXPool(t testing.TB, path string, connect func(context.Context) (postgres.PGXPool, error)) postgres.PGXPoolDriver {
+	t.Helper()
+
+	if !Updating() {
+		c, err := Load(path)
+		if err != nil {
+			t.Fatalf("load cassette: %v", err)
+		}
+		p := NewPoolPlayer(c)
+		t.Cleanup(func() {
+			if err := p.AllEventsReplayed(); err != nil {
+				t.Error(err)
+			}
+		})
+		return open(t, postgres.OpenPGXWithPool(p))
+	}
+
+	pool, err := connect(context.Background())
+	if err != nil {
+		t.Fatalf("connect to record %s: %v", path, err)
+	}
+	r := NewPoolRecorder(pool)
+	t.Cleanup(func() {
+		save(t, path, &r.recorder)
+		pool.Close()
+	})
+	return open(t, postgres.OpenPGXWithPool(r))
+}
+
+// OpenPGX returns a connection driver that replays the cassette at path. With
+// -replay.update it instead connects with connect and records a new cassette,
+// which is written when the test passes.
+func OpenPGX(t testing.TB, path string, connect func(context.Context) (postgres.PGXConn, error)) postgres.PGXDriver {
+	t.Helper()
+
+	if !Updating() {
+		c, err := Load(path)
+		if err != nil {
+			t.Fatalf("load cassette: %v", err)
+		}
+		p := NewConnPlayer(c)
+		t.Cleanup(func() {
+			if err := p.AllEventsReplayed(); err != nil {
+				t.Error(err)
+			}
+		})
+		return open(t, postgres.OpenPGXWithConn(p))
+	}
+
+	ctx := context.Background()
+	conn, err := connect(ctx)
+	if err != nil {
+		t.Fatalf("connect to record %s: %v", path, err)
+	}
+	r := NewConnRecorder(conn)
+	t.Cleanup(func() {
+		save(t, path, &r.recorder)
+		_ = conn.Close(ctx)
+	})
+	return open(t, postgres.OpenPGXWithConn(r))
+}
+
+func open[DRIVER any](t testing.TB, init octobe.Open[DRIVER, postgres.Config, postgres.Builder]) octobe.Driver[DRIVER, postgres.Config, postgres.Builder] {
+	t.Helper()
+	db, err := octobe.New(init)
+	if err != nil {
+		t.Fatalf("open driver: %v", err)
+	}
+	return db
+}
+
+// save writes the recording unless the test failed, so a broken run never replaces a good cassette.
+func save(t testing.TB, path string, r *recorder) {
+	if t.Failed() {
+		t.Logf("not writing cassette %s: test failed", path)
+		return
+	}
+	if err := r.Save(path); err != nil {
+		t.Errorf("write cassette: %v", err)
+	}
+}
@@ -0,0 +1,181 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/Kansuler/octobe/v3"
+	"github.com/Kansuler/octobe/v3/internal/sqlsession"
+)
+
+// DB defines the *sql.DB methods used by the driver.
+type DB interface {
+	Close() error
+	PingContext(ctx context.Context) error
+	Conn(ctx context.Context) (*sql.Conn, error)
+	BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error)
+}
+
+var _ DB = &sql.DB{}
+
+type sqlDB struct {
+	db DB
+}
+
+var _ SQLDriver = &sqlDB{}
+
+// Open creates a driver from a database/sql driver name and DSN and verifies connectivity.
+func Open(ctx context.Context, driverName, dsn string) SQLOpen {
+	return func() (SQLDriver, error) {
+		db, err := sql.Open(driverName, dsn)
+		if err != nil {
+			return nil, err
+		}
+		if err := db.PingContext(ctx); err != nil {
+			_ = db.Close()
+			return nil, err
+		}
+
+		return &sqlDB{
+			db: db,
+		}, nil
+	}
+}
+
+// OpenWithDB creates a driver from an existing database handle.
+func OpenWithDB(db DB) SQLOpen {
+	return func() (SQLDriver, error) {
+		if db == nil {
+			return nil, errors.New("db is nil")
+		}
+
+		return &sqlDB{
+			db: db,
+		}, nil
+	}
+}
+
+// Begin starts a new non-transactional session.
+// The session pins one connection from the pool and keeps it until Close.
+func (d *sqlDB) Begin(ctx context.Context) (octobe.Session[Builder], error) {
+	if d.db == nil {
+		return nil, errors.New("db is nil")
+	}
+
+	session, err := sqlsession.Begin(ctx, d.db)
+	if err != nil {
+		return nil, err
+	}
+
+	return &sqlSession{
+		Session: session,
+	}, nil
+}
+
+// BeginTx starts a new transactional session.
+func (d *sqlDB) BeginTx(ctx context.Context, opts ...Option) (octobe.Session[Builder], error) {
+	if d.db == nil {
+		return nil, errors.New("db is nil")
+	}
+
+	var cfg Config
+	for _, opt := range transactionOptions(opts) {
+		opt(&cfg)
+	}
+
+	session, err := sqlsession.BeginTx(ctx, d.db, (*sql.TxOptions)(cfg.txOptions))
+	if err != nil {
+		return nil, err
+	}
+
+	return &sqlSession{
+		Session: session,
+	}, nil
+}
+
+// Close closes the database handle and all of its connections.
+func (d *sqlDB) Close(_ context.Context) error {
+	if d.db == nil {
+		return errors.New("db is nil")
+	}
+	return d.db.Close()
+}
+
+// Ping verifies that the database is reachable.
+func (d *sqlDB) Ping(ctx context.Context) error {
+	if d.db == nil {
+		return errors.New("db is nil")
+	}
+	return d.db.PingContext(ctx)
+}
+
+// StartTransaction starts a transactional session.
+func (d *sqlDB) StartTransaction(ctx context.Context, fn func(session octobe.BuilderSession[Builder]) error, opts ...Option) (err error) {
+	return octobe.StartTransaction[DB](ctx, d, fn, opts...)
+}
+
+// sqlSession adds the query builder of the driver to the shared database/sql session.
+type sqlSession struct {
+	*sqlsession.Session
+}
+
+var _ octobe.Session[Builder] = &sqlSession{}
+
+// Builder returns a query builder function for this session.
+func (s *sqlSession) Builder() Builder {
+	return func(query string) Segment {
+		return &sqlSegment{
+			segment: s.Segment(query),
+		}
+	}
+}
+
+// sqlSegment represents a single-use query with arguments and execution tracking.
+type sqlSegment struct {
+	segment *sqlsession.Segment
+}
+
+var _ ContextSegment = &sqlSegment{}
+
+// Arguments sets query parameters and returns the segment for method chaining.
+func (s *sqlSegment) Arguments(args ...any) Segment {
+	s.segment.Arguments(args...)
+	return s
+}
+
+// WithContext sets a context for this statement alone, used together with the session context.
+func (s *sqlSegment) WithContext(ctx context.Context) Segment {
+	s.segment.WithContext(ctx)
+	return s
+}
+
+// Exec executes the query and returns the number of affected rows.
+func (s *sqlSegment) Exec() (ExecResult, error) {
+	var result ExecResult
+	err := s.segment.Exec(func(res sql.Result) error {
+		affected, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+		result.RowsAffected = affected
+		return nil
+	})
+	if err != nil {
+		return ExecResult{}, err
+	}
+	return result, nil
+}
+
+// QueryRow executes the query expecting exactly one row and scans into dest.
+// It returns sql.ErrNoRows when the query selects no rows.
+func (s *sqlSegment) QueryRow(dest ...any) error {
+	return s.segment.QueryRow(dest...)
+}
+
+// Query executes the query and calls cb for each row in the result set.
+func (s *sqlSegment) Query(cb func(Rows) error) error {
+	return s.segment.Query(func(rows *sql.Rows) error {
+		return cb(rows)
+	})
+}
@@ -0,0 +1,276 @@
+package sql_test
+
+import (
+	"context"
+	stdsql "database/sql"
+	"errors"
+	"testing"
+
+	"github.com/Kansuler/octobe/v3"
+	"github.com/Kansuler/octobe/v3/driver/sql"
+	"github.com/Kansuler/octobe/v3/driver/sql/mock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type Product struct {
+	ID   int
+	Name string
+}
+
+func Migration() octobe.Handler[octobe.Void, sql.Builder] {
+	return func(builder sql.Builder) (octobe.Void, error) {
+		query := builder(`
+			CREATE TABLE IF NOT EXISTS products (
+				id SERIAL PRIMARY KEY,
+				name TEXT NOT NULL
+			);
+		`)
+		_, err := query.Exec()
+		return nil, err
+	}
+}
+
+func AddProduct(name string) octobe.Handler[Product, sql.Builder] {
+	return func(builder sql.Builder) (Product, error) {
+		var product Product
+		query := builder(`
+			INSERT INTO products (name) VALUES ($1) RETURNING id, name;
+		`)
+		err := query.Arguments(name).QueryRow(&product.ID, &product.Name)
+		return product, err
+	}
+}
+
+func ProductsByName(name string) octobe.Handler[[]Product, sql.Builder] {
+	return func(builder sql.Builder) ([]Product, error) {
+		var products []Product
+		query := builder(`
+			SELECT id, name FROM products WHERE name = $1;
+		`)
+		err := query.Arguments(name).Query(func(rows sql.Rows) error {
+			for rows.Next() {
+				var product Product
+				if err := rows.Scan(&product.ID, &product.Name); err != nil {
+					return err
+				}
+				products = append(products, product)
+			}
+			return rows.Err()
+		})
+		return products, err
+	}
+}
+
+func TestSQLWithTxInsideStartTransaction(t *testing.T) {
+	m := mock.NewSQLMock()
+	name := "Some name"
+	m.ExpectBeginTx()
+	m.ExpectExec("CREATE TABLE IF NOT EXISTS products").Contains().WillReturnResult(mock.NewResult(0, 0))
+	m.ExpectQuery("INSERT INTO products").Contains().WithArgs(name).WillReturnRows(mock.NewRows([]string{"id", "name"}).AddRow(1, name))
+	m.ExpectQuery("SELECT id, name FROM products").Contains().WithArgs(name).WillReturnRows(mock.NewRows([]string{"id", "name"}).AddRow(1, name))
+	m.ExpectCommit()
+	m.ExpectClose()
+
+	ob, err := octobe.New(sql.OpenWithDB(m.DB()))
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	err = ob.StartTransaction(ctx, func(session octobe.BuilderSession[sql.Builder]) error {
+		if err := octobe.ExecuteVoid(session, Migration()); err != nil {
+			return err
+		}
+
+		product, err := octobe.Execute(session, AddProduct(name))
+		if err != nil {
+			return err
+		}
+		assert.Equal(t, Product{ID: 1, Name: name}, product)
+
+		products, err := octobe.Execute(session, ProductsByName(name))
+		if err != nil {
+			return err
+		}
+		assert.Equal(t, []Product{{ID: 1, Name: name}}, products)
+		return nil
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, ob.Close(ctx))
+	require.NoError(t, m.AllExpectationsMet())
+}
+
+func TestSQLStartTransactionRollbackOnError(t *testing.T) {
+	m := mock.NewSQLMock()
+	m.ExpectBeginTx().WithOptions(stdsql.TxOptions{Isolation: stdsql.LevelSerializable, ReadOnly: true})
+	m.ExpectExec("CREATE TABLE IF NOT EXISTS products").Contains()
+	m.ExpectRollback()
+
+	ob, err := octobe.New(sql.OpenWithDB(m.DB()))
+	require.NoError(t, err)
+
+	expectedErr := errors.New("something went wrong")
+	err = ob.StartTransaction(context.Background(), func(session octobe.BuilderSession[sql.Builder]) error {
+		if err := octobe.ExecuteVoid(session, Migration()); err != nil {
+			return err
+		}
+		return expectedErr
+	}, sql.WithTxOptions(sql.TxOptions{Isolation: stdsql.LevelSerializable, ReadOnly: true}))
+	require.Equal(t, expectedErr, err)
+	require.NoError(t, m.AllExpectationsMet())
+}
+
+func TestSQLStartTransactionRollbackOnPanic(t *testing.T) {
+	m := mock.NewSQLMock()
+	m.ExpectBeginTx()
+	m.ExpectRollback()
+
+	ob, err := octobe.New(sql.OpenWithDB(m.DB()))
+	require.NoError(t, err)
+
+	require.PanicsWithValue(t, "oh no!", func() {
+		_ = ob.StartTransaction(context.Background(), func(session octobe.BuilderSession[sql.Builder]) error {
+			panic("oh no!")
+		})
+	})
+	require.NoError(t, m.AllExpectationsMet())
+}
+
+func TestSQLWithoutTx(t *testing.T) {
+	m := mock.NewSQLMock()
+	name := "Some name"
+	m.ExpectQuery("INSERT INTO products").Contains().WithArgs(name).WillReturnRows(mock.NewRows([]string{"id", "name"}).AddRow(7, name))
+	m.ExpectExec("DELETE FROM products").WithArgs(7).WillReturnResult(mock.NewResult(0, 1))
+
+	ob, err := octobe.New(sql.OpenWithDB(m.DB()))
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	session, err := ob.Begin(ctx)
+	require.NoError(t, err)
+
+	product, err := octobe.Execute(session, AddProduct(name))
+	require.NoError(t, err)
+
+	res, err := session.Builder()(`DELETE FROM products`).Arguments(product.ID).Exec()
+	require.NoError(t, err)
+	require.Equal(t, sql.ExecResult{RowsAffected: 1}, res)
+
+	require.EqualError(t, session.Commit(), "cannot commit without transaction")
+	require.EqualError(t, session.Rollback(), "cannot rollback without transaction")
+	require.NoError(t, session.Close())
+	require.NoError(t, session.Close())
+
+	_, err = session.Builder()(`DELETE FROM products`).Exec()
+	require.EqualError(t, err, "session is closed")
+	require.NoError(t, m.AllExpectationsMet())
+}
+
+func TestSQLSegmentSingleUse(t *testing.T) {
+	m := mock.NewSQLMock()
+	m.ExpectBeginTx()
+	m.ExpectExec("UPDATE products SET name = $1").WithArgs("x")
+	m.ExpectCommit()
+
+	ob, err := octobe.New(sql.OpenWithDB(m.DB()))
+	require.NoError(t, err)
+
+	err = ob.StartTransaction(context.Background(), func(session octobe.BuilderSession[sql.Builder]) error {
+		segment := session.Builder()(`UPDATE products SET name = $1`).Arguments("x")
+		if _, err := segment.Exec(); err != nil {
+			return err
+		}
+
+		_, err := segment.Exec()
+		assert.ErrorIs(t, err, octobe.ErrAlreadyUsed)
+		assert.ErrorIs(t, segment.QueryRow(), octobe.ErrAlreadyUsed)
+		assert.ErrorIs(t, segment.Query(func(sql.Rows) error { return nil }), octobe.ErrAlreadyUsed)
+		return nil
+	})
+	require.NoError(t, err)
+	require.NoError(t, m.AllExpectationsMet())
+}
+
+func TestSQLQueryRowNoRows(t *testing.T) {
+	m := mock.NewSQLMock()
+	m.ExpectBeginTx()
+	m.ExpectQuery("SELECT name FROM products WHERE id = $1").WithArgs(1).WillReturnRows(mock.NewRows([]string{"name"}))
+	m.ExpectRollback()
+
+	ob, err := octobe.New(sql.OpenWithDB(m.DB()))
+	require.NoError(t, err)
+
+	err = ob.StartTransaction(context.Background(), func(session octobe.BuilderSession[sql.Builder]) error {
+		var name string
+		return session.Builder()(`SELECT name FROM products WHERE id = $1`).Arguments(1).QueryRow(&name)
+	})
+	require.ErrorIs(t, err, stdsql.ErrNoRows)
+	require.NoError(t, m.AllExpectationsMet())
+}
+
+func TestSQLQueryIterationError(t *testing.T) {
+	m := mock.NewSQLMock()
+	expectedErr := errors.New("connection reset")
+	m.ExpectBeginTx()
+	m.ExpectQuery("SELECT id, name FROM products").Contains().WillReturnRows(
+		mock.NewRows([]string{"id", "name"}).AddRow(1, "a").RowError(1, expectedErr),
+	)
+	m.ExpectRollback()
+
+	ob, err := octobe.New(sql.OpenWithDB(m.DB()))
+	require.NoError(t, err)
+
+	var products []Product
+	err = ob.StartTransaction(context.Background(), func(session octobe.BuilderSession[sql.Builder]) error {
+		var err error
+		products, err = octobe.Execute(session, ProductsByName("a"))
+		return err
+	})
+	require.ErrorIs(t, err, expectedErr)
+	require.Equal(t, []Product{{ID: 1, Name: "a"}}, products)
+	require.NoError(t, m.AllExpectationsMet())
+}
+
+func TestSQLPingAndErrors(t *testing.T) {
+	m := mock.NewSQLMock()
+	expectedErr := errors.New("begin failed")
+	m.ExpectPing()
+	m.ExpectBeginTx().WillReturnError(expectedErr)
+
+	ob, err := octobe.New(sql.OpenWithDB(m.DB()))
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	require.NoError(t, ob.Ping(ctx))
+
+	_, err = ob.BeginTx(ctx)
+	require.ErrorIs(t, err, expectedErr)
+	require.NoError(t, m.AllExpectationsMet())
+
+	_, err = octobe.New(sql.OpenWithDB(nil))
+	require.EqualError(t, err, "db is nil")
+}
+
+func TestSQLSegmentWithContext(t *testing.T) {
+	m := mock.NewSQLMock()
+	m.ExpectBeginTx()
+	m.ExpectRollback()
+
+	ob, err := octobe.New(sql.OpenWithDB(m.DB()))
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	stmtCtx, cancel := context.WithCancel(ctx)
+	cancel()
+	err = ob.StartTransaction(ctx, func(session octobe.BuilderSession[sql.Builder]) error {
+		_, err := session.Builder()(`DELETE FROM products`).(sql.ContextSegment).WithContext(stmtCtx).Exec()
+		require.ErrorIs(t, err, octobe.ErrStatementCanceled)
+		require.ErrorIs(t, err, context.Canceled)
+
+		_, err = octobe.Execute(session, ProductsByName("Some name"))
+		require.ErrorContains(t, err, "transaction aborted by an earlier statement")
+		return nil
+	})
+	require.ErrorIs(t, err, octobe.ErrStatementCanceled)
+	require.NoError(t, m.AllExpectationsMet())
+}
@@ -0,0 +1,36 @@
+package mock
+
+import (
+	"time"
+
+	"github.com/Kansuler/octobe/v3/internal/mockmatch"
+)
+
+// ArgMatcher matches a single argument of a mocked call. WithArgs accepts
+// matchers alongside literal values, which are compared with reflect.DeepEqual.
+// String describes the matcher in mismatch messages.
+type ArgMatcher = mockmatch.ArgMatcher
+
+// AnyArg matches any argument, including nil.
+func AnyArg() ArgMatcher { return mockmatch.AnyArg() }
+
+// AnyOfType matches any argument of type T. If T is an interface type, it
+// matches arguments implementing it.
+func AnyOfType[T any]() ArgMatcher { return mockmatch.AnyOfType[T]() }
+
+// TimeWithin matches a time.Time, or a non-nil *time.Time, that lies within d
+// of the time the call is matched, for timestamps set with time.Now().
+func TimeWithin(d time.Duration) ArgMatcher { return mockmatch.TimeWithin(d) }
+
+// Regexp matches string and []byte arguments against the regular expression.
+// It panics if pattern does not compile.
+func Regexp(pattern string) ArgMatcher { return mockmatch.Regexp(pattern) }
+
+// JSONEq matches arguments that hold JSON semantically equal to expected,
+// ignoring formatting and key order. String and []byte arguments are parsed as
+// JSON, any other argument is marshaled first. It panics if expected is not
+// valid JSON.
+func JSONEq(expected string) ArgMatcher { return mockmatch.JSONEq(expected) }
+
+// Func matches arguments for which fn returns true.
+func Func(fn func(any) bool) ArgMatcher { return mockmatch.Func(fn) }
@@ -0,0 +1,240 @@
+package mock
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"io"
+
+	"github.com/Kansuler/octobe/v3/internal/mockmatch"
+)
+
+// expectation defines the interface for mock database operation expectations.
+type expectation interface {
+	fulfilled() bool
+	match(method string, args ...any) error
+	getReturns() []any
+	String() string
+}
+
+type basicExpectation struct {
+	isFulfilled bool
+	returns     []any
+	statement   mockmatch.Statement
+}
+
+func (e *basicExpectation) fulfilled() bool {
+	return e.isFulfilled
+}
+
+func (e *basicExpectation) getReturns() []any {
+	e.isFulfilled = true
+	return e.returns
+}
+
+// WithArgs sets the expected arguments. Each one is a literal value compared
+// with reflect.DeepEqual or an ArgMatcher.
+func (e *basicExpectation) WithArgs(args ...any) {
+	e.statement.Args = args
+}
+
+func (e *basicExpectation) setContains() {
+	e.statement.SetContains()
+}
+
+func (e *basicExpectation) setRegex() {
+	e.statement.SetRegex()
+}
+
+// match validates that the method call matches the expected signature and arguments.
+func (e *basicExpectation) match(method string, args ...any) error {
+	return e.statement.Match(method, args...)
+}
+
+func (e *basicExpectation) String() string {
+	return e.statement.String()
+}
+
+type PingExpectation struct {
+	basicExpectation
+}
+
+func (e *PingExpectation) WillReturnError(err error) {
+	e.returns = []any{err}
+}
+
+type CloseExpectation struct {
+	basicExpectation
+}
+
+func (e *CloseExpectation) WillReturnError(err error) {
+	e.returns = []any{err}
+}
+
+// Result is a driver.Result for mocking Exec operation results.
+type Result struct {
+	lastInsertID int64
+	rowsAffected int64
+}
+
+var _ driver.Result = Result{}
+
+// NewResult creates a driver.Result for mocking Exec operation results.
+func NewResult(lastInsertID, rowsAffected int64) Result {
+	return Result{lastInsertID: lastInsertID, rowsAffected: rowsAffected}
+}
+
+func (r Result) LastInsertId() (int64, error) { return r.lastInsertID, nil }
+
+func (r Result) RowsAffected() (int64, error) { return r.rowsAffected, nil }
+
+type ExecExpectation struct {
+	basicExpectation
+}
+
+func (e *ExecExpectation) WithArgs(args ...any) *ExecExpectation {
+	e.basicExpectation.WithArgs(args...)
+	return e
+}
+
+// Contains makes this expectation match queries containing the configured query string.
+func (e *ExecExpectation) Contains() *ExecExpectation {
+	e.setContains()
+	return e
+}
+
+// Regex makes this expectation match queries with the configured regular expression.
+func (e *ExecExpectation) Regex() *ExecExpectation {
+	e.setRegex()
+	return e
+}
+
+// NormalizeWhitespace compares queries with leading and trailing whitespace
+// trimmed and every other run of whitespace collapsed into a single space, so
+// indentation and line breaks in long SQL do not matter. It applies to exact,
+// Contains and Regex matching.
+func (e *ExecExpectation) NormalizeWhitespace() *ExecExpectation {
+	e.statement.Normalize = true
+	return e
+}
+
+func (e *ExecExpectation) WillReturnResult(res driver.Result) {
+	e.returns = []any{res, nil}
+}
+
+func (e *ExecExpectation) WillReturnError(err error) {
+	e.returns = []any{nil, err}
+}
+
+// QueryExpectation matches Query calls. database/sql implements QueryRow on
+// top of Query, so QueryRow segments are matched by query expectations too.
+type QueryExpectation struct {
+	basicExpectation
+}
+
+func (e *QueryExpectation) WithArgs(args ...any) *QueryExpectation {
+	e.basicExpectation.WithArgs(args...)
+	return e
+}
+
+// Contains makes this expectation match queries containing the configured query string.
+func (e *QueryExpectation) Contains() *QueryExpectation {
+	e.setContains()
+	return e
+}
+
+// Regex makes this expectation match queries with the configured regular expression.
+func (e *QueryExpectation) Regex() *QueryExpectation {
+	e.setRegex()
+	return e
+}
+
+// NormalizeWhitespace compares queries with leading and trailing whitespace
+// trimmed and every other run of whitespace collapsed into a single space, so
+// indentation and line breaks in long SQL do not matter. It applies to exact,
+// Contains and Regex matching.
+func (e *QueryExpectation) NormalizeWhitespace() *QueryExpectation {
+	e.statement.Normalize = true
+	return e
+}
+
+func (e *QueryExpectation) WillReturnRows(rows *Rows) {
+	e.returns = []any{rows, nil}
+}
+
+func (e *QueryExpectation) WillReturnError(err error) {
+	e.returns = []any{nil, err}
+}
+
+type BeginTxExpectation struct{ basicExpectation }
+
+func (e *BeginTxExpectation) WithOptions(opts sql.TxOptions) *BeginTxExpectation {
+	e.statement.Args = []any{opts}
+	return e
+}
+
+func (e *BeginTxExpectation) WillReturnError(err error) { e.returns = []any{err} }
+
+type CommitExpectation struct{ basicExpectation }
+
+func (e *CommitExpectation) WillReturnError(err error) { e.returns = []any{err} }
+
+type RollbackExpectation struct{ basicExpectation }
+
+func (e *RollbackExpectation) WillReturnError(err error) { e.returns = []any{err} }
+
+// Rows provides a mock implementation of driver.Rows for testing Query operations.
+type Rows struct {
+	columns   []string
+	rows      [][]any
+	rowErrors map[int]error
+	pos       int
+}
+
+var _ driver.Rows = (*Rows)(nil)
+
+func NewRows(columns []string) *Rows {
+	return &Rows{columns: columns}
+}
+
+// AddRow appends a data row with values matching the column count.
+func (r *Rows) AddRow(values ...any) *Rows {
+	if len(values) != len(r.columns) {
+		panic("number of values does not match number of columns")
+	}
+	r.rows = append(r.rows, values)
+	return r
+}
+
+// RowError makes iteration fail with err when it reaches the row at index row.
+func (r *Rows) RowError(row int, err error) *Rows {
+	if r.rowErrors == nil {
+		r.rowErrors = make(map[int]error)
+	}
+	r.rowErrors[row] = err
+	return r
+}
+
+func (r *Rows) Columns() []string { return r.columns }
+
+func (r *Rows) Close() error { return nil }
+
+// Next copies the next row into dest. Values are handed to database/sql as
+// they were added, which converts them into the scan destinations.
+func (r *Rows) Next(dest []driver.Value) error {
+	if err, ok := r.rowErrors[r.pos]; ok {
+		return err
+	}
+	if r.pos >= len(r.rows) {
+		return io.EOF
+	}
+	for i, val := range r.rows[r.pos] {
+		dest[i] = val
+	}
+	r.pos++
+	return nil
+}
+
+// GetRowsForTesting exposes internal row data for test verification.
+func (r *Rows) GetRowsForTesting() [][]any {
+	return r.rows
+}
@@ -0,0 +1,298 @@
+// Package mock provides an expectation-based fake for the database/sql driver.
+//
+// Queries and arguments are matched the same way as in driver/postgres/mock:
+// exact, Contains or Regex queries, NormalizeWhitespace, and WithArgs with
+// literal values or argument matchers such as AnyArg. The expectations are
+// simpler, though. Every expectation matches exactly one call, in the order the
+// expectations were added, and a later WillReturn call replaces the earlier
+// response. Exec expectations without a response report no affected rows,
+// while queries without one fail as they do in the postgres mock.
+//
+// The postgres mock features that are not supported are call counts (Times,
+// AtLeast, AnyTimes, Maybe), response sequences with Then, unordered groups,
+// Scoped sessions, InTransaction and OutsideTransaction, WillDelayFor, and the
+// Calls and TransactionReport logs.
+package mock
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/Kansuler/octobe/v3/internal/mockmatch"
+)
+
+var ErrNoExpectation = errors.New("no expectation found")
+
+// errNoRows is the error of a query whose expectation was not given a response.
+var errNoRows = errors.New("no response configured for Query, set one with WillReturnRows or WillReturnError")
+
+// SQLMock provides a fake driver.Connector for testing database/sql interactions
+// without requiring an actual database. Open a *sql.DB on it with DB or
+// sql.OpenDB and pass that to sql.OpenWithDB.
+type SQLMock struct {
+	mu           sync.Mutex
+	expectations []expectation
+}
+
+var (
+	_ driver.Connector = (*SQLMock)(nil)
+	_ io.Closer        = (*SQLMock)(nil)
+)
+
+// NewSQLMock creates a new mock connector for testing.
+func NewSQLMock() *SQLMock {
+	return &SQLMock{}
+}
+
+// DB opens a *sql.DB whose connections are served by the mock.
+func (m *SQLMock) DB() *sql.DB {
+	return sql.OpenDB(m)
+}
+
+// findExpectation locates the first unfulfilled expectation matching the method and arguments.
+func (m *SQLMock) findExpectation(method string, args ...any) (expectation, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, e := range m.expectations {
+		if e.fulfilled() {
+			continue
+		}
+		if err := e.match(method, args...); err != nil {
+			return nil, fmt.Errorf("%w: next expectation %s does not match %s with args %v: %w", ErrNoExpectation, e, method, args, err)
+		}
+		return e, nil
+	}
+
+	return nil, fmt.Errorf("%w for %s with args %v", ErrNoExpectation, method, args)
+}
+
+func (m *SQLMock) addExpectation(e expectation) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.expectations = append(m.expectations, e)
+}
+
+// AllExpectationsMet verifies that all configured expectations have been fulfilled.
+func (m *SQLMock) AllExpectationsMet() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, e := range m.expectations {
+		if !e.fulfilled() {
+			return fmt.Errorf("unfulfilled expectation: %s", e)
+		}
+	}
+	return nil
+}
+
+// Connect returns a new mock connection. Connections are created lazily by
+// database/sql and are not subject to expectations.
+func (m *SQLMock) Connect(context.Context) (driver.Conn, error) {
+	return &conn{m: m}, nil
+}
+
+// Driver returns a driver.Driver that opens connections on the mock.
+func (m *SQLMock) Driver() driver.Driver {
+	return mockDriver{m: m}
+}
+
+func (m *SQLMock) ExpectClose() *CloseExpectation {
+	e := &CloseExpectation{basicExpectation: basicExpectation{statement: mockmatch.Statement{Method: "Close"}}}
+	m.addExpectation(e)
+	return e
+}
+
+// Close is called by (*sql.DB).Close.
+func (m *SQLMock) Close() error {
+	e, err := m.findExpectation("Close")
+	if err != nil {
+		return err
+	}
+	return errorReturn(e.getReturns())
+}
+
+func (m *SQLMock) ExpectPing() *PingExpectation {
+	e := &PingExpectation{basicExpectation: basicExpectation{statement: mockmatch.Statement{Method: "Ping"}}}
+	m.addExpectation(e)
+	return e
+}
+
+// ExpectExec configures an expectation for an Exec operation with the specified query.
+func (m *SQLMock) ExpectExec(query string) *ExecExpectation {
+	e := &ExecExpectation{
+		basicExpectation: basicExpectation{
+			statement: mockmatch.Statement{Method: "Exec", Query: query, Mode: mockmatch.Exact},
+			returns:   []any{NewResult(0, 0), nil},
+		},
+	}
+	m.addExpectation(e)
+	return e
+}
+
+// ExpectQuery configures an expectation for a Query or QueryRow operation with the specified query.
+func (m *SQLMock) ExpectQuery(query string) *QueryExpectation {
+	e := &QueryExpectation{
+		basicExpectation: basicExpectation{
+			statement: mockmatch.Statement{Method: "Query", Query: query, Mode: mockmatch.Exact},
+		},
+	}
+	m.addExpectation(e)
+	return e
+}
+
+// ExpectBeginTx configures an expectation for starting a transaction.
+func (m *SQLMock) ExpectBeginTx() *BeginTxExpectation {
+	e := &BeginTxExpectation{basicExpectation: basicExpectation{statement: mockmatch.Statement{Method: "BeginTx"}}}
+	m.addExpectation(e)
+	return e
+}
+
+func (m *SQLMock) ExpectCommit() *CommitExpectation {
+	e := &CommitExpectation{basicExpectation: basicExpectation{statement: mockmatch.Statement{Method: "Commit"}}}
+	m.addExpectation(e)
+	return e
+}
+
+func (m *SQLMock) ExpectRollback() *RollbackExpectation {
+	e := &RollbackExpectation{basicExpectation: basicExpectation{statement: mockmatch.Statement{Method: "Rollback"}}}
+	m.addExpectation(e)
+	return e
+}
+
+func errorReturn(ret []any) error {
+	if len(ret) > 0 && ret[0] != nil {
+		return ret[0].(error)
+	}
+	return nil
+}
+
+type mockDriver struct {
+	m *SQLMock
+}
+
+func (d mockDriver) Open(string) (driver.Conn, error) {
+	return &conn{m: d.m}, nil
+}
+
+// conn is a mock driver connection that routes every call to the mock's expectations.
+type conn struct {
+	m *SQLMock
+}
+
+var (
+	_ driver.Conn               = (*conn)(nil)
+	_ driver.ConnBeginTx        = (*conn)(nil)
+	_ driver.ExecerContext      = (*conn)(nil)
+	_ driver.QueryerContext     = (*conn)(nil)
+	_ driver.Pinger             = (*conn)(nil)
+	_ driver.NamedValueChecker  = (*conn)(nil)
+	_ driver.SessionResetter    = (*conn)(nil)
+	_ driver.Validator          = (*conn)(nil)
+	_ driver.Tx                 = (*tx)(nil)
+	_ driver.ConnPrepareContext = (*conn)(nil)
+)
+
+func (c *conn) Prepare(query string) (driver.Stmt, error) {
+	return nil, errors.New("mock: prepared statements are not supported")
+}
+
+func (c *conn) PrepareContext(context.Context, string) (driver.Stmt, error) {
+	return nil, errors.New("mock: prepared statements are not supported")
+}
+
+func (c *conn) Close() error { return nil }
+
+func (c *conn) Begin() (driver.Tx, error) {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+func (c *conn) BeginTx(_ context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	e, err := c.m.findExpectation("BeginTx", sql.TxOptions{
+		Isolation: sql.IsolationLevel(opts.Isolation),
+		ReadOnly:  opts.ReadOnly,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := errorReturn(e.getReturns()); err != nil {
+		return nil, err
+	}
+	return &tx{m: c.m}, nil
+}
+
+func (c *conn) Ping(context.Context) error {
+	e, err := c.m.findExpectation("Ping")
+	if err != nil {
+		return err
+	}
+	return errorReturn(e.getReturns())
+}
+
+func (c *conn) ExecContext(_ context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	e, err := c.m.findExpectation("Exec", append([]any{query}, values(args)...)...)
+	if err != nil {
+		return nil, err
+	}
+	ret := e.getReturns()
+	if ret[1] != nil {
+		return nil, ret[1].(error)
+	}
+	return ret[0].(driver.Result), nil
+}
+
+func (c *conn) QueryContext(_ context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	e, err := c.m.findExpectation("Query", append([]any{query}, values(args)...)...)
+	if err != nil {
+		return nil, err
+	}
+	ret := e.getReturns()
+	if len(ret) > 1 && ret[1] != nil {
+		return nil, ret[1].(error)
+	}
+	if len(ret) == 0 || ret[0] == nil {
+		return nil, errNoRows
+	}
+	return ret[0].(*Rows), nil
+}
+
+// CheckNamedValue accepts every argument unchanged, so expectations compare
+// against the values the handler passed rather than driver-converted ones.
+func (c *conn) CheckNamedValue(*driver.NamedValue) error { return nil }
+
+func (c *conn) ResetSession(context.Context) error { return nil }
+
+func (c *conn) IsValid() bool { return true }
+
+// tx is a mock driver transaction.
+type tx struct {
+	m *SQLMock
+}
+
+func (t *tx) Commit() error {
+	e, err := t.m.findExpectation("Commit")
+	if err != nil {
+		return err
+	}
+	return errorReturn(e.getReturns())
+}
+
+func (t *tx) Rollback() error {
+	e, err := t.m.findExpectation("Rollback")
+	if err != nil {
+		return err
+	}
+	return errorReturn(e.getReturns())
+}
+
+func values(args []driver.NamedValue) []any {
+	vals := make([]any, len(args))
+	for i, arg := range args {
+		vals[i] = arg.Value
+	}
+	return vals
+}
@@ -0,0 +1,141 @@
+package mock
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSQLMock(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Exec with args", func(t *testing.T) {
+		m := NewSQLMock()
+		db := m.DB()
+
+		m.ExpectExec("INSERT INTO events").Contains().WithArgs(1, "test").WillReturnResult(NewResult(42, 1))
+
+		res, err := db.ExecContext(ctx, "INSERT INTO events (id, name) VALUES ($1, $2)", 1, "test")
+		require.NoError(t, err)
+		id, err := res.LastInsertId()
+		require.NoError(t, err)
+		require.Equal(t, int64(42), id)
+		require.NoError(t, m.AllExpectationsMet())
+	})
+
+	t.Run("Exec error", func(t *testing.T) {
+		m := NewSQLMock()
+		expectedErr := errors.New("exec error")
+		m.ExpectExec("INSERT INTO events").WillReturnError(expectedErr)
+
+		_, err := m.DB().ExecContext(ctx, "INSERT INTO events")
+		require.ErrorIs(t, err, expectedErr)
+		require.NoError(t, m.AllExpectationsMet())
+	})
+
+	t.Run("Query rows", func(t *testing.T) {
+		m := NewSQLMock()
+		m.ExpectQuery("SELECT id, name FROM users").WillReturnRows(NewRows([]string{"id", "name"}).AddRow(1, "John").AddRow(2, "Jane"))
+
+		rows, err := m.DB().QueryContext(ctx, "SELECT id, name FROM users")
+		require.NoError(t, err)
+		defer rows.Close()
+
+		var names []string
+		for rows.Next() {
+			var id int
+			var name string
+			require.NoError(t, rows.Scan(&id, &name))
+			names = append(names, name)
+		}
+		require.NoError(t, rows.Err())
+		require.Equal(t, []string{"John", "Jane"}, names)
+		require.NoError(t, m.AllExpectationsMet())
+	})
+
+	t.Run("Regex query", func(t *testing.T) {
+		m := NewSQLMock()
+		m.ExpectQuery(`^SELECT .* FROM users WHERE id = \$1$`).Regex().WithArgs(1).WillReturnRows(NewRows([]string{"name"}).AddRow("John"))
+
+		var name string
+		require.NoError(t, m.DB().QueryRowContext(ctx, "SELECT name FROM users WHERE id = $1", 1).Scan(&name))
+		require.Equal(t, "John", name)
+		require.NoError(t, m.AllExpectationsMet())
+	})
+
+	t.Run("Argument matchers and whitespace", func(t *testing.T) {
+		m := NewSQLMock()
+		m.ExpectExec("UPDATE users SET name = $1, updated_at = $2 WHERE id = $3").
+			NormalizeWhitespace().
+			WithArgs("John", AnyOfType[time.Time](), AnyArg()).
+			WillReturnResult(NewResult(0, 1))
+
+		_, err := m.DB().ExecContext(ctx, `
+			UPDATE users
+			SET name = $1, updated_at = $2
+			WHERE id = $3`, "John", time.Now(), 7)
+		require.NoError(t, err)
+		require.NoError(t, m.AllExpectationsMet())
+	})
+
+	t.Run("Query without a response", func(t *testing.T) {
+		m := NewSQLMock()
+		m.ExpectQuery("SELECT name FROM users")
+
+		_, err := m.DB().QueryContext(ctx, "SELECT name FROM users")
+		require.EqualError(t, err, "no response configured for Query, set one with WillReturnRows or WillReturnError")
+		require.NoError(t, m.AllExpectationsMet())
+	})
+
+	t.Run("Mismatch", func(t *testing.T) {
+		m := NewSQLMock()
+		m.ExpectExec("DELETE FROM users")
+
+		_, err := m.DB().ExecContext(ctx, "DELETE FROM posts")
+		require.ErrorIs(t, err, ErrNoExpectation)
+		require.ErrorContains(t, err, "query mismatch:\n--- expected\n+++ actual\n@@ -1 +1 @@\n-DELETE FROM users\n+DELETE FROM posts")
+		require.EqualError(t, m.AllExpectationsMet(), `unfulfilled expectation: method Exec with query exact "DELETE FROM users" and args []`)
+	})
+
+	t.Run("Transaction", func(t *testing.T) {
+		m := NewSQLMock()
+		m.ExpectBeginTx().WithOptions(sql.TxOptions{Isolation: sql.LevelReadCommitted})
+		m.ExpectExec("UPDATE users SET name = $1").WithArgs("x")
+		m.ExpectCommit()
+		m.ExpectBeginTx()
+		m.ExpectRollback().WillReturnError(errors.New("rollback failed"))
+
+		db := m.DB()
+		tx, err := db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelReadCommitted})
+		require.NoError(t, err)
+		_, err = tx.ExecContext(ctx, "UPDATE users SET name = $1", "x")
+		require.NoError(t, err)
+		require.NoError(t, tx.Commit())
+
+		tx, err = db.BeginTx(ctx, nil)
+		require.NoError(t, err)
+		require.EqualError(t, tx.Rollback(), "rollback failed")
+		require.NoError(t, m.AllExpectationsMet())
+	})
+
+	t.Run("Ping and Close", func(t *testing.T) {
+		m := NewSQLMock()
+		m.ExpectPing().WillReturnError(errors.New("ping failed"))
+		m.ExpectClose()
+
+		db := m.DB()
+		require.EqualError(t, db.PingContext(ctx), "ping failed")
+		require.NoError(t, db.Close())
+		require.NoError(t, m.AllExpectationsMet())
+	})
+
+	t.Run("Unexpected call", func(t *testing.T) {
+		m := NewSQLMock()
+		_, err := m.DB().ExecContext(ctx, "DELETE FROM users")
+		require.ErrorIs(t, err, ErrNoExpectation)
+	})
+}
@@ -0,0 +1,105 @@
+// Package sql implements an Octobe driver on top of database/sql, so handlers
+// can run against any engine that ships a database/sql driver.
+//
+// Handlers look the same as with the PostgreSQL driver, only the Builder type
+// and the placeholder syntax of the underlying engine change:
+//
+//	func CreateUser(name string) octobe.Handler[int64, sql.Builder] {
+//	    return func(builder sql.Builder) (int64, error) {
+//	        var id int64
+//	        err := builder(`INSERT INTO users (name) VALUES ($1) RETURNING id`).
+//	            Arguments(name).
+//	            QueryRow(&id)
+//	        return id, err
+//	    }
+//	}
+package sql
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/Kansuler/octobe/v3"
+)
+
+type (
+	// SQLDriver is the driver interface returned by Open and OpenWithDB.
+	SQLDriver = octobe.Driver[DB, Config, Builder]
+
+	// SQLOpen opens a database/sql driver.
+	SQLOpen = octobe.Open[DB, Config, Builder]
+
+	// Option configures database/sql driver behavior.
+	Option = octobe.Option[Config]
+)
+
+// Builder constructs executable query segments from SQL strings.
+type Builder func(query string) Segment
+
+// TxOptions configures transaction isolation level and read-only mode.
+type TxOptions sql.TxOptions
+
+// Config stores database/sql driver options.
+type Config struct {
+	txOptions *TxOptions
+}
+
+// WithTxOptions configures transaction options for the session.
+func WithTxOptions(options TxOptions) Option {
+	return func(c *Config) {
+		c.txOptions = &options
+	}
+}
+
+// transactionOptions applies transaction options to the given options slice, ensuring a non-nil txOptions field.
+func transactionOptions(opts []Option) []Option {
+	txOpts := make([]Option, 0, len(opts)+1)
+	txOpts = append(txOpts, opts...)
+	txOpts = append(txOpts, func(c *Config) {
+		if c.txOptions == nil {
+			c.txOptions = &TxOptions{}
+		}
+	})
+	return txOpts
+}
+
+// Segment represents a prepared query with arguments that can be executed once.
+// Once executed, the segment becomes invalid and cannot be reused.
+//
+// The single-use nature prevents accidental query reuse and ensures predictable behavior.
+// To execute the same query multiple times, create new segments each time.
+type Segment interface {
+	Arguments(args ...any) Segment
+	Exec() (ExecResult, error)
+	QueryRow(dest ...any) error
+	Query(cb func(Rows) error) error
+}
+
+// ContextSegment is a Segment that can run under a context of its own, see octobe.ErrStatementCanceled.
+type ContextSegment interface {
+	Segment
+	WithContext(ctx context.Context) Segment
+}
+
+// ExecResult contains the outcome of an INSERT, UPDATE, or DELETE operation.
+type ExecResult struct {
+	RowsAffected int64
+}
+
+// Rows provides iteration over query result sets.
+// Callers must check Err() after Next() returns false to detect premature termination.
+type Rows interface {
+	// Err returns any error encountered during iteration.
+	// Only call after rows are closed or Next() returns false.
+	Err() error
+
+	// Next advances to the next row, returning false when no more rows exist.
+	// Automatically closes rows when iteration completes.
+	Next() bool
+
+	// Scan copies column values from the current row into dest variables.
+	// Must call Next() and verify it returned true before calling Scan.
+	Scan(dest ...any) error
+}
+
+var _ Rows = (*sql.Rows)(nil)
@@ -0,0 +1,229 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/Kansuler/octobe/v3"
+	"github.com/Kansuler/octobe/v3/internal/sqlsession"
+	_ "modernc.org/sqlite"
+)
+
+type sqliteDB struct {
+	db *sql.DB
+}
+
+var _ SQLiteDriver = &sqliteDB{}
+
+// Open creates a driver for the SQLite database at path and verifies connectivity.
+// Path is a file name, a "file:" URI or ":memory:". File databases default to the
+// WAL journal mode, a five second busy timeout and enforced foreign keys.
+//
+// Every connection to ":memory:" opens a separate empty database, so in-memory
+// databases are limited to a single connection. A session from Begin holds that
+// connection until it is closed.
+func Open(ctx context.Context, path string, opts ...OpenOption) SQLiteOpen {
+	return func() (SQLiteDriver, error) {
+		cfg := OpenConfig{
+			journalMode: JournalWAL,
+			busyTimeout: 5 * time.Second,
+			foreignKeys: true,
+		}
+		for _, opt := range opts {
+			opt(&cfg)
+		}
+
+		memory := isMemory(path)
+		db, err := sql.Open("sqlite", dsn(path, memory, cfg))
+		if err != nil {
+			return nil, err
+		}
+		if memory {
+			db.SetMaxOpenConns(1)
+			db.SetConnMaxLifetime(0)
+			db.SetConnMaxIdleTime(0)
+		}
+		if err := db.PingContext(ctx); err != nil {
+			_ = db.Close()
+			return nil, err
+		}
+
+		return &sqliteDB{
+			db: db,
+		}, nil
+	}
+}
+
+// OpenWithDB creates a driver from an existing database handle opened with the "sqlite" driver name.
+func OpenWithDB(db *sql.DB) SQLiteOpen {
+	return func() (SQLiteDriver, error) {
+		if db == nil {
+			return nil, errors.New("db is nil")
+		}
+
+		return &sqliteDB{
+			db: db,
+		}, nil
+	}
+}
+
+// isMemory reports whether path names an in-memory database.
+func isMemory(path string) bool {
+	return path == ":memory:" || strings.HasPrefix(path, "file::memory:") || strings.Contains(path, "mode=memory")
+}
+
+// dsn appends the connection pragmas from cfg to path in the form understood by modernc.org/sqlite.
+func dsn(path string, memory bool, cfg OpenConfig) string {
+	params := url.Values{}
+	params.Add("_pragma", fmt.Sprintf("busy_timeout(%d)", cfg.busyTimeout.Milliseconds()))
+	if cfg.foreignKeys {
+		params.Add("_pragma", "foreign_keys(1)")
+	} else {
+		params.Add("_pragma", "foreign_keys(0)")
+	}
+	if !memory && cfg.journalMode != "" {
+		params.Add("_pragma", fmt.Sprintf("journal_mode(%s)", cfg.journalMode))
+	}
+	for _, pragma := range cfg.pragmas {
+		params.Add("_pragma", pragma)
+	}
+
+	sep := "?"
+	if strings.Contains(path, "?") {
+		sep = "&"
+	}
+	return path + sep + params.Encode()
+}
+
+// Begin starts a new non-transactional session.
+// The session pins one connection and keeps it until Close.
+func (d *sqliteDB) Begin(ctx context.Context) (octobe.Session[Builder], error) {
+	if d.db == nil {
+		return nil, errors.New("db is nil")
+	}
+
+	session, err := sqlsession.Begin(ctx, d.db)
+	if err != nil {
+		return nil, err
+	}
+
+	return &sqliteSession{
+		Session: session,
+	}, nil
+}
+
+// BeginTx starts a new transactional session with the configured transaction mode.
+// The transaction is started with an explicit BEGIN statement on a pinned connection,
+// because database/sql has no notion of SQLite's locking modes.
+func (d *sqliteDB) BeginTx(ctx context.Context, opts ...Option) (octobe.Session[Builder], error) {
+	if d.db == nil {
+		return nil, errors.New("db is nil")
+	}
+
+	var cfg Config
+	for _, opt := range transactionOptions(opts) {
+		opt(&cfg)
+	}
+
+	session, err := sqlsession.BeginConn(ctx, d.db, "BEGIN "+string(*cfg.txMode))
+	if err != nil {
+		return nil, err
+	}
+
+	return &sqliteSession{
+		Session: session,
+	}, nil
+}
+
+// Close closes the database handle and all of its connections.
+func (d *sqliteDB) Close(_ context.Context) error {
+	if d.db == nil {
+		return errors.New("db is nil")
+	}
+	return d.db.Close()
+}
+
+// Ping verifies that the database is reachable.
+func (d *sqliteDB) Ping(ctx context.Context) error {
+	if d.db == nil {
+		return errors.New("db is nil")
+	}
+	return d.db.PingContext(ctx)
+}
+
+// StartTransaction starts a transactional session.
+func (d *sqliteDB) StartTransaction(ctx context.Context, fn func(session octobe.BuilderSession[Builder]) error, opts ...Option) (err error) {
+	return octobe.StartTransaction[*sql.DB](ctx, d, fn, opts...)
+}
+
+// sqliteSession adds the query builder of the driver to the shared database/sql session.
+type sqliteSession struct {
+	*sqlsession.Session
+}
+
+var _ octobe.Session[Builder] = &sqliteSession{}
+
+// Builder returns a query builder function for this session.
+func (s *sqliteSession) Builder() Builder {
+	return func(query string) Segment {
+		return &sqliteSegment{
+			segment: s.Segment(query),
+		}
+	}
+}
+
+// sqliteSegment represents a single-use query with arguments and execution tracking.
+type sqliteSegment struct {
+	segment *sqlsession.Segment
+}
+
+var _ ContextSegment = &sqliteSegment{}
+
+// Arguments sets query parameters and returns the segment for method chaining.
+func (s *sqliteSegment) Arguments(args ...any) Segment {
+	s.segment.Arguments(args...)
+	return s
+}
+
+// WithContext sets a context for this statement alone, used together with the session context.
+func (s *sqliteSegment) WithContext(ctx context.Context) Segment {
+	s.segment.WithContext(ctx)
+	return s
+}
+
+// Exec executes the query and returns the number of affected rows.
+func (s *sqliteSegment) Exec() (ExecResult, error) {
+	var result ExecResult
+	err := s.segment.Exec(func(res sql.Result) error {
+		affected, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+		result = ExecResult{
+			RowsAffected: affected,
+		}
+		return nil
+	})
+	if err != nil {
+		return ExecResult{}, err
+	}
+	return result, nil
+}
+
+// QueryRow executes the query expecting exactly one row and scans into dest.
+// It returns sql.ErrNoRows when the query selects no rows.
+func (s *sqliteSegment) QueryRow(dest ...any) error {
+	return s.segment.QueryRow(dest...)
+}
+
+// Query executes the query and calls cb for each row in the result set.
+func (s *sqliteSegment) Query(cb func(Rows) error) error {
+	return s.segment.Query(func(rows *sql.Rows) error {
+		return cb(rows)
+	})
+}
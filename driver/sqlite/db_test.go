@@ -0,0 +1,355 @@
+package sqlite_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Kansuler/octobe/v3"
+	"github.com/Kansuler/octobe/v3/driver/sqlite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type Product struct {
+	ID   int
+	Name string
+}
+
+func Migration() octobe.Handler[octobe.Void, sqlite.Builder] {
+	return func(builder sqlite.Builder) (octobe.Void, error) {
+		query := builder(`
+			CREATE TABLE IF NOT EXISTS products (
+				id INTEGER PRIMARY KEY,
+				name TEXT NOT NULL
+			);
+		`)
+		_, err := query.Exec()
+		return nil, err
+	}
+}
+
+func AddProduct(name string) octobe.Handler[Product, sqlite.Builder] {
+	return func(builder sqlite.Builder) (Product, error) {
+		var product Product
+		query := builder(`
+			INSERT INTO products (name) VALUES ($1) RETURNING id, name;
+		`)
+		err := query.Arguments(name).QueryRow(&product.ID, &product.Name)
+		return product, err
+	}
+}
+
+func ProductsByName(name string) octobe.Handler[[]Product, sqlite.Builder] {
+	return func(builder sqlite.Builder) ([]Product, error) {
+		var products []Product
+		query := builder(`
+			SELECT id, name FROM products WHERE name = $1 ORDER BY id;
+		`)
+		err := query.Arguments(name).Query(func(rows sqlite.Rows) error {
+			for rows.Next() {
+				var product Product
+				if err := rows.Scan(&product.ID, &product.Name); err != nil {
+					return err
+				}
+				products = append(products, product)
+			}
+			return rows.Err()
+		})
+		return products, err
+	}
+}
+
+func CountProducts() octobe.Handler[int, sqlite.Builder] {
+	return func(builder sqlite.Builder) (int, error) {
+		var count int
+		err := builder(`SELECT count(*) FROM products`).QueryRow(&count)
+		return count, err
+	}
+}
+
+func openFile(t *testing.T, opts ...sqlite.OpenOption) (sqlite.SQLiteDriver, context.Context) {
+	t.Helper()
+	ctx := context.Background()
+	ob, err := octobe.New(sqlite.Open(ctx, filepath.Join(t.TempDir(), "test.db"), opts...))
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, ob.Close(ctx))
+	})
+	require.NoError(t, ob.StartTransaction(ctx, func(session octobe.BuilderSession[sqlite.Builder]) error {
+		return octobe.ExecuteVoid(session, Migration())
+	}))
+	return ob, ctx
+}
+
+func TestSQLiteWithTxInsideStartTransaction(t *testing.T) {
+	ob, ctx := openFile(t)
+	name := "Some name"
+
+	err := ob.StartTransaction(ctx, func(session octobe.BuilderSession[sqlite.Builder]) error {
+		product, err := octobe.Execute(session, AddProduct(name))
+		if err != nil {
+			return err
+		}
+		assert.Equal(t, name, product.Name)
+
+		products, err := octobe.Execute(session, ProductsByName(name))
+		if err != nil {
+			return err
+		}
+		assert.Equal(t, []Product{product}, products)
+		return nil
+	}, sqlite.WithTxMode(sqlite.Immediate))
+	require.NoError(t, err)
+
+	session, err := ob.Begin(ctx)
+	require.NoError(t, err)
+	defer session.Close()
+
+	count, err := octobe.Execute(session, CountProducts())
+	require.NoError(t, err)
+	require.Equal(t, 1, count)
+
+	var mode string
+	require.NoError(t, session.Builder()(`PRAGMA journal_mode`).QueryRow(&mode))
+	require.Equal(t, "wal", mode)
+}
+
+func TestSQLiteStartTransactionRollbackOnError(t *testing.T) {
+	ob, ctx := openFile(t)
+
+	expectedErr := errors.New("something went wrong")
+	err := ob.StartTransaction(ctx, func(session octobe.BuilderSession[sqlite.Builder]) error {
+		if _, err := octobe.Execute(session, AddProduct("a")); err != nil {
+			return err
+		}
+		return expectedErr
+	})
+	require.Equal(t, expectedErr, err)
+
+	require.NoError(t, ob.StartTransaction(ctx, func(session octobe.BuilderSession[sqlite.Builder]) error {
+		count, err := octobe.Execute(session, CountProducts())
+		assert.Equal(t, 0, count)
+		return err
+	}))
+}
+
+func TestSQLiteStartTransactionRollbackOnPanic(t *testing.T) {
+	ob, ctx := openFile(t)
+
+	require.PanicsWithValue(t, "oh no!", func() {
+		_ = ob.StartTransaction(ctx, func(session octobe.BuilderSession[sqlite.Builder]) error {
+			if _, err := octobe.Execute(session, AddProduct("a")); err != nil {
+				return err
+			}
+			panic("oh no!")
+		})
+	})
+
+	require.NoError(t, ob.StartTransaction(ctx, func(session octobe.BuilderSession[sqlite.Builder]) error {
+		count, err := octobe.Execute(session, CountProducts())
+		assert.Equal(t, 0, count)
+		return err
+	}))
+}
+
+func TestSQLiteTxModes(t *testing.T) {
+	ob, ctx := openFile(t, sqlite.WithBusyTimeout(10*time.Millisecond))
+
+	writer, err := ob.BeginTx(ctx, sqlite.WithTxMode(sqlite.Immediate))
+	require.NoError(t, err)
+
+	// An immediate transaction holds the write lock from BEGIN, so a second one cannot start.
+	_, err = ob.BeginTx(ctx, sqlite.WithTxMode(sqlite.Immediate))
+	require.ErrorContains(t, err, "database is locked")
+
+	// A deferred transaction starts without locks and can still read in WAL mode.
+	reader, err := ob.BeginTx(ctx)
+	require.NoError(t, err)
+	count, err := octobe.Execute(reader, CountProducts())
+	require.NoError(t, err)
+	require.Equal(t, 0, count)
+	require.NoError(t, reader.Commit())
+
+	_, err = octobe.Execute(writer, AddProduct("a"))
+	require.NoError(t, err)
+	require.NoError(t, writer.Commit())
+	require.NoError(t, writer.Close())
+
+	exclusive, err := ob.BeginTx(ctx, sqlite.WithTxMode(sqlite.Exclusive))
+	require.NoError(t, err)
+	_, err = ob.BeginTx(ctx, sqlite.WithTxMode(sqlite.Immediate))
+	require.ErrorContains(t, err, "database is locked")
+	require.NoError(t, exclusive.Rollback())
+	require.NoError(t, exclusive.Rollback())
+}
+
+func TestSQLiteOpenOptions(t *testing.T) {
+	ctx := context.Background()
+	ob, err := octobe.New(sqlite.Open(ctx, filepath.Join(t.TempDir(), "test.db"),
+		sqlite.WithJournalMode(sqlite.JournalDelete),
+		sqlite.WithForeignKeys(false),
+		sqlite.WithPragma("synchronous(OFF)"),
+	))
+	require.NoError(t, err)
+	defer ob.Close(ctx)
+
+	session, err := ob.Begin(ctx)
+	require.NoError(t, err)
+	defer session.Close()
+
+	var mode string
+	require.NoError(t, session.Builder()(`PRAGMA journal_mode`).QueryRow(&mode))
+	require.Equal(t, "delete", mode)
+
+	var foreignKeys, synchronous int
+	require.NoError(t, session.Builder()(`PRAGMA foreign_keys`).QueryRow(&foreignKeys))
+	require.Equal(t, 0, foreignKeys)
+	require.NoError(t, session.Builder()(`PRAGMA synchronous`).QueryRow(&synchronous))
+	require.Equal(t, 0, synchronous)
+}
+
+func TestSQLiteForeignKeysEnforced(t *testing.T) {
+	ob, ctx := openFile(t)
+
+	err := ob.StartTransaction(ctx, func(session octobe.BuilderSession[sqlite.Builder]) error {
+		if _, err := session.Builder()(`
+			CREATE TABLE reviews (
+				id INTEGER PRIMARY KEY,
+				product_id INTEGER NOT NULL REFERENCES products (id)
+			)
+		`).Exec(); err != nil {
+			return err
+		}
+		_, err := session.Builder()(`INSERT INTO reviews (product_id) VALUES (?)`).Arguments(42).Exec()
+		return err
+	})
+	require.ErrorContains(t, err, "FOREIGN KEY constraint failed")
+}
+
+func TestSQLiteInMemoryWithoutTx(t *testing.T) {
+	ctx := context.Background()
+	ob, err := octobe.New(sqlite.Open(ctx, ":memory:"))
+	require.NoError(t, err)
+	defer ob.Close(ctx)
+
+	session, err := ob.Begin(ctx)
+	require.NoError(t, err)
+
+	require.NoError(t, octobe.ExecuteVoid(session, Migration()))
+	product, err := octobe.Execute(session, AddProduct("a"))
+	require.NoError(t, err)
+
+	res, err := session.Builder()(`DELETE FROM products WHERE id = ?`).Arguments(product.ID).Exec()
+	require.NoError(t, err)
+	require.Equal(t, sqlite.ExecResult{RowsAffected: 1}, res)
+
+	require.EqualError(t, session.Commit(), "cannot commit without transaction")
+	require.EqualError(t, session.Rollback(), "cannot rollback without transaction")
+	require.NoError(t, session.Close())
+	require.NoError(t, session.Close())
+
+	_, err = session.Builder()(`DELETE FROM products`).Exec()
+	require.EqualError(t, err, "session is closed")
+
+	// The in-memory database lives on its single connection and survives between sessions.
+	err = ob.StartTransaction(ctx, func(session octobe.BuilderSession[sqlite.Builder]) error {
+		_, err := octobe.Execute(session, AddProduct("b"))
+		return err
+	})
+	require.NoError(t, err)
+
+	session, err = ob.Begin(ctx)
+	require.NoError(t, err)
+	defer session.Close()
+	products, err := octobe.Execute(session, ProductsByName("b"))
+	require.NoError(t, err)
+	require.Len(t, products, 1)
+}
+
+func TestSQLiteSegmentSingleUse(t *testing.T) {
+	ob, ctx := openFile(t)
+
+	err := ob.StartTransaction(ctx, func(session octobe.BuilderSession[sqlite.Builder]) error {
+		segment := session.Builder()(`UPDATE products SET name = $1`).Arguments("x")
+		if _, err := segment.Exec(); err != nil {
+			return err
+		}
+
+		_, err := segment.Exec()
+		assert.ErrorIs(t, err, octobe.ErrAlreadyUsed)
+		assert.ErrorIs(t, segment.QueryRow(), octobe.ErrAlreadyUsed)
+		assert.ErrorIs(t, segment.Query(func(sqlite.Rows) error { return nil }), octobe.ErrAlreadyUsed)
+		return nil
+	})
+	require.NoError(t, err)
+}
+
+func TestSQLiteQueryRowNoRows(t *testing.T) {
+	ob, ctx := openFile(t)
+
+	err := ob.StartTransaction(ctx, func(session octobe.BuilderSession[sqlite.Builder]) error {
+		var name string
+		return session.Builder()(`SELECT name FROM products WHERE id = $1`).Arguments(1).QueryRow(&name)
+	})
+	require.ErrorIs(t, err, sql.ErrNoRows)
+}
+
+func TestSQLiteSessionLifecycle(t *testing.T) {
+	ob, ctx := openFile(t)
+
+	session, err := ob.BeginTx(ctx)
+	require.NoError(t, err)
+	require.NoError(t, session.Commit())
+	require.EqualError(t, session.Commit(), "cannot commit a session that has already been committed")
+	require.NoError(t, session.Rollback())
+
+	session, err = ob.BeginTx(ctx)
+	require.NoError(t, err)
+	require.NoError(t, session.Close())
+	require.EqualError(t, session.Commit(), "cannot commit a session that has already been closed")
+
+	require.NoError(t, ob.Ping(ctx))
+
+	_, err = octobe.New(sqlite.OpenWithDB(nil))
+	require.EqualError(t, err, "db is nil")
+}
+
+func TestSQLiteSegmentWithContext(t *testing.T) {
+	ob, ctx := openFile(t)
+	const endless = `WITH RECURSIVE c(x) AS (SELECT 1 UNION ALL SELECT x + 1 FROM c) SELECT count(*) FROM c`
+
+	err := ob.StartTransaction(ctx, func(session octobe.BuilderSession[sqlite.Builder]) error {
+		if _, err := octobe.Execute(session, AddProduct("kept")); err != nil {
+			return err
+		}
+
+		stmtCtx, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+		defer cancel()
+		var n int
+		err := session.Builder()(endless).(sqlite.ContextSegment).WithContext(stmtCtx).QueryRow(&n)
+		require.ErrorIs(t, err, octobe.ErrStatementCanceled)
+		require.ErrorIs(t, err, context.DeadlineExceeded)
+
+		_, err = octobe.Execute(session, CountProducts())
+		require.ErrorIs(t, err, octobe.ErrStatementCanceled)
+		return nil
+	})
+	require.ErrorContains(t, err, "transaction aborted by an earlier statement")
+
+	// The transaction was rolled back.
+	session, err := ob.Begin(ctx)
+	require.NoError(t, err)
+	defer session.Close()
+	count, err := octobe.Execute(session, CountProducts())
+	require.NoError(t, err)
+	require.Zero(t, count)
+
+	stmtCtx, cancel := context.WithTimeout(ctx, time.Minute)
+	defer cancel()
+	var n int
+	require.NoError(t, session.Builder()(`SELECT 1`).(sqlite.ContextSegment).WithContext(stmtCtx).QueryRow(&n))
+}
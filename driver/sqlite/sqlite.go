@@ -0,0 +1,176 @@
+// Package sqlite implements an Octobe driver for SQLite on top of the pure-Go
+// modernc.org/sqlite engine, so handlers can run in CLI tools and tests
+// without cgo or a database server.
+//
+// Handlers look the same as with the PostgreSQL driver, only the Builder type
+// changes. SQLite accepts both `?` and `$1` style placeholders:
+//
+//	func CreateNote(body string) octobe.Handler[int64, sqlite.Builder] {
+//	    return func(builder sqlite.Builder) (int64, error) {
+//	        var id int64
+//	        err := builder(`INSERT INTO notes (body) VALUES ($1) RETURNING id`).
+//	            Arguments(body).
+//	            QueryRow(&id)
+//	        return id, err
+//	    }
+//	}
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/Kansuler/octobe/v3"
+)
+
+type (
+	// SQLiteDriver is the driver interface returned by Open and OpenWithDB.
+	SQLiteDriver = octobe.Driver[*sql.DB, Config, Builder]
+
+	// SQLiteOpen opens a SQLite driver.
+	SQLiteOpen = octobe.Open[*sql.DB, Config, Builder]
+
+	// Option configures SQLite session behavior.
+	Option = octobe.Option[Config]
+
+	// OpenOption configures how Open connects to the database file.
+	OpenOption = octobe.Option[OpenConfig]
+)
+
+// Builder constructs executable query segments from SQL strings.
+type Builder func(query string) Segment
+
+// TxMode selects how a transaction acquires its locks, see
+// https://www.sqlite.org/lang_transaction.html.
+type TxMode string
+
+const (
+	// Deferred starts the transaction without locks; they are acquired by the first read or write.
+	Deferred TxMode = "DEFERRED"
+
+	// Immediate starts a write transaction right away, so concurrent writers wait on
+	// BEGIN instead of failing with SQLITE_BUSY halfway through the transaction.
+	Immediate TxMode = "IMMEDIATE"
+
+	// Exclusive is like Immediate and additionally keeps readers out in non-WAL journal modes.
+	Exclusive TxMode = "EXCLUSIVE"
+)
+
+// Config stores SQLite session options.
+type Config struct {
+	txMode *TxMode
+}
+
+// WithTxMode configures the transaction mode for the session. Transactions are deferred by default.
+func WithTxMode(mode TxMode) Option {
+	return func(c *Config) {
+		c.txMode = &mode
+	}
+}
+
+// transactionOptions applies transaction options to the given options slice, ensuring a non-nil txMode field.
+func transactionOptions(opts []Option) []Option {
+	txOpts := make([]Option, 0, len(opts)+1)
+	txOpts = append(txOpts, opts...)
+	txOpts = append(txOpts, func(c *Config) {
+		if c.txMode == nil {
+			mode := Deferred
+			c.txMode = &mode
+		}
+	})
+	return txOpts
+}
+
+// JournalMode is the SQLite journal mode configured when a connection is opened.
+type JournalMode string
+
+const (
+	JournalDelete   JournalMode = "DELETE"
+	JournalTruncate JournalMode = "TRUNCATE"
+	JournalPersist  JournalMode = "PERSIST"
+	JournalMemory   JournalMode = "MEMORY"
+	JournalWAL      JournalMode = "WAL"
+	JournalOff      JournalMode = "OFF"
+)
+
+// OpenConfig stores the connection settings applied by Open.
+type OpenConfig struct {
+	journalMode JournalMode
+	busyTimeout time.Duration
+	foreignKeys bool
+	pragmas     []string
+}
+
+// WithJournalMode sets the journal mode. File databases use WAL by default;
+// in-memory databases ignore the setting.
+func WithJournalMode(mode JournalMode) OpenOption {
+	return func(c *OpenConfig) {
+		c.journalMode = mode
+	}
+}
+
+// WithBusyTimeout sets how long a connection waits on a locked database before
+// failing with SQLITE_BUSY. Defaults to five seconds.
+func WithBusyTimeout(timeout time.Duration) OpenOption {
+	return func(c *OpenConfig) {
+		c.busyTimeout = timeout
+	}
+}
+
+// WithForeignKeys toggles foreign key enforcement. SQLite disables it by
+// default for compatibility; Open enables it unless told otherwise.
+func WithForeignKeys(enabled bool) OpenOption {
+	return func(c *OpenConfig) {
+		c.foreignKeys = enabled
+	}
+}
+
+// WithPragma runs an additional pragma on every new connection, for example
+// WithPragma("synchronous(NORMAL)").
+func WithPragma(pragma string) OpenOption {
+	return func(c *OpenConfig) {
+		c.pragmas = append(c.pragmas, pragma)
+	}
+}
+
+// Segment represents a prepared query with arguments that can be executed once.
+// Once executed, the segment becomes invalid and cannot be reused.
+//
+// The single-use nature prevents accidental query reuse and ensures predictable behavior.
+// To execute the same query multiple times, create new segments each time.
+type Segment interface {
+	Arguments(args ...any) Segment
+	Exec() (ExecResult, error)
+	QueryRow(dest ...any) error
+	Query(cb func(Rows) error) error
+}
+
+// ContextSegment is a Segment that can run under a context of its own, see octobe.ErrStatementCanceled.
+type ContextSegment interface {
+	Segment
+	WithContext(ctx context.Context) Segment
+}
+
+// ExecResult contains the outcome of an INSERT, UPDATE, or DELETE operation.
+type ExecResult struct {
+	RowsAffected int64
+}
+
+// Rows provides iteration over query result sets.
+// Callers must check Err() after Next() returns false to detect premature termination.
+type Rows interface {
+	// Err returns any error encountered during iteration.
+	// Only call after rows are closed or Next() returns false.
+	Err() error
+
+	// Next advances to the next row, returning false when no more rows exist.
+	// Automatically closes rows when iteration completes.
+	Next() bool
+
+	// Scan copies column values from the current row into dest variables.
+	// Must call Next() and verify it returned true before calling Scan.
+	Scan(dest ...any) error
+}
+
+var _ Rows = (*sql.Rows)(nil)
@@ -0,0 +1,203 @@
+package mockmatch
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// ArgMatcher matches a single argument of a mocked call. WithArgs accepts
+// matchers alongside literal values, which are compared with reflect.DeepEqual.
+// String describes the matcher in mismatch messages.
+type ArgMatcher interface {
+	Match(arg any) bool
+	String() string
+}
+
+// MatchArgs compares call arguments against expected literals and matchers.
+// The error lists the arguments by position after a one line summary.
+func MatchArgs(expected, actual []any) error {
+	if len(expected) != len(actual) {
+		return fmt.Errorf("args mismatch: expected %v, got %v%s", expected, actual, argsDiff(expected, actual))
+	}
+	for i, want := range expected {
+		if argMatches(want, actual[i]) {
+			continue
+		}
+		if m, ok := want.(ArgMatcher); ok {
+			return fmt.Errorf("args mismatch: argument %d does not match %s, got %#v%s", i, m, actual[i], argsDiff(expected, actual))
+		}
+		return fmt.Errorf("args mismatch: expected %v, got %v%s", expected, actual, argsDiff(expected, actual))
+	}
+	return nil
+}
+
+func argMatches(want, arg any) bool {
+	if m, ok := want.(ArgMatcher); ok {
+		return m.Match(arg)
+	}
+	return reflect.DeepEqual(want, arg)
+}
+
+type anyArg struct{}
+
+// AnyArg matches any argument, including nil.
+func AnyArg() ArgMatcher { return anyArg{} }
+
+func (anyArg) Match(any) bool { return true }
+func (anyArg) String() string { return "AnyArg()" }
+
+type anyOfType[T any] struct{}
+
+// AnyOfType matches any argument of type T. If T is an interface type, it
+// matches arguments implementing it.
+func AnyOfType[T any]() ArgMatcher { return anyOfType[T]{} }
+
+func (anyOfType[T]) Match(arg any) bool {
+	_, ok := arg.(T)
+	return ok
+}
+
+func (anyOfType[T]) String() string {
+	return fmt.Sprintf("AnyOfType[%s]()", reflect.TypeFor[T]())
+}
+
+type timeWithin time.Duration
+
+// TimeWithin matches a time.Time, or a non-nil *time.Time, that lies within d
+// of the time the call is matched, for timestamps set with time.Now().
+func TimeWithin(d time.Duration) ArgMatcher { return timeWithin(d) }
+
+func (m timeWithin) Match(arg any) bool {
+	var t time.Time
+	switch v := arg.(type) {
+	case time.Time:
+		t = v
+	case *time.Time:
+		if v == nil {
+			return false
+		}
+		t = *v
+	default:
+		return false
+	}
+	diff := time.Since(t)
+	if diff < 0 {
+		diff = -diff
+	}
+	return diff <= time.Duration(m)
+}
+
+func (m timeWithin) String() string {
+	return fmt.Sprintf("TimeWithin(%s)", time.Duration(m))
+}
+
+type regexpArg struct{ re *regexp.Regexp }
+
+// Regexp matches string and []byte arguments against the regular expression.
+// It panics if pattern does not compile.
+func Regexp(pattern string) ArgMatcher { return regexpArg{re: regexp.MustCompile(pattern)} }
+
+func (m regexpArg) Match(arg any) bool {
+	switch v := arg.(type) {
+	case string:
+		return m.re.MatchString(v)
+	case []byte:
+		return m.re.Match(v)
+	}
+	return false
+}
+
+func (m regexpArg) String() string {
+	return fmt.Sprintf("Regexp(%q)", m.re)
+}
+
+type jsonEq struct {
+	raw  string
+	want any
+}
+
+// JSONEq matches arguments that hold JSON semantically equal to expected,
+// ignoring formatting and key order. String and []byte arguments are parsed as
+// JSON, any other argument is marshaled first. It panics if expected is not
+// valid JSON.
+func JSONEq(expected string) ArgMatcher {
+	var want any
+	if err := json.Unmarshal([]byte(expected), &want); err != nil {
+		panic(fmt.Sprintf("JSONEq: invalid expected JSON: %v", err))
+	}
+	return jsonEq{raw: expected, want: want}
+}
+
+func (m jsonEq) Match(arg any) bool {
+	var data []byte
+	switch v := arg.(type) {
+	case string:
+		data = []byte(v)
+	case []byte:
+		data = v
+	case json.RawMessage:
+		data = v
+	default:
+		var err error
+		if data, err = json.Marshal(v); err != nil {
+			return false
+		}
+	}
+	var got any
+	if err := json.Unmarshal(data, &got); err != nil {
+		return false
+	}
+	return reflect.DeepEqual(m.want, got)
+}
+
+func (m jsonEq) String() string {
+	return fmt.Sprintf("JSONEq(%s)", m.raw)
+}
+
+type funcArg struct{ fn func(any) bool }
+
+// Func matches arguments for which fn returns true.
+func Func(fn func(any) bool) ArgMatcher { return funcArg{fn: fn} }
+
+func (m funcArg) Match(arg any) bool { return m.fn(arg) }
+
+func (m funcArg) String() string {
+	if f := runtime.FuncForPC(reflect.ValueOf(m.fn).Pointer()); f != nil {
+		return fmt.Sprintf("Func(%s)", f.Name())
+	}
+	return "Func()"
+}
+
+// argsDiff lists expected and actual arguments by position, marking the
+// positions that differ.
+func argsDiff(expected, actual []any) string {
+	var b strings.Builder
+	for i := range max(len(expected), len(actual)) {
+		switch {
+		case i >= len(actual):
+			fmt.Fprintf(&b, "\n  - argument %d: %s", i, formatArg(expected[i]))
+		case i >= len(expected):
+			fmt.Fprintf(&b, "\n  + argument %d: %s", i, formatArg(actual[i]))
+		case argMatches(expected[i], actual[i]):
+			fmt.Fprintf(&b, "\n    argument %d: %s", i, formatArg(actual[i]))
+		default:
+			fmt.Fprintf(&b, "\n  - argument %d: %s\n  + argument %d: %s", i, formatArg(expected[i]), i, formatArg(actual[i]))
+		}
+	}
+	return b.String()
+}
+
+func formatArg(v any) string {
+	switch v := v.(type) {
+	case ArgMatcher:
+		return v.String()
+	case string:
+		return fmt.Sprintf("%q", v)
+	}
+	return fmt.Sprintf("%v", v)
+}
@@ -0,0 +1,149 @@
+// Package mockmatch matches the calls received by the driver mocks against the
+// method, query and arguments of their expectations.
+package mockmatch
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/Kansuler/octobe/v3/internal/textdiff"
+)
+
+// Mode selects how the query of a Statement is compared.
+type Mode int
+
+const (
+	// None matches calls without a query.
+	None Mode = iota
+	// Exact requires the query to equal the expected one.
+	Exact
+	// Contains requires the query to contain the expected one.
+	Contains
+	// Regex requires the query to match the expected regular expression.
+	Regex
+)
+
+// Statement describes the calls an expectation accepts.
+type Statement struct {
+	Method string
+	Query  string
+	Mode   Mode
+	// Normalize compares queries with their whitespace collapsed.
+	Normalize bool
+	// Args are literal values compared with reflect.DeepEqual or ArgMatchers.
+	// Nil accepts any arguments.
+	Args []any
+
+	queryRE *regexp.Regexp
+}
+
+// SetContains makes the statement match queries containing Query.
+func (s *Statement) SetContains() {
+	s.Mode = Contains
+	s.queryRE = nil
+}
+
+// SetRegex makes the statement match queries with the regular expression in
+// Query. It panics if Query does not compile.
+func (s *Statement) SetRegex() {
+	s.Mode = Regex
+	s.queryRE = regexp.MustCompile(s.Query)
+}
+
+// HasQuery reports whether the first argument of a call is its query.
+func (s *Statement) HasQuery() bool {
+	return s.Mode != None
+}
+
+// Match validates that the method call matches the expected signature and
+// arguments. When the statement has a query, it is the first argument.
+func (s *Statement) Match(method string, args ...any) error {
+	if s.Method != method {
+		return fmt.Errorf("method mismatch: expected %s, got %s", s.Method, method)
+	}
+
+	if s.Mode != None {
+		if len(args) == 0 {
+			return fmt.Errorf("missing query argument")
+		}
+		query, ok := args[0].(string)
+		if !ok {
+			return fmt.Errorf("first argument was not a string query")
+		}
+		if err := s.matchQuery(query); err != nil {
+			return err
+		}
+		args = args[1:]
+	}
+
+	if s.Args != nil {
+		if err := MatchArgs(s.Args, args); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *Statement) matchQuery(query string) error {
+	want, got := s.Query, query
+	if s.Normalize {
+		want, got = normalizeWhitespace(want), normalizeWhitespace(got)
+	}
+	switch s.Mode {
+	case Exact:
+		if got != want {
+			return fmt.Errorf("query mismatch:\n%s", strings.TrimSuffix(queryDiff(s.Query, query, s.Normalize), "\n"))
+		}
+	case Contains:
+		if !strings.Contains(got, want) {
+			return fmt.Errorf("query does not contain %q", want)
+		}
+	case Regex:
+		if !s.queryRE.MatchString(got) {
+			return fmt.Errorf("query does not match regexp %s", s.queryRE)
+		}
+	}
+	return nil
+}
+
+func (s *Statement) String() string {
+	queryStr := "<nil>"
+	switch s.Mode {
+	case Exact:
+		queryStr = fmt.Sprintf("exact %q", s.Query)
+	case Contains:
+		queryStr = fmt.Sprintf("contains %q", s.Query)
+	case Regex:
+		queryStr = fmt.Sprintf("regexp %s", s.queryRE)
+	}
+	if s.Normalize {
+		queryStr += " ignoring whitespace"
+	}
+	return fmt.Sprintf("method %s with query %s and args %v", s.Method, queryStr, s.Args)
+}
+
+// queryDiff returns a unified diff of two SQL strings by line. With normalize,
+// lines are compared with their whitespace collapsed and blank lines ignored.
+func queryDiff(want, got string, normalize bool) string {
+	if !normalize {
+		return textdiff.Unified(strings.Split(want, "\n"), strings.Split(got, "\n"))
+	}
+	lines := func(s string) []string {
+		var out []string
+		for line := range strings.Lines(s) {
+			if line = normalizeWhitespace(line); line != "" {
+				out = append(out, line)
+			}
+		}
+		return out
+	}
+	return textdiff.Unified(lines(want), lines(got))
+}
+
+// normalizeWhitespace trims s and collapses every run of whitespace in it
+// into a single space.
+func normalizeWhitespace(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
@@ -0,0 +1,48 @@
+package mockmatch
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestStatementMatch(t *testing.T) {
+	t.Run("method without a query", func(t *testing.T) {
+		s := Statement{Method: "Commit"}
+		require.NoError(t, s.Match("Commit"))
+		require.EqualError(t, s.Match("Rollback"), "method mismatch: expected Commit, got Rollback")
+	})
+
+	t.Run("exact query", func(t *testing.T) {
+		s := Statement{Method: "Exec", Query: "DELETE FROM users", Mode: Exact}
+		require.NoError(t, s.Match("Exec", "DELETE FROM users"))
+		require.EqualError(t, s.Match("Exec", "DELETE FROM posts"),
+			"query mismatch:\n--- expected\n+++ actual\n@@ -1 +1 @@\n-DELETE FROM users\n+DELETE FROM posts")
+		require.EqualError(t, s.Match("Exec"), "missing query argument")
+	})
+
+	t.Run("normalized whitespace", func(t *testing.T) {
+		s := Statement{Method: "Exec", Query: "DELETE FROM users WHERE id = $1", Mode: Exact, Normalize: true}
+		require.NoError(t, s.Match("Exec", "\n\tDELETE FROM users\n\tWHERE id = $1\n", 1))
+		require.Equal(t, `method Exec with query exact "DELETE FROM users WHERE id = $1" ignoring whitespace and args []`, s.String())
+	})
+
+	t.Run("contains and regex", func(t *testing.T) {
+		s := Statement{Method: "Query", Query: "FROM users"}
+		s.SetContains()
+		require.NoError(t, s.Match("Query", "SELECT id FROM users"))
+		require.EqualError(t, s.Match("Query", "SELECT id FROM posts"), `query does not contain "FROM users"`)
+
+		s = Statement{Method: "Query", Query: `^SELECT .* FROM users$`}
+		s.SetRegex()
+		require.NoError(t, s.Match("Query", "SELECT id FROM users"))
+		require.EqualError(t, s.Match("Query", "SELECT id FROM posts"), "query does not match regexp ^SELECT .* FROM users$")
+	})
+
+	t.Run("arguments", func(t *testing.T) {
+		s := Statement{Method: "Exec", Query: "UPDATE users SET name = $1 WHERE id = $2", Mode: Exact, Args: []any{"John", AnyArg()}}
+		require.NoError(t, s.Match("Exec", s.Query, "John", 7))
+		require.ErrorContains(t, s.Match("Exec", s.Query, "Jane", 7), "args mismatch")
+		require.ErrorContains(t, s.Match("Exec", s.Query, "John"), "args mismatch")
+	})
+}
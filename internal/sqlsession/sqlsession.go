@@ -0,0 +1,341 @@
+// Package sqlsession implements the sessions and query segments of the drivers
+// built on database/sql. The drivers wrap them in their own Builder, Segment
+// and ExecResult types.
+package sqlsession
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+
+	"github.com/Kansuler/octobe/v3"
+	"github.com/Kansuler/octobe/v3/internal/stmtctx"
+)
+
+// DB defines the *sql.DB methods used to start sessions.
+type DB interface {
+	Conn(ctx context.Context) (*sql.Conn, error)
+	BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error)
+}
+
+var _ DB = &sql.DB{}
+
+// querier is implemented by both *sql.Conn and *sql.Tx.
+type querier interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+var (
+	_ querier = &sql.Conn{}
+	_ querier = &sql.Tx{}
+)
+
+// Session manages a database session that may be transactional or non-transactional.
+// Not thread-safe - use one session per goroutine.
+type Session struct {
+	ctx  context.Context
+	tx   *sql.Tx
+	conn *sql.Conn
+	// connTx is set for a transaction begun with a statement on conn, which
+	// COMMIT and ROLLBACK statements end.
+	connTx    bool
+	committed bool
+	closed    bool
+
+	// aborted holds the error of a statement canceled by its own context,
+	// which aborted the transaction.
+	aborted error
+}
+
+// Begin starts a non-transactional session. The session pins one connection
+// from the pool and keeps it until Close, so session state such as variables
+// and temporary tables is shared by its statements.
+func Begin(ctx context.Context, db DB) (*Session, error) {
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Session{
+		ctx:  ctx,
+		conn: conn,
+	}, nil
+}
+
+// BeginTx starts a transactional session.
+func BeginTx(ctx context.Context, db DB, opts *sql.TxOptions) (*Session, error) {
+	tx, err := db.BeginTx(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Session{
+		ctx: ctx,
+		tx:  tx,
+	}, nil
+}
+
+// BeginConn starts a transactional session by running begin, such as
+// "BEGIN IMMEDIATE", on a pinned connection, for transaction modes that
+// sql.TxOptions cannot express.
+func BeginConn(ctx context.Context, db DB, begin string) (*Session, error) {
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := conn.ExecContext(ctx, begin); err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+
+	return &Session{
+		ctx:    ctx,
+		conn:   conn,
+		connTx: true,
+	}, nil
+}
+
+// inTx reports whether the session is transactional.
+func (s *Session) inTx() bool {
+	return s.tx != nil || s.connTx
+}
+
+// Commit commits the transaction. Only works for transactional sessions.
+// If COMMIT fails in a session from BeginConn, for example with SQLITE_BUSY,
+// the transaction may still be open and the session must be rolled back.
+func (s *Session) Commit() error {
+	if s.committed {
+		return errors.New("cannot commit a session that has already been committed")
+	}
+	if !s.inTx() {
+		return errors.New("cannot commit without transaction")
+	}
+	if s.closed {
+		return errors.New("cannot commit a session that has already been closed")
+	}
+	if s.aborted != nil {
+		return stmtctx.Aborted(s.aborted)
+	}
+	if s.connTx {
+		_, err := s.conn.ExecContext(s.ctx, "COMMIT")
+		s.committed = true
+		if err == nil {
+			s.release(nil)
+		}
+		return err
+	}
+	err := s.tx.Commit()
+	s.committed = true
+	if err == nil {
+		s.closed = true
+	}
+	return err
+}
+
+// Rollback rolls back the transaction. Only works for transactional sessions.
+func (s *Session) Rollback() error {
+	if !s.inTx() {
+		return errors.New("cannot rollback without transaction")
+	}
+	if s.closed {
+		return nil
+	}
+	if s.connTx {
+		// The session context may already be cancelled, which must not prevent the rollback.
+		_, err := s.conn.ExecContext(context.WithoutCancel(s.ctx), "ROLLBACK")
+		if err != nil && s.committed {
+			// A failed COMMIT may already have ended the transaction, in which case
+			// there is nothing left to roll back.
+			err = nil
+		}
+		s.release(err)
+		return err
+	}
+	defer func() {
+		s.closed = true
+	}()
+	err := s.tx.Rollback()
+	if errors.Is(err, sql.ErrTxDone) && s.committed {
+		return nil
+	}
+	return err
+}
+
+// release returns the pinned connection of a session from BeginConn to the
+// pool. A connection whose rollback failed may still be inside a transaction,
+// so it is discarded instead of being reused.
+func (s *Session) release(cause error) {
+	s.closed = true
+	if cause != nil {
+		_ = s.conn.Raw(func(any) error {
+			return driver.ErrBadConn
+		})
+	}
+	_ = s.conn.Close()
+}
+
+// Close closes the session, rolling back if it is transactional and not committed.
+// Non-transactional sessions return their pinned connection to the pool.
+func (s *Session) Close() error {
+	if s.closed {
+		return nil
+	}
+	if s.inTx() {
+		return s.Rollback()
+	}
+	s.closed = true
+	if s.conn != nil {
+		err := s.conn.Close()
+		s.conn = nil
+		return err
+	}
+	return nil
+}
+
+// Segment returns a single-use segment running query in this session.
+func (s *Session) Segment(query string) *Segment {
+	return &Segment{
+		query:   query,
+		session: s,
+	}
+}
+
+// querier returns the transaction or pinned connection that runs the session's statements.
+func (s *Session) querier() (querier, error) {
+	if s.tx != nil {
+		return s.tx, nil
+	}
+	if s.conn == nil {
+		return nil, errors.New("session connection is nil")
+	}
+	return s.conn, nil
+}
+
+// Segment represents a single-use query with arguments and execution tracking.
+type Segment struct {
+	query   string
+	args    []any
+	used    bool
+	ctx     context.Context
+	session *Session
+}
+
+func (s *Segment) use() {
+	s.used = true
+}
+
+// activeQuerier returns the querier of the segment's session, or an error if the session is closed.
+func (s *Segment) activeQuerier() (*Session, querier, error) {
+	if s.session == nil || s.session.closed {
+		return nil, nil, errors.New("session is closed")
+	}
+	if s.session.aborted != nil {
+		return nil, nil, stmtctx.Aborted(s.session.aborted)
+	}
+	q, err := s.session.querier()
+	if err != nil {
+		return nil, nil, err
+	}
+	return s.session, q, nil
+}
+
+// Arguments sets the query parameters.
+func (s *Segment) Arguments(args ...any) {
+	s.args = args
+}
+
+// WithContext sets a context for this statement alone, used together with the session context.
+func (s *Segment) WithContext(ctx context.Context) {
+	s.ctx = ctx
+}
+
+// finish wraps the error of a statement canceled by the segment context, which
+// aborts the transaction of the session.
+func (s *Segment) finish(session *Session, err error) error {
+	err = stmtctx.Err(session.ctx, s.ctx, err, nil)
+	if session.inTx() && errors.Is(err, octobe.ErrStatementCanceled) {
+		session.aborted = err
+	}
+	return err
+}
+
+// Exec executes the query. result reads the values the driver reports from
+// the sql.Result.
+func (s *Segment) Exec(result func(sql.Result) error) (err error) {
+	if s.used {
+		return octobe.ErrAlreadyUsed
+	}
+	defer s.use()
+	session, q, err := s.activeQuerier()
+	if err != nil {
+		return err
+	}
+	ctx, cancel := stmtctx.Merge(session.ctx, s.ctx)
+	defer cancel()
+	defer func() {
+		err = s.finish(session, err)
+	}()
+
+	res, err := q.ExecContext(ctx, s.query, s.args...)
+	if err != nil {
+		return err
+	}
+	return result(res)
+}
+
+// QueryRow executes the query expecting exactly one row and scans into dest.
+// It returns sql.ErrNoRows when the query selects no rows.
+func (s *Segment) QueryRow(dest ...any) (err error) {
+	if s.used {
+		return octobe.ErrAlreadyUsed
+	}
+	defer s.use()
+	session, q, err := s.activeQuerier()
+	if err != nil {
+		return err
+	}
+	ctx, cancel := stmtctx.Merge(session.ctx, s.ctx)
+	defer cancel()
+	defer func() {
+		err = s.finish(session, err)
+	}()
+	return q.QueryRowContext(ctx, s.query, s.args...).Scan(dest...)
+}
+
+// Query executes the query and calls cb for each row in the result set.
+func (s *Segment) Query(cb func(*sql.Rows) error) (err error) {
+	if s.used {
+		return octobe.ErrAlreadyUsed
+	}
+	defer s.use()
+
+	session, q, err := s.activeQuerier()
+	if err != nil {
+		return err
+	}
+	ctx, cancel := stmtctx.Merge(session.ctx, s.ctx)
+	defer cancel()
+	defer func() {
+		err = s.finish(session, err)
+	}()
+
+	rows, err := q.QueryContext(ctx, s.query, s.args...)
+	if err != nil {
+		return err
+	}
+
+	defer rows.Close()
+	if err = cb(rows); err != nil {
+		return err
+	}
+
+	if err = rows.Err(); err != nil {
+		return err
+	}
+
+	return nil
+}
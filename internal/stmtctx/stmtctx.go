@@ -0,0 +1,66 @@
+// Package stmtctx runs a single statement of a session under a context of its
+// own, for the WithContext method of driver segments.
+package stmtctx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/Kansuler/octobe/v3"
+)
+
+// Merge returns the context a statement runs with: it is derived from
+// session and also ends when call does. A nil call leaves the session
+// context as is. The returned function releases the context once the
+// statement has finished.
+//
+// The context only ends after the context that ends it, so Err can tell
+// which one did. It keeps the error of the session context, while one ended
+// by call reports context.Canceled; Err adds the cause of call to the error
+// of such a statement.
+func Merge(session, call context.Context) (context.Context, context.CancelFunc) {
+	if call == nil {
+		return session, func() {}
+	}
+
+	ctx, cancel := context.WithCancelCause(session)
+	// AfterFunc runs in a goroutine of its own, which a statement with a
+	// context that already ended must not race with.
+	if call.Err() != nil {
+		cancel(context.Cause(call))
+		return ctx, func() {}
+	}
+	stop := context.AfterFunc(call, func() {
+		cancel(context.Cause(call))
+	})
+	return ctx, func() {
+		stop()
+		cancel(context.Canceled)
+	}
+}
+
+// Err wraps the error of a statement with octobe.ErrStatementCanceled when
+// call, and not session, ended the statement. Other errors, such as a
+// constraint violation returned just as call ended, are returned as is.
+//
+// Besides the context errors, canceled, when not nil, reports the errors a
+// driver gets for a statement that the database canceled on its request.
+func Err(session, call context.Context, err error, canceled func(error) bool) error {
+	if err == nil || call == nil || call.Err() == nil || session.Err() != nil {
+		return err
+	}
+	if !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded) && (canceled == nil || !canceled(err)) {
+		return err
+	}
+	if cause := context.Cause(call); !errors.Is(err, cause) {
+		return fmt.Errorf("%w: %w: %w", octobe.ErrStatementCanceled, cause, err)
+	}
+	return fmt.Errorf("%w: %w", octobe.ErrStatementCanceled, err)
+}
+
+// Aborted returns the error of the statements of a transaction after the
+// statement that failed with cause, which aborted it.
+func Aborted(cause error) error {
+	return fmt.Errorf("transaction aborted by an earlier statement, roll it back: %w", cause)
+}
@@ -0,0 +1,133 @@
+package stmtctx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/Kansuler/octobe/v3"
+	"github.com/stretchr/testify/require"
+)
+
+type key struct{}
+
+func TestMerge(t *testing.T) {
+	t.Run("without a statement context", func(t *testing.T) {
+		session := context.Background()
+		ctx, cancel := Merge(session, nil)
+		defer cancel()
+		require.Equal(t, session, ctx)
+	})
+
+	t.Run("statement context ends the statement", func(t *testing.T) {
+		session := context.WithValue(context.Background(), key{}, "session")
+		call, cancelCall := context.WithCancel(context.Background())
+		ctx, cancel := Merge(session, call)
+		defer cancel()
+		require.Equal(t, "session", ctx.Value(key{}))
+
+		cancelCall()
+		<-ctx.Done()
+		require.ErrorIs(t, ctx.Err(), context.Canceled)
+		require.ErrorIs(t, context.Cause(ctx), context.Canceled)
+		require.Error(t, call.Err(), "the statement context ends first")
+	})
+
+	t.Run("session context still applies", func(t *testing.T) {
+		boom := errors.New("session closed")
+		session, cancelSession := context.WithCancelCause(context.Background())
+		ctx, cancel := Merge(session, context.Background())
+		defer cancel()
+
+		cancelSession(boom)
+		<-ctx.Done()
+		require.ErrorIs(t, context.Cause(ctx), boom)
+	})
+
+	t.Run("session deadline is kept exactly", func(t *testing.T) {
+		session, cancelSession := context.WithTimeout(context.Background(), time.Millisecond)
+		defer cancelSession()
+		call, cancelCall := context.WithTimeout(context.Background(), time.Minute)
+		defer cancelCall()
+		ctx, cancel := Merge(session, call)
+		defer cancel()
+
+		<-ctx.Done()
+		require.ErrorIs(t, ctx.Err(), context.DeadlineExceeded)
+		deadline, _ := session.Deadline()
+		got, ok := ctx.Deadline()
+		require.True(t, ok)
+		require.Equal(t, deadline, got)
+	})
+
+	t.Run("statement context that already ended", func(t *testing.T) {
+		call, cancelCall := context.WithCancel(context.Background())
+		cancelCall()
+		ctx, cancel := Merge(context.Background(), call)
+		defer cancel()
+		require.ErrorIs(t, ctx.Err(), context.Canceled, "ended before the statement starts")
+	})
+
+	t.Run("release", func(t *testing.T) {
+		ctx, cancel := Merge(context.Background(), context.Background())
+		cancel()
+		require.ErrorIs(t, ctx.Err(), context.Canceled)
+	})
+}
+
+func TestErr(t *testing.T) {
+	failed := errors.New("statement failed")
+	done, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	t.Run("statement context ended", func(t *testing.T) {
+		interrupted := fmt.Errorf("interrupted: %w", context.Canceled)
+		err := Err(context.Background(), done, interrupted, nil)
+		require.ErrorIs(t, err, octobe.ErrStatementCanceled)
+		require.ErrorIs(t, err, interrupted)
+		require.EqualError(t, err, "statement canceled by its context: interrupted: context canceled")
+	})
+
+	t.Run("cause of the statement context", func(t *testing.T) {
+		boom := errors.New("request abandoned")
+		call, cancel := context.WithCancelCause(context.Background())
+		cancel(boom)
+		err := Err(context.Background(), call, context.Canceled, nil)
+		require.ErrorIs(t, err, octobe.ErrStatementCanceled)
+		require.ErrorIs(t, err, boom)
+		require.EqualError(t, err, "statement canceled by its context: request abandoned: context canceled")
+	})
+
+	t.Run("canceled by the database", func(t *testing.T) {
+		interrupted := errors.New("canceling statement due to user request")
+		err := Err(context.Background(), done, interrupted, func(err error) bool { return err == interrupted })
+		require.ErrorIs(t, err, octobe.ErrStatementCanceled)
+		require.ErrorIs(t, err, interrupted)
+	})
+
+	t.Run("other errors pass through", func(t *testing.T) {
+		require.Equal(t, failed, Err(context.Background(), done, failed, nil))
+		require.Equal(t, failed, Err(context.Background(), done, failed, func(error) bool { return false }))
+	})
+
+	t.Run("session context ended", func(t *testing.T) {
+		require.Equal(t, context.Canceled, Err(done, done, context.Canceled, nil))
+	})
+
+	t.Run("statement context still running", func(t *testing.T) {
+		require.Equal(t, context.Canceled, Err(context.Background(), context.Background(), context.Canceled, nil))
+		require.Equal(t, context.Canceled, Err(context.Background(), nil, context.Canceled, nil))
+	})
+
+	t.Run("no error", func(t *testing.T) {
+		require.NoError(t, Err(context.Background(), done, nil, nil))
+	})
+
+	t.Run("aborted", func(t *testing.T) {
+		err := Aborted(Err(context.Background(), done, context.Canceled, nil))
+		require.ErrorIs(t, err, octobe.ErrStatementCanceled)
+		require.ErrorContains(t, err, "transaction aborted by an earlier statement")
+	})
+}
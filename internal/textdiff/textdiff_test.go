@@ -0,0 +1,52 @@
+package textdiff
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestUnified(t *testing.T) {
+	lines := func(s string) []string { return strings.Split(s, " ") }
+
+	t.Run("Distant changes get separate hunks", func(t *testing.T) {
+		require.Equal(t, `--- expected
++++ actual
+@@ -1,4 +1,4 @@
+-a
++A
+ b
+ c
+ d
+@@ -9,4 +9,4 @@
+ i
+ j
+ k
+-l
++L
+`, Unified(lines("a b c d e f g h i j k l"), lines("A b c d e f g h i j k L")))
+	})
+
+	t.Run("Close changes share a hunk", func(t *testing.T) {
+		require.Equal(t, `--- expected
++++ actual
+@@ -1,8 +1,8 @@
+-a
++A
+ b
+ c
+ d
+ e
+ f
+ g
+-h
++H
+`, Unified(lines("a b c d e f g h"), lines("A b c d e f g H")))
+	})
+
+	t.Run("Added and removed lines", func(t *testing.T) {
+		require.Equal(t, "--- expected\n+++ actual\n@@ -1 +1,2 @@\n a\n+b\n", Unified([]string{"a"}, lines("a b")))
+		require.Equal(t, "--- expected\n+++ actual\n@@ -1 +0,0 @@\n-a\n", Unified([]string{"a"}, nil))
+	})
+}
@@ -23,10 +23,24 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"reflect"
 )
 
 var ErrAlreadyUsed = errors.New("segment has already been executed - segments can only be used once, create a new segment for additional queries")
 
+// ErrStatementCanceled wraps the error of a statement that failed because the
+// context given to its segment with WithContext ended while the session
+// context had not.
+//
+// The segments of the bundled drivers implement a ContextSegment interface
+// whose WithContext sets a context for one statement alone, such as a tighter
+// deadline for one slow query in a long transaction. The statement still ends
+// when the session context does. A statement canceled by its own context
+// aborts the transaction of its session: the statements after it and Commit
+// fail with an error wrapping ErrStatementCanceled, and the session has to be
+// rolled back.
+var ErrStatementCanceled = errors.New("statement canceled by its context")
+
 // Option applies configuration to a driver config. Use this to customize
 // transaction options, connection settings, or other driver-specific behavior.
 //
@@ -211,9 +225,48 @@ type Handler[RESULT, BUILDER any] func(BUILDER) (RESULT, error)
 
 // Execute runs a handler function with the session's query builder.
 func Execute[RESULT, BUILDER any](session BuilderSession[BUILDER], f Handler[RESULT, BUILDER]) (RESULT, error) {
+	if result, err, ok := intercept(session, f); ok {
+		return result, err
+	}
 	return f(session.Builder())
 }
 
+// Interceptor is an optional interface of sessions, and the extension point for
+// drivers that run handlers some other way than by calling them with a query
+// builder. Execute, ExecuteVoid and ExecuteMany offer every handler to a
+// session implementing it before running the handler. When Intercept reports
+// handled, its result and error are returned and the handler does not run; a
+// nil result returns the zero value of the handler's result type, and a result
+// of any other type than the handler's is an error. Otherwise the handler runs
+// as usual.
+//
+// The sessions of the bundled database drivers do not implement Interceptor,
+// so for them Execute only pays for the type assertion. The fake driver in
+// octobetest implements it to return stubbed results instead of running SQL.
+type Interceptor interface {
+	Intercept(handler any) (result any, err error, handled bool)
+}
+
+func intercept[RESULT, BUILDER any](session BuilderSession[BUILDER], f Handler[RESULT, BUILDER]) (RESULT, error, bool) {
+	var zero RESULT
+	i, ok := session.(Interceptor)
+	if !ok {
+		return zero, nil, false
+	}
+	result, err, handled := i.Intercept(f)
+	if !handled {
+		return zero, nil, false
+	}
+	if result == nil {
+		return zero, err, true
+	}
+	r, ok := result.(RESULT)
+	if !ok {
+		return zero, fmt.Errorf("intercepted result of type %T does not match handler result type %s", result, reflect.TypeFor[RESULT]()), true
+	}
+	return r, err, true
+}
+
 // ExecuteVoid runs a void handler (one that returns octobe.Void) and returns only the error.
 // This provides cleaner syntax for operations that don't return data.
 //
@@ -224,7 +277,7 @@ func Execute[RESULT, BUILDER any](session BuilderSession[BUILDER], f Handler[RES
 //	    return fmt.Errorf("failed to delete user: %w", err)
 //	}
 func ExecuteVoid[BUILDER any](session BuilderSession[BUILDER], f Handler[Void, BUILDER]) error {
-	_, err := f(session.Builder())
+	_, err := Execute(session, f)
 	return err
 }
 
@@ -242,7 +295,7 @@ func ExecuteVoid[BUILDER any](session BuilderSession[BUILDER], f Handler[Void, B
 func ExecuteMany[RESULT, BUILDER any](session BuilderSession[BUILDER], handlers ...Handler[RESULT, BUILDER]) ([]RESULT, error) {
 	results := make([]RESULT, 0, len(handlers))
 	for i, handler := range handlers {
-		result, err := handler(session.Builder())
+		result, err := Execute(session, handler)
 		if err != nil {
 			return nil, fmt.Errorf("handler %d failed: %w", i, err)
 		}
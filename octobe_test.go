@@ -0,0 +1,91 @@
+package octobe_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/Kansuler/octobe/v3"
+	"github.com/stretchr/testify/require"
+)
+
+// builder records the queries handlers build.
+type builder func(query string) string
+
+type session struct {
+	queries []string
+}
+
+func (s *session) Commit() error   { return nil }
+func (s *session) Rollback() error { return nil }
+func (s *session) Close() error    { return nil }
+
+func (s *session) Builder() builder {
+	return func(query string) string {
+		s.queries = append(s.queries, query)
+		return query
+	}
+}
+
+// interceptingSession answers handlers from results instead of running them.
+type interceptingSession struct {
+	session
+	result  any
+	err     error
+	handled bool
+	offered []any
+}
+
+func (s *interceptingSession) Intercept(handler any) (any, error, bool) {
+	s.offered = append(s.offered, handler)
+	return s.result, s.err, s.handled
+}
+
+var _ octobe.Interceptor = &interceptingSession{}
+
+func count(query string) octobe.Handler[int, builder] {
+	return func(b builder) (int, error) {
+		return len(b(query)), nil
+	}
+}
+
+func TestExecuteInterceptor(t *testing.T) {
+	t.Run("sessions without Intercept run the handler", func(t *testing.T) {
+		s := &session{}
+		n, err := octobe.Execute[int, builder](s, count("SELECT 1"))
+		require.NoError(t, err)
+		require.Equal(t, 8, n)
+		require.Equal(t, []string{"SELECT 1"}, s.queries)
+	})
+
+	t.Run("handled results replace the handler", func(t *testing.T) {
+		expectedErr := errors.New("stubbed")
+		s := &interceptingSession{result: 42, err: expectedErr, handled: true}
+		n, err := octobe.Execute[int, builder](s, count("SELECT 1"))
+		require.ErrorIs(t, err, expectedErr)
+		require.Equal(t, 42, n)
+		require.Empty(t, s.queries)
+		require.Len(t, s.offered, 1)
+	})
+
+	t.Run("nil result is the zero value", func(t *testing.T) {
+		s := &interceptingSession{handled: true}
+		n, err := octobe.Execute[int, builder](s, count("SELECT 1"))
+		require.NoError(t, err)
+		require.Zero(t, n)
+	})
+
+	t.Run("result of another type fails", func(t *testing.T) {
+		s := &interceptingSession{result: "42", handled: true}
+		_, err := octobe.Execute[int, builder](s, count("SELECT 1"))
+		require.EqualError(t, err, "intercepted result of type string does not match handler result type int")
+	})
+
+	t.Run("unhandled handlers run", func(t *testing.T) {
+		s := &interceptingSession{}
+		results, err := octobe.ExecuteMany[int, builder](s, count("SELECT 1"), count("SELECT 22"))
+		require.NoError(t, err)
+		require.Equal(t, []int{8, 9}, results)
+		require.Len(t, s.offered, 2)
+		require.Equal(t, []string{"SELECT 1", "SELECT 22"}, s.queries)
+	})
+}
@@ -0,0 +1,277 @@
+package octobetest
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"flag"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Kansuler/octobe/v3"
+	"github.com/Kansuler/octobe/v3/driver/postgres"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var keep = flag.Bool("octobetest.keep", false, "keep the test databases of failed tests for debugging")
+
+// maxIdentifierLength is the length PostgreSQL truncates identifiers to.
+const maxIdentifierLength = 63
+
+// Migration prepares the schema of a template database.
+type Migration func(ctx context.Context, db postgres.PGXPoolDriver) error
+
+// Template creates a database per test by cloning a migrated template
+// database with CREATE DATABASE ... TEMPLATE, which copies the schema and
+// seed data in milliseconds. Tests get a database of their own, so they can
+// run in parallel and never clean up tables by hand.
+//
+// The template is rebuilt and migrated once per test binary, when the first
+// test database is created, so schema changes are always picked up. Advisory
+// locks keep test binaries of other packages sharing the template name from
+// rebuilding it while they clone it.
+//
+// Example:
+//
+//	var products = octobetest.NewTemplate(os.Getenv("DSN"), "products_template",
+//	    octobetest.ExecSQL(`CREATE TABLE products (id SERIAL PRIMARY KEY, name TEXT NOT NULL)`))
+//
+//	func TestCreateProduct(t *testing.T) {
+//	    t.Parallel()
+//	    db := products.Database(t)
+//	    // run handlers against db
+//	}
+type Template struct {
+	adminDSN string
+	name     string
+	migrate  Migration
+
+	once  sync.Once
+	admin *pgxpool.Pool
+	err   error
+}
+
+// NewTemplate returns a template database named name, created on the server
+// adminDSN points to and migrated with migrate. The DSN needs permission to
+// create and drop databases.
+func NewTemplate(adminDSN, name string, migrate Migration) *Template {
+	return &Template{adminDSN: adminDSN, name: name, migrate: migrate}
+}
+
+// ExecSQL returns a migration that executes statements in order, in one transaction.
+func ExecSQL(statements ...string) Migration {
+	return func(ctx context.Context, db postgres.PGXPoolDriver) error {
+		return db.StartTransaction(ctx, func(session octobe.BuilderSession[postgres.Builder]) error {
+			for _, statement := range statements {
+				if _, err := session.Builder()(statement).Exec(); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	}
+}
+
+// Database creates a database for t from the template and returns a driver
+// connected to it. The database is dropped when the test ends, unless the
+// test failed and the test binary runs with -octobetest.keep, in which case
+// its name is logged.
+func (tpl *Template) Database(t testing.TB) postgres.PGXPoolDriver {
+	t.Helper()
+	ctx := context.Background()
+
+	tpl.once.Do(func() {
+		tpl.err = tpl.prepare(ctx)
+	})
+	if tpl.err != nil {
+		t.Fatalf("prepare template database %s: %v", tpl.name, tpl.err)
+	}
+
+	name, err := databaseName(tpl.name, t.Name())
+	if err != nil {
+		t.Fatalf("name test database: %v", err)
+	}
+	if err := tpl.clone(ctx, name); err != nil {
+		t.Fatalf("create test database %s: %v", name, err)
+	}
+
+	db, err := tpl.open(ctx, name)
+	if err != nil {
+		_ = tpl.drop(ctx, name)
+		t.Fatalf("open test database %s: %v", name, err)
+	}
+
+	t.Cleanup(func() {
+		_ = db.Close(ctx)
+		if t.Failed() && *keep {
+			t.Logf("keeping test database %s", name)
+			return
+		}
+		if err := tpl.drop(ctx, name); err != nil {
+			t.Errorf("drop test database %s: %v", name, err)
+		}
+	})
+	return db
+}
+
+// Close drops the template database and closes the admin connections. Call
+// it from TestMain once the tests have run; without it the template is left
+// behind for the next run to rebuild.
+func (tpl *Template) Close() error {
+	if tpl.admin == nil {
+		return nil
+	}
+	defer tpl.admin.Close()
+	return tpl.withLock(context.Background(), "pg_advisory_lock", func(conn *pgxpool.Conn) error {
+		return dropTemplate(context.Background(), conn, tpl.name)
+	})
+}
+
+// prepare connects to the admin database and rebuilds the template.
+func (tpl *Template) prepare(ctx context.Context) error {
+	admin, err := pgxpool.New(ctx, tpl.adminDSN)
+	if err != nil {
+		return err
+	}
+	tpl.admin = admin
+
+	return tpl.withLock(ctx, "pg_advisory_lock", func(conn *pgxpool.Conn) error {
+		if err := dropTemplate(ctx, conn, tpl.name); err != nil {
+			return err
+		}
+		ident := pgx.Identifier{tpl.name}.Sanitize()
+		if _, err := conn.Exec(ctx, "CREATE DATABASE "+ident); err != nil {
+			return err
+		}
+
+		db, err := tpl.open(ctx, tpl.name)
+		if err != nil {
+			return err
+		}
+		err = tpl.migrate(ctx, db)
+		_ = db.Close(ctx)
+		if err != nil {
+			return fmt.Errorf("migrate: %w", err)
+		}
+
+		// Marking the database as a template keeps anyone from connecting
+		// to it by accident, which would make cloning fail.
+		_, err = conn.Exec(ctx, "ALTER DATABASE "+ident+" WITH IS_TEMPLATE true ALLOW_CONNECTIONS false")
+		return err
+	})
+}
+
+func dropTemplate(ctx context.Context, conn *pgxpool.Conn, name string) error {
+	var exists bool
+	if err := conn.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM pg_database WHERE datname = $1)`, name).Scan(&exists); err != nil {
+		return err
+	}
+	if !exists {
+		return nil
+	}
+	ident := pgx.Identifier{name}.Sanitize()
+	if _, err := conn.Exec(ctx, "ALTER DATABASE "+ident+" WITH IS_TEMPLATE false"); err != nil {
+		return err
+	}
+	_, err := conn.Exec(ctx, "DROP DATABASE "+ident+" WITH (FORCE)")
+	return err
+}
+
+// clone creates database name from the template. Cloning takes a shared lock
+// so any number of tests clone at once while no one rebuilds the template.
+func (tpl *Template) clone(ctx context.Context, name string) error {
+	return tpl.withLock(ctx, "pg_advisory_lock_shared", func(conn *pgxpool.Conn) error {
+		query := fmt.Sprintf("CREATE DATABASE %s TEMPLATE %s", pgx.Identifier{name}.Sanitize(), pgx.Identifier{tpl.name}.Sanitize())
+		for attempt := 0; ; attempt++ {
+			_, err := conn.Exec(ctx, query)
+			// PostgreSQL refuses to copy a template while another clone of it
+			// is starting up, so retry briefly.
+			var pgErr *pgconn.PgError
+			if err == nil || attempt == 20 || !errors.As(err, &pgErr) || pgErr.Code != "55006" {
+				return err
+			}
+			time.Sleep(50 * time.Millisecond)
+		}
+	})
+}
+
+func (tpl *Template) drop(ctx context.Context, name string) error {
+	_, err := tpl.admin.Exec(ctx, "DROP DATABASE IF EXISTS "+pgx.Identifier{name}.Sanitize()+" WITH (FORCE)")
+	return err
+}
+
+// withLock runs fn on an admin connection holding the advisory lock of the
+// template, taken with lockFunc.
+func (tpl *Template) withLock(ctx context.Context, lockFunc string, fn func(conn *pgxpool.Conn) error) error {
+	conn, err := tpl.admin.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	unlockFunc := strings.Replace(lockFunc, "_lock", "_unlock", 1)
+	if _, err := conn.Exec(ctx, "SELECT "+lockFunc+"(hashtext($1))", tpl.name); err != nil {
+		return err
+	}
+	defer func() {
+		_, _ = conn.Exec(ctx, "SELECT "+unlockFunc+"(hashtext($1))", tpl.name)
+	}()
+	return fn(conn)
+}
+
+// open returns a pool driver for database name on the admin server.
+func (tpl *Template) open(ctx context.Context, name string) (postgres.PGXPoolDriver, error) {
+	config, err := pgxpool.ParseConfig(tpl.adminDSN)
+	if err != nil {
+		return nil, err
+	}
+	config.ConnConfig.Database = name
+
+	pool, err := pgxpool.NewWithConfig(ctx, config)
+	if err != nil {
+		return nil, err
+	}
+	if err := pool.Ping(ctx); err != nil {
+		pool.Close()
+		return nil, err
+	}
+	return octobe.New(postgres.OpenPGXWithPool(pool))
+}
+
+// databaseName returns a unique database name that starts with the template
+// name and contains as much of the test name as fits, so databases kept for
+// debugging can be told apart.
+func databaseName(template, test string) (string, error) {
+	suffix := make([]byte, 4)
+	if _, err := rand.Read(suffix); err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+	for _, r := range strings.ToLower(test) {
+		if r >= 'a' && r <= 'z' || r >= '0' && r <= '9' {
+			b.WriteRune(r)
+		} else if !strings.HasSuffix(b.String(), "_") {
+			b.WriteByte('_')
+		}
+	}
+	test = strings.Trim(b.String(), "_")
+
+	prefix := template + "_"
+	tail := "_" + hex.EncodeToString(suffix)
+	room := max(maxIdentifierLength-len(prefix)-len(tail), 0)
+	if len(test) > room {
+		test = test[:room]
+	}
+	name := prefix + test + tail
+	if len(name) > maxIdentifierLength {
+		name = name[len(name)-maxIdentifierLength:]
+	}
+	return name, nil
+}
@@ -0,0 +1,31 @@
+package octobetest
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDatabaseName(t *testing.T) {
+	t.Run("contains the test name", func(t *testing.T) {
+		name, err := databaseName("shop_template", "TestOrders/Pay by card")
+		require.NoError(t, err)
+		require.Regexp(t, `^shop_template_testorders_pay_by_card_[0-9a-f]{8}$`, name)
+	})
+
+	t.Run("is unique", func(t *testing.T) {
+		a, err := databaseName("shop_template", "TestOrders")
+		require.NoError(t, err)
+		b, err := databaseName("shop_template", "TestOrders")
+		require.NoError(t, err)
+		require.NotEqual(t, a, b)
+	})
+
+	t.Run("fits in an identifier", func(t *testing.T) {
+		name, err := databaseName("shop_template", strings.Repeat("TestLong", 20))
+		require.NoError(t, err)
+		require.Len(t, name, maxIdentifierLength)
+		require.True(t, strings.HasPrefix(name, "shop_template_testlongtestlong"))
+	})
+}
@@ -0,0 +1,329 @@
+// Package octobetest provides helpers for testing code built on octobe.
+//
+// Template gives every test a PostgreSQL database of its own, cloned from a
+// migrated template database and dropped when the test ends.
+//
+// Driver is a fake octobe.Driver for service-layer tests. Its sessions run no
+// SQL: handlers executed through octobe.Execute, ExecuteVoid and ExecuteMany
+// return the results stubbed for them, while the commit and rollback decisions
+// of StartTransaction and manual sessions are recorded for assertions.
+//
+// Example:
+//
+//	db := octobetest.NewDriver[postgres.PGXConn, postgres.Config, postgres.Builder]()
+//	octobetest.Stub(db, CreateUser("", ""), User{ID: 1, Username: "alice"}, nil)
+//	db.StubName("CreatePost", nil, errors.New("disk full"))
+//
+//	_, _, err := NewBlogService(db).CreateUserAndWelcomePost(ctx, "alice", "alice@example.com")
+//	require.Error(t, err)
+//	require.True(t, db.Sessions()[0].RolledBack)
+package octobetest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"runtime"
+	"strings"
+	"sync"
+
+	"github.com/Kansuler/octobe/v3"
+)
+
+// ErrNotStubbed is returned by handlers executed on a Driver session that no
+// stub matches.
+var ErrNotStubbed = errors.New("octobetest: handler is not stubbed")
+
+// Driver is a fake octobe.Driver whose sessions return stubbed handler results
+// instead of running SQL. Handlers are identified either by the handler value
+// itself, with Stub, or by name, with StubName.
+//
+// Builder on its sessions returns the zero BUILDER, so code that builds
+// queries outside a handler cannot run against a Driver.
+type Driver[DRIVER, CONFIG, BUILDER any] struct {
+	mu       sync.Mutex
+	stubs    []*stub
+	sessions []*SessionRecord[CONFIG]
+	closed   bool
+}
+
+var _ octobe.Driver[any, any, any] = (*Driver[any, any, any])(nil)
+
+// NewDriver returns a Driver without stubs.
+func NewDriver[DRIVER, CONFIG, BUILDER any]() *Driver[DRIVER, CONFIG, BUILDER] {
+	return &Driver[DRIVER, CONFIG, BUILDER]{}
+}
+
+// SessionRecord describes a session opened on a Driver.
+type SessionRecord[CONFIG any] struct {
+	// Transactional is set for sessions begun with BeginTx or StartTransaction.
+	Transactional bool
+	// Config is the configuration produced by the options the transaction was begun with.
+	Config CONFIG
+	// Handlers holds the names of the handlers executed in the session, in order.
+	Handlers []string
+	// Committed is set once the transaction is committed.
+	Committed bool
+	// RolledBack is set once the transaction is rolled back, including by Close.
+	RolledBack bool
+	// Closed is set once the session is committed, rolled back or closed.
+	Closed bool
+}
+
+// stub holds the responses for the handlers matching it. Stubs keyed by a
+// handler match its name exactly, named stubs also match it as a suffix.
+type stub struct {
+	keyed     bool
+	name      string
+	responses []response
+	calls     int
+}
+
+type response struct {
+	result any
+	err    error
+}
+
+// next returns the response for the next call. The last response is repeated
+// once the others are used up.
+func (s *stub) next() response {
+	r := s.responses[min(s.calls, len(s.responses)-1)]
+	s.calls++
+	return r
+}
+
+func (s *stub) matches(name string) bool {
+	if s.keyed {
+		return name == s.name
+	}
+	return name == s.name || strings.HasSuffix(name, "."+s.name)
+}
+
+// Stub makes handlers built by the same function as handler return result and
+// err. The handler is used as a typed key: its arguments are irrelevant, so
+// CreateUser("", "") stubs every handler CreateUser returns, and the result is
+// checked against the handler's result type at compile time. Handlers are
+// compared by HandlerName rather than by code pointer, because the compiler
+// emits a separate copy of a closure wherever its constructor is inlined.
+//
+// Stubbing the same handler again queues another response. Calls consume the
+// responses in order and repeat the last one.
+func Stub[RESULT, DRIVER, CONFIG, BUILDER any](d *Driver[DRIVER, CONFIG, BUILDER], handler octobe.Handler[RESULT, BUILDER], result RESULT, err error) {
+	d.add(&stub{keyed: true, name: HandlerName(handler)}, response{result: result, err: err})
+}
+
+// StubName makes handlers named name return result and err. The name matches
+// the name reported by HandlerName, either fully or as a suffix following a
+// dot, so "CreateUser" matches "blog.CreateUser". A nil result returns the
+// zero value of the handler's result type; any other result must have that
+// type, or the handler fails.
+//
+// Stubbing the same name again queues another response. Calls consume the
+// responses in order and repeat the last one. Stubs added with Stub take
+// precedence over named stubs.
+func (d *Driver[DRIVER, CONFIG, BUILDER]) StubName(name string, result any, err error) {
+	d.add(&stub{name: name}, response{result: result, err: err})
+}
+
+func (d *Driver[DRIVER, CONFIG, BUILDER]) add(s *stub, r response) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for _, existing := range d.stubs {
+		if existing.keyed == s.keyed && existing.name == s.name {
+			existing.responses = append(existing.responses, r)
+			return
+		}
+	}
+	s.responses = []response{r}
+	d.stubs = append(d.stubs, s)
+}
+
+// lookup returns the stub for a handler, preferring stubs keyed by handler.
+func (d *Driver[DRIVER, CONFIG, BUILDER]) lookup(name string) *stub {
+	for _, s := range d.stubs {
+		if s.keyed && s.matches(name) {
+			return s
+		}
+	}
+	for _, s := range d.stubs {
+		if !s.keyed && s.matches(name) {
+			return s
+		}
+	}
+	return nil
+}
+
+// Sessions returns a snapshot of the sessions opened on the driver, in the
+// order they were opened.
+func (d *Driver[DRIVER, CONFIG, BUILDER]) Sessions() []SessionRecord[CONFIG] {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	records := make([]SessionRecord[CONFIG], len(d.sessions))
+	for i, r := range d.sessions {
+		records[i] = *r
+		records[i].Handlers = append([]string(nil), r.Handlers...)
+	}
+	return records
+}
+
+// Closed reports whether Close was called on the driver.
+func (d *Driver[DRIVER, CONFIG, BUILDER]) Closed() bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.closed
+}
+
+// Begin opens a non-transactional session.
+func (d *Driver[DRIVER, CONFIG, BUILDER]) Begin(ctx context.Context) (octobe.Session[BUILDER], error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return d.open(&SessionRecord[CONFIG]{}), nil
+}
+
+// BeginTx opens a transactional session, recording the configuration opts produce.
+func (d *Driver[DRIVER, CONFIG, BUILDER]) BeginTx(ctx context.Context, opts ...octobe.Option[CONFIG]) (octobe.Session[BUILDER], error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	record := &SessionRecord[CONFIG]{Transactional: true}
+	for _, opt := range opts {
+		opt(&record.Config)
+	}
+	return d.open(record), nil
+}
+
+func (d *Driver[DRIVER, CONFIG, BUILDER]) open(record *SessionRecord[CONFIG]) *session[DRIVER, CONFIG, BUILDER] {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.sessions = append(d.sessions, record)
+	return &session[DRIVER, CONFIG, BUILDER]{driver: d, record: record}
+}
+
+// StartTransaction runs fn in a transactional session, committing it when fn
+// returns nil and rolling it back otherwise, like the real drivers.
+func (d *Driver[DRIVER, CONFIG, BUILDER]) StartTransaction(ctx context.Context, fn func(session octobe.BuilderSession[BUILDER]) error, opts ...octobe.Option[CONFIG]) error {
+	return octobe.StartTransaction[DRIVER](ctx, d, fn, opts...)
+}
+
+// Close marks the driver as closed.
+func (d *Driver[DRIVER, CONFIG, BUILDER]) Close(_ context.Context) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.closed = true
+	return nil
+}
+
+// Ping always succeeds.
+func (d *Driver[DRIVER, CONFIG, BUILDER]) Ping(_ context.Context) error {
+	return nil
+}
+
+// session is a session opened on a Driver. It implements octobe.Interceptor.
+type session[DRIVER, CONFIG, BUILDER any] struct {
+	driver *Driver[DRIVER, CONFIG, BUILDER]
+	record *SessionRecord[CONFIG]
+}
+
+var _ octobe.Interceptor = (*session[any, any, any])(nil)
+
+func (s *session[DRIVER, CONFIG, BUILDER]) Builder() BUILDER {
+	var builder BUILDER
+	return builder
+}
+
+// Intercept returns the next stubbed response for handler, or ErrNotStubbed.
+func (s *session[DRIVER, CONFIG, BUILDER]) Intercept(handler any) (any, error, bool) {
+	name := HandlerName(handler)
+
+	s.driver.mu.Lock()
+	defer s.driver.mu.Unlock()
+	s.record.Handlers = append(s.record.Handlers, name)
+	st := s.driver.lookup(name)
+	if st == nil {
+		return nil, fmt.Errorf("%w: %s", ErrNotStubbed, name), true
+	}
+	r := st.next()
+	return r.result, r.err, true
+}
+
+func (s *session[DRIVER, CONFIG, BUILDER]) Commit() error {
+	s.driver.mu.Lock()
+	defer s.driver.mu.Unlock()
+	if !s.record.Transactional {
+		return errors.New("octobetest: cannot commit a non-transactional session")
+	}
+	if s.record.Closed {
+		return errors.New("octobetest: transaction is already closed")
+	}
+	s.record.Committed = true
+	s.record.Closed = true
+	return nil
+}
+
+func (s *session[DRIVER, CONFIG, BUILDER]) Rollback() error {
+	s.driver.mu.Lock()
+	defer s.driver.mu.Unlock()
+	if !s.record.Transactional {
+		return errors.New("octobetest: cannot roll back a non-transactional session")
+	}
+	if s.record.Closed {
+		return nil
+	}
+	s.record.RolledBack = true
+	s.record.Closed = true
+	return nil
+}
+
+// Close rolls back a transaction that was neither committed nor rolled back.
+func (s *session[DRIVER, CONFIG, BUILDER]) Close() error {
+	s.driver.mu.Lock()
+	defer s.driver.mu.Unlock()
+	if s.record.Transactional && !s.record.Closed {
+		s.record.RolledBack = true
+	}
+	s.record.Closed = true
+	return nil
+}
+
+// HandlerName returns the name handlers are stubbed and recorded by: the
+// package-qualified name of the function that built the handler, such as
+// "blog.CreateUser" for the closure returned by CreateUser, or the name of the
+// handler itself when it is a named function.
+func HandlerName(handler any) string {
+	v := reflect.ValueOf(handler)
+	if v.Kind() != reflect.Func || v.IsNil() {
+		return fmt.Sprintf("%T", handler)
+	}
+	fn := runtime.FuncForPC(v.Pointer())
+	if fn == nil {
+		return fmt.Sprintf("%T", handler)
+	}
+	name := fn.Name()
+	if i := strings.LastIndex(name, "/"); i >= 0 {
+		name = name[i+1:]
+	}
+	// Closures are named after the enclosing function, followed by ".func1",
+	// or by ".1", ".2" and so on when nested or inlined.
+	for {
+		i := strings.LastIndex(name, ".")
+		if i < 0 || !isClosureSuffix(name[i+1:]) {
+			return name
+		}
+		name = name[:i]
+	}
+}
+
+func isClosureSuffix(s string) bool {
+	s = strings.TrimPrefix(s, "func")
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
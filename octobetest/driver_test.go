@@ -0,0 +1,213 @@
+package octobetest_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/Kansuler/octobe/v3"
+	"github.com/Kansuler/octobe/v3/driver/postgres"
+	"github.com/Kansuler/octobe/v3/octobetest"
+	"github.com/jackc/pgx/v5"
+	"github.com/stretchr/testify/require"
+)
+
+type User struct {
+	ID   int
+	Name string
+}
+
+type Post struct {
+	ID     int
+	UserID int
+}
+
+func CreateUser(name string) octobe.Handler[User, postgres.Builder] {
+	return func(builder postgres.Builder) (User, error) {
+		var user User
+		err := builder(`INSERT INTO users (name) VALUES ($1) RETURNING id, name`).
+			Arguments(name).
+			QueryRow(&user.ID, &user.Name)
+		return user, err
+	}
+}
+
+func CreatePost(userID int) octobe.Handler[Post, postgres.Builder] {
+	return func(builder postgres.Builder) (Post, error) {
+		post := Post{UserID: userID}
+		err := builder(`INSERT INTO posts (user_id) VALUES ($1) RETURNING id`).
+			Arguments(userID).
+			QueryRow(&post.ID)
+		return post, err
+	}
+}
+
+func DeleteUser(id int) octobe.Handler[octobe.Void, postgres.Builder] {
+	return func(builder postgres.Builder) (octobe.Void, error) {
+		_, err := builder(`DELETE FROM users WHERE id = $1`).Arguments(id).Exec()
+		return nil, err
+	}
+}
+
+type service struct {
+	db postgres.PGXDriver
+}
+
+func (s *service) signup(ctx context.Context, name string) (User, Post, error) {
+	var user User
+	var post Post
+	err := s.db.StartTransaction(ctx, func(session octobe.BuilderSession[postgres.Builder]) error {
+		var err error
+		user, err = octobe.Execute(session, CreateUser(name))
+		if err != nil {
+			return err
+		}
+		post, err = octobe.Execute(session, CreatePost(user.ID))
+		return err
+	}, postgres.WithPGXTxOptions(postgres.PGXTxOptions{IsoLevel: pgx.Serializable}))
+	return user, post, err
+}
+
+func newDriver() *octobetest.Driver[postgres.PGXConn, postgres.Config, postgres.Builder] {
+	return octobetest.NewDriver[postgres.PGXConn, postgres.Config, postgres.Builder]()
+}
+
+func TestDriver(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("stubbed handlers commit", func(t *testing.T) {
+		db := newDriver()
+		octobetest.Stub(db, CreateUser(""), User{ID: 7, Name: "alice"}, nil)
+		db.StubName("CreatePost", Post{ID: 3, UserID: 7}, nil)
+
+		user, post, err := (&service{db: db}).signup(ctx, "alice")
+		require.NoError(t, err)
+		require.Equal(t, User{ID: 7, Name: "alice"}, user)
+		require.Equal(t, Post{ID: 3, UserID: 7}, post)
+
+		sessions := db.Sessions()
+		require.Len(t, sessions, 1)
+		require.True(t, sessions[0].Transactional)
+		require.True(t, sessions[0].Committed)
+		require.False(t, sessions[0].RolledBack)
+		var config postgres.Config
+		postgres.WithPGXTxOptions(postgres.PGXTxOptions{IsoLevel: pgx.Serializable})(&config)
+		require.Equal(t, config, sessions[0].Config)
+		require.Equal(t, []string{"octobetest_test.CreateUser", "octobetest_test.CreatePost"}, sessions[0].Handlers)
+	})
+
+	t.Run("stubbed error rolls back", func(t *testing.T) {
+		db := newDriver()
+		boom := errors.New("boom")
+		octobetest.Stub(db, CreateUser(""), User{}, boom)
+
+		_, _, err := (&service{db: db}).signup(ctx, "alice")
+		require.ErrorIs(t, err, boom)
+
+		sessions := db.Sessions()
+		require.Len(t, sessions, 1)
+		require.True(t, sessions[0].RolledBack)
+		require.False(t, sessions[0].Committed)
+		require.Equal(t, []string{"octobetest_test.CreateUser"}, sessions[0].Handlers)
+	})
+
+	t.Run("unstubbed handler fails", func(t *testing.T) {
+		db := newDriver()
+		octobetest.Stub(db, CreateUser(""), User{ID: 1}, nil)
+
+		_, _, err := (&service{db: db}).signup(ctx, "alice")
+		require.ErrorIs(t, err, octobetest.ErrNotStubbed)
+		require.ErrorContains(t, err, "octobetest_test.CreatePost")
+		require.True(t, db.Sessions()[0].RolledBack)
+	})
+
+	t.Run("responses are consumed in order", func(t *testing.T) {
+		db := newDriver()
+		octobetest.Stub(db, CreateUser(""), User{ID: 1}, nil)
+		octobetest.Stub(db, CreateUser(""), User{ID: 2}, nil)
+
+		session, err := db.Begin(ctx)
+		require.NoError(t, err)
+		defer session.Close()
+
+		for _, want := range []int{1, 2, 2} {
+			user, err := octobe.Execute(session, CreateUser("bob"))
+			require.NoError(t, err)
+			require.Equal(t, want, user.ID)
+		}
+	})
+
+	t.Run("typed stubs take precedence over names", func(t *testing.T) {
+		db := newDriver()
+		db.StubName("CreateUser", User{ID: 1}, nil)
+		octobetest.Stub(db, CreateUser(""), User{ID: 2}, nil)
+
+		session, err := db.Begin(ctx)
+		require.NoError(t, err)
+		user, err := octobe.Execute(session, CreateUser("bob"))
+		require.NoError(t, err)
+		require.Equal(t, 2, user.ID)
+	})
+
+	t.Run("named stub of the wrong type", func(t *testing.T) {
+		db := newDriver()
+		db.StubName("CreateUser", Post{}, nil)
+
+		session, err := db.Begin(ctx)
+		require.NoError(t, err)
+		_, err = octobe.Execute(session, CreateUser("bob"))
+		require.EqualError(t, err, "intercepted result of type octobetest_test.Post does not match handler result type octobetest_test.User")
+	})
+
+	t.Run("void and many", func(t *testing.T) {
+		db := newDriver()
+		db.StubName("DeleteUser", nil, nil)
+		octobetest.Stub(db, CreatePost(0), Post{ID: 1}, nil)
+		octobetest.Stub(db, CreatePost(0), Post{}, errors.New("full"))
+
+		session, err := db.BeginTx(ctx)
+		require.NoError(t, err)
+		require.NoError(t, octobe.ExecuteVoid(session, DeleteUser(1)))
+		_, err = octobe.ExecuteMany(session, CreatePost(1), CreatePost(1))
+		require.EqualError(t, err, "handler 1 failed: full")
+		require.NoError(t, session.Close())
+
+		sessions := db.Sessions()
+		require.True(t, sessions[0].RolledBack)
+		require.Equal(t, []string{"octobetest_test.DeleteUser", "octobetest_test.CreatePost", "octobetest_test.CreatePost"}, sessions[0].Handlers)
+	})
+
+	t.Run("manual sessions", func(t *testing.T) {
+		db := newDriver()
+
+		session, err := db.Begin(ctx)
+		require.NoError(t, err)
+		require.Error(t, session.Commit())
+		require.NoError(t, session.Close())
+
+		tx, err := db.BeginTx(ctx)
+		require.NoError(t, err)
+		require.NoError(t, tx.Commit())
+		require.Error(t, tx.Commit())
+		require.NoError(t, tx.Rollback())
+		require.NoError(t, tx.Close())
+
+		sessions := db.Sessions()
+		require.Len(t, sessions, 2)
+		require.False(t, sessions[0].Transactional)
+		require.True(t, sessions[0].Closed)
+		require.True(t, sessions[1].Committed)
+		require.False(t, sessions[1].RolledBack)
+
+		require.NoError(t, db.Ping(ctx))
+		require.NoError(t, db.Close(ctx))
+		require.True(t, db.Closed())
+	})
+}
+
+func TestHandlerName(t *testing.T) {
+	require.Equal(t, "octobetest_test.CreateUser", octobetest.HandlerName(CreateUser("")))
+	require.Equal(t, "octobetest_test.TestHandlerName", octobetest.HandlerName(TestHandlerName))
+	require.Equal(t, "octobetest_test.TestHandlerName", octobetest.HandlerName(func() {}))
+	require.Equal(t, "octobe.Handler[int,string]", octobetest.HandlerName(octobe.Handler[int, string](nil)))
+}
@@ -0,0 +1,230 @@
+package octobetest
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/Kansuler/octobe/v3"
+	"github.com/Kansuler/octobe/v3/driver/postgres"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// RollbackPGXPool begins a transaction on pool and returns a pool driver whose
+// sessions all run inside it. The transaction is rolled back when the test
+// ends, so integration tests can call service code against a real database
+// without cleaning up after it.
+//
+// Transactions begun with BeginTx or StartTransaction become savepoints of
+// the test transaction: committing releases the savepoint and rolling back
+// returns to it. Their transaction options are ignored, since PostgreSQL
+// fixes them when the test transaction begins. Statements of sessions opened
+// with Begin run in a savepoint of their own, so one that fails does not
+// abort the test transaction, just as it would not abort anything outside a
+// transaction.
+//
+// All sessions share the connection of the test transaction, so the driver
+// must not be used from several goroutines at once.
+func RollbackPGXPool(t testing.TB, pool postgres.PGXPool) postgres.PGXPoolDriver {
+	t.Helper()
+	tx := begin(t, func(ctx context.Context) (pgx.Tx, error) {
+		return pool.BeginTx(ctx, pgx.TxOptions{})
+	})
+	db, err := octobe.New(postgres.OpenPGXWithPool(&rollbackPool{conn: &rollbackConn{tx: tx}}))
+	if err != nil {
+		t.Fatalf("open rollback-only driver: %v", err)
+	}
+	return db
+}
+
+// RollbackPGX is RollbackPGXPool for a single connection.
+func RollbackPGX(t testing.TB, conn postgres.PGXConn) postgres.PGXDriver {
+	t.Helper()
+	tx := begin(t, func(ctx context.Context) (pgx.Tx, error) {
+		return conn.BeginTx(ctx, pgx.TxOptions{})
+	})
+	db, err := octobe.New(postgres.OpenPGXWithConn(&rollbackConn{tx: tx}))
+	if err != nil {
+		t.Fatalf("open rollback-only driver: %v", err)
+	}
+	return db
+}
+
+// begin begins the test transaction and rolls it back when the test ends.
+func begin(t testing.TB, beginTx func(context.Context) (pgx.Tx, error)) pgx.Tx {
+	t.Helper()
+	ctx := context.Background()
+	tx, err := beginTx(ctx)
+	if err != nil {
+		t.Fatalf("begin test transaction: %v", err)
+	}
+	t.Cleanup(func() {
+		if err := tx.Rollback(ctx); err != nil && !errors.Is(err, pgx.ErrTxClosed) {
+			t.Errorf("roll back test transaction: %v", err)
+		}
+	})
+	return tx
+}
+
+// rollbackConn runs a driver on the test transaction. It serves as the
+// connection of a PGX driver, and as the pinned connection of the sessions of
+// a pool driver.
+type rollbackConn struct {
+	tx pgx.Tx
+}
+
+var (
+	_ postgres.PGXConn            = (*rollbackConn)(nil)
+	_ postgres.PGXPoolSessionConn = (*rollbackConn)(nil)
+)
+
+// Close leaves the test transaction open until the test ends.
+func (c *rollbackConn) Close(context.Context) error {
+	return nil
+}
+
+// Release leaves the test transaction open until the test ends.
+func (c *rollbackConn) Release() {}
+
+func (c *rollbackConn) Prepare(ctx context.Context, name, sql string) (*pgconn.StatementDescription, error) {
+	return c.tx.Prepare(ctx, name, sql)
+}
+
+func (c *rollbackConn) Deallocate(ctx context.Context, name string) error {
+	return c.tx.Conn().Deallocate(ctx, name)
+}
+
+func (c *rollbackConn) DeallocateAll(ctx context.Context) error {
+	return c.tx.Conn().DeallocateAll(ctx)
+}
+
+func (c *rollbackConn) Ping(ctx context.Context) error {
+	return c.tx.Conn().Ping(ctx)
+}
+
+func (c *rollbackConn) PgConn() *pgconn.PgConn {
+	return c.tx.Conn().PgConn()
+}
+
+func (c *rollbackConn) Config() *pgx.ConnConfig {
+	return c.tx.Conn().Config()
+}
+
+func (c *rollbackConn) Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error) {
+	sp, err := c.tx.Begin(ctx)
+	if err != nil {
+		return pgconn.CommandTag{}, err
+	}
+	tag, err := sp.Exec(ctx, sql, args...)
+	return tag, release(ctx, sp, err)
+}
+
+func (c *rollbackConn) Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error) {
+	sp, err := c.tx.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := sp.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, release(ctx, sp, err)
+	}
+	return &savepointRows{Rows: rows, ctx: ctx, sp: sp}, nil
+}
+
+func (c *rollbackConn) QueryRow(ctx context.Context, sql string, args ...any) pgx.Row {
+	return &savepointRow{ctx: ctx, conn: c, sql: sql, args: args}
+}
+
+func (c *rollbackConn) SendBatch(ctx context.Context, b *pgx.Batch) pgx.BatchResults {
+	return c.tx.SendBatch(ctx, b)
+}
+
+// Begin begins a savepoint.
+func (c *rollbackConn) Begin(ctx context.Context) (pgx.Tx, error) {
+	return c.tx.Begin(ctx)
+}
+
+// BeginTx begins a savepoint, ignoring the options.
+func (c *rollbackConn) BeginTx(ctx context.Context, _ pgx.TxOptions) (pgx.Tx, error) {
+	return c.tx.Begin(ctx)
+}
+
+func (c *rollbackConn) CopyFrom(ctx context.Context, table pgx.Identifier, columns []string, src pgx.CopyFromSource) (int64, error) {
+	return c.tx.CopyFrom(ctx, table, columns, src)
+}
+
+// release releases the savepoint of a statement that succeeded and rolls
+// back to it after one that failed, returning err.
+func release(ctx context.Context, sp pgx.Tx, err error) error {
+	if err != nil {
+		_ = sp.Rollback(ctx)
+		return err
+	}
+	return sp.Commit(ctx)
+}
+
+// savepointRow runs QueryRow in a savepoint when it is scanned.
+type savepointRow struct {
+	ctx  context.Context
+	conn *rollbackConn
+	sql  string
+	args []any
+}
+
+func (r *savepointRow) Scan(dest ...any) error {
+	sp, err := r.conn.tx.Begin(r.ctx)
+	if err != nil {
+		return err
+	}
+	return release(r.ctx, sp, sp.QueryRow(r.ctx, r.sql, r.args...).Scan(dest...))
+}
+
+// savepointRows ends the savepoint of a query when its rows are closed.
+type savepointRows struct {
+	pgx.Rows
+	ctx    context.Context
+	sp     pgx.Tx
+	closed bool
+}
+
+func (r *savepointRows) Close() {
+	r.Rows.Close()
+	if !r.closed {
+		r.closed = true
+		_ = release(r.ctx, r.sp, r.Rows.Err())
+	}
+}
+
+// rollbackPool runs a pool driver on the test transaction.
+type rollbackPool struct {
+	conn *rollbackConn
+}
+
+var (
+	_ postgres.PGXPool                = (*rollbackPool)(nil)
+	_ postgres.PGXPoolSessionAcquirer = (*rollbackPool)(nil)
+)
+
+// Close leaves the test transaction open until the test ends.
+func (p *rollbackPool) Close() {}
+
+// Acquire is not supported: a pool connection cannot share the test
+// transaction. The driver acquires sessions with AcquireSession instead.
+func (p *rollbackPool) Acquire(context.Context) (*pgxpool.Conn, error) {
+	return nil, errors.New("octobetest: Acquire is not supported by rollback-only drivers")
+}
+
+func (p *rollbackPool) AcquireSession(context.Context) (postgres.PGXPoolSessionConn, error) {
+	return p.conn, nil
+}
+
+// BeginTx begins a savepoint, ignoring the options.
+func (p *rollbackPool) BeginTx(ctx context.Context, opts pgx.TxOptions) (pgx.Tx, error) {
+	return p.conn.BeginTx(ctx, opts)
+}
+
+func (p *rollbackPool) Ping(ctx context.Context) error {
+	return p.conn.Ping(ctx)
+}
@@ -0,0 +1,136 @@
+package octobetest_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/Kansuler/octobe/v3"
+	"github.com/Kansuler/octobe/v3/driver/postgres"
+	"github.com/Kansuler/octobe/v3/driver/postgres/fakeserver"
+	"github.com/Kansuler/octobe/v3/driver/postgres/mock"
+	"github.com/Kansuler/octobe/v3/octobetest"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	insertUserSQL = `INSERT INTO users (name) VALUES ($1) RETURNING id, name`
+	deleteUserSQL = `DELETE FROM users WHERE id = $1`
+)
+
+func TestRollbackPGXPool(t *testing.T) {
+	ctx := context.Background()
+	srv := fakeserver.NewT(t)
+	pool, err := pgxpool.New(ctx, srv.DSN())
+	require.NoError(t, err)
+	t.Cleanup(pool.Close)
+
+	srv.ExpectBegin()
+	db := octobetest.RollbackPGXPool(t, pool)
+
+	t.Run("transactions become savepoints", func(t *testing.T) {
+		srv.Expect("savepoint sp_1")
+		srv.Expect(insertUserSQL).WithArgs("alice").WillReturnRows([]string{"id", "name"}, []any{1, "alice"})
+		srv.Expect("release savepoint sp_1")
+
+		var user User
+		err := db.StartTransaction(ctx, func(session octobe.BuilderSession[postgres.Builder]) error {
+			var err error
+			user, err = octobe.Execute(session, CreateUser("alice"))
+			return err
+		}, postgres.WithPGXTxOptions(postgres.PGXTxOptions{IsoLevel: pgx.Serializable}))
+		require.NoError(t, err)
+		require.Equal(t, User{ID: 1, Name: "alice"}, user)
+	})
+
+	t.Run("rolled back transactions return to the savepoint", func(t *testing.T) {
+		srv.Expect("savepoint sp_2")
+		srv.Expect(insertUserSQL).WithArgs("alice").WillReturnError(mock.UniqueViolation("users_name_key"))
+		srv.Expect("rollback to savepoint sp_2")
+
+		err := db.StartTransaction(ctx, func(session octobe.BuilderSession[postgres.Builder]) error {
+			_, err := octobe.Execute(session, CreateUser("alice"))
+			return err
+		})
+		var pgErr *pgconn.PgError
+		require.ErrorAs(t, err, &pgErr)
+		require.Equal(t, "23505", pgErr.Code)
+	})
+
+	t.Run("failed statements outside transactions", func(t *testing.T) {
+		srv.Expect("savepoint sp_3")
+		srv.Expect(deleteUserSQL).WithArgs(1).WillReturnError(mock.ForeignKeyViolation("posts", "posts_user_id_fkey"))
+		srv.Expect("rollback to savepoint sp_3")
+		srv.Expect("savepoint sp_4")
+		srv.Expect(insertUserSQL).WithArgs("bob").WillReturnRows([]string{"id", "name"}, []any{2, "bob"})
+		srv.Expect("release savepoint sp_4")
+
+		session, err := db.Begin(ctx)
+		require.NoError(t, err)
+		defer session.Close()
+
+		// The failure does not abort the test transaction.
+		require.Error(t, octobe.ExecuteVoid(session, DeleteUser(1)))
+		user, err := octobe.Execute(session, CreateUser("bob"))
+		require.NoError(t, err)
+		require.Equal(t, User{ID: 2, Name: "bob"}, user)
+	})
+
+	t.Run("closing the driver keeps the transaction open", func(t *testing.T) {
+		require.NoError(t, db.Close(ctx))
+		require.NoError(t, db.Ping(ctx))
+		require.NoError(t, srv.AllExpectationsMet())
+	})
+
+	// The test transaction is rolled back when the test ends.
+	srv.ExpectRollback()
+}
+
+func TestRollbackPGX(t *testing.T) {
+	ctx := context.Background()
+	srv := fakeserver.NewT(t)
+	conn, err := pgx.Connect(ctx, srv.DSN())
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close(ctx) })
+
+	srv.ExpectBegin()
+	db := octobetest.RollbackPGX(t, conn)
+
+	srv.Expect("savepoint sp_1")
+	srv.Expect(`SELECT id, name FROM users`).WillReturnRows([]string{"id", "name"}, []any{1, "alice"}, []any{2, "bob"})
+	srv.Expect("release savepoint sp_1")
+	srv.Expect("savepoint sp_2")
+	srv.Expect(deleteUserSQL).WithArgs(2)
+	srv.Expect("rollback to savepoint sp_2")
+
+	session, err := db.Begin(ctx)
+	require.NoError(t, err)
+	var names []string
+	err = session.Builder()(`SELECT id, name FROM users`).Query(func(rows postgres.Rows) error {
+		for rows.Next() {
+			var user User
+			if err := rows.Scan(&user.ID, &user.Name); err != nil {
+				return err
+			}
+			names = append(names, user.Name)
+		}
+		return rows.Err()
+	})
+	require.NoError(t, err)
+	require.Equal(t, []string{"alice", "bob"}, names)
+	require.NoError(t, session.Close())
+
+	boom := errors.New("boom")
+	err = db.StartTransaction(ctx, func(session octobe.BuilderSession[postgres.Builder]) error {
+		if err := octobe.ExecuteVoid(session, DeleteUser(2)); err != nil {
+			return err
+		}
+		return boom
+	})
+	require.ErrorIs(t, err, boom)
+
+	srv.ExpectRollback()
+}
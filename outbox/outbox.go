@@ -0,0 +1,323 @@
+// Package outbox implements the transactional outbox pattern on top of the
+// PostgreSQL driver.
+//
+// Handlers enqueue events with Enqueue inside the same StartTransaction as the
+// business write, so an event is stored if and only if the write commits. A
+// Relay later claims pending events with FOR UPDATE SKIP LOCKED, hands them to a
+// Publisher, and marks them delivered or schedules a retry with backoff.
+//
+// Basic usage:
+//
+//	box := outbox.New()
+//
+//	err := db.StartTransaction(ctx, func(session octobe.BuilderSession[postgres.Builder]) error {
+//	    user, err := octobe.Execute(session, CreateUser("Alice"))
+//	    if err != nil {
+//	        return err
+//	    }
+//
+//	    _, err = octobe.Execute(session, box.Enqueue(outbox.Message{
+//	        AggregateKey: fmt.Sprintf("user:%d", user.ID),
+//	        Topic:        "user.created",
+//	        Payload:      payload,
+//	    }))
+//	    return err
+//	})
+//
+// Ordering is guaranteed per aggregate key: a relay only claims the oldest
+// undelivered event of each key, so a later event is never published before an
+// earlier one with the same key has been delivered, or has exhausted the
+// attempts allowed by WithMaxAttempts.
+package outbox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Kansuler/octobe/v3"
+	"github.com/Kansuler/octobe/v3/driver/postgres"
+)
+
+// DefaultTable is the outbox table name used when WithTable is not set.
+const DefaultTable = "octobe_outbox"
+
+var (
+	// ErrAggregateKeyRequired is returned by Enqueue for a message without an aggregate key.
+	ErrAggregateKeyRequired = errors.New("outbox: aggregate key is required")
+	// ErrTopicRequired is returned by Enqueue for a message without a topic.
+	ErrTopicRequired = errors.New("outbox: topic is required")
+)
+
+type (
+	// Option configures the outbox and its relay.
+	Option = octobe.Option[Config]
+)
+
+// Config stores outbox options.
+type Config struct {
+	table       string
+	batchSize   int
+	maxAttempts int
+	backoff     Backoff
+}
+
+// Backoff returns how long to wait before retrying an event that has failed attempts times.
+type Backoff func(attempts int) time.Duration
+
+// ExponentialBackoff returns a Backoff that doubles base for every failed attempt, capped at max.
+func ExponentialBackoff(base, max time.Duration) Backoff {
+	return func(attempts int) time.Duration {
+		if attempts < 1 {
+			attempts = 1
+		}
+		d := base
+		for i := 1; i < attempts; i++ {
+			d *= 2
+			if d >= max || d <= 0 {
+				return max
+			}
+		}
+		if d > max {
+			return max
+		}
+		return d
+	}
+}
+
+// WithTable stores events in the given table instead of DefaultTable.
+// The name may be schema qualified, for example "events.outbox".
+func WithTable(table string) Option {
+	return func(c *Config) {
+		c.table = table
+	}
+}
+
+// WithBatchSize sets the maximum number of events a relay claims per poll.
+func WithBatchSize(size int) Option {
+	return func(c *Config) {
+		c.batchSize = size
+	}
+}
+
+// WithMaxAttempts stops retrying an event once it has failed attempts times.
+// Events that exhausted their attempts stay in the table with their last error,
+// and later events with the same aggregate key are delivered without them.
+// Zero, the default, retries forever.
+func WithMaxAttempts(attempts int) Option {
+	return func(c *Config) {
+		c.maxAttempts = attempts
+	}
+}
+
+// WithBackoff configures the delay between retries of a failed event.
+func WithBackoff(backoff Backoff) Option {
+	return func(c *Config) {
+		c.backoff = backoff
+	}
+}
+
+func newConfig(opts []Option) Config {
+	cfg := Config{
+		table:     DefaultTable,
+		batchSize: 100,
+		backoff:   ExponentialBackoff(time.Second, 5*time.Minute),
+	}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	return cfg
+}
+
+// Message is an event to enqueue.
+type Message struct {
+	// AggregateKey groups events that must be published in order, e.g. "order:42".
+	AggregateKey string
+	// Topic tells the publisher where the event should go.
+	Topic string
+	// Payload is the encoded event body.
+	Payload []byte
+}
+
+// Event is a stored outbox row handed to a Publisher.
+type Event struct {
+	ID           int64
+	AggregateKey string
+	Topic        string
+	Payload      []byte
+	Attempts     int
+	CreatedAt    time.Time
+}
+
+// Outbox builds handlers that read and write the outbox table.
+type Outbox struct {
+	cfg Config
+}
+
+// New creates an Outbox with the given options.
+func New(opts ...Option) *Outbox {
+	return &Outbox{cfg: newConfig(opts)}
+}
+
+// Table returns the quoted table name used in SQL statements.
+func (o *Outbox) Table() string {
+	return quoteTable(o.cfg.table)
+}
+
+// Schema returns the DDL that creates the outbox table and its indexes.
+func (o *Outbox) Schema() string {
+	table := o.Table()
+	index := quoteIdentifier(strings.ReplaceAll(o.cfg.table, ".", "_") + "_pending_idx")
+	cleanup := quoteIdentifier(strings.ReplaceAll(o.cfg.table, ".", "_") + "_delivered_idx")
+	return fmt.Sprintf(`
+		CREATE TABLE IF NOT EXISTS %[1]s (
+			id BIGSERIAL PRIMARY KEY,
+			aggregate_key TEXT NOT NULL,
+			topic TEXT NOT NULL,
+			payload BYTEA NOT NULL,
+			attempts INTEGER NOT NULL DEFAULT 0,
+			last_error TEXT,
+			created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+			available_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+			delivered_at TIMESTAMPTZ
+		);
+
+		CREATE INDEX IF NOT EXISTS %[2]s ON %[1]s (aggregate_key, id) WHERE delivered_at IS NULL;
+
+		CREATE INDEX IF NOT EXISTS %[3]s ON %[1]s (delivered_at) WHERE delivered_at IS NOT NULL;`,
+		table, index, cleanup)
+}
+
+// Migrate creates the outbox table if it does not exist.
+func (o *Outbox) Migrate() octobe.Handler[octobe.Void, postgres.Builder] {
+	return func(builder postgres.Builder) (octobe.Void, error) {
+		_, err := builder(o.Schema()).Exec()
+		return nil, err
+	}
+}
+
+// Enqueue stores msg in the outbox and returns its id. Execute it in the same
+// transaction as the write that produced the event.
+func (o *Outbox) Enqueue(msg Message) octobe.Handler[int64, postgres.Builder] {
+	return func(builder postgres.Builder) (int64, error) {
+		if msg.AggregateKey == "" {
+			return 0, ErrAggregateKeyRequired
+		}
+		if msg.Topic == "" {
+			return 0, ErrTopicRequired
+		}
+
+		var id int64
+		query := builder(fmt.Sprintf(`
+			INSERT INTO %s (aggregate_key, topic, payload)
+			VALUES ($1, $2, $3)
+			RETURNING id`, o.Table()))
+		err := query.Arguments(msg.AggregateKey, msg.Topic, payload(msg.Payload)).QueryRow(&id)
+		return id, err
+	}
+}
+
+// claim locks up to limit publishable events. Only the oldest undelivered event
+// of every aggregate key is eligible, which keeps delivery ordered per key even
+// with several relays polling concurrently. Events that exhausted their
+// attempts are skipped and no longer hold back later events of their key. The
+// query is canceled when ctx ends if the segment supports it.
+func (o *Outbox) claim(ctx context.Context, limit int) octobe.Handler[[]Event, postgres.Builder] {
+	return func(builder postgres.Builder) ([]Event, error) {
+		query := builder(fmt.Sprintf(`
+			SELECT o.id, o.aggregate_key, o.topic, o.payload, o.attempts, o.created_at
+			FROM %[1]s o
+			WHERE o.delivered_at IS NULL
+				AND o.available_at <= NOW()
+				AND ($2 = 0 OR o.attempts < $2)
+				AND NOT EXISTS (
+					SELECT 1
+					FROM %[1]s prior
+					WHERE prior.aggregate_key = o.aggregate_key
+						AND prior.delivered_at IS NULL
+						AND ($2 = 0 OR prior.attempts < $2)
+						AND prior.id < o.id
+				)
+			ORDER BY o.id
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED`, o.Table()))
+		if segment, ok := query.(postgres.ContextSegment); ok {
+			query = segment.WithContext(ctx)
+		}
+
+		var events []Event
+		err := query.Arguments(limit, o.cfg.maxAttempts).Query(func(rows postgres.Rows) error {
+			for rows.Next() {
+				var event Event
+				if err := rows.Scan(&event.ID, &event.AggregateKey, &event.Topic,
+					&event.Payload, &event.Attempts, &event.CreatedAt); err != nil {
+					return err
+				}
+				events = append(events, event)
+			}
+			return rows.Err()
+		})
+		return events, err
+	}
+}
+
+// markDelivered flags the given events as delivered.
+func (o *Outbox) markDelivered(ids []int64) octobe.Handler[octobe.Void, postgres.Builder] {
+	return func(builder postgres.Builder) (octobe.Void, error) {
+		query := builder(fmt.Sprintf(`
+			UPDATE %s
+			SET delivered_at = NOW(), last_error = NULL
+			WHERE id = ANY($1)`, o.Table()))
+		_, err := query.Arguments(ids).Exec()
+		return nil, err
+	}
+}
+
+// scheduleRetry records a failed attempt and postpones the event by delay.
+func (o *Outbox) scheduleRetry(id int64, delay time.Duration, cause error) octobe.Handler[octobe.Void, postgres.Builder] {
+	return func(builder postgres.Builder) (octobe.Void, error) {
+		query := builder(fmt.Sprintf(`
+			UPDATE %s
+			SET attempts = attempts + 1,
+				last_error = $2,
+				available_at = NOW() + make_interval(secs => $3)
+			WHERE id = $1`, o.Table()))
+		_, err := query.Arguments(id, cause.Error(), delay.Seconds()).Exec()
+		return nil, err
+	}
+}
+
+// Cleanup deletes events that were delivered more than olderThan ago and
+// returns the number of deleted rows.
+func (o *Outbox) Cleanup(olderThan time.Duration) octobe.Handler[int64, postgres.Builder] {
+	return func(builder postgres.Builder) (int64, error) {
+		query := builder(fmt.Sprintf(`
+			DELETE FROM %s
+			WHERE delivered_at IS NOT NULL
+				AND delivered_at < NOW() - make_interval(secs => $1)`, o.Table()))
+		res, err := query.Arguments(olderThan.Seconds()).Exec()
+		return res.RowsAffected, err
+	}
+}
+
+// payload never sends a nil slice, which pgx would encode as NULL.
+func payload(p []byte) []byte {
+	if p == nil {
+		return []byte{}
+	}
+	return p
+}
+
+func quoteTable(table string) string {
+	parts := strings.Split(table, ".")
+	for i, part := range parts {
+		parts[i] = quoteIdentifier(part)
+	}
+	return strings.Join(parts, ".")
+}
+
+func quoteIdentifier(identifier string) string {
+	return `"` + strings.ReplaceAll(identifier, `"`, `""`) + `"`
+}
@@ -0,0 +1,152 @@
+package outbox_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Kansuler/octobe/v3"
+	"github.com/Kansuler/octobe/v3/driver/postgres"
+	"github.com/Kansuler/octobe/v3/driver/postgres/mock"
+	"github.com/Kansuler/octobe/v3/outbox"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEnqueueInsideStartTransaction(t *testing.T) {
+	ctx := context.Background()
+	m := mock.NewPGXPoolMock()
+	db, err := octobe.New(postgres.OpenPGXWithPool(m))
+	require.NoError(t, err)
+
+	box := outbox.New()
+
+	m.ExpectBeginTx()
+	m.ExpectExec("INSERT INTO users").Contains().WithArgs("alice").WillReturnResult(mock.NewResult("INSERT", 1))
+	m.ExpectQueryRow(`INSERT INTO "octobe_outbox" (aggregate_key, topic, payload)`).Contains().
+		WithArgs("user:1", "user.created", []byte(`{"name":"alice"}`)).
+		WillReturnRow(mock.NewRow(int64(7)))
+	m.ExpectCommit()
+
+	var id int64
+	err = db.StartTransaction(ctx, func(session octobe.BuilderSession[postgres.Builder]) error {
+		_, err := session.Builder()(`INSERT INTO users (name) VALUES ($1)`).Arguments("alice").Exec()
+		if err != nil {
+			return err
+		}
+
+		id, err = octobe.Execute(session, box.Enqueue(outbox.Message{
+			AggregateKey: "user:1",
+			Topic:        "user.created",
+			Payload:      []byte(`{"name":"alice"}`),
+		}))
+		return err
+	})
+	require.NoError(t, err)
+	require.Equal(t, int64(7), id)
+	require.NoError(t, m.AllExpectationsMet())
+}
+
+func TestEnqueueValidatesMessage(t *testing.T) {
+	ctx := context.Background()
+	m := mock.NewPGXPoolMock()
+	db, err := octobe.New(postgres.OpenPGXWithPool(m))
+	require.NoError(t, err)
+
+	box := outbox.New()
+
+	m.ExpectBeginTx()
+	m.ExpectRollback()
+	m.ExpectBeginTx()
+	m.ExpectRollback()
+
+	err = db.StartTransaction(ctx, func(session octobe.BuilderSession[postgres.Builder]) error {
+		_, err := octobe.Execute(session, box.Enqueue(outbox.Message{Topic: "user.created"}))
+		return err
+	})
+	require.ErrorIs(t, err, outbox.ErrAggregateKeyRequired)
+
+	err = db.StartTransaction(ctx, func(session octobe.BuilderSession[postgres.Builder]) error {
+		_, err := octobe.Execute(session, box.Enqueue(outbox.Message{AggregateKey: "user:1"}))
+		return err
+	})
+	require.ErrorIs(t, err, outbox.ErrTopicRequired)
+	require.NoError(t, m.AllExpectationsMet())
+}
+
+func TestEnqueueEmptyPayload(t *testing.T) {
+	ctx := context.Background()
+	m := mock.NewPGXPoolMock()
+	db, err := octobe.New(postgres.OpenPGXWithPool(m))
+	require.NoError(t, err)
+
+	m.ExpectBeginTx()
+	m.ExpectQueryRow(`INSERT INTO "octobe_outbox"`).Contains().
+		WithArgs("user:1", "user.deleted", []byte{}).
+		WillReturnRow(mock.NewRow(int64(1)))
+	m.ExpectCommit()
+
+	err = db.StartTransaction(ctx, func(session octobe.BuilderSession[postgres.Builder]) error {
+		_, err := octobe.Execute(session, outbox.New().Enqueue(outbox.Message{
+			AggregateKey: "user:1",
+			Topic:        "user.deleted",
+		}))
+		return err
+	})
+	require.NoError(t, err)
+	require.NoError(t, m.AllExpectationsMet())
+}
+
+func TestMigrateUsesConfiguredTable(t *testing.T) {
+	ctx := context.Background()
+	m := mock.NewPGXPoolMock()
+	db, err := octobe.New(postgres.OpenPGXWithPool(m))
+	require.NoError(t, err)
+
+	box := outbox.New(outbox.WithTable("events.outbox"))
+	require.Equal(t, `"events"."outbox"`, box.Table())
+	require.Contains(t, box.Schema(), `CREATE TABLE IF NOT EXISTS "events"."outbox"`)
+	require.Contains(t, box.Schema(), `"events_outbox_pending_idx"`)
+
+	m.ExpectBeginTx()
+	m.ExpectExec(box.Schema()).WillReturnResult(mock.NewResult("CREATE TABLE", 0))
+	m.ExpectCommit()
+
+	err = db.StartTransaction(ctx, func(session octobe.BuilderSession[postgres.Builder]) error {
+		return octobe.ExecuteVoid(session, box.Migrate())
+	})
+	require.NoError(t, err)
+	require.NoError(t, m.AllExpectationsMet())
+}
+
+func TestCleanup(t *testing.T) {
+	ctx := context.Background()
+	m := mock.NewPGXPoolMock()
+	db, err := octobe.New(postgres.OpenPGXWithPool(m))
+	require.NoError(t, err)
+
+	m.ExpectBeginTx()
+	m.ExpectExec(`DELETE FROM "octobe_outbox"`).Contains().WithArgs(float64(3600)).
+		WillReturnResult(mock.NewResult("DELETE", 12))
+	m.ExpectCommit()
+
+	var deleted int64
+	err = db.StartTransaction(ctx, func(session octobe.BuilderSession[postgres.Builder]) error {
+		var err error
+		deleted, err = octobe.Execute(session, outbox.New().Cleanup(time.Hour))
+		return err
+	})
+	require.NoError(t, err)
+	require.Equal(t, int64(12), deleted)
+	require.NoError(t, m.AllExpectationsMet())
+}
+
+func TestExponentialBackoff(t *testing.T) {
+	backoff := outbox.ExponentialBackoff(time.Second, 10*time.Second)
+
+	require.Equal(t, time.Second, backoff(0))
+	require.Equal(t, time.Second, backoff(1))
+	require.Equal(t, 2*time.Second, backoff(2))
+	require.Equal(t, 8*time.Second, backoff(4))
+	require.Equal(t, 10*time.Second, backoff(5))
+	require.Equal(t, 10*time.Second, backoff(100))
+}
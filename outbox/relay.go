@@ -0,0 +1,174 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Kansuler/octobe/v3"
+	"github.com/Kansuler/octobe/v3/driver/postgres"
+)
+
+// Publisher delivers claimed events to a message broker or any other consumer.
+//
+// Publish is called with events ordered by id, holding the row locks of the
+// claiming transaction. Returning nil marks every event delivered. Returning a
+// *PublishError retries only the events it lists and marks the rest delivered.
+// Any other error retries the whole batch.
+type Publisher interface {
+	Publish(ctx context.Context, events []Event) error
+}
+
+// PublisherFunc adapts an ordinary function to the Publisher interface.
+type PublisherFunc func(ctx context.Context, events []Event) error
+
+// Publish calls f(ctx, events).
+func (f PublisherFunc) Publish(ctx context.Context, events []Event) error {
+	return f(ctx, events)
+}
+
+// PublishError reports which events of a batch failed to publish.
+type PublishError struct {
+	// Failed maps event ids to the error that prevented their delivery.
+	Failed map[int64]error
+}
+
+// Error implements the error interface.
+func (e *PublishError) Error() string {
+	return fmt.Sprintf("outbox: %d events failed to publish", len(e.Failed))
+}
+
+// Transactor starts transactions on a PostgreSQL driver. Both postgres.PGXDriver
+// and postgres.PGXPoolDriver implement it.
+type Transactor interface {
+	StartTransaction(ctx context.Context, fn func(session octobe.BuilderSession[postgres.Builder]) error, opts ...postgres.Option) error
+}
+
+// Relay moves events from the outbox table to a Publisher.
+type Relay struct {
+	db        Transactor
+	outbox    *Outbox
+	publisher Publisher
+}
+
+// NewRelay creates a relay that polls the outbox described by opts through db.
+func NewRelay(db Transactor, publisher Publisher, opts ...Option) *Relay {
+	return &Relay{
+		db:        db,
+		outbox:    New(opts...),
+		publisher: publisher,
+	}
+}
+
+// RunOnce claims one batch of events, publishes it and records the outcome in
+// a single transaction. It returns the number of events that were delivered.
+//
+// ctx bounds claiming and publishing. Once the publisher has returned, the
+// outcome is recorded and committed even if ctx has ended meanwhile, so that
+// published events are not published again after a shutdown.
+func (r *Relay) RunOnce(ctx context.Context) (int, error) {
+	if r.publisher == nil {
+		return 0, errors.New("outbox: publisher is nil")
+	}
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	var delivered int
+	err := r.db.StartTransaction(context.WithoutCancel(ctx), func(session octobe.BuilderSession[postgres.Builder]) error {
+		events, err := octobe.Execute(session, r.outbox.claim(ctx, r.outbox.cfg.batchSize))
+		if err != nil {
+			return fmt.Errorf("claim events: %w", err)
+		}
+		if len(events) == 0 {
+			return nil
+		}
+
+		failed := failedEvents(events, r.publisher.Publish(ctx, events))
+
+		ids := make([]int64, 0, len(events))
+		for _, event := range events {
+			cause, ok := failed[event.ID]
+			if !ok {
+				ids = append(ids, event.ID)
+				continue
+			}
+
+			delay := r.outbox.cfg.backoff(event.Attempts + 1)
+			if err := octobe.ExecuteVoid(session, r.outbox.scheduleRetry(event.ID, delay, cause)); err != nil {
+				return fmt.Errorf("schedule retry of event %d: %w", event.ID, err)
+			}
+		}
+
+		if len(ids) > 0 {
+			if err := octobe.ExecuteVoid(session, r.outbox.markDelivered(ids)); err != nil {
+				return fmt.Errorf("mark events delivered: %w", err)
+			}
+		}
+
+		delivered = len(ids)
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return delivered, nil
+}
+
+// Run polls the outbox every interval until ctx is done. A poll that delivers a
+// full batch is followed immediately by another one. Errors are passed to
+// onError, which may be nil, and do not stop the relay.
+func (r *Relay) Run(ctx context.Context, interval time.Duration, onError func(error)) error {
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-timer.C:
+		}
+
+		delivered, err := r.RunOnce(ctx)
+		if err != nil && onError != nil && ctx.Err() == nil {
+			onError(err)
+		}
+
+		if err == nil && delivered >= r.outbox.cfg.batchSize {
+			timer.Reset(0)
+			continue
+		}
+		timer.Reset(interval)
+	}
+}
+
+// Cleanup deletes events that were delivered more than olderThan ago.
+func (r *Relay) Cleanup(ctx context.Context, olderThan time.Duration) (int64, error) {
+	var deleted int64
+	err := r.db.StartTransaction(ctx, func(session octobe.BuilderSession[postgres.Builder]) error {
+		var err error
+		deleted, err = octobe.Execute(session, r.outbox.Cleanup(olderThan))
+		return err
+	})
+	return deleted, err
+}
+
+// failedEvents maps the publisher result to the set of events that must be retried.
+func failedEvents(events []Event, err error) map[int64]error {
+	if err == nil {
+		return nil
+	}
+
+	var publishErr *PublishError
+	if errors.As(err, &publishErr) {
+		return publishErr.Failed
+	}
+
+	failed := make(map[int64]error, len(events))
+	for _, event := range events {
+		failed[event.ID] = err
+	}
+	return failed
+}
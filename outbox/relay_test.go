@@ -0,0 +1,225 @@
+package outbox_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Kansuler/octobe/v3"
+	"github.com/Kansuler/octobe/v3/driver/postgres"
+	"github.com/Kansuler/octobe/v3/driver/postgres/mock"
+	"github.com/Kansuler/octobe/v3/outbox"
+	"github.com/stretchr/testify/require"
+)
+
+var claimColumns = []string{"id", "aggregate_key", "topic", "payload", "attempts", "created_at"}
+
+func TestRelayRunOnceDeliversBatch(t *testing.T) {
+	ctx := context.Background()
+	m := mock.NewPGXPoolMock()
+	db, err := octobe.New(postgres.OpenPGXWithPool(m))
+	require.NoError(t, err)
+
+	created := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	m.ExpectBeginTx()
+	m.ExpectQuery("FOR UPDATE SKIP LOCKED").Contains().WithArgs(10, 0).WillReturnRows(
+		mock.NewRows(claimColumns).
+			AddRow(int64(1), "order:1", "order.created", []byte("a"), 0, created).
+			AddRow(int64(2), "order:2", "order.created", []byte("b"), 0, created),
+	)
+	m.ExpectExec("SET delivered_at = NOW()").Contains().WithArgs([]int64{1, 2}).
+		WillReturnResult(mock.NewResult("UPDATE", 2))
+	m.ExpectCommit()
+
+	var published []outbox.Event
+	relay := outbox.NewRelay(db, outbox.PublisherFunc(func(_ context.Context, events []outbox.Event) error {
+		published = events
+		return nil
+	}), outbox.WithBatchSize(10))
+
+	delivered, err := relay.RunOnce(ctx)
+	require.NoError(t, err)
+	require.Equal(t, 2, delivered)
+	require.Equal(t, []outbox.Event{
+		{ID: 1, AggregateKey: "order:1", Topic: "order.created", Payload: []byte("a"), CreatedAt: created},
+		{ID: 2, AggregateKey: "order:2", Topic: "order.created", Payload: []byte("b"), CreatedAt: created},
+	}, published)
+	require.NoError(t, m.AllExpectationsMet())
+}
+
+func TestRelayRunOnceEmpty(t *testing.T) {
+	ctx := context.Background()
+	m := mock.NewPGXPoolMock()
+	db, err := octobe.New(postgres.OpenPGXWithPool(m))
+	require.NoError(t, err)
+
+	m.ExpectBeginTx()
+	m.ExpectQuery("FOR UPDATE SKIP LOCKED").Contains().WillReturnRows(mock.NewRows(claimColumns))
+	m.ExpectCommit()
+
+	relay := outbox.NewRelay(db, outbox.PublisherFunc(func(context.Context, []outbox.Event) error {
+		t.Fatal("publisher must not be called without events")
+		return nil
+	}))
+
+	delivered, err := relay.RunOnce(ctx)
+	require.NoError(t, err)
+	require.Zero(t, delivered)
+	require.NoError(t, m.AllExpectationsMet())
+}
+
+func TestRelayRunOnceSchedulesRetryForFailedEvents(t *testing.T) {
+	ctx := context.Background()
+	m := mock.NewPGXPoolMock()
+	db, err := octobe.New(postgres.OpenPGXWithPool(m))
+	require.NoError(t, err)
+
+	created := time.Now()
+	m.ExpectBeginTx()
+	m.ExpectQuery("FOR UPDATE SKIP LOCKED").Contains().WithArgs(100, 5).WillReturnRows(
+		mock.NewRows(claimColumns).
+			AddRow(int64(1), "order:1", "order.created", []byte("a"), 0, created).
+			AddRow(int64(2), "order:2", "order.created", []byte("b"), 2, created),
+	)
+	m.ExpectExec("SET attempts = attempts + 1").Contains().WithArgs(int64(2), "broker unavailable", float64(12)).
+		WillReturnResult(mock.NewResult("UPDATE", 1))
+	m.ExpectExec("SET delivered_at = NOW()").Contains().WithArgs([]int64{1}).
+		WillReturnResult(mock.NewResult("UPDATE", 1))
+	m.ExpectCommit()
+
+	relay := outbox.NewRelay(db, outbox.PublisherFunc(func(context.Context, []outbox.Event) error {
+		return &outbox.PublishError{Failed: map[int64]error{2: errors.New("broker unavailable")}}
+	}),
+		outbox.WithMaxAttempts(5),
+		outbox.WithBackoff(func(attempts int) time.Duration {
+			return time.Duration(attempts) * 4 * time.Second
+		}),
+	)
+
+	delivered, err := relay.RunOnce(ctx)
+	require.NoError(t, err)
+	require.Equal(t, 1, delivered)
+	require.NoError(t, m.AllExpectationsMet())
+}
+
+func TestRelayRunOnceRetriesWholeBatchOnError(t *testing.T) {
+	ctx := context.Background()
+	m := mock.NewPGXPoolMock()
+	db, err := octobe.New(postgres.OpenPGXWithPool(m))
+	require.NoError(t, err)
+
+	created := time.Now()
+	m.ExpectBeginTx()
+	m.ExpectQuery("FOR UPDATE SKIP LOCKED").Contains().WillReturnRows(
+		mock.NewRows(claimColumns).
+			AddRow(int64(1), "order:1", "order.created", []byte("a"), 0, created).
+			AddRow(int64(2), "order:2", "order.created", []byte("b"), 0, created),
+	)
+	m.ExpectExec("SET attempts = attempts + 1").Contains().WithArgs(int64(1), "timeout", float64(1)).
+		WillReturnResult(mock.NewResult("UPDATE", 1))
+	m.ExpectExec("SET attempts = attempts + 1").Contains().WithArgs(int64(2), "timeout", float64(1)).
+		WillReturnResult(mock.NewResult("UPDATE", 1))
+	m.ExpectCommit()
+
+	relay := outbox.NewRelay(db, outbox.PublisherFunc(func(context.Context, []outbox.Event) error {
+		return errors.New("timeout")
+	}))
+
+	delivered, err := relay.RunOnce(ctx)
+	require.NoError(t, err)
+	require.Zero(t, delivered)
+	require.NoError(t, m.AllExpectationsMet())
+}
+
+func TestRelayRunOnceRollsBackOnClaimError(t *testing.T) {
+	ctx := context.Background()
+	m := mock.NewPGXPoolMock()
+	db, err := octobe.New(postgres.OpenPGXWithPool(m))
+	require.NoError(t, err)
+
+	expectedErr := errors.New("relation does not exist")
+	m.ExpectBeginTx()
+	m.ExpectQuery("FOR UPDATE SKIP LOCKED").Contains().WillReturnError(expectedErr)
+	m.ExpectRollback()
+
+	relay := outbox.NewRelay(db, outbox.PublisherFunc(func(context.Context, []outbox.Event) error {
+		return nil
+	}))
+
+	_, err = relay.RunOnce(ctx)
+	require.ErrorIs(t, err, expectedErr)
+	require.NoError(t, m.AllExpectationsMet())
+}
+
+func TestRelayRunOnceRecordsPublishedEventsAfterCancel(t *testing.T) {
+	m := mock.NewPGXPoolMock()
+	db, err := octobe.New(postgres.OpenPGXWithPool(m))
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	m.ExpectBeginTx()
+	m.ExpectQuery("FOR UPDATE SKIP LOCKED").Contains().WillReturnRows(
+		mock.NewRows(claimColumns).AddRow(int64(1), "order:1", "order.created", []byte("a"), 0, time.Now()),
+	)
+	m.ExpectExec("SET delivered_at = NOW()").Contains().WithArgs([]int64{1}).
+		WillReturnResult(mock.NewResult("UPDATE", 1))
+	m.ExpectCommit()
+
+	// A shutdown while publishing must not roll back the delivery of events
+	// the publisher accepted.
+	relay := outbox.NewRelay(db, outbox.PublisherFunc(func(context.Context, []outbox.Event) error {
+		cancel()
+		return nil
+	}))
+
+	delivered, err := relay.RunOnce(ctx)
+	require.NoError(t, err)
+	require.Equal(t, 1, delivered)
+	require.NoError(t, m.AllExpectationsMet())
+
+	_, err = relay.RunOnce(ctx)
+	require.ErrorIs(t, err, context.Canceled)
+}
+
+func TestRelayRunOnceSkipsDeadEventsInOrdering(t *testing.T) {
+	m := mock.NewPGXPoolMock()
+	db, err := octobe.New(postgres.OpenPGXWithPool(m))
+	require.NoError(t, err)
+
+	m.ExpectBeginTx()
+	m.ExpectQuery("AND ($2 = 0 OR prior.attempts < $2)").Contains().WithArgs(100, 3).WillReturnRows(mock.NewRows(claimColumns))
+	m.ExpectCommit()
+
+	relay := outbox.NewRelay(db, outbox.PublisherFunc(func(context.Context, []outbox.Event) error {
+		return nil
+	}), outbox.WithMaxAttempts(3))
+
+	_, err = relay.RunOnce(context.Background())
+	require.NoError(t, err)
+	require.NoError(t, m.AllExpectationsMet())
+}
+
+func TestRelayRunStopsWithContext(t *testing.T) {
+	m := mock.NewPGXPoolMock()
+	db, err := octobe.New(postgres.OpenPGXWithPool(m))
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	m.ExpectBeginTx()
+	m.ExpectQuery("FOR UPDATE SKIP LOCKED").Contains().WillReturnRows(
+		mock.NewRows(claimColumns).AddRow(int64(1), "order:1", "order.created", []byte("a"), 0, time.Now()),
+	)
+	m.ExpectExec("SET delivered_at = NOW()").Contains().WithArgs([]int64{1}).
+		WillReturnResult(mock.NewResult("UPDATE", 1))
+	m.ExpectCommit()
+
+	relay := outbox.NewRelay(db, outbox.PublisherFunc(func(context.Context, []outbox.Event) error {
+		cancel()
+		return nil
+	}))
+
+	err = relay.Run(ctx, time.Hour, nil)
+	require.ErrorIs(t, err, context.Canceled)
+	require.NoError(t, m.AllExpectationsMet())
+}
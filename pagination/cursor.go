@@ -0,0 +1,88 @@
+package pagination
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// cursor is the decoded form of an opaque page cursor.
+type cursor struct {
+	// Values holds the sort key of the row the page starts after.
+	Values []any `json:"v"`
+	// Backward is set for cursors that fetch the previous page.
+	Backward bool `json:"b,omitempty"`
+}
+
+// encodeCursor serializes c as base64url JSON, followed by an HMAC-SHA256
+// signature when key is set.
+func encodeCursor(c cursor, key []byte) (string, error) {
+	payload, err := json.Marshal(c)
+	if err != nil {
+		return "", fmt.Errorf("pagination: encode cursor: %w", err)
+	}
+
+	encoded := base64.RawURLEncoding.EncodeToString(payload)
+	if len(key) == 0 {
+		return encoded, nil
+	}
+	return encoded + "." + base64.RawURLEncoding.EncodeToString(sign(payload, key)), nil
+}
+
+// decodeCursor parses and, when key is set, verifies a cursor created by encodeCursor.
+//
+// Numbers are decoded to their decimal string form, which pgx sends in text
+// format so PostgreSQL parses them as the type of the compared column. Times
+// are already strings in RFC 3339 format.
+func decodeCursor(s string, key []byte) (cursor, error) {
+	encoded, signature, signed := strings.Cut(s, ".")
+	if len(key) > 0 && !signed {
+		return cursor{}, fmt.Errorf("%w: missing signature", ErrInvalidCursor)
+	}
+	if len(key) == 0 && signed {
+		return cursor{}, fmt.Errorf("%w: unexpected signature", ErrInvalidCursor)
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return cursor{}, fmt.Errorf("%w: %w", ErrInvalidCursor, err)
+	}
+
+	if signed {
+		mac, err := base64.RawURLEncoding.DecodeString(signature)
+		if err != nil {
+			return cursor{}, fmt.Errorf("%w: %w", ErrInvalidCursor, err)
+		}
+		if !hmac.Equal(mac, sign(payload, key)) {
+			return cursor{}, fmt.Errorf("%w: signature mismatch", ErrInvalidCursor)
+		}
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(payload))
+	decoder.UseNumber()
+
+	var c cursor
+	if err := decoder.Decode(&c); err != nil {
+		return cursor{}, fmt.Errorf("%w: %w", ErrInvalidCursor, err)
+	}
+	for i, value := range c.Values {
+		switch v := value.(type) {
+		case json.Number:
+			c.Values[i] = v.String()
+		case string, bool, nil:
+		default:
+			return cursor{}, fmt.Errorf("%w: unsupported sort value %T", ErrInvalidCursor, value)
+		}
+	}
+	return c, nil
+}
+
+func sign(payload, key []byte) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write(payload)
+	return mac.Sum(nil)
+}
@@ -0,0 +1,54 @@
+package pagination
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	created := time.Date(2024, 3, 2, 12, 30, 0, 500, time.UTC)
+
+	encoded, err := encodeCursor(cursor{Values: []any{created, 42, "alice", true, nil}, Backward: true}, nil)
+	require.NoError(t, err)
+
+	decoded, err := decodeCursor(encoded, nil)
+	require.NoError(t, err)
+	require.True(t, decoded.Backward)
+	require.Equal(t, []any{"2024-03-02T12:30:00.0000005Z", "42", "alice", true, nil}, decoded.Values)
+}
+
+func TestCursorSignature(t *testing.T) {
+	key := []byte("secret")
+
+	encoded, err := encodeCursor(cursor{Values: []any{1}}, key)
+	require.NoError(t, err)
+
+	_, err = decodeCursor(encoded, key)
+	require.NoError(t, err)
+
+	_, err = decodeCursor(encoded, []byte("other"))
+	require.ErrorIs(t, err, ErrInvalidCursor)
+
+	unsigned, err := encodeCursor(cursor{Values: []any{1}}, nil)
+	require.NoError(t, err)
+	_, err = decodeCursor(unsigned, key)
+	require.ErrorIs(t, err, ErrInvalidCursor)
+}
+
+func TestCursorRejectsGarbage(t *testing.T) {
+	for _, s := range []string{"!!!", "bm90IGpzb24", "eyJ2Ijpbe31dfQ"} {
+		_, err := decodeCursor(s, nil)
+		require.ErrorIs(t, err, ErrInvalidCursor, s)
+	}
+}
+
+func TestKeysetCondition(t *testing.T) {
+	require.Equal(t, `"id" > $1`, keysetCondition([]string{`"id"`}, []string{"$1"}, []bool{false}))
+	require.Equal(t, `("a", "b") < ($1, $2)`, keysetCondition([]string{`"a"`, `"b"`}, []string{"$1", "$2"}, []bool{true, true}))
+	require.Equal(t,
+		`(("a" > $1) OR ("a" = $1 AND "b" < $2) OR ("a" = $1 AND "b" = $2 AND "c" > $3))`,
+		keysetCondition([]string{`"a"`, `"b"`, `"c"`}, []string{"$1", "$2", "$3"}, []bool{false, true, false}),
+	)
+}
@@ -0,0 +1,248 @@
+// Package pagination implements keyset (cursor) pagination for PostgreSQL handlers.
+//
+// A base query is wrapped in a sub-select, filtered with a row-value comparison
+// against the sort key of the cursor and limited to one row more than the page
+// size to detect whether another page exists. Cursors are opaque strings that
+// can optionally be signed with an HMAC key to make them tamper-evident.
+//
+// Example:
+//
+//	func ListPosts(cursor string) octobe.Handler[pagination.Page[Post], postgres.Builder] {
+//	    return pagination.Query(pagination.Request{
+//	        Query:  `SELECT id, title, created_at FROM posts WHERE author_id = $1`,
+//	        Args:   []any{authorID},
+//	        Sort:   []pagination.Sort{pagination.Desc("created_at"), pagination.Desc("id")},
+//	        Limit:  20,
+//	        Cursor: cursor,
+//	    }, scanPost, func(p Post) []any { return []any{p.CreatedAt, p.ID} })
+//	}
+package pagination
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/Kansuler/octobe/v3"
+	"github.com/Kansuler/octobe/v3/driver/postgres"
+	"github.com/jackc/pgx/v5"
+)
+
+// ErrInvalidCursor is returned when a cursor cannot be decoded, does not match
+// the sort columns of the request, or fails signature verification.
+var ErrInvalidCursor = errors.New("invalid pagination cursor")
+
+type (
+	// Option configures pagination behavior.
+	Option = octobe.Option[Config]
+)
+
+// Config stores pagination options.
+type Config struct {
+	key []byte
+}
+
+// WithSigningKey signs cursors with HMAC-SHA256 using key and rejects cursors
+// with a missing or invalid signature.
+func WithSigningKey(key []byte) Option {
+	return func(c *Config) {
+		c.key = key
+	}
+}
+
+// Sort is a column of the sort key and its direction.
+type Sort struct {
+	// Column is the name of a column returned by the base query.
+	Column string
+	// Descending sorts the column from high to low.
+	Descending bool
+}
+
+// Asc sorts column in ascending order.
+func Asc(column string) Sort {
+	return Sort{Column: column}
+}
+
+// Desc sorts column in descending order.
+func Desc(column string) Sort {
+	return Sort{Column: column, Descending: true}
+}
+
+// Request describes a page to fetch.
+type Request struct {
+	// Query is the base SELECT statement. It must not contain ORDER BY or LIMIT,
+	// and it must return every column listed in Sort.
+	Query string
+	// Args are the arguments of the base query, referenced as $1..$n.
+	Args []any
+	// Sort is the ordered sort key. It must be unique across rows, so it
+	// usually ends with the primary key.
+	Sort []Sort
+	// Limit is the page size.
+	Limit int
+	// Cursor is a Next or Previous cursor of an earlier page, or empty for the first page.
+	Cursor string
+}
+
+// Page is one page of results.
+type Page[T any] struct {
+	Items []T
+	// Next fetches the page after this one, empty when this is the last page.
+	Next string
+	// Previous fetches the page before this one, empty when this is the first page.
+	Previous string
+}
+
+// Query returns a handler that fetches the page described by req. scan reads
+// one item from the current row, and key returns the values of the sort
+// columns for an item, in the order of req.Sort.
+func Query[T any](req Request, scan func(postgres.Rows) (T, error), key func(T) []any, opts ...Option) octobe.Handler[Page[T], postgres.Builder] {
+	return func(builder postgres.Builder) (Page[T], error) {
+		var cfg Config
+		for _, opt := range opts {
+			opt(&cfg)
+		}
+
+		if len(req.Sort) == 0 {
+			return Page[T]{}, errors.New("pagination: at least one sort column is required")
+		}
+		if req.Limit <= 0 {
+			return Page[T]{}, errors.New("pagination: limit must be positive")
+		}
+
+		var c *cursor
+		if req.Cursor != "" {
+			decoded, err := decodeCursor(req.Cursor, cfg.key)
+			if err != nil {
+				return Page[T]{}, err
+			}
+			if len(decoded.Values) != len(req.Sort) {
+				return Page[T]{}, fmt.Errorf("%w: expected %d sort values, got %d", ErrInvalidCursor, len(req.Sort), len(decoded.Values))
+			}
+			c = &decoded
+		}
+
+		backward := c != nil && c.Backward
+		query, args := buildQuery(req, c)
+
+		var items []T
+		err := builder(query).Arguments(args...).Query(func(rows postgres.Rows) error {
+			for rows.Next() {
+				item, err := scan(rows)
+				if err != nil {
+					return err
+				}
+				items = append(items, item)
+			}
+			return rows.Err()
+		})
+		if err != nil {
+			return Page[T]{}, err
+		}
+
+		hasMore := len(items) > req.Limit
+		if hasMore {
+			items = items[:req.Limit]
+		}
+		if backward {
+			slices.Reverse(items)
+		}
+
+		page := Page[T]{Items: items}
+		if len(items) == 0 {
+			return page, nil
+		}
+
+		first, last := key(items[0]), key(items[len(items)-1])
+		if len(first) != len(req.Sort) || len(last) != len(req.Sort) {
+			return Page[T]{}, fmt.Errorf("pagination: key returned %d values for %d sort columns", len(first), len(req.Sort))
+		}
+
+		switch {
+		case backward:
+			page.Next, err = encodeCursor(cursor{Values: last}, cfg.key)
+			if err == nil && hasMore {
+				page.Previous, err = encodeCursor(cursor{Values: first, Backward: true}, cfg.key)
+			}
+		default:
+			if hasMore {
+				page.Next, err = encodeCursor(cursor{Values: last}, cfg.key)
+			}
+			if err == nil && c != nil {
+				page.Previous, err = encodeCursor(cursor{Values: first, Backward: true}, cfg.key)
+			}
+		}
+		if err != nil {
+			return Page[T]{}, err
+		}
+
+		return page, nil
+	}
+}
+
+// buildQuery wraps the base query with the keyset filter, ordering and limit.
+// Backward pages invert every direction and are reversed after scanning.
+func buildQuery(req Request, c *cursor) (string, []any) {
+	args := slices.Clone(req.Args)
+	backward := c != nil && c.Backward
+
+	columns := make([]string, len(req.Sort))
+	orders := make([]string, len(req.Sort))
+	descending := make([]bool, len(req.Sort))
+	for i, sort := range req.Sort {
+		columns[i] = pgx.Identifier{sort.Column}.Sanitize()
+		descending[i] = sort.Descending != backward
+		if descending[i] {
+			orders[i] = columns[i] + " DESC"
+		} else {
+			orders[i] = columns[i] + " ASC"
+		}
+	}
+
+	var where string
+	if c != nil {
+		placeholders := make([]string, len(c.Values))
+		for i, value := range c.Values {
+			args = append(args, value)
+			placeholders[i] = fmt.Sprintf("$%d", len(args))
+		}
+		where = "\nWHERE " + keysetCondition(columns, placeholders, descending)
+	}
+
+	args = append(args, req.Limit+1)
+	query := fmt.Sprintf("SELECT * FROM (%s) AS octobe_page%s\nORDER BY %s\nLIMIT $%d",
+		req.Query, where, strings.Join(orders, ", "), len(args))
+	return query, args
+}
+
+// keysetCondition selects the rows that sort after the cursor. When every column
+// has the same direction a single row-value comparison is used so PostgreSQL can
+// use a composite index; mixed directions are expanded into a disjunction.
+func keysetCondition(columns, placeholders []string, descending []bool) string {
+	if !slices.Contains(descending, !descending[0]) {
+		op := ">"
+		if descending[0] {
+			op = "<"
+		}
+		if len(columns) == 1 {
+			return fmt.Sprintf("%s %s %s", columns[0], op, placeholders[0])
+		}
+		return fmt.Sprintf("(%s) %s (%s)", strings.Join(columns, ", "), op, strings.Join(placeholders, ", "))
+	}
+
+	terms := make([]string, len(columns))
+	for i := range columns {
+		parts := make([]string, 0, i+1)
+		for j := 0; j < i; j++ {
+			parts = append(parts, fmt.Sprintf("%s = %s", columns[j], placeholders[j]))
+		}
+		op := ">"
+		if descending[i] {
+			op = "<"
+		}
+		parts = append(parts, fmt.Sprintf("%s %s %s", columns[i], op, placeholders[i]))
+		terms[i] = "(" + strings.Join(parts, " AND ") + ")"
+	}
+	return "(" + strings.Join(terms, " OR ") + ")"
+}
@@ -0,0 +1,211 @@
+package pagination_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Kansuler/octobe/v3"
+	"github.com/Kansuler/octobe/v3/driver/postgres"
+	"github.com/Kansuler/octobe/v3/driver/postgres/mock"
+	"github.com/Kansuler/octobe/v3/pagination"
+	"github.com/stretchr/testify/require"
+)
+
+type post struct {
+	ID        int
+	CreatedAt time.Time
+}
+
+func scanPost(rows postgres.Rows) (post, error) {
+	var p post
+	err := rows.Scan(&p.ID, &p.CreatedAt)
+	return p, err
+}
+
+func postKey(p post) []any {
+	return []any{p.CreatedAt, p.ID}
+}
+
+func listPosts(cursor string, opts ...pagination.Option) octobe.Handler[pagination.Page[post], postgres.Builder] {
+	return pagination.Query(pagination.Request{
+		Query:  `SELECT id, created_at FROM posts WHERE author_id = $1`,
+		Args:   []any{7},
+		Sort:   []pagination.Sort{pagination.Desc("created_at"), pagination.Desc("id")},
+		Limit:  2,
+		Cursor: cursor,
+	}, scanPost, postKey, opts...)
+}
+
+func fetch[T any](t *testing.T, m *mock.PGXPoolMock, handler octobe.Handler[pagination.Page[T], postgres.Builder]) (pagination.Page[T], error) {
+	t.Helper()
+
+	db, err := octobe.New(postgres.OpenPGXWithPool(m))
+	require.NoError(t, err)
+
+	var page pagination.Page[T]
+	err = db.StartTransaction(context.Background(), func(session octobe.BuilderSession[postgres.Builder]) error {
+		var err error
+		page, err = octobe.Execute(session, handler)
+		return err
+	})
+	return page, err
+}
+
+func TestQueryFirstPage(t *testing.T) {
+	m := mock.NewPGXPoolMock()
+	t1 := time.Date(2024, 3, 3, 0, 0, 0, 0, time.UTC)
+	t2 := time.Date(2024, 3, 2, 0, 0, 0, 0, time.UTC)
+	t3 := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	m.ExpectBeginTx()
+	m.ExpectQuery("SELECT * FROM (SELECT id, created_at FROM posts WHERE author_id = $1) AS octobe_page\n"+
+		"ORDER BY \"created_at\" DESC, \"id\" DESC\n"+
+		"LIMIT $2").
+		WithArgs(7, 3).
+		WillReturnRows(mock.NewRows([]string{"id", "created_at"}).AddRow(3, t1).AddRow(2, t2).AddRow(1, t3))
+	m.ExpectCommit()
+
+	page, err := fetch(t, m, listPosts(""))
+	require.NoError(t, err)
+	require.Equal(t, []post{{3, t1}, {2, t2}}, page.Items)
+	require.NotEmpty(t, page.Next)
+	require.Empty(t, page.Previous)
+	require.NoError(t, m.AllExpectationsMet())
+}
+
+func TestQueryNextAndPreviousPage(t *testing.T) {
+	t2 := time.Date(2024, 3, 2, 0, 0, 0, 0, time.UTC)
+	t3 := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	m := mock.NewPGXPoolMock()
+	m.ExpectBeginTx()
+	m.ExpectQuery("SELECT id, created_at FROM posts").Contains().
+		WithArgs(7, 3).
+		WillReturnRows(mock.NewRows([]string{"id", "created_at"}).AddRow(3, t2).AddRow(2, t2).AddRow(1, t3))
+	m.ExpectCommit()
+
+	first, err := fetch(t, m, listPosts(""))
+	require.NoError(t, err)
+	require.NoError(t, m.AllExpectationsMet())
+
+	m = mock.NewPGXPoolMock()
+	m.ExpectBeginTx()
+	m.ExpectQuery("WHERE (\"created_at\", \"id\") < ($2, $3)\nORDER BY \"created_at\" DESC, \"id\" DESC\nLIMIT $4").Contains().
+		WithArgs(7, "2024-03-02T00:00:00Z", "2", 3).
+		WillReturnRows(mock.NewRows([]string{"id", "created_at"}).AddRow(1, t3))
+	m.ExpectCommit()
+
+	second, err := fetch(t, m, listPosts(first.Next))
+	require.NoError(t, err)
+	require.Equal(t, []post{{1, t3}}, second.Items)
+	require.Empty(t, second.Next)
+	require.NotEmpty(t, second.Previous)
+	require.NoError(t, m.AllExpectationsMet())
+
+	m = mock.NewPGXPoolMock()
+	m.ExpectBeginTx()
+	m.ExpectQuery("WHERE (\"created_at\", \"id\") > ($2, $3)\nORDER BY \"created_at\" ASC, \"id\" ASC\nLIMIT $4").Contains().
+		WithArgs(7, "2024-03-01T00:00:00Z", "1", 3).
+		WillReturnRows(mock.NewRows([]string{"id", "created_at"}).AddRow(2, t2).AddRow(3, t2))
+	m.ExpectCommit()
+
+	previous, err := fetch(t, m, listPosts(second.Previous))
+	require.NoError(t, err)
+	require.Equal(t, []post{{3, t2}, {2, t2}}, previous.Items)
+	require.NotEmpty(t, previous.Next)
+	require.Empty(t, previous.Previous)
+	require.NoError(t, m.AllExpectationsMet())
+}
+
+type player struct {
+	Name  string
+	Score int
+}
+
+func listPlayers(cursor string) octobe.Handler[pagination.Page[player], postgres.Builder] {
+	return pagination.Query(pagination.Request{
+		Query:  `SELECT name, score FROM players`,
+		Sort:   []pagination.Sort{pagination.Desc("score"), pagination.Asc("name")},
+		Limit:  1,
+		Cursor: cursor,
+	}, func(rows postgres.Rows) (player, error) {
+		var p player
+		err := rows.Scan(&p.Name, &p.Score)
+		return p, err
+	}, func(p player) []any { return []any{p.Score, p.Name} })
+}
+
+func TestQueryMixedDirections(t *testing.T) {
+	m := mock.NewPGXPoolMock()
+	m.ExpectBeginTx()
+	m.ExpectQuery("ORDER BY \"score\" DESC, \"name\" ASC").Contains().
+		WithArgs(2).
+		WillReturnRows(mock.NewRows([]string{"name", "score"}).AddRow("alice", 10).AddRow("bob", 10))
+	m.ExpectCommit()
+
+	first, err := fetch(t, m, listPlayers(""))
+	require.NoError(t, err)
+	require.Equal(t, []player{{"alice", 10}}, first.Items)
+	require.NoError(t, m.AllExpectationsMet())
+
+	m = mock.NewPGXPoolMock()
+	m.ExpectBeginTx()
+	m.ExpectQuery("WHERE ((\"score\" < $1) OR (\"score\" = $1 AND \"name\" > $2))").Contains().
+		WithArgs("10", "alice", 2).
+		WillReturnRows(mock.NewRows([]string{"name", "score"}).AddRow("bob", 10))
+	m.ExpectCommit()
+
+	second, err := fetch(t, m, listPlayers(first.Next))
+	require.NoError(t, err)
+	require.Equal(t, []player{{"bob", 10}}, second.Items)
+	require.Empty(t, second.Next)
+	require.NoError(t, m.AllExpectationsMet())
+}
+
+func TestQuerySignedCursor(t *testing.T) {
+	key := []byte("secret")
+	t1 := time.Date(2024, 3, 3, 0, 0, 0, 0, time.UTC)
+
+	m := mock.NewPGXPoolMock()
+	m.ExpectBeginTx()
+	m.ExpectQuery("SELECT id, created_at FROM posts").Contains().
+		WillReturnRows(mock.NewRows([]string{"id", "created_at"}).AddRow(3, t1).AddRow(2, t1).AddRow(1, t1))
+	m.ExpectCommit()
+
+	page, err := fetch(t, m, listPosts("", pagination.WithSigningKey(key)))
+	require.NoError(t, err)
+	require.Contains(t, page.Next, ".")
+
+	tampered := "x" + page.Next[1:]
+	for _, tc := range []struct {
+		name   string
+		cursor string
+		opts   []pagination.Option
+	}{
+		{"tampered", tampered, []pagination.Option{pagination.WithSigningKey(key)}},
+		{"wrong key", page.Next, []pagination.Option{pagination.WithSigningKey([]byte("other"))}},
+		{"unsigned", "eyJ2IjpbXX0", []pagination.Option{pagination.WithSigningKey(key)}},
+		{"signed without key", page.Next, nil},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			m := mock.NewPGXPoolMock()
+			m.ExpectBeginTx()
+			m.ExpectRollback()
+
+			_, err := fetch(t, m, listPosts(tc.cursor, tc.opts...))
+			require.ErrorIs(t, err, pagination.ErrInvalidCursor)
+			require.NoError(t, m.AllExpectationsMet())
+		})
+	}
+}
+
+func TestQueryRejectsInvalidRequest(t *testing.T) {
+	m := mock.NewPGXPoolMock()
+	m.ExpectBeginTx()
+	m.ExpectRollback()
+
+	_, err := fetch(t, m, pagination.Query(pagination.Request{Query: `SELECT 1`, Limit: 1}, scanPost, postKey))
+	require.ErrorContains(t, err, "at least one sort column")
+	require.NoError(t, m.AllExpectationsMet())
+}
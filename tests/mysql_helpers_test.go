@@ -0,0 +1,128 @@
+package tests
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Kansuler/octobe/v3"
+	"github.com/Kansuler/octobe/v3/driver/mysql"
+)
+
+type mysqlProduct struct {
+	ID   int64
+	Name string
+}
+
+func mysqlIntegrationDSN(t *testing.T) string {
+	t.Helper()
+
+	dsn := os.Getenv("MYSQL_DSN")
+	if dsn == "" {
+		t.Skip("MYSQL_DSN is not set; run these integration tests with docker-compose.yaml")
+	}
+
+	return dsn
+}
+
+func openMySQLWithRetry(t *testing.T, ctx context.Context, dsn string) mysql.MySQLDriver {
+	t.Helper()
+
+	deadline := time.Now().Add(8 * time.Second)
+	var lastErr error
+
+	for {
+		attemptCtx, cancel := context.WithTimeout(ctx, time.Second)
+		db, err := octobe.New(mysql.Open(attemptCtx, dsn))
+		cancel()
+		if err == nil {
+			return db
+		}
+
+		lastErr = err
+		if time.Now().After(deadline) {
+			t.Fatalf("connect mysql integration database: %v", lastErr)
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+}
+
+func migrateMySQLProducts(table string) octobe.Handler[octobe.Void, mysql.Builder] {
+	return func(builder mysql.Builder) (octobe.Void, error) {
+		_, err := builder(fmt.Sprintf(`
+			CREATE TABLE IF NOT EXISTS %s (
+				id BIGINT AUTO_INCREMENT PRIMARY KEY,
+				name VARCHAR(255) NOT NULL
+			) ENGINE = InnoDB
+		`, quoteMySQLIdentifier(table))).Exec()
+		return nil, err
+	}
+}
+
+func truncateMySQLProducts(table string) octobe.Handler[octobe.Void, mysql.Builder] {
+	return func(builder mysql.Builder) (octobe.Void, error) {
+		_, err := builder(fmt.Sprintf(`TRUNCATE TABLE %s`, quoteMySQLIdentifier(table))).Exec()
+		return nil, err
+	}
+}
+
+func dropMySQLProducts(table string) octobe.Handler[octobe.Void, mysql.Builder] {
+	return func(builder mysql.Builder) (octobe.Void, error) {
+		_, err := builder(fmt.Sprintf(`DROP TABLE IF EXISTS %s`, quoteMySQLIdentifier(table))).Exec()
+		return nil, err
+	}
+}
+
+func createMySQLProduct(table, name string) octobe.Handler[mysqlProduct, mysql.Builder] {
+	return func(builder mysql.Builder) (mysqlProduct, error) {
+		res, err := builder(fmt.Sprintf(`
+			INSERT INTO %s (name)
+			VALUES (?)
+		`, quoteMySQLIdentifier(table))).Arguments(name).Exec()
+		if err != nil {
+			return mysqlProduct{}, err
+		}
+		return mysqlProduct{ID: res.LastInsertID, Name: name}, nil
+	}
+}
+
+func mysqlProductByID(table string, id int64) octobe.Handler[mysqlProduct, mysql.Builder] {
+	return func(builder mysql.Builder) (mysqlProduct, error) {
+		var product mysqlProduct
+		err := builder(fmt.Sprintf(`
+			SELECT id, name
+			FROM %s
+			WHERE id = ?
+		`, quoteMySQLIdentifier(table))).Arguments(id).QueryRow(&product.ID, &product.Name)
+		return product, err
+	}
+}
+
+func mysqlProductsByName(table, name string) octobe.Handler[[]mysqlProduct, mysql.Builder] {
+	return func(builder mysql.Builder) ([]mysqlProduct, error) {
+		var products []mysqlProduct
+		err := builder(fmt.Sprintf(`
+			SELECT id, name
+			FROM %s
+			WHERE name = ?
+			ORDER BY id
+		`, quoteMySQLIdentifier(table))).Arguments(name).Query(func(rows mysql.Rows) error {
+			for rows.Next() {
+				var product mysqlProduct
+				if err := rows.Scan(&product.ID, &product.Name); err != nil {
+					return err
+				}
+				products = append(products, product)
+			}
+			return rows.Err()
+		})
+		return products, err
+	}
+}
+
+func quoteMySQLIdentifier(identifier string) string {
+	return "`" + strings.ReplaceAll(identifier, "`", "``") + "`"
+}
@@ -0,0 +1,127 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/Kansuler/octobe/v3"
+	"github.com/Kansuler/octobe/v3/driver/mysql"
+	"github.com/stretchr/testify/suite"
+)
+
+const mysqlProductsTable = "mysql_integration_products"
+
+type MySQLIntegrationSuite struct {
+	suite.Suite
+
+	ctx context.Context
+	db  mysql.MySQLDriver
+}
+
+func TestMySQLIntegrationSuite(t *testing.T) {
+	suite.Run(t, new(MySQLIntegrationSuite))
+}
+
+func (s *MySQLIntegrationSuite) SetupSuite() {
+	s.ctx = context.Background()
+	s.db = openMySQLWithRetry(s.T(), s.ctx, mysqlIntegrationDSN(s.T()))
+
+	// MySQL commits DDL implicitly, so schema changes run outside a transaction.
+	session, err := s.db.Begin(s.ctx)
+	s.Require().NoError(err)
+	defer func() { s.Require().NoError(session.Close()) }()
+	s.Require().NoError(octobe.ExecuteVoid(session, migrateMySQLProducts(mysqlProductsTable)))
+}
+
+func (s *MySQLIntegrationSuite) SetupTest() {
+	session, err := s.db.Begin(s.ctx)
+	s.Require().NoError(err)
+	defer func() { s.Require().NoError(session.Close()) }()
+	s.Require().NoError(octobe.ExecuteVoid(session, truncateMySQLProducts(mysqlProductsTable)))
+}
+
+func (s *MySQLIntegrationSuite) TearDownSuite() {
+	if s.db == nil {
+		return
+	}
+
+	if session, err := s.db.Begin(s.ctx); err == nil {
+		_ = octobe.ExecuteVoid(session, dropMySQLProducts(mysqlProductsTable))
+		_ = session.Close()
+	}
+	s.Require().NoError(s.db.Close(s.ctx))
+}
+
+func (s *MySQLIntegrationSuite) TestStartTransactionCommits() {
+	name := "mysql committed product"
+	var created mysqlProduct
+
+	err := s.db.StartTransaction(s.ctx, func(session octobe.BuilderSession[mysql.Builder]) error {
+		var err error
+		created, err = octobe.Execute(session, createMySQLProduct(mysqlProductsTable, name))
+		return err
+	})
+	s.Require().NoError(err)
+	s.NotZero(created.ID)
+
+	loaded, err := s.findMySQLProduct(created.ID)
+	s.Require().NoError(err)
+	s.Equal(created, loaded)
+}
+
+func (s *MySQLIntegrationSuite) TestStartTransactionRollsBackOnError() {
+	name := "mysql rolled back product"
+	expectedErr := errors.New("force rollback")
+
+	err := s.db.StartTransaction(s.ctx, func(session octobe.BuilderSession[mysql.Builder]) error {
+		_, err := octobe.Execute(session, createMySQLProduct(mysqlProductsTable, name))
+		if err != nil {
+			return err
+		}
+		return expectedErr
+	})
+	s.ErrorIs(err, expectedErr)
+
+	products, err := s.findMySQLProductsByName(name)
+	s.Require().NoError(err)
+	s.Empty(products)
+}
+
+func (s *MySQLIntegrationSuite) TestReadOnlyTransactionRejectsWrites() {
+	err := s.db.StartTransaction(s.ctx, func(session octobe.BuilderSession[mysql.Builder]) error {
+		_, err := octobe.Execute(session, createMySQLProduct(mysqlProductsTable, "mysql read only product"))
+		return err
+	}, mysql.WithTxOptions(mysql.TxOptions{Isolation: mysql.Serializable, ReadOnly: true}))
+	s.Error(err)
+}
+
+func (s *MySQLIntegrationSuite) TestManualTransactionRollsBack() {
+	name := "mysql manual rollback product"
+
+	session, err := s.db.BeginTx(s.ctx, mysql.WithTxOptions(mysql.TxOptions{Isolation: mysql.ReadCommitted}))
+	s.Require().NoError(err)
+	defer func() { _ = session.Rollback() }()
+
+	_, err = octobe.Execute(session, createMySQLProduct(mysqlProductsTable, name))
+	s.Require().NoError(err)
+	s.Require().NoError(session.Rollback())
+
+	products, err := s.findMySQLProductsByName(name)
+	s.Require().NoError(err)
+	s.Empty(products)
+}
+
+func (s *MySQLIntegrationSuite) findMySQLProduct(id int64) (mysqlProduct, error) {
+	session, err := s.db.Begin(s.ctx)
+	s.Require().NoError(err)
+	defer func() { s.Require().NoError(session.Close()) }()
+	return octobe.Execute(session, mysqlProductByID(mysqlProductsTable, id))
+}
+
+func (s *MySQLIntegrationSuite) findMySQLProductsByName(name string) ([]mysqlProduct, error) {
+	session, err := s.db.Begin(s.ctx)
+	s.Require().NoError(err)
+	defer func() { s.Require().NoError(session.Close()) }()
+	return octobe.Execute(session, mysqlProductsByName(mysqlProductsTable, name))
+}
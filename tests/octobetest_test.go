@@ -0,0 +1,56 @@
+package tests
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/Kansuler/octobe/v3"
+	"github.com/Kansuler/octobe/v3/driver/postgres"
+	"github.com/Kansuler/octobe/v3/octobetest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTemplateDatabases(t *testing.T) {
+	ctx := context.Background()
+	const table = "template_products"
+
+	template := octobetest.NewTemplate(integrationDSN(t), "octobe_products_template", func(ctx context.Context, db postgres.PGXPoolDriver) error {
+		return db.StartTransaction(ctx, func(session octobe.BuilderSession[postgres.Builder]) error {
+			if err := octobe.ExecuteVoid(session, migrateProducts(table)); err != nil {
+				return err
+			}
+			_, err := octobe.Execute(session, createProduct(table, "seed"))
+			return err
+		})
+	})
+	t.Cleanup(func() {
+		require.NoError(t, template.Close())
+	})
+
+	// Each parallel test writes to its own copy of the seeded table.
+	t.Run("group", func(t *testing.T) {
+		for i := range 4 {
+			t.Run(fmt.Sprint(i), func(t *testing.T) {
+				t.Parallel()
+				db := template.Database(t)
+
+				var product integrationProduct
+				err := db.StartTransaction(ctx, func(session octobe.BuilderSession[postgres.Builder]) error {
+					var err error
+					product, err = octobe.Execute(session, createProduct(table, fmt.Sprint("product ", i)))
+					return err
+				})
+				require.NoError(t, err)
+				require.Equal(t, 2, product.ID)
+
+				session, err := db.Begin(ctx)
+				require.NoError(t, err)
+				defer session.Close()
+				seeded, err := octobe.Execute(session, productByID(table, 1))
+				require.NoError(t, err)
+				require.Equal(t, "seed", seeded.Name)
+			})
+		}
+	})
+}
@@ -0,0 +1,140 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/Kansuler/octobe/v3"
+	"github.com/Kansuler/octobe/v3/driver/postgres"
+	"github.com/Kansuler/octobe/v3/outbox"
+	"github.com/stretchr/testify/suite"
+)
+
+const outboxTable = "outbox_integration_events"
+
+type OutboxIntegrationSuite struct {
+	suite.Suite
+
+	ctx context.Context
+	db  postgres.PGXPoolDriver
+	box *outbox.Outbox
+}
+
+func TestOutboxIntegrationSuite(t *testing.T) {
+	suite.Run(t, new(OutboxIntegrationSuite))
+}
+
+func (s *OutboxIntegrationSuite) SetupSuite() {
+	s.ctx = context.Background()
+	s.db = openPGXPoolWithRetry(s.T(), s.ctx, integrationDSN(s.T()))
+	s.box = outbox.New(outbox.WithTable(outboxTable))
+
+	err := s.db.StartTransaction(s.ctx, func(session octobe.BuilderSession[postgres.Builder]) error {
+		return octobe.ExecuteVoid(session, s.box.Migrate())
+	})
+	s.Require().NoError(err)
+}
+
+func (s *OutboxIntegrationSuite) SetupTest() {
+	err := s.db.StartTransaction(s.ctx, func(session octobe.BuilderSession[postgres.Builder]) error {
+		_, err := session.Builder()(fmt.Sprintf(`TRUNCATE TABLE %s RESTART IDENTITY`, s.box.Table())).Exec()
+		return err
+	})
+	s.Require().NoError(err)
+}
+
+func (s *OutboxIntegrationSuite) TearDownSuite() {
+	if s.db == nil {
+		return
+	}
+
+	_ = s.db.StartTransaction(s.ctx, func(session octobe.BuilderSession[postgres.Builder]) error {
+		_, err := session.Builder()(fmt.Sprintf(`DROP TABLE IF EXISTS %s`, s.box.Table())).Exec()
+		return err
+	})
+	s.Require().NoError(s.db.Close(s.ctx))
+}
+
+func (s *OutboxIntegrationSuite) enqueue(key, topic string) {
+	err := s.db.StartTransaction(s.ctx, func(session octobe.BuilderSession[postgres.Builder]) error {
+		_, err := octobe.Execute(session, s.box.Enqueue(outbox.Message{AggregateKey: key, Topic: topic, Payload: []byte(topic)}))
+		return err
+	})
+	s.Require().NoError(err)
+}
+
+func (s *OutboxIntegrationSuite) pending() int {
+	var count int
+	err := s.db.StartTransaction(s.ctx, func(session octobe.BuilderSession[postgres.Builder]) error {
+		return session.Builder()(fmt.Sprintf(`SELECT COUNT(*) FROM %s WHERE delivered_at IS NULL`, s.box.Table())).QueryRow(&count)
+	})
+	s.Require().NoError(err)
+	return count
+}
+
+func (s *OutboxIntegrationSuite) TestRolledBackTransactionDoesNotEnqueue() {
+	expectedErr := errors.New("business write failed")
+	err := s.db.StartTransaction(s.ctx, func(session octobe.BuilderSession[postgres.Builder]) error {
+		_, err := octobe.Execute(session, s.box.Enqueue(outbox.Message{AggregateKey: "order:1", Topic: "order.created"}))
+		if err != nil {
+			return err
+		}
+		return expectedErr
+	})
+	s.Require().ErrorIs(err, expectedErr)
+	s.Require().Zero(s.pending())
+}
+
+func (s *OutboxIntegrationSuite) TestRelayKeepsOrderPerAggregateKey() {
+	s.enqueue("order:1", "order.created")
+	s.enqueue("order:1", "order.paid")
+	s.enqueue("order:2", "order.created")
+
+	var topics []string
+	relay := outbox.NewRelay(s.db, outbox.PublisherFunc(func(_ context.Context, events []outbox.Event) error {
+		for _, event := range events {
+			topics = append(topics, event.AggregateKey+" "+event.Topic)
+		}
+		return nil
+	}), outbox.WithTable(outboxTable))
+
+	delivered, err := relay.RunOnce(s.ctx)
+	s.Require().NoError(err)
+	s.Require().Equal(2, delivered)
+	s.Require().Equal([]string{"order:1 order.created", "order:2 order.created"}, topics)
+
+	delivered, err = relay.RunOnce(s.ctx)
+	s.Require().NoError(err)
+	s.Require().Equal(1, delivered)
+	s.Require().Equal("order:1 order.paid", topics[2])
+	s.Require().Zero(s.pending())
+}
+
+func (s *OutboxIntegrationSuite) TestRelaySchedulesRetryAndCleansUp() {
+	s.enqueue("order:1", "order.created")
+
+	fail := true
+	relay := outbox.NewRelay(s.db, outbox.PublisherFunc(func(context.Context, []outbox.Event) error {
+		if fail {
+			return errors.New("broker unavailable")
+		}
+		return nil
+	}), outbox.WithTable(outboxTable), outbox.WithBackoff(func(int) time.Duration { return 0 }))
+
+	delivered, err := relay.RunOnce(s.ctx)
+	s.Require().NoError(err)
+	s.Require().Zero(delivered)
+	s.Require().Equal(1, s.pending())
+
+	fail = false
+	delivered, err = relay.RunOnce(s.ctx)
+	s.Require().NoError(err)
+	s.Require().Equal(1, delivered)
+
+	deleted, err := relay.Cleanup(s.ctx, -time.Minute)
+	s.Require().NoError(err)
+	s.Require().Equal(int64(1), deleted)
+}
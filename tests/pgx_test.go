@@ -4,9 +4,11 @@ import (
 	"context"
 	"errors"
 	"testing"
+	"time"
 
 	"github.com/Kansuler/octobe/v3"
 	"github.com/Kansuler/octobe/v3/driver/postgres"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/stretchr/testify/suite"
 )
 
@@ -117,6 +119,35 @@ func (s *PGXIntegrationSuite) TestManualTransactionRollsBack() {
 	s.Empty(products)
 }
 
+func (s *PGXIntegrationSuite) TestStatementContextKeepsConnection() {
+	session, err := s.db.BeginTx(s.ctx)
+	s.Require().NoError(err)
+	defer func() { _ = session.Rollback() }()
+
+	pid, err := octobe.Execute(session, backendPID())
+	s.Require().NoError(err)
+
+	stmtCtx, cancel := context.WithTimeout(s.ctx, 100*time.Millisecond)
+	defer cancel()
+	_, err = session.Builder()(`SELECT pg_sleep(10)`).(postgres.ContextSegment).WithContext(stmtCtx).Exec()
+	s.Require().ErrorIs(err, octobe.ErrStatementCanceled)
+	var pgErr *pgconn.PgError
+	s.Require().ErrorAs(err, &pgErr)
+	s.Equal("57014", pgErr.Code)
+
+	s.Require().ErrorIs(session.Commit(), octobe.ErrStatementCanceled)
+	s.Require().NoError(session.Rollback())
+
+	// The statement was canceled with a cancel request, so the driver still
+	// has its connection.
+	err = s.db.StartTransaction(s.ctx, func(session octobe.BuilderSession[postgres.Builder]) error {
+		after, err := octobe.Execute(session, backendPID())
+		s.Equal(pid, after)
+		return err
+	})
+	s.Require().NoError(err)
+}
+
 func (s *PGXIntegrationSuite) findPGXProduct(id int) (integrationProduct, error) {
 	session, err := s.db.Begin(s.ctx)
 	s.Require().NoError(err)
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"testing"
+	"time"
 
 	"github.com/Kansuler/octobe/v3"
 	"github.com/Kansuler/octobe/v3/driver/postgres"
@@ -130,6 +131,25 @@ func (s *PGXPoolIntegrationSuite) TestNonTransactionalSessionPinsConnection() {
 	s.Equal(first, second)
 }
 
+func (s *PGXPoolIntegrationSuite) TestStatementContextKeepsConnection() {
+	session, err := s.db.Begin(s.ctx)
+	s.Require().NoError(err)
+	defer func() { s.Require().NoError(session.Close()) }()
+
+	pid, err := octobe.Execute(session, backendPID())
+	s.Require().NoError(err)
+
+	stmtCtx, cancel := context.WithTimeout(s.ctx, 100*time.Millisecond)
+	defer cancel()
+	_, err = session.Builder()(`SELECT pg_sleep(10)`).(postgres.ContextSegment).WithContext(stmtCtx).Exec()
+	s.Require().ErrorIs(err, octobe.ErrStatementCanceled)
+
+	// The session keeps its pool connection after the cancel request.
+	after, err := octobe.Execute(session, backendPID())
+	s.Require().NoError(err)
+	s.Equal(pid, after)
+}
+
 func (s *PGXPoolIntegrationSuite) findPGXPoolProduct(id int) (integrationProduct, error) {
 	session, err := s.db.Begin(s.ctx)
 	s.Require().NoError(err)
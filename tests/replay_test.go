@@ -0,0 +1,67 @@
+package tests
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/Kansuler/octobe/v3"
+	"github.com/Kansuler/octobe/v3/driver/postgres"
+	"github.com/Kansuler/octobe/v3/driver/postgres/replay"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/stretchr/testify/require"
+)
+
+const replayProductsTable = "replay_integration_products"
+
+// TestReplayRoundTrip records handlers against PostgreSQL and replays the
+// cassette offline, checking that binary row data decodes the same way.
+func TestReplayRoundTrip(t *testing.T) {
+	dsn := integrationDSN(t)
+	ctx := context.Background()
+
+	scenario := func(db postgres.PGXPoolDriver) ([]integrationProduct, error) {
+		var products []integrationProduct
+		err := db.StartTransaction(ctx, func(session octobe.BuilderSession[postgres.Builder]) error {
+			if err := octobe.ExecuteVoid(session, migrateProducts(replayProductsTable)); err != nil {
+				return err
+			}
+			if err := octobe.ExecuteVoid(session, truncateProducts(replayProductsTable)); err != nil {
+				return err
+			}
+			if _, err := octobe.Execute(session, createProduct(replayProductsTable, "replayed")); err != nil {
+				return err
+			}
+			var err error
+			products, err = octobe.Execute(session, productsByName(replayProductsTable, "replayed"))
+			if err != nil {
+				return err
+			}
+			return octobe.ExecuteVoid(session, dropProducts(replayProductsTable))
+		})
+		return products, err
+	}
+
+	pool, err := pgxpool.New(ctx, dsn)
+	require.NoError(t, err)
+	defer pool.Close()
+
+	recorder := replay.NewPoolRecorder(pool)
+	db, err := octobe.New(postgres.OpenPGXWithPool(recorder))
+	require.NoError(t, err)
+	recorded, err := scenario(db)
+	require.NoError(t, err)
+
+	path := filepath.Join(t.TempDir(), "round_trip.json")
+	require.NoError(t, recorder.Save(path))
+	cassette, err := replay.Load(path)
+	require.NoError(t, err)
+
+	player := replay.NewPoolPlayer(cassette)
+	db, err = octobe.New(postgres.OpenPGXWithPool(player))
+	require.NoError(t, err)
+	replayed, err := scenario(db)
+	require.NoError(t, err)
+	require.Equal(t, recorded, replayed)
+	require.NoError(t, player.AllEventsReplayed())
+}